@@ -0,0 +1,48 @@
+// Package main reconciles user_functions with the Casbin grouping rules that assign
+// functions to users in org units.
+//
+// Usage:
+//
+//	go run ./cmd/reconcilefunctions [-dry-run]
+//
+// A running server keeps its loaded policies until it is restarted or POST
+// /api/v1/user_functions/reconcile is called.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/nomenarkt/lamina/common/database"
+	"github.com/nomenarkt/lamina/config"
+	"github.com/nomenarkt/lamina/internal/access"
+	"github.com/nomenarkt/lamina/internal/org"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the drift without fixing it")
+	flag.Parse()
+
+	config.LoadEnv()
+
+	db := database.ConnectDB()
+	defer db.Close()
+	access.InitEnforcer(os.Getenv("DATABASE_URL"))
+
+	service := org.NewService(org.NewRepository(db), access.PolicyIndex{})
+	report, err := service.Reconcile(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatalf("❌ Reconciliation failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatalf("❌ Failed to write report: %v", err)
+	}
+	log.Printf("✅ %d rules added to Casbin, %d user functions added, %d orphaned rules removed",
+		len(report.AddedToCasbin), len(report.AddedToFunctions), len(report.RemovedFromCasbin))
+}
@@ -0,0 +1,75 @@
+// Package main re-encrypts the encrypted HR columns with the active data key, e.g. after
+// a master key rotation, and fills in missing blind indexes.
+//
+// Usage:
+//
+//	go run ./cmd/reencryptfields [-batch 500] [-pause 200ms] [-prune] [-decrypt]
+//
+// Rows are rewritten in batches and only if they did not change in the meantime, so the
+// command can run while the API is serving. Rows that changed are counted as conflicts and
+// picked up by the next run. -prune deletes data keys that no longer seal any value;
+// -decrypt writes plaintext back before encryption is switched off.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nomenarkt/lamina/common/database"
+	"github.com/nomenarkt/lamina/common/fieldcrypt"
+	"github.com/nomenarkt/lamina/config"
+	"github.com/nomenarkt/lamina/internal/user"
+)
+
+func main() {
+	batch := flag.Int("batch", 500, "rows per batch")
+	pause := flag.Duration("pause", 200*time.Millisecond, "pause between batches")
+	prune := flag.Bool("prune", false, "delete data keys no longer in use")
+	decrypt := flag.Bool("decrypt", false, "write plaintext back instead of re-encrypting")
+	flag.Parse()
+
+	config.LoadEnv()
+
+	db := database.ConnectDB()
+	defer db.Close()
+
+	cfg, err := fieldcrypt.LoadConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Field encryption keys: %v", err)
+	}
+	ctx := context.Background()
+	keyring, err := fieldcrypt.Open(ctx, fieldcrypt.NewKeyStore(db), cfg)
+	if err != nil {
+		log.Fatalf("❌ Opening keyring: %v", err)
+	}
+
+	report, err := fieldcrypt.Reencrypt(ctx, db, keyring, user.EncryptedTables(), fieldcrypt.ReencryptOptions{
+		BatchSize: *batch,
+		Pause:     *pause,
+		Decrypt:   *decrypt,
+		Prune:     *prune,
+		Logf:      log.Printf,
+	})
+	if err != nil {
+		log.Fatalf("❌ Re-encryption failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatalf("❌ Failed to write report: %v", err)
+	}
+	conflicts := 0
+	for _, t := range report.Tables {
+		conflicts += t.Conflicts
+	}
+	if conflicts > 0 {
+		log.Printf("⚠️ %d rows changed during the run; run the command again", conflicts)
+		return
+	}
+	log.Printf("✅ All values sealed with data key %s", report.ActiveKey)
+}
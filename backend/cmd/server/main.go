@@ -2,8 +2,11 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"time"
 
@@ -11,13 +14,21 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/nomenarkt/lamina/common/database"
+	"github.com/nomenarkt/lamina/common/fieldcrypt"
+	"github.com/nomenarkt/lamina/common/mailer"
+	"github.com/nomenarkt/lamina/common/storage"
 	"github.com/nomenarkt/lamina/common/utils"
 	"github.com/nomenarkt/lamina/config"
 	"github.com/nomenarkt/lamina/internal/access"
 	"github.com/nomenarkt/lamina/internal/admin"
 	"github.com/nomenarkt/lamina/internal/adminaccess"
+	"github.com/nomenarkt/lamina/internal/audit"
 	"github.com/nomenarkt/lamina/internal/auth"
 	"github.com/nomenarkt/lamina/internal/crew"
+	"github.com/nomenarkt/lamina/internal/flight"
+	"github.com/nomenarkt/lamina/internal/org"
+	"github.com/nomenarkt/lamina/internal/privacy"
+	"github.com/nomenarkt/lamina/internal/qualification"
 	"github.com/nomenarkt/lamina/internal/tasks"
 	"github.com/nomenarkt/lamina/internal/user"
 )
@@ -38,6 +49,11 @@ func main() {
 	// ✅ Initialize Casbin enforcer (uses GORM)
 	access.InitEnforcer(os.Getenv("DATABASE_URL"))
 
+	// ✅ Keep policies in step across API instances over LISTEN/NOTIFY
+	if _, err := access.StartPolicyWatcher(db, os.Getenv("DATABASE_URL")); err != nil {
+		log.Printf("⚠️ Policy watcher not started, changes from other instances need a restart: %v", err)
+	}
+
 	gin.ForceConsoleColor()
 	gin.SetMode(gin.DebugMode)
 
@@ -46,42 +62,109 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", audit.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", audit.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	router.Use(audit.RequestID())
+
 	api := router.Group("/api/v1")
 
+	// ✅ Administrative actions are appended to the hash-chained audit log
+	auditService := audit.NewService(audit.NewRepository(db))
+	audit.SetRecorder(auditService)
+
+	// ✅ Emails are queued in the outbox and delivered by a background worker
+	outboxRepo := mailer.NewOutboxRepository(db)
+	outboxMailer := mailer.NewOutboxMailer(outboxRepo)
+	tasks.StartEmailOutboxTask(mailer.NewDispatcher(outboxRepo, mailer.NewTransportFromEnv()))
+
 	// ✅ Use global DB instance for auth
 	authRepo := auth.NewAuthRepository(db)
-	authService := auth.NewService(authRepo)
+	authService := auth.NewService(authRepo, outboxMailer)
 	auth.RegisterRoutes(api, db, authService)
+	auth.RegisterMFALoginRoutes(api, authService)
+
+	// ✅ Roster calendar feeds authenticate with a secret token in the URL
+	crewRepo := crew.NewRepository(db)
+	crewService := crew.NewService(crewRepo, crew.RuleSetFromEnv())
+	crewHandler := crew.NewHandler(crewService)
+	crew.RegisterPublicRoutes(api, crewHandler)
+
+	// ✅ Uploaded files; the local driver serves them here behind signed URLs
+	fileStorage, err := storage.NewFromEnv()
+	if err != nil {
+		log.Printf("⚠️ File storage not configured, profile picture uploads are disabled: %v", err)
+	}
+	if local, ok := fileStorage.(*storage.LocalStorage); ok {
+		api.GET("/files/*key", gin.WrapH(http.StripPrefix("/api/v1/files", local.Handler())))
+	}
 
-	// ✅ Secure endpoints with userRepo
-	userRepo := user.NewUserRepository(db)
-	api.Use(auth.Middleware(userRepo))
+	// ✅ Secure endpoints with userRepo; sensitive HR columns are encrypted when keys are configured
+	var userRepo user.Repo = user.NewUserRepository(db)
+	if cfg, err := fieldcrypt.LoadConfigFromEnv(); err == nil {
+		keyring, err := fieldcrypt.Open(context.Background(), fieldcrypt.NewKeyStore(db), cfg)
+		if err != nil {
+			log.Fatalf("❌ Field encryption keyring: %v", err)
+		}
+		userRepo = user.NewEncryptedRepo(userRepo, keyring)
+	} else if errors.Is(err, fieldcrypt.ErrNotConfigured) {
+		log.Printf("⚠️ FIELD_ENCRYPTION_KEYS not set, HR fields are stored in plaintext")
+	} else {
+		log.Fatalf("❌ Field encryption keys: %v", err)
+	}
+	api.Use(auth.Middleware(userRepo, authRepo), audit.CaptureActor())
 
 	{
+		// ✅ Crew, user and flight operations are checked against org-unit Casbin policies
+		orgScope := access.CasbinMiddleware(access.NewMembershipRepository(db))
+
 		userService := user.NewUserService(userRepo)
+		if fileStorage != nil {
+			userService.SetPictureStorage(fileStorage, storage.URLTTLFromEnv())
+		}
 		userHandler := user.NewUserHandler(userService)
-		user.RegisterRoutes(api, userHandler)
+		user.RegisterRoutes(api, userHandler, orgScope)
+		auth.RegisterSessionRoutes(api, authService)
+		auth.RegisterMFARoutes(api, authService)
 
 		tasks.StartUserCleanupTask(userRepo)
 
 		adminRepo := admin.NewAdminRepository(db)
 		hasher := &utils.BcryptHasher{}
-		adminService := admin.NewAdminService(adminRepo, hasher)
+		adminService := admin.NewAdminService(adminRepo, hasher, outboxMailer)
 		admin.RegisterRoutes(api, adminService)
 
-		crewRepo := crew.NewRepository(db)
-		crewService := crew.NewService(crewRepo)
-		crewHandler := crew.NewHandler(crewService)
-		crew.RegisterRoutes(api, crewHandler)
+		crew.RegisterRoutes(api, crewHandler, orgScope)
+
+		flightRepo := flight.NewRepository(db)
+		flightService := flight.NewService(flightRepo)
+		flight.RegisterRoutes(api, flight.NewHandler(flightService), orgScope)
+
+		qualificationRepo := qualification.NewRepository(db)
+		qualificationService := qualification.NewService(qualificationRepo)
+		qualification.RegisterRoutes(api, qualification.NewHandler(qualificationService))
+
+		orgRepo := org.NewRepository(db)
+		orgService := org.NewService(orgRepo, access.PolicyIndex{})
+		org.RegisterRoutes(api, org.NewHandler(orgService))
 
 		// ✅ Register Casbin-admin access control endpoints
+		adminaccess.SetRoleService(orgService)
+		adminaccess.SetPolicyCatalogue(orgService)
 		adminaccess.RegisterRoutes(api)
+
+		audit.RegisterRoutes(api, audit.NewHandler(auditService), auth.RequireRoles("admin"))
+
+		// ✅ Personal data exports and erasures, carried out once an administrator approves them
+		privacyService := privacy.NewService(privacy.NewRepository(db), userRepo, audit.NewRepository(db),
+			access.PolicyIndex{}, privacy.ExportTTLFromEnv())
+		if fileStorage != nil {
+			privacyService.SetPictureStorage(fileStorage)
+		}
+		privacy.RegisterRoutes(api, privacy.NewHandler(privacyService))
 	}
 
 	port := os.Getenv("PORT")
@@ -0,0 +1,57 @@
+// Package main imports an IATA SSIM schedule file into the flights table.
+//
+// Usage:
+//
+//	go run ./cmd/ssimimport -file schedule.ssim [-dry-run]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/nomenarkt/lamina/common/database"
+	"github.com/nomenarkt/lamina/config"
+	"github.com/nomenarkt/lamina/internal/flight"
+)
+
+func main() {
+	path := flag.String("file", "", "path to the SSIM file")
+	dryRun := flag.Bool("dry-run", false, "report the changes without writing them")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config.LoadEnv()
+
+	file, err := os.Open(*path)
+	if err != nil {
+		log.Fatalf("❌ Failed to open %s: %v", *path, err)
+	}
+	defer file.Close()
+
+	db := database.ConnectDB()
+	defer db.Close()
+
+	service := flight.NewService(flight.NewRepository(db))
+	report, err := service.ImportSSIM(context.Background(), file, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ Import failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatalf("❌ Failed to write report: %v", err)
+	}
+	log.Printf("✅ %d records, %d flights: %d created, %d updated, %d unchanged, %d rejected",
+		report.Records, report.Flights, len(report.Created), len(report.Updated), report.Unchanged, len(report.Rejected))
+	if len(report.Removed) > 0 {
+		log.Printf("⚠️ %d scheduled flights are missing from the file and were left as they are", len(report.Removed))
+	}
+}
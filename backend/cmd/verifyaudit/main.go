@@ -0,0 +1,43 @@
+// Package main checks the integrity of the audit log hash chain.
+//
+// Usage:
+//
+//	go run ./cmd/verifyaudit
+//
+// The result is printed as JSON; the command exits with status 1 when the chain is broken.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/nomenarkt/lamina/common/database"
+	"github.com/nomenarkt/lamina/config"
+	"github.com/nomenarkt/lamina/internal/audit"
+)
+
+func main() {
+	config.LoadEnv()
+
+	db := database.ConnectDB()
+	defer db.Close()
+
+	service := audit.NewService(audit.NewRepository(db))
+	result, err := service.Verify(context.Background())
+	if err != nil {
+		log.Fatalf("❌ Verification failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		log.Fatalf("❌ Failed to write result: %v", err)
+	}
+	if !result.Valid {
+		log.Printf("❌ Audit chain broken at entry %d: %s", result.BrokenAt, result.Reason)
+		os.Exit(1)
+	}
+	log.Printf("✅ %d entries verified, head %s", result.Entries, result.Head)
+}
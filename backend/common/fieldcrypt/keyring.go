@@ -0,0 +1,307 @@
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// prefix marks encrypted values: enc:v2:<data key ID>:<base64 nonce+ciphertext>. The column
+	// name and the ID of the owning user are bound to the ciphertext as associated data.
+	prefix = "enc:v2:"
+	// legacyPrefix marks values sealed before the owner was bound; only the column name is bound.
+	// They stay readable and are rewritten by the next re-encryption pass.
+	legacyPrefix = "enc:v1:"
+)
+
+// minReloadInterval is the shortest time between two key store reloads triggered by values
+// sealed with an unknown data key, so unreadable values cannot turn every read into a query.
+const minReloadInterval = 10 * time.Second
+
+var (
+	// ErrUnknownKey is returned when a value was sealed with a data key that cannot be unwrapped.
+	ErrUnknownKey = errors.New("unknown data key")
+	// ErrMalformed is returned for values that carry the prefix but cannot be parsed or opened.
+	ErrMalformed = errors.New("malformed encrypted value")
+)
+
+// Keyring encrypts and decrypts values with the unwrapped data keys and computes blind indexes.
+// It is safe for concurrent use.
+type Keyring struct {
+	store  KeyStore
+	config *Config
+
+	mu     sync.RWMutex
+	keys   map[string]cipher.AEAD
+	active string
+
+	reloadMu   sync.Mutex
+	reloadedAt time.Time
+	now        func() time.Time
+}
+
+// Open loads and unwraps the stored data keys and makes sure a data key wrapped by the active
+// master key exists, creating one if needed. Data keys wrapped by master keys that are no longer
+// configured are skipped; values sealed with them cannot be read.
+func Open(ctx context.Context, store KeyStore, cfg *Config) (*Keyring, error) {
+	k := &Keyring{store: store, config: cfg, now: time.Now}
+	if err := k.reload(ctx); err != nil {
+		return nil, err
+	}
+	if k.active != "" {
+		return k, nil
+	}
+
+	id, wrapped, err := k.newDataKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.SaveDataKey(ctx, WrappedKey{ID: id, MasterKeyID: cfg.ActiveMasterKey, Wrapped: wrapped}); err != nil {
+		return nil, fmt.Errorf("saving data key: %w", err)
+	}
+	if err := k.reload(ctx); err != nil {
+		return nil, err
+	}
+	log.Printf("🔑 Created data key %s under master key %s", id, cfg.ActiveMasterKey)
+	return k, nil
+}
+
+// reload unwraps every stored data key. The newest key wrapped by the active master key
+// becomes the active data key.
+func (k *Keyring) reload(ctx context.Context) error {
+	stored, err := k.store.LoadDataKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("loading data keys: %w", err)
+	}
+	keys := map[string]cipher.AEAD{}
+	active := ""
+	for _, w := range stored {
+		master, ok := k.config.MasterKeys[w.MasterKeyID]
+		if !ok {
+			log.Printf("⚠️ Data key %s is wrapped by unknown master key %s", w.ID, w.MasterKeyID)
+			continue
+		}
+		raw, err := open(master, w.Wrapped, []byte(w.ID))
+		if err != nil {
+			return fmt.Errorf("unwrapping data key %s: %w", w.ID, err)
+		}
+		aead, err := newGCM(raw)
+		if err != nil {
+			return err
+		}
+		keys[w.ID] = aead
+		if w.MasterKeyID == k.config.ActiveMasterKey {
+			active = w.ID // stored keys are ordered oldest first
+		}
+	}
+
+	k.mu.Lock()
+	k.keys, k.active = keys, active
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *Keyring) newDataKey() (string, []byte, error) {
+	idBytes := make([]byte, 8)
+	raw := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(idBytes)
+	wrapped, err := seal(k.config.MasterKeys[k.config.ActiveMasterKey], raw, []byte(id))
+	return id, wrapped, err
+}
+
+// ActiveKeyID returns the ID of the data key used for new values.
+func (k *Keyring) ActiveKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// Encrypt seals plaintext with the active data key. The column name and owner, the ID of the user
+// the row belongs to, are bound to the ciphertext as associated data, so a value cannot be moved
+// to another column or to another user's row. Rows that share an owner, such as one user's
+// children, can still be swapped with each other.
+func (k *Keyring) Encrypt(column string, owner int64, plaintext string) (string, error) {
+	k.mu.RLock()
+	id, aead := k.active, k.keys[k.active]
+	k.mu.RUnlock()
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), associatedData(column, owner))
+	return prefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt for the same column and owner. Values without the
+// encryption prefix are returned unchanged, so rows written before encryption was enabled stay
+// readable until re-encrypted. A data key created by another instance since the keyring was
+// loaded is fetched on first use.
+func (k *Keyring) Decrypt(ctx context.Context, column string, owner int64, value string) (string, error) {
+	v, ok := parse(value)
+	if !ok {
+		if strings.HasPrefix(value, prefix) || strings.HasPrefix(value, legacyPrefix) {
+			return "", ErrMalformed
+		}
+		return value, nil
+	}
+
+	k.mu.RLock()
+	aead, found := k.keys[v.id]
+	k.mu.RUnlock()
+	if !found {
+		if err := k.reloadFor(ctx, v.id); err != nil {
+			return "", err
+		}
+		k.mu.RLock()
+		aead, found = k.keys[v.id]
+		k.mu.RUnlock()
+		if !found {
+			return "", fmt.Errorf("%w %s", ErrUnknownKey, v.id)
+		}
+	}
+
+	additional := associatedData(column, owner)
+	if v.legacy {
+		additional = []byte(column)
+	}
+	if len(v.sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	plain, err := aead.Open(nil, v.sealed[:aead.NonceSize()], v.sealed[aead.NonceSize():], additional)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	return string(plain), nil
+}
+
+// reloadFor reloads the data keys to pick up id, at most once per minReloadInterval. Callers
+// waiting on a reload in progress share its result.
+func (k *Keyring) reloadFor(ctx context.Context, id string) error {
+	k.reloadMu.Lock()
+	defer k.reloadMu.Unlock()
+
+	k.mu.RLock()
+	_, found := k.keys[id]
+	k.mu.RUnlock()
+	if found || k.now().Sub(k.reloadedAt) < minReloadInterval {
+		return nil
+	}
+	k.reloadedAt = k.now()
+	return k.reload(ctx)
+}
+
+// IsCurrent reports whether value is encrypted with the active data key in the current format.
+func (k *Keyring) IsCurrent(value string) bool {
+	v, ok := parse(value)
+	return ok && !v.legacy && v.id == k.ActiveKeyID()
+}
+
+// BlindIndex returns a keyed hash of the normalised value for exact-match lookups. Case,
+// whitespace and punctuation are ignored, so "ab-12 34" and "AB1234" match.
+func (k *Keyring) BlindIndex(column, value string) string {
+	mac := hmac.New(sha256.New, k.config.BlindIndexKey)
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write([]byte(normalize(value)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// KeyID returns the data key ID of an encrypted value.
+func KeyID(value string) (string, bool) {
+	v, ok := parse(value)
+	return v.id, ok
+}
+
+// sealedValue is a parsed encrypted value.
+type sealedValue struct {
+	id     string
+	sealed []byte
+	legacy bool
+}
+
+func parse(value string) (sealedValue, bool) {
+	var v sealedValue
+	rest, found := strings.CutPrefix(value, prefix)
+	if !found {
+		if rest, found = strings.CutPrefix(value, legacyPrefix); !found {
+			return v, false
+		}
+		v.legacy = true
+	}
+	id, encoded, found := strings.Cut(rest, ":")
+	if !found || id == "" {
+		return v, false
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return v, false
+	}
+	v.id, v.sealed = id, sealed
+	return v, true
+}
+
+func associatedData(column string, owner int64) []byte {
+	return []byte(column + "\x00" + strconv.FormatInt(owner, 10))
+}
+
+func normalize(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(key, sealed, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+}
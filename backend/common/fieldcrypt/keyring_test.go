@@ -0,0 +1,199 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mu    sync.Mutex
+	keys  []WrappedKey
+	loads int
+}
+
+func (m *memoryStore) LoadDataKeys(context.Context) ([]WrappedKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loads++
+	return append([]WrappedKey{}, m.keys...), nil
+}
+
+func (m *memoryStore) SaveDataKey(_ context.Context, k WrappedKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = append(m.keys, k)
+	return nil
+}
+
+func (m *memoryStore) DeleteDataKey(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, k := range m.keys {
+		if k.ID == id {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func testConfig(active string, ids ...string) *Config {
+	cfg := &Config{MasterKeys: map[string][]byte{}, ActiveMasterKey: active, BlindIndexKey: bytes.Repeat([]byte{9}, 32)}
+	for _, id := range ids {
+		cfg.MasterKeys[id] = bytes.Repeat([]byte(id[len(id)-1:]), 32)
+	}
+	return cfg
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{}
+	k, err := Open(ctx, store, testConfig("m1", "m1"))
+	require.NoError(t, err)
+	require.Len(t, store.keys, 1)
+	assert.Equal(t, "m1", store.keys[0].MasterKeyID)
+
+	sealed, err := k.Encrypt("users.national_id", 7, "101 234 567 890")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "enc:v2:"+k.ActiveKeyID()+":"))
+	assert.NotContains(t, sealed, "567")
+	assert.True(t, k.IsCurrent(sealed))
+
+	plain, err := k.Decrypt(ctx, "users.national_id", 7, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "101 234 567 890", plain)
+
+	_, err = k.Decrypt(ctx, "users.spouse_name", 7, sealed)
+	assert.ErrorIs(t, err, ErrMalformed, "ciphertexts are bound to their column")
+
+	_, err = k.Decrypt(ctx, "users.national_id", 8, sealed)
+	assert.ErrorIs(t, err, ErrMalformed, "ciphertexts are bound to their owner")
+
+	plain, err = k.Decrypt(ctx, "users.national_id", 7, "legacy plaintext")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy plaintext", plain)
+}
+
+func TestKeyring_MasterKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{}
+	k1, err := Open(ctx, store, testConfig("m1", "m1"))
+	require.NoError(t, err)
+	old, err := k1.Encrypt("c", 1, "secret")
+	require.NoError(t, err)
+
+	k2, err := Open(ctx, store, testConfig("m2", "m1", "m2"))
+	require.NoError(t, err)
+	assert.Len(t, store.keys, 2)
+	assert.NotEqual(t, k1.ActiveKeyID(), k2.ActiveKeyID())
+	assert.False(t, k2.IsCurrent(old))
+
+	plain, err := k2.Decrypt(ctx, "c", 1, old)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plain)
+
+	// A keyring that has not seen the new data key picks it up on first use.
+	fresh, err := k2.Encrypt("c", 1, "new")
+	require.NoError(t, err)
+	plain, err = k1.Decrypt(ctx, "c", 1, fresh)
+	assert.ErrorIs(t, err, ErrUnknownKey, "m2 is not configured for k1")
+	assert.Empty(t, plain)
+
+	k3, err := Open(ctx, store, testConfig("m2", "m2"))
+	require.NoError(t, err)
+	_, err = k3.Decrypt(ctx, "c", 1, old)
+	assert.ErrorIs(t, err, ErrUnknownKey, "the retired master key is gone")
+}
+
+func TestKeyring_DecryptsColumnBoundValues(t *testing.T) {
+	ctx := context.Background()
+	k, err := Open(ctx, &memoryStore{}, testConfig("m1", "m1"))
+	require.NoError(t, err)
+
+	// Values written before owners were bound carry only the column as associated data.
+	aead := k.keys[k.ActiveKeyID()]
+	nonce := make([]byte, aead.NonceSize())
+	legacy := "enc:v1:" + k.ActiveKeyID() + ":" +
+		base64.RawStdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte("Hery"), []byte("users.spouse_name")))
+
+	plain, err := k.Decrypt(ctx, "users.spouse_name", 7, legacy)
+	require.NoError(t, err)
+	assert.Equal(t, "Hery", plain)
+	assert.False(t, k.IsCurrent(legacy), "re-encryption binds the owner")
+}
+
+func TestKeyring_ThrottlesReloadsForUnknownKeys(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{}
+	k, err := Open(ctx, store, testConfig("m1", "m1"))
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	k.now = func() time.Time { return now }
+	loads := store.loads
+
+	forged := "enc:v2:0123456789abcdef:" + base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+	for range 3 {
+		_, err = k.Decrypt(ctx, "c", 1, forged)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	}
+	assert.Equal(t, loads+1, store.loads, "one reload per interval")
+
+	now = now.Add(minReloadInterval)
+	_, err = k.Decrypt(ctx, "c", 1, forged)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, loads+2, store.loads)
+}
+
+func TestKeyring_BlindIndex(t *testing.T) {
+	k, err := Open(context.Background(), &memoryStore{}, testConfig("m1", "m1"))
+	require.NoError(t, err)
+
+	assert.Equal(t, k.BlindIndex("users.national_id", "ab-12 34"), k.BlindIndex("users.national_id", "AB1234"))
+	assert.NotEqual(t, k.BlindIndex("users.national_id", "AB1234"), k.BlindIndex("users.national_id", "AB1235"))
+	assert.NotEqual(t, k.BlindIndex("users.national_id", "AB1234"), k.BlindIndex("users.spouse_name", "AB1234"))
+	assert.Len(t, k.BlindIndex("users.national_id", "AB1234"), 64)
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	key := func(b byte) string { return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32)) }
+
+	t.Setenv("FIELD_ENCRYPTION_KEYS", "")
+	_, err := LoadConfigFromEnv()
+	assert.ErrorIs(t, err, ErrNotConfigured)
+
+	file := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(file, []byte("# rotated 2026-10\nm1:"+key(1)+"\nm2:"+key(2)+"\n"), 0o600))
+	t.Setenv("FIELD_ENCRYPTION_KEYS_FILE", file)
+	t.Setenv("FIELD_BLIND_INDEX_KEY", key(9))
+	cfg, err := LoadConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "m2", cfg.ActiveMasterKey)
+	assert.Len(t, cfg.MasterKeys, 2)
+
+	t.Setenv("FIELD_ENCRYPTION_ACTIVE_KEY", "m3")
+	_, err = LoadConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("FIELD_ENCRYPTION_KEYS_FILE", "")
+	t.Setenv("FIELD_ENCRYPTION_ACTIVE_KEY", "")
+	t.Setenv("FIELD_ENCRYPTION_KEYS", "m1:"+base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = LoadConfigFromEnv()
+	assert.Error(t, err)
+
+	// A key pasted without its ID must not end up in the error, which is logged.
+	t.Setenv("FIELD_ENCRYPTION_KEYS", "m1:"+key(1)+","+key(2))
+	_, err = LoadConfigFromEnv()
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), key(2))
+	assert.Contains(t, err.Error(), "entry 2")
+}
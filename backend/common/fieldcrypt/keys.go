@@ -0,0 +1,98 @@
+// Package fieldcrypt encrypts individual database values with envelope encryption:
+// values are sealed with AES-GCM under data keys, and data keys are stored wrapped by a
+// master key that never reaches the database. Every ciphertext names the data key that
+// sealed it, so master keys can be rotated and values re-encrypted in the background.
+// Blind indexes (keyed HMACs) allow exact-match lookups on encrypted columns.
+package fieldcrypt
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNotConfigured is returned by LoadConfigFromEnv when no master key is configured.
+var ErrNotConfigured = errors.New("field encryption is not configured")
+
+// Config holds the master keys and the blind index key.
+type Config struct {
+	// MasterKeys maps master key IDs to 32-byte AES keys.
+	MasterKeys map[string][]byte
+	// ActiveMasterKey wraps new data keys.
+	ActiveMasterKey string
+	// BlindIndexKey keys the HMAC of blind indexes. It must not change when master keys rotate.
+	BlindIndexKey []byte
+}
+
+// LoadConfigFromEnv reads master keys from the file named by FIELD_ENCRYPTION_KEYS_FILE (one
+// "<id>:<base64 key>" per line, # comments allowed) or from FIELD_ENCRYPTION_KEYS (comma-separated
+// entries). FIELD_ENCRYPTION_ACTIVE_KEY selects the active key (default: the last entry) and
+// FIELD_BLIND_INDEX_KEY holds the base64 blind index key.
+func LoadConfigFromEnv() (*Config, error) {
+	var entries []string
+	if path := os.Getenv("FIELD_ENCRYPTION_KEYS_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading master keys: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entries = append(entries, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading master keys: %w", err)
+		}
+	} else {
+		entries = strings.Split(os.Getenv("FIELD_ENCRYPTION_KEYS"), ",")
+	}
+
+	cfg := &Config{MasterKeys: map[string][]byte{}}
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			// Never echo the entry: without a separator it may be a bare key.
+			return nil, fmt.Errorf("master key entry %d must be <id>:<base64 key>", i+1)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", id, err)
+		}
+		cfg.MasterKeys[id] = key
+		cfg.ActiveMasterKey = id
+	}
+	if len(cfg.MasterKeys) == 0 {
+		return nil, ErrNotConfigured
+	}
+	if active := os.Getenv("FIELD_ENCRYPTION_ACTIVE_KEY"); active != "" {
+		if _, ok := cfg.MasterKeys[active]; !ok {
+			return nil, fmt.Errorf("FIELD_ENCRYPTION_ACTIVE_KEY=%q is not among the master keys", active)
+		}
+		cfg.ActiveMasterKey = active
+	}
+
+	indexKey, err := decodeKey(os.Getenv("FIELD_BLIND_INDEX_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("FIELD_BLIND_INDEX_KEY: %w", err)
+	}
+	cfg.BlindIndexKey = indexKey
+	return cfg, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("not valid base64")
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
@@ -0,0 +1,256 @@
+package fieldcrypt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Table describes the encrypted columns of a table with an integer primary key.
+type Table struct {
+	Name string
+	ID   string
+	// Owner is the column holding the ID of the user a row belongs to; it is bound to the
+	// row's ciphertexts as associated data.
+	Owner   string
+	Columns []string
+	// BlindIndexes maps an encrypted column to the column holding its blind index.
+	BlindIndexes map[string]string
+}
+
+// Column returns the name bound to a column's ciphertexts as associated data: "<table>.<column>".
+func (t Table) Column(column string) string {
+	return t.Name + "." + column
+}
+
+// ReencryptOptions tunes a re-encryption pass.
+type ReencryptOptions struct {
+	BatchSize int
+	// Pause between batches keeps the load on a live database low.
+	Pause time.Duration
+	// Decrypt writes plaintext back instead, e.g. before reverting the schema.
+	Decrypt bool
+	// Prune deletes data keys that no longer seal any value once a pass completes without conflicts.
+	Prune bool
+	// Logf reports progress; nil disables it.
+	Logf func(format string, args ...any)
+}
+
+// TableReport counts the rows of one table handled by a pass.
+type TableReport struct {
+	Table     string `json:"table"`
+	Scanned   int    `json:"scanned"`
+	Rewritten int    `json:"rewritten"`
+	// Conflicts are rows changed concurrently; they are left for the next pass.
+	Conflicts int `json:"conflicts"`
+}
+
+// ReencryptReport summarises a re-encryption pass.
+type ReencryptReport struct {
+	ActiveKey string         `json:"active_key"`
+	Tables    []TableReport  `json:"tables"`
+	KeysInUse map[string]int `json:"keys_in_use"`
+	Pruned    []string       `json:"pruned,omitempty"`
+}
+
+// Reencrypt walks the tables in batches and rewrites every value that is not sealed with the
+// active data key (including plaintext), refreshing blind indexes on the way. Each row is updated
+// only if it still holds the values that were read, so the pass can run while the API is serving.
+func Reencrypt(ctx context.Context, db *sqlx.DB, k *Keyring, tables []Table, opts ReencryptOptions) (*ReencryptReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	report := &ReencryptReport{ActiveKey: k.ActiveKeyID(), KeysInUse: map[string]int{}}
+	conflicts := 0
+	for _, t := range tables {
+		tr, err := reencryptTable(ctx, db, k, t, opts, report.KeysInUse)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+		report.Tables = append(report.Tables, tr)
+		conflicts += tr.Conflicts
+	}
+
+	if opts.Prune && !opts.Decrypt && conflicts == 0 {
+		stored, err := k.store.LoadDataKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range stored {
+			if w.ID == report.ActiveKey || report.KeysInUse[w.ID] > 0 {
+				continue
+			}
+			if err := k.store.DeleteDataKey(ctx, w.ID); err != nil {
+				return nil, err
+			}
+			report.Pruned = append(report.Pruned, w.ID)
+		}
+	}
+	return report, nil
+}
+
+func reencryptTable(ctx context.Context, db *sqlx.DB, k *Keyring, t Table, opts ReencryptOptions, inUse map[string]int) (TableReport, error) {
+	tr := TableReport{Table: t.Name}
+
+	indexColumns := make([]string, 0, len(t.BlindIndexes))
+	for _, c := range t.Columns {
+		if idx, ok := t.BlindIndexes[c]; ok {
+			indexColumns = append(indexColumns, idx)
+		}
+	}
+	sort.Strings(indexColumns)
+	all := append(append([]string{}, t.Columns...), indexColumns...)
+
+	query := fmt.Sprintf("SELECT %s, %s, %s FROM %s WHERE %s > $1 ORDER BY %s LIMIT $2",
+		t.ID, t.Owner, strings.Join(all, ", "), t.Name, t.ID, t.ID)
+
+	var last int64
+	for {
+		rows, err := db.QueryxContext(ctx, query, last, opts.BatchSize)
+		if err != nil {
+			return tr, err
+		}
+		batch, err := scanBatch(rows, len(all))
+		if err != nil {
+			return tr, err
+		}
+		if len(batch) == 0 {
+			return tr, nil
+		}
+
+		for _, row := range batch {
+			last = row.id
+			tr.Scanned++
+			current := map[string]sql.NullString{}
+			for i, c := range all {
+				current[c] = row.values[i]
+			}
+
+			next, err := reencryptRow(ctx, k, t, row.owner, current, opts.Decrypt)
+			if err != nil {
+				return tr, fmt.Errorf("row %d: %w", row.id, err)
+			}
+			if len(next) > 0 {
+				ok, err := updateRow(ctx, db, t, row.id, all, current, next)
+				if err != nil {
+					return tr, fmt.Errorf("row %d: %w", row.id, err)
+				}
+				if !ok {
+					tr.Conflicts++
+					next = nil
+				} else {
+					tr.Rewritten++
+				}
+			}
+			for _, c := range t.Columns {
+				v := current[c]
+				if nv, ok := next[c]; ok {
+					v = nv
+				}
+				if id, ok := KeyID(v.String); v.Valid && ok {
+					inUse[id]++
+				}
+			}
+		}
+		if opts.Logf != nil {
+			opts.Logf("%s: %d rows scanned, %d rewritten", t.Name, tr.Scanned, tr.Rewritten)
+		}
+		if opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return tr, ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+	}
+}
+
+// reencryptRow returns the columns of a row that need new values.
+func reencryptRow(ctx context.Context, k *Keyring, t Table, owner int64, current map[string]sql.NullString, decrypt bool) (map[string]sql.NullString, error) {
+	next := map[string]sql.NullString{}
+	for _, c := range t.Columns {
+		v := current[c]
+		if !v.Valid {
+			continue
+		}
+		plain, err := k.Decrypt(ctx, t.Column(c), owner, v.String)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c, err)
+		}
+		_, encrypted := KeyID(v.String)
+		switch {
+		case decrypt && encrypted:
+			next[c] = sql.NullString{String: plain, Valid: true}
+		case !decrypt && !k.IsCurrent(v.String):
+			sealed, err := k.Encrypt(t.Column(c), owner, plain)
+			if err != nil {
+				return nil, err
+			}
+			next[c] = sql.NullString{String: sealed, Valid: true}
+		}
+		if idx, ok := t.BlindIndexes[c]; ok && !decrypt {
+			want := k.BlindIndex(t.Column(c), plain)
+			if current[idx].String != want {
+				next[idx] = sql.NullString{String: want, Valid: true}
+			}
+		}
+	}
+	return next, nil
+}
+
+// updateRow writes next if the row still holds current, reporting whether it did.
+func updateRow(ctx context.Context, db *sqlx.DB, t Table, id int64, all []string, current, next map[string]sql.NullString) (bool, error) {
+	var (
+		sets  []string
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	for _, c := range all {
+		if v, ok := next[c]; ok {
+			sets = append(sets, c+" = "+arg(v))
+		}
+	}
+	conds = append(conds, t.ID+" = "+arg(id))
+	for _, c := range all {
+		conds = append(conds, c+" IS NOT DISTINCT FROM "+arg(current[c]))
+	}
+	res, err := db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		t.Name, strings.Join(sets, ", "), strings.Join(conds, " AND ")), args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+type batchRow struct {
+	id     int64
+	owner  int64
+	values []sql.NullString
+}
+
+func scanBatch(rows *sqlx.Rows, columns int) ([]batchRow, error) {
+	defer func() { _ = rows.Close() }()
+	var batch []batchRow
+	for rows.Next() {
+		r := batchRow{values: make([]sql.NullString, columns)}
+		dest := []any{&r.id, &r.owner}
+		for i := range r.values {
+			dest = append(dest, &r.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		batch = append(batch, r)
+	}
+	return batch, rows.Err()
+}
@@ -0,0 +1,59 @@
+package fieldcrypt
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WrappedKey is a data key sealed by a master key, as stored in field_encryption_keys.
+type WrappedKey struct {
+	ID          string    `db:"id" json:"id"`
+	MasterKeyID string    `db:"master_key_id" json:"master_key_id"`
+	Wrapped     []byte    `db:"wrapped_key" json:"-"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// KeyStore persists wrapped data keys.
+type KeyStore interface {
+	// LoadDataKeys returns every data key, oldest first.
+	LoadDataKeys(ctx context.Context) ([]WrappedKey, error)
+	SaveDataKey(ctx context.Context, k WrappedKey) error
+	DeleteDataKey(ctx context.Context, id string) error
+}
+
+// SQLKeyStore keeps wrapped data keys in the field_encryption_keys table.
+type SQLKeyStore struct {
+	db *sqlx.DB
+}
+
+// NewKeyStore creates a SQLKeyStore.
+func NewKeyStore(db *sqlx.DB) *SQLKeyStore {
+	return &SQLKeyStore{db: db}
+}
+
+// LoadDataKeys returns every data key, oldest first.
+func (s *SQLKeyStore) LoadDataKeys(ctx context.Context) ([]WrappedKey, error) {
+	var keys []WrappedKey
+	err := s.db.SelectContext(ctx, &keys, `
+		SELECT id, master_key_id, wrapped_key, created_at
+		FROM field_encryption_keys
+		ORDER BY created_at, id
+	`)
+	return keys, err
+}
+
+// SaveDataKey stores a new wrapped data key.
+func (s *SQLKeyStore) SaveDataKey(ctx context.Context, k WrappedKey) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO field_encryption_keys (id, master_key_id, wrapped_key) VALUES ($1, $2, $3)
+	`, k.ID, k.MasterKeyID, k.Wrapped)
+	return err
+}
+
+// DeleteDataKey removes a data key that no longer seals any value.
+func (s *SQLKeyStore) DeleteDataKey(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM field_encryption_keys WHERE id = $1`, id)
+	return err
+}
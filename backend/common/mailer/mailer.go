@@ -0,0 +1,48 @@
+// Package mailer provides outgoing email delivery: an SMTP transport, a log
+// transport for development, and a persistent outbox that retries failed sends.
+package mailer
+
+import (
+	"context"
+	"log"
+	"os"
+)
+
+// Message is a single outgoing HTML email.
+type Message struct {
+	To       string
+	Subject  string
+	HTMLBody string
+}
+
+// Mailer sends email messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the application log instead of sending them.
+type LogMailer struct{}
+
+// NewLogMailer returns a Mailer for local development.
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the message.
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("📧 Email to %s: %s\n%s", msg.To, msg.Subject, msg.HTMLBody)
+	return nil
+}
+
+// NewTransportFromEnv returns the transport selected by MAIL_DRIVER ("smtp" or "log", default "log").
+func NewTransportFromEnv() Mailer {
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		return NewSMTPMailerFromEnv()
+	case "", "log":
+		return NewLogMailer()
+	default:
+		log.Printf("❌ Unknown MAIL_DRIVER=%q, falling back to log mailer", os.Getenv("MAIL_DRIVER"))
+		return NewLogMailer()
+	}
+}
@@ -0,0 +1,181 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OutboxEntry is a queued email awaiting delivery.
+type OutboxEntry struct {
+	ID        int64  `db:"id"`
+	Recipient string `db:"recipient"`
+	Subject   string `db:"subject"`
+	HTMLBody  string `db:"html_body"`
+	Attempts  int    `db:"attempts"`
+}
+
+// OutboxStore persists queued emails and their delivery state.
+type OutboxStore interface {
+	Enqueue(ctx context.Context, msg Message) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt *time.Time) error
+}
+
+type outboxRepository struct {
+	db *sqlx.DB
+}
+
+// NewOutboxRepository returns an OutboxStore backed by the email_outbox table.
+func NewOutboxRepository(db *sqlx.DB) OutboxStore {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, msg Message) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO email_outbox (recipient, subject, html_body)
+		VALUES ($1, $2, $3)
+	`, msg.To, msg.Subject, msg.HTMLBody)
+	return err
+}
+
+// ClaimDue leases up to limit due entries to the caller. Entries whose lease
+// expired (e.g. the worker crashed mid-send) are claimed again.
+func (r *outboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error) {
+	entries := []OutboxEntry{}
+	err := r.db.SelectContext(ctx, &entries, `
+		UPDATE email_outbox
+		SET status = 'sending', locked_until = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE (status = 'pending' AND next_attempt_at <= now())
+			   OR (status = 'sending' AND locked_until < now())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, html_body, attempts
+	`, limit, lease.Seconds())
+	return entries, err
+}
+
+// MarkSent records a delivery and clears the body, which may carry live confirmation or reset tokens.
+func (r *outboxRepository) MarkSent(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = 'sent', attempts = attempts + 1, sent_at = now(),
+		    locked_until = NULL, last_error = NULL, html_body = ''
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// MarkFailed records a failed attempt. A nil nextAttemptAt gives up on the entry and clears its body.
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET attempts = attempts + 1,
+		    last_error = $2,
+		    locked_until = NULL,
+		    status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+		    html_body = CASE WHEN $3::timestamptz IS NULL THEN '' ELSE html_body END,
+		    next_attempt_at = COALESCE($3::timestamptz, next_attempt_at)
+		WHERE id = $1
+	`, id, lastError, nextAttemptAt)
+	return err
+}
+
+// OutboxMailer is the Mailer handed to services: it only persists the message,
+// so a mail server outage never fails the request that triggered the email.
+type OutboxMailer struct {
+	store OutboxStore
+}
+
+// NewOutboxMailer returns a Mailer that queues messages in the outbox.
+func NewOutboxMailer(store OutboxStore) *OutboxMailer {
+	return &OutboxMailer{store: store}
+}
+
+// Send queues the message for the background worker.
+func (m *OutboxMailer) Send(ctx context.Context, msg Message) error {
+	if err := m.store.Enqueue(ctx, msg); err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	return nil
+}
+
+// Dispatcher drains the outbox through a transport, retrying failures with exponential backoff.
+type Dispatcher struct {
+	Store       OutboxStore
+	Transport   Mailer
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Lease       time.Duration
+	Now         func() time.Time
+}
+
+// NewDispatcher returns a Dispatcher with default batch size, retry limit and backoff.
+func NewDispatcher(store OutboxStore, transport Mailer) *Dispatcher {
+	return &Dispatcher{
+		Store:       store,
+		Transport:   transport,
+		BatchSize:   20,
+		MaxAttempts: 8,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  time.Hour,
+		Lease:       5 * time.Minute,
+		Now:         time.Now,
+	}
+}
+
+// DispatchDue sends every due entry once and returns how many were delivered.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	entries, err := d.Store.ClaimDue(ctx, d.BatchSize, d.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox entries: %w", err)
+	}
+
+	sent := 0
+	for _, e := range entries {
+		sendErr := d.Transport.Send(ctx, Message{To: e.Recipient, Subject: e.Subject, HTMLBody: e.HTMLBody})
+		if sendErr == nil {
+			if err := d.Store.MarkSent(ctx, e.ID); err != nil {
+				log.Printf("❌ Failed to mark email %d as sent: %v", e.ID, err)
+			}
+			sent++
+			continue
+		}
+
+		attempts := e.Attempts + 1
+		var next *time.Time
+		if attempts < d.MaxAttempts {
+			t := d.Now().Add(d.Backoff(attempts))
+			next = &t
+			log.Printf("⚠️ Email %d to %s failed (attempt %d), retrying at %s: %v", e.ID, e.Recipient, attempts, t.Format(time.RFC3339), sendErr)
+		} else {
+			log.Printf("❌ Email %d to %s failed permanently after %d attempts: %v", e.ID, e.Recipient, attempts, sendErr)
+		}
+		if err := d.Store.MarkFailed(ctx, e.ID, sendErr.Error(), next); err != nil {
+			log.Printf("❌ Failed to record email %d failure: %v", e.ID, err)
+		}
+	}
+	return sent, nil
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts.
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,92 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryOutbox struct {
+	due    []OutboxEntry
+	sent   []int64
+	failed map[int64]*time.Time
+}
+
+func (m *memoryOutbox) Enqueue(_ context.Context, msg Message) error {
+	m.due = append(m.due, OutboxEntry{ID: int64(len(m.due) + 1), Recipient: msg.To, Subject: msg.Subject, HTMLBody: msg.HTMLBody})
+	return nil
+}
+
+func (m *memoryOutbox) ClaimDue(_ context.Context, limit int, _ time.Duration) ([]OutboxEntry, error) {
+	if len(m.due) < limit {
+		limit = len(m.due)
+	}
+	claimed := m.due[:limit]
+	m.due = m.due[limit:]
+	return claimed, nil
+}
+
+func (m *memoryOutbox) MarkSent(_ context.Context, id int64) error {
+	m.sent = append(m.sent, id)
+	return nil
+}
+
+func (m *memoryOutbox) MarkFailed(_ context.Context, id int64, _ string, next *time.Time) error {
+	if m.failed == nil {
+		m.failed = map[int64]*time.Time{}
+	}
+	m.failed[id] = next
+	return nil
+}
+
+type stubTransport struct {
+	failFor map[string]bool
+}
+
+func (s stubTransport) Send(_ context.Context, msg Message) error {
+	if s.failFor[msg.To] {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestOutboxMailer_QueuesInsteadOfSending(t *testing.T) {
+	store := &memoryOutbox{}
+	err := NewOutboxMailer(store).Send(context.Background(), Message{To: "a@example.com", Subject: "s", HTMLBody: "b"})
+	assert.NoError(t, err)
+	assert.Len(t, store.due, 1)
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryOutbox{due: []OutboxEntry{
+		{ID: 1, Recipient: "ok@example.com"},
+		{ID: 2, Recipient: "down@example.com", Attempts: 2},
+		{ID: 3, Recipient: "down@example.com", Attempts: 7},
+	}}
+	d := NewDispatcher(store, stubTransport{failFor: map[string]bool{"down@example.com": true}})
+	d.Now = func() time.Time { return now }
+
+	sent, err := d.DispatchDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []int64{1}, store.sent)
+
+	// Third attempt waits 30s * 2^2.
+	if assert.NotNil(t, store.failed[2]) {
+		assert.Equal(t, now.Add(2*time.Minute), *store.failed[2])
+	}
+	// Eighth attempt reaches MaxAttempts and gives up.
+	assert.Contains(t, store.failed, int64(3))
+	assert.Nil(t, store.failed[3])
+}
+
+func TestDispatcher_BackoffIsCapped(t *testing.T) {
+	d := NewDispatcher(nil, nil)
+	assert.Equal(t, 30*time.Second, d.Backoff(1))
+	assert.Equal(t, time.Minute, d.Backoff(2))
+	assert.Equal(t, time.Hour, d.Backoff(20))
+}
@@ -0,0 +1,169 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nomenarkt/lamina/common/utils"
+)
+
+// SMTPMailer delivers messages to an SMTP relay, upgrading with STARTTLS when enabled.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// StartTLS requires the server to support STARTTLS before credentials are sent.
+	StartTLS bool
+	// TLSConfig overrides the TLS settings used for STARTTLS (e.g. custom root CAs).
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+}
+
+// NewSMTPMailerFromEnv configures an SMTPMailer from SMTP_HOST, SMTP_PORT, SMTP_USERNAME,
+// SMTP_PASSWORD, SMTP_FROM and SMTP_STARTTLS.
+func NewSMTPMailerFromEnv() *SMTPMailer {
+	port := 587
+	if envPort := os.Getenv("SMTP_PORT"); envPort != "" {
+		if parsed, err := strconv.Atoi(envPort); err == nil {
+			port = parsed
+		}
+	}
+	startTLS := true
+	if envTLS := os.Getenv("SMTP_STARTTLS"); envTLS != "" {
+		if parsed, err := strconv.ParseBool(envTLS); err == nil {
+			startTLS = parsed
+		}
+	}
+	return &SMTPMailer{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		StartTLS: startTLS,
+		Timeout:  30 * time.Second,
+	}
+}
+
+// Send delivers a single message over a fresh SMTP connection.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if m.Host == "" || m.From == "" {
+		return errors.New("smtp mailer not configured: SMTP_HOST and SMTP_FROM are required")
+	}
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	timeout := m.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.Host, strconv.Itoa(m.Port)))
+	if err != nil {
+		return fmt.Errorf("smtp dial: %w", err)
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if m.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		tlsConfig := m.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		tlsConfig = tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = m.Host
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("smtp RCPT TO: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	body, err := buildMIMEMessage(from, to, msg)
+	if err != nil {
+		_ = w.Close()
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp end of data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func buildMIMEMessage(from, to *mail.Address, msg Message) ([]byte, error) {
+	id, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if at := strings.LastIndexByte(from.Address, '@'); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", id, domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.HTMLBody)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,204 @@
+package mailer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer speaks just enough ESMTP (STARTTLS, AUTH PLAIN, MAIL, RCPT, DATA)
+// to exercise SMTPMailer end to end.
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+
+	mu       sync.Mutex
+	usedTLS  bool
+	authUser string
+	authPass string
+	rcpt     []string
+	data     string
+}
+
+func newFakeSMTPServer(t *testing.T, withTLS bool) (*fakeSMTPServer, *x509.CertPool) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	srv := &fakeSMTPServer{listener: ln}
+	pool := x509.NewCertPool()
+	if withTLS {
+		cert, leaf := selfSignedCert(t)
+		srv.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		pool.AddCert(leaf)
+	}
+
+	go srv.serve()
+	return srv, pool
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 fake.local ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			s.mu.Lock()
+			secured := s.usedTLS
+			s.mu.Unlock()
+			if s.tlsConfig != nil && !secured {
+				_ = tp.PrintfLine("250-fake.local\r\n250-STARTTLS\r\n250 AUTH PLAIN")
+			} else {
+				_ = tp.PrintfLine("250-fake.local\r\n250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(tlsConn)
+			s.mu.Lock()
+			s.usedTLS = true
+			s.mu.Unlock()
+		case "AUTH":
+			parts := strings.Fields(line)
+			raw, _ := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+			creds := strings.Split(string(raw), "\x00")
+			s.mu.Lock()
+			if len(creds) == 3 {
+				s.authUser, s.authPass = creds[1], creds[2]
+			}
+			s.mu.Unlock()
+			_ = tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, line)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			body, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data = string(body)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake.local"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf
+}
+
+func TestSMTPMailer_StartTLSAndAuth(t *testing.T) {
+	srv, pool := newFakeSMTPServer(t, true)
+
+	m := &SMTPMailer{
+		Host:      "127.0.0.1",
+		Port:      srv.port(),
+		Username:  "lamina",
+		Password:  "s3cret",
+		From:      "Lamina <no-reply@madagascarairlines.com>",
+		StartTLS:  true,
+		TLSConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		Timeout:   5 * time.Second,
+	}
+
+	err := m.Send(context.Background(), Message{
+		To:       "crew@madagascarairlines.com",
+		Subject:  "Confirm your account",
+		HTMLBody: "<p>Hello</p>",
+	})
+	require.NoError(t, err)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.True(t, srv.usedTLS)
+	assert.Equal(t, "lamina", srv.authUser)
+	assert.Equal(t, "s3cret", srv.authPass)
+	assert.Equal(t, []string{"RCPT TO:<crew@madagascarairlines.com>"}, srv.rcpt)
+	assert.Contains(t, srv.data, "Subject: Confirm your account")
+	assert.Contains(t, srv.data, "Content-Type: text/html; charset=UTF-8")
+	assert.Contains(t, srv.data, "<p>Hello</p>")
+}
+
+func TestSMTPMailer_RefusesWithoutStartTLS(t *testing.T) {
+	srv, _ := newFakeSMTPServer(t, false)
+
+	m := &SMTPMailer{
+		Host:     "127.0.0.1",
+		Port:     srv.port(),
+		Username: "lamina",
+		Password: "s3cret",
+		From:     "no-reply@madagascarairlines.com",
+		StartTLS: true,
+		Timeout:  5 * time.Second,
+	}
+
+	err := m.Send(context.Background(), Message{To: "crew@madagascarairlines.com", Subject: "x", HTMLBody: "y"})
+	assert.ErrorContains(t, err, "STARTTLS")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Empty(t, srv.authUser, "credentials must not be sent over plaintext")
+}
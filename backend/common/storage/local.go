@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStorage keeps objects as files below Root. Its signed URLs point at BaseURL, where
+// Handler serves them after checking the HMAC signature and expiry.
+type LocalStorage struct {
+	Root    string
+	BaseURL string
+	Secret  []byte
+	Now     func() time.Time
+}
+
+// errNoSigningKey is returned when a LocalStorage has no key to sign or verify URLs with.
+var errNoSigningKey = errors.New("STORAGE_SIGNING_KEY is required for the local storage driver")
+
+// NewLocalStorageFromEnv configures a LocalStorage from STORAGE_LOCAL_DIR (default ./uploads),
+// STORAGE_SIGNING_KEY (required) and API_PUBLIC_URL. Files are served from <API_PUBLIC_URL>/api/v1/files.
+func NewLocalStorageFromEnv() (*LocalStorage, error) {
+	root := os.Getenv("STORAGE_LOCAL_DIR")
+	if root == "" {
+		root = "./uploads"
+	}
+	secret := os.Getenv("STORAGE_SIGNING_KEY")
+	if secret == "" {
+		return nil, errNoSigningKey
+	}
+	return &LocalStorage{
+		Root:    root,
+		BaseURL: strings.TrimSuffix(os.Getenv("API_PUBLIC_URL"), "/") + "/api/v1/files",
+		Secret:  []byte(secret),
+	}, nil
+}
+
+func (l *LocalStorage) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+func (l *LocalStorage) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the object to a temporary file and renames it into place.
+func (l *LocalStorage) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Get opens the object for reading.
+func (l *LocalStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes the object. Deleting a missing object is not an error.
+func (l *LocalStorage) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL returns BaseURL/<key>?expires=<unix>&sig=<hmac>.
+func (l *LocalStorage) SignedURL(key string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if len(l.Secret) == 0 {
+		return "", errNoSigningKey
+	}
+	expires := l.now().Add(ttl).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("sig", l.sign(key, expires))
+	return l.BaseURL + "/" + escapeKey(key) + "?" + q.Encode(), nil
+}
+
+func (l *LocalStorage) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, l.Secret)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by SignedURL.
+func (l *LocalStorage) Verify(key, expires, sig string) error {
+	if len(l.Secret) == 0 {
+		return errNoSigningKey
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("invalid expiry")
+	}
+	if !hmac.Equal([]byte(sig), []byte(l.sign(key, exp))) {
+		return errors.New("invalid signature")
+	}
+	if l.now().Unix() > exp {
+		return errors.New("link expired")
+	}
+	return nil
+}
+
+// Handler serves objects requested through signed URLs. Mount it with the BaseURL path
+// stripped, so that the request path is the object key.
+func (l *LocalStorage) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if err := l.Verify(key, r.URL.Query().Get("expires"), r.URL.Query().Get("sig")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		p, err := l.path(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer func() { _ = f.Close() }()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, path.Base(key), info.ModTime(), f)
+	})
+}
+
+// escapeKey percent-encodes each path segment of key.
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
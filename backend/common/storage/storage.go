@@ -0,0 +1,84 @@
+// Package storage stores uploaded files behind a small object-store interface with a
+// local-filesystem driver and an S3-compatible driver. Objects are read by clients
+// through signed, expiring URLs.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or climb out of the store.
+var ErrInvalidKey = errors.New("invalid object key")
+
+// Storage persists objects under slash-separated keys.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that allows reading the object until ttl has passed.
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// NewFromEnv returns the driver selected by STORAGE_DRIVER ("local" or "s3", default "local").
+func NewFromEnv() (Storage, error) {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "", "local":
+		local, err := NewLocalStorageFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return local, nil
+	case "s3":
+		s3, err := NewS3StorageFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return s3, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER=%q", os.Getenv("STORAGE_DRIVER"))
+	}
+}
+
+// URLTTLFromEnv returns the lifetime of signed URLs from STORAGE_URL_TTL (default 15m).
+func URLTTLFromEnv() time.Duration {
+	if v := os.Getenv("STORAGE_URL_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️ Invalid STORAGE_URL_TTL=%q, using 15m", v)
+	}
+	return 15 * time.Minute
+}
+
+// validKey rejects keys that could escape the store's root or bucket.
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+func envBool(name string, fallback bool) bool {
+	if v := os.Getenv(name); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
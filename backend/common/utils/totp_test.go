@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B vectors (SHA-1), truncated to 6 digits.
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, want := range vectors {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) error: %v", unix, err)
+		}
+		if got != want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTP_SkewAndReplayStep(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret error: %v", err)
+	}
+
+	now := time.Now()
+	previous, _ := TOTPCode(secret, TOTPStep(now)-1)
+
+	step, ok := ValidateTOTP(secret, previous, now, 1)
+	if !ok || step != TOTPStep(now)-1 {
+		t.Errorf("expected previous-step code to validate with skew 1, got ok=%v step=%d", ok, step)
+	}
+
+	if _, ok := ValidateTOTP(secret, previous, now, 0); ok {
+		t.Errorf("previous-step code should not validate without skew")
+	}
+
+	if _, ok := ValidateTOTP(secret, "12345", now, 1); ok {
+		t.Errorf("short code should never validate")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Lamina", "crew@madagascarairlines.com", "ABCDEF")
+	if !strings.HasPrefix(uri, "otpauth://totp/Lamina:crew@madagascarairlines.com?") {
+		t.Errorf("unexpected URI prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=ABCDEF") || !strings.Contains(uri, "issuer=Lamina") {
+		t.Errorf("URI missing secret or issuer: %s", uri)
+	}
+}
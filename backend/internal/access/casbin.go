@@ -2,6 +2,7 @@
 package access
 
 import (
+	"context"
 	"log"
 	"os"
 	"path/filepath"
@@ -13,10 +14,12 @@ import (
 	"gorm.io/gorm"
 )
 
-var enforcer *casbin.Enforcer
+// enforcer is shared by every request and the policy watcher, so it is the synced variant:
+// reads take its read lock and in-memory model changes its write lock.
+var enforcer *casbin.SyncedEnforcer
 
 // InitEnforcer initializes and returns a singleton Casbin enforcer.
-func InitEnforcer(dsn string) *casbin.Enforcer {
+func InitEnforcer(dsn string) *casbin.SyncedEnforcer {
 	if enforcer != nil {
 		return enforcer
 	}
@@ -31,15 +34,21 @@ func InitEnforcer(dsn string) *casbin.Enforcer {
 		log.Fatalf("Failed to create Casbin adapter: %v", err)
 	}
 
-	// ✅ Use caller file path to resolve model.conf location safely
+	// ✅ Use caller file path to resolve model.conf location safely.
+	// CASBIN_MODEL=hierarchical lets rules on an org unit apply to the units below it.
+	modelFile := "model.conf"
+	hierarchical := os.Getenv("CASBIN_MODEL") == "hierarchical"
+	if hierarchical {
+		modelFile = "model_hierarchical.conf"
+	}
 	_, callerPath, _, _ := runtime.Caller(0)
-	modelPath := filepath.Join(filepath.Dir(callerPath), "model.conf")
+	modelPath := filepath.Join(filepath.Dir(callerPath), modelFile)
 
 	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
 		log.Fatalf("model.conf not found at expected path: %s", modelPath)
 	}
 
-	e, err := casbin.NewEnforcer(modelPath, adapter)
+	e, err := casbin.NewSyncedEnforcer(modelPath, adapter)
 	if err != nil {
 		log.Fatalf("Failed to create Casbin enforcer: %v", err)
 	}
@@ -48,11 +57,113 @@ func InitEnforcer(dsn string) *casbin.Enforcer {
 		log.Fatalf("Failed to load Casbin policies: %v", err)
 	}
 
+	if hierarchical {
+		if err := EnableHierarchy(e, NewUnitHierarchy(gormUnitParents(db))); err != nil {
+			log.Fatalf("Failed to enable org-unit hierarchy: %v", err)
+		}
+	}
+
 	enforcer = e
 	return enforcer
 }
 
+// gormUnitParents loads the organizational unit tree over the enforcer's connection.
+func gormUnitParents(db *gorm.DB) UnitParentsLoader {
+	return func(ctx context.Context) (map[int]int, error) {
+		var rows []struct {
+			ID       int
+			ParentID *int
+		}
+		if err := db.WithContext(ctx).Raw("SELECT id, parent_id FROM organizational_units").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		parents := make(map[int]int, len(rows))
+		for _, r := range rows {
+			parents[r.ID] = 0
+			if r.ParentID != nil {
+				parents[r.ID] = *r.ParentID
+			}
+		}
+		return parents, nil
+	}
+}
+
 // GetEnforcer returns the already initialized Casbin enforcer.
-func GetEnforcer() *casbin.Enforcer {
+func GetEnforcer() *casbin.SyncedEnforcer {
 	return enforcer
 }
+
+// PolicyIndex answers questions about the loaded policies of the global enforcer and keeps
+// its role links in step with rules other packages write to the casbin_rule table. Changes
+// are also published to other instances when a PolicyWatcher runs.
+type PolicyIndex struct{}
+
+// UnitHasRules reports whether any policy or role assignment is scoped to the org unit's domain.
+func (PolicyIndex) UnitHasRules(unitID int) (bool, error) {
+	e := GetEnforcer()
+	dom := Domain(unitID)
+	policies, err := e.GetFilteredPolicy(1, dom)
+	if err != nil || len(policies) > 0 {
+		return len(policies) > 0, err
+	}
+	if Hierarchical() {
+		denies, err := e.GetFilteredNamedPolicy(DenyPolicyType, 1, dom)
+		if err != nil || len(denies) > 0 {
+			return len(denies) > 0, err
+		}
+	}
+	groupings, err := e.GetFilteredGroupingPolicy(2, dom)
+	return len(groupings) > 0, err
+}
+
+// FunctionHasRules reports whether any policy or role assignment refers to the function as a role.
+func (PolicyIndex) FunctionHasRules(name string) (bool, error) {
+	e := GetEnforcer()
+	policies, err := e.GetFilteredPolicy(0, name)
+	if err != nil || len(policies) > 0 {
+		return len(policies) > 0, err
+	}
+	groupings, err := e.GetFilteredGroupingPolicy(1, name)
+	return len(groupings) > 0, err
+}
+
+// LinkRole adds the grouping rule g(user, function, unit) to the enforcer's in-memory model.
+// The caller has already stored the rule in casbin_rule, so the adapter is not written to.
+func (PolicyIndex) LinkRole(userID int, function string, unitID int) error {
+	rule := []string{Subject(int64(userID)), function, Domain(unitID)}
+	added, err := addRules(GetEnforcer(), "g", "g", [][]string{rule})
+	if err != nil || len(added) == 0 {
+		return err
+	}
+	broadcast(PolicyUpdate{Op: UpdateAdd, Sec: "g", PType: "g", Rules: added})
+	return nil
+}
+
+// UnlinkRole removes the grouping rule g(user, function, unit) from the enforcer's in-memory model.
+// The caller has already deleted the rule from casbin_rule.
+func (PolicyIndex) UnlinkRole(userID int, function string, unitID int) error {
+	rule := []string{Subject(int64(userID)), function, Domain(unitID)}
+	removed, err := removeRules(GetEnforcer(), "g", "g", [][]string{rule})
+	if err != nil || len(removed) == 0 {
+		return err
+	}
+	broadcast(PolicyUpdate{Op: UpdateRemove, Sec: "g", PType: "g", Rules: removed})
+	return nil
+}
+
+// UnitsChanged drops the cached org-unit tree after units were created, moved or deleted,
+// and rebuilds the role links inherited along it.
+func (PolicyIndex) UnitsChanged() error {
+	if hierarchy == nil {
+		return nil
+	}
+	hierarchy.Invalidate()
+	broadcast(PolicyUpdate{Op: UpdateUnits})
+	return GetEnforcer().BuildRoleLinks()
+}
+
+// ReloadPolicies reloads every rule from casbin_rule after it was changed in bulk.
+func (PolicyIndex) ReloadPolicies() error {
+	broadcast(PolicyUpdate{Op: UpdateReload})
+	return GetEnforcer().LoadPolicy()
+}
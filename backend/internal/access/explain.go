@@ -0,0 +1,104 @@
+package access
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Decision explains the outcome of checking one request against the loaded policies.
+type Decision struct {
+	Subject string `json:"subject"`
+	Domain  string `json:"domain"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+	Allowed bool   `json:"allowed"`
+	// Policy is the p rule that allowed the request: role, domain, object, action.
+	Policy []string `json:"policy,omitempty"`
+	// RoleChain leads from the subject to the policy's role through the grouping rules.
+	RoleChain []string `json:"role_chain,omitempty"`
+	// DeniedBy is the p2 rule that withheld an otherwise granted request.
+	DeniedBy []string `json:"denied_by,omitempty"`
+	// Roles lists every role the subject holds in the domain.
+	Roles  []string `json:"roles"`
+	Reason string   `json:"reason"`
+}
+
+// Explain enforces (sub, orgunit:unitID, obj, act) the way CasbinMiddleware does and reports
+// which policy matched and through which roles.
+func Explain(sub string, unitID int, obj, act string) (Decision, error) {
+	e := GetEnforcer()
+	dom := Domain(unitID)
+	d := Decision{Subject: sub, Domain: dom, Object: obj, Action: act}
+
+	roles, err := e.GetImplicitRolesForUser(sub, dom)
+	if err != nil {
+		return d, err
+	}
+	d.Roles = append([]string{}, roles...)
+
+	allowed, explain, err := e.EnforceEx(sub, dom, obj, act)
+	if err != nil {
+		return d, err
+	}
+	d.Allowed = allowed
+	if !allowed {
+		if d.DeniedBy, err = DenyingRule(sub, dom, obj, act); err != nil {
+			return d, err
+		}
+		switch {
+		case d.DeniedBy != nil:
+			d.Reason = fmt.Sprintf("denied by %s, %s", DenyPolicyType, strings.Join(d.DeniedBy, ", "))
+		case len(roles) == 0:
+			d.Reason = fmt.Sprintf("%s holds no role in %s", sub, dom)
+		default:
+			d.Reason = fmt.Sprintf("no policy for %s in %s allows %s %s", strings.Join(roles, ", "), dom, act, obj)
+		}
+		return d, nil
+	}
+
+	d.Policy = explain
+	if len(explain) > 0 {
+		if d.RoleChain, err = roleChain(sub, explain[0], dom); err != nil {
+			return d, err
+		}
+	}
+	d.Reason = fmt.Sprintf("allowed by p, %s", strings.Join(explain, ", "))
+	return d, nil
+}
+
+// roleChain returns the shortest path of grouping rules from sub to role within dom,
+// starting with sub and ending with role.
+func roleChain(sub, role, dom string) ([]string, error) {
+	if sub == role {
+		return []string{sub}, nil
+	}
+	e := GetEnforcer()
+	e.GetLock().RLock()
+	defer e.GetLock().RUnlock()
+	rm := e.GetRoleManager()
+	parent := map[string]string{sub: ""}
+	queue := []string{sub}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		next, err := rm.GetRoles(name, dom)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range next {
+			if _, seen := parent[r]; seen {
+				continue
+			}
+			parent[r] = name
+			if r == role {
+				chain := []string{r}
+				for n := name; n != ""; n = parent[n] {
+					chain = append([]string{n}, chain...)
+				}
+				return chain, nil
+			}
+			queue = append(queue, r)
+		}
+	}
+	return nil, nil
+}
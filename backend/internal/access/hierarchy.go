@@ -0,0 +1,160 @@
+package access
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+// DenyPolicyType is the policy type of explicit deny rules in the hierarchical model:
+// p2, <role>, orgunit:<id>, <path pattern>, <method or *> withholds what a p rule on
+// an ancestor unit grants, in that unit and below it.
+const DenyPolicyType = "p2"
+
+// UnitParentsLoader returns the parent of every organizational unit; roots map to 0.
+type UnitParentsLoader func(ctx context.Context) (map[int]int, error)
+
+// UnitHierarchy caches the ancestors of organizational units for domain matching.
+// The cache is filled on first use and dropped by Invalidate.
+type UnitHierarchy struct {
+	load UnitParentsLoader
+
+	mu        sync.RWMutex
+	parents   map[int]int
+	ancestors map[int]map[int]bool
+}
+
+// NewUnitHierarchy creates a UnitHierarchy that reads the unit tree through load.
+func NewUnitHierarchy(load UnitParentsLoader) *UnitHierarchy {
+	return &UnitHierarchy{load: load}
+}
+
+// Invalidate drops the cached tree; the next lookup reloads it.
+func (h *UnitHierarchy) Invalidate() {
+	h.mu.Lock()
+	h.parents = nil
+	h.ancestors = nil
+	h.mu.Unlock()
+}
+
+// IsSelfOrAncestor reports whether ancestor is unit or one of the units above it.
+func (h *UnitHierarchy) IsSelfOrAncestor(ancestor, unit int) bool {
+	if ancestor == unit {
+		return true
+	}
+	h.mu.RLock()
+	set, ok := h.ancestors[unit]
+	h.mu.RUnlock()
+	if !ok {
+		set = h.ancestorsOf(unit)
+	}
+	return set[ancestor]
+}
+
+func (h *UnitHierarchy) ancestorsOf(unit int) map[int]bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if set, ok := h.ancestors[unit]; ok {
+		return set
+	}
+	if h.parents == nil {
+		parents, err := h.load(context.Background())
+		if err != nil {
+			// Without the tree only exact domains match; retry on the next lookup.
+			log.Printf("❌ Failed to load organizational unit tree: %v", err)
+			return nil
+		}
+		h.parents = parents
+		h.ancestors = map[int]map[int]bool{}
+	}
+	set := map[int]bool{}
+	for p := h.parents[unit]; p != 0 && !set[p]; p = h.parents[p] {
+		set[p] = true
+	}
+	h.ancestors[unit] = set
+	return set
+}
+
+// DomainMatch reports whether the policy or role domain covers the request domain:
+// the same org unit, or a unit above it. It has the signature of a Casbin matching function.
+func (h *UnitHierarchy) DomainMatch(requestDomain, policyDomain string) bool {
+	if requestDomain == policyDomain {
+		return true
+	}
+	unit, ok := unitOfDomain(requestDomain)
+	if !ok {
+		return false
+	}
+	ancestor, ok := unitOfDomain(policyDomain)
+	return ok && h.IsSelfOrAncestor(ancestor, unit)
+}
+
+// EnableHierarchy makes e apply rules and roles of an org unit to every unit below it, and
+// honour p2 deny rules. e must use model_hierarchical.conf.
+func EnableHierarchy(e *casbin.SyncedEnforcer, h *UnitHierarchy) error {
+	e.AddFunction("domainMatch", func(args ...interface{}) (interface{}, error) {
+		return h.DomainMatch(args[0].(string), args[1].(string)), nil
+	})
+	// The matcher runs while Enforce holds the read lock, so it reads the unsynced enforcer;
+	// taking the read lock again would deadlock behind a waiting writer.
+	e.AddFunction("denied", func(args ...interface{}) (interface{}, error) {
+		rule, err := denyingRule(e.Enforcer, h, args[0].(string), args[1].(string), args[2].(string), args[3].(string))
+		return rule != nil, err
+	})
+	e.AddNamedDomainMatchingFunc("g", "domainMatch", h.DomainMatch)
+	hierarchy = h
+	return e.BuildRoleLinks()
+}
+
+// DenyingRule returns the p2 rule that withholds the request, or nil.
+func DenyingRule(sub, dom, obj, act string) ([]string, error) {
+	if hierarchy == nil {
+		return nil, nil
+	}
+	e := GetEnforcer()
+	e.GetLock().RLock()
+	defer e.GetLock().RUnlock()
+	return denyingRule(e.Enforcer, hierarchy, sub, dom, obj, act)
+}
+
+func denyingRule(e *casbin.Enforcer, h *UnitHierarchy, sub, dom, obj, act string) ([]string, error) {
+	denies, err := e.GetNamedPolicy(DenyPolicyType)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range denies {
+		if len(d) < 4 || !h.DomainMatch(dom, d[1]) || !util.KeyMatch(obj, d[2]) || (d[3] != act && d[3] != "*") {
+			continue
+		}
+		held, err := e.GetRoleManager().HasLink(sub, d[0], dom)
+		if err != nil {
+			return nil, err
+		}
+		if held || sub == d[0] {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+// hierarchy is set when the enforcer uses the hierarchical model.
+var hierarchy *UnitHierarchy
+
+// Hierarchical reports whether org-unit rules are inherited by sub-units.
+func Hierarchical() bool {
+	return hierarchy != nil
+}
+
+func unitOfDomain(domain string) (int, bool) {
+	rest, ok := strings.CutPrefix(domain, "orgunit:")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(rest)
+	return id, err == nil
+}
@@ -0,0 +1,27 @@
+package access
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// membershipRepository reads org unit memberships from user_organizational_units.
+type membershipRepository struct {
+	db *sqlx.DB
+}
+
+// NewMembershipRepository returns a MembershipResolver backed by PostgreSQL.
+func NewMembershipRepository(db *sqlx.DB) MembershipResolver {
+	return &membershipRepository{db: db}
+}
+
+// ListUserUnitIDs returns the IDs of the units a user belongs to, in ascending order.
+func (r *membershipRepository) ListUserUnitIDs(ctx context.Context, userID int64) ([]int, error) {
+	units := []int{}
+	err := r.db.SelectContext(ctx, &units, `
+		SELECT unit_id FROM user_organizational_units
+		WHERE user_id = $1
+		ORDER BY unit_id`, userID)
+	return units, err
+}
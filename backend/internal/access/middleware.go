@@ -2,35 +2,112 @@
 package access
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/common/utils"
 )
 
+const (
+	// OrgUnitHeader selects the organizational unit a request acts in.
+	OrgUnitHeader = "X-Org-Unit-ID"
+	// OrgUnitQueryParam is the query-string alternative to OrgUnitHeader.
+	OrgUnitQueryParam = "org_unit_id"
+	// ContextOrgUnitIDKey holds the org unit (int) whose policies allowed the request.
+	ContextOrgUnitIDKey = "orgUnitID"
+)
+
+// bypassRole is the JWT role that is not subject to org-unit policies, so the
+// platform administrator can bootstrap policies without locking themselves out.
+const bypassRole = "admin"
+
+// MembershipResolver lists the organizational units a user belongs to.
+type MembershipResolver interface {
+	ListUserUnitIDs(ctx context.Context, userID int64) ([]int, error)
+}
+
+// Subject returns the Casbin subject of a user.
+func Subject(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// Domain returns the Casbin domain of an organizational unit.
+func Domain(unitID int) string {
+	return fmt.Sprintf("orgunit:%d", unitID)
+}
+
 // CasbinMiddleware returns a Gin middleware handler that uses Casbin for RBAC enforcement.
-func CasbinMiddleware() gin.HandlerFunc {
+// It must run after auth.Middleware. The request is checked against the org unit named by
+// the X-Org-Unit-ID header or org_unit_id query parameter; without one, it is allowed if
+// any of the user's org unit memberships grants the path and method.
+func CasbinMiddleware(members MembershipResolver) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		enforcer := GetEnforcer()
-		userID := c.MustGet("userID").(int)
-		companyID := c.MustGet("companyID").(int)
+		if c.GetString("userRole") == bypassRole {
+			c.Next()
+			return
+		}
 
-		sub := fmt.Sprintf("user:%d", userID)
-		dom := fmt.Sprintf("company:%d", companyID)
-		obj := c.Request.URL.Path
-		act := c.Request.Method
+		userID, ok := utils.GetUserIDFromContext(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
 
-		ok, err := enforcer.Enforce(sub, dom, obj, act)
+		units, status, err := requestedUnits(c, members, userID)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "enforcement error"})
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
 			return
 		}
 
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
-			return
+		enforcer := GetEnforcer()
+		sub := Subject(userID)
+		obj := c.Request.URL.Path
+		act := c.Request.Method
+
+		for _, unit := range units {
+			ok, err := enforcer.Enforce(sub, Domain(unit), obj, act)
+			if err != nil {
+				log.Printf("❌ Casbin enforcement failed for %s in %s: %v", sub, Domain(unit), err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "enforcement error"})
+				return
+			}
+			if ok {
+				c.Set(ContextOrgUnitIDKey, unit)
+				c.Next()
+				return
+			}
 		}
 
-		c.Next()
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+	}
+}
+
+// requestedUnits returns the org units to enforce in, or the status and error to abort with.
+func requestedUnits(c *gin.Context, members MembershipResolver, userID int64) ([]int, int, error) {
+	raw := c.GetHeader(OrgUnitHeader)
+	if raw == "" {
+		raw = c.Query(OrgUnitQueryParam)
+	}
+	if raw != "" {
+		unit, err := strconv.Atoi(raw)
+		if err != nil || unit <= 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid organizational unit %q", raw)
+		}
+		return []int{unit}, 0, nil
+	}
+
+	units, err := members.ListUserUnitIDs(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Failed to load org units of user %d: %v", userID, err)
+		return nil, http.StatusInternalServerError, errors.New("failed to resolve organizational unit")
+	}
+	if len(units) == 0 {
+		return nil, http.StatusForbidden, errors.New("user belongs to no organizational unit")
 	}
+	return units, 0, nil
 }
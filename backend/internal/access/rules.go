@@ -0,0 +1,79 @@
+package access
+
+import (
+	"context"
+	"errors"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"gorm.io/gorm"
+)
+
+// RuleChanges lists the policy (p), deny (p2) and grouping (g) rules to remove and add in one step.
+type RuleChanges struct {
+	AddPolicies     [][]string
+	RemovePolicies  [][]string
+	AddDenies       [][]string
+	RemoveDenies    [][]string
+	AddGroupings    [][]string
+	RemoveGroupings [][]string
+}
+
+// Empty reports whether there is nothing to change.
+func (c RuleChanges) Empty() bool {
+	return len(c.AddPolicies)+len(c.RemovePolicies)+len(c.AddDenies)+len(c.RemoveDenies)+
+		len(c.AddGroupings)+len(c.RemoveGroupings) == 0
+}
+
+// ApplyRuleChanges writes the changes through the gorm adapter in a single transaction and
+// reloads the enforcer once it commits. also, when set, runs in the same transaction so
+// related tables can be kept in step; an error from it rolls everything back.
+func ApplyRuleChanges(ctx context.Context, changes RuleChanges, also func(tx *gorm.DB) error) error {
+	e := GetEnforcer()
+	adapter, ok := e.GetAdapter().(*gormadapter.Adapter)
+	if !ok {
+		return errors.New("casbin adapter does not support transactions")
+	}
+
+	err := adapter.GetDb().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		gormadapter.TurnOffAutoMigrate(tx)
+		txAdapter, err := gormadapter.NewAdapterByDBUseTableName(tx, "", "casbin_rule")
+		if err != nil {
+			return err
+		}
+		steps := []struct {
+			sec   string
+			ptype string
+			rules [][]string
+			add   bool
+		}{
+			{"p", "p", changes.RemovePolicies, false},
+			{"p", DenyPolicyType, changes.RemoveDenies, false},
+			{"g", "g", changes.RemoveGroupings, false},
+			{"p", "p", changes.AddPolicies, true},
+			{"p", DenyPolicyType, changes.AddDenies, true},
+			{"g", "g", changes.AddGroupings, true},
+		}
+		for _, step := range steps {
+			if len(step.rules) == 0 {
+				continue
+			}
+			if step.add {
+				err = txAdapter.AddPolicies(step.sec, step.ptype, step.rules)
+			} else {
+				err = txAdapter.RemovePolicies(step.sec, step.ptype, step.rules)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if also != nil {
+			return also(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	broadcast(PolicyUpdate{Op: UpdateReload})
+	return e.LoadPolicy()
+}
@@ -2,6 +2,7 @@
 package access
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -13,27 +14,51 @@ import (
 )
 
 // SetEnforcer injects a test enforcer into the package's global singleton
-func SetEnforcer(e *casbin.Enforcer) {
+func SetEnforcer(e *casbin.SyncedEnforcer) {
 	enforcer = e // uses the `var enforcer` from casbin.go
 }
 
 // InitTestEnforcer sets up an in-memory Casbin enforcer with SQLite for isolated unit testing.
-func InitTestEnforcer(t *testing.T) *casbin.Enforcer {
+func InitTestEnforcer(t *testing.T) *casbin.SyncedEnforcer {
+	t.Helper()
+	hierarchy = nil
+	return initTestEnforcer(t, "model.conf")
+}
+
+// InitHierarchicalTestEnforcer sets up an in-memory enforcer with the hierarchical model over
+// the given unit tree (unit ID to parent ID, 0 for roots). The tree is read again after
+// PolicyIndex.UnitsChanged, so tests may change the map.
+func InitHierarchicalTestEnforcer(t *testing.T, parents map[int]int) *casbin.SyncedEnforcer {
+	t.Helper()
+	enf := initTestEnforcer(t, "model_hierarchical.conf")
+	h := NewUnitHierarchy(func(context.Context) (map[int]int, error) {
+		copied := make(map[int]int, len(parents))
+		for id, parent := range parents {
+			copied[id] = parent
+		}
+		return copied, nil
+	})
+	require.NoError(t, EnableHierarchy(enf, h))
+	t.Cleanup(func() { hierarchy = nil })
+	return enf
+}
+
+func initTestEnforcer(t *testing.T, modelFile string) *casbin.SyncedEnforcer {
 	t.Helper()
 
-	// Resolve the absolute path to model.conf relative to this file
+	// Resolve the absolute path to the model relative to this file
 	_, currentFile, _, ok := runtime.Caller(0)
 	require.True(t, ok)
 
-	modelPath := filepath.Join(filepath.Dir(currentFile), "model.conf")
+	modelPath := filepath.Join(filepath.Dir(currentFile), modelFile)
 	_, err := os.Stat(modelPath)
-	require.NoError(t, err, "model.conf not found at path: %s", modelPath)
+	require.NoError(t, err, "%s not found at path: %s", modelFile, modelPath)
 
 	// Use in-memory SQLite adapter
 	adapter, err := gormadapter.NewAdapter("sqlite3", ":memory:", true)
 	require.NoError(t, err)
 
-	enf, err := casbin.NewEnforcer(modelPath, adapter)
+	enf, err := casbin.NewSyncedEnforcer(modelPath, adapter)
 	require.NoError(t, err)
 
 	err = enf.LoadPolicy()
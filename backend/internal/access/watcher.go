@@ -0,0 +1,369 @@
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// PolicyChannel is the PostgreSQL NOTIFY channel policy changes are published on.
+const PolicyChannel = "casbin_policy"
+
+// Operations carried by a PolicyUpdate.
+const (
+	UpdateAdd    = "add"
+	UpdateRemove = "remove"
+	UpdateReload = "reload" // reload every rule from casbin_rule
+	UpdateUnits  = "units"  // the org-unit tree changed
+)
+
+// maxNotifyPayload stays below PostgreSQL's 8000-byte NOTIFY limit; larger changes are
+// published as a reload.
+const maxNotifyPayload = 7000
+
+// watcherPingInterval is how often the LISTEN connection is checked and the heartbeat written.
+const watcherPingInterval = 90 * time.Second
+
+// PolicyUpdate is the NOTIFY payload describing a change made by one instance.
+type PolicyUpdate struct {
+	Instance string     `json:"instance"`
+	Op       string     `json:"op"`
+	Sec      string     `json:"sec,omitempty"`
+	PType    string     `json:"ptype,omitempty"`
+	Rules    [][]string `json:"rules,omitempty"`
+}
+
+// SyncStatus reports when an instance last brought its policies up to date.
+type SyncStatus struct {
+	Instance   string     `db:"instance_id" json:"instance"`
+	Listening  bool       `db:"listening" json:"listening"`
+	LastSyncAt *time.Time `db:"last_sync_at" json:"last_sync_at"`
+	LastError  string     `db:"last_error" json:"last_error"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// PolicyWatcher is a Casbin watcher over PostgreSQL LISTEN/NOTIFY. Changes made through the
+// enforcer are published incrementally, and changes published by other instances are
+// applied to the local enforcer without reloading everything.
+type PolicyWatcher struct {
+	db       *sqlx.DB
+	listener *pq.Listener
+	instance string
+	done     chan struct{}
+
+	mu     sync.Mutex
+	status SyncStatus
+}
+
+// InstanceID identifies this process: INSTANCE_ID, or the host name and process ID.
+func InstanceID() string {
+	if id := os.Getenv("INSTANCE_ID"); id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// StartPolicyWatcher listens for policy changes on dsn, attaches the watcher to the global
+// enforcer and records this instance's sync status in casbin_sync_status.
+func StartPolicyWatcher(db *sqlx.DB, dsn string) (*PolicyWatcher, error) {
+	w := &PolicyWatcher{
+		db:       db,
+		instance: InstanceID(),
+		done:     make(chan struct{}),
+	}
+	w.status.Instance = w.instance
+	w.listener = pq.NewListener(dsn, time.Second, time.Minute, w.listenerEvent)
+	if err := w.listener.Listen(PolicyChannel); err != nil {
+		_ = w.listener.Close()
+		return nil, err
+	}
+
+	if err := GetEnforcer().SetWatcher(w); err != nil {
+		_ = w.listener.Close()
+		return nil, err
+	}
+	watcher = w
+	// The enforcer loaded its policies just before listening started.
+	w.recordSync(nil)
+
+	go w.run()
+	return w, nil
+}
+
+// watcher publishes changes written around the enforcer API; nil until StartPolicyWatcher.
+var watcher *PolicyWatcher
+
+// broadcast publishes a change the enforcer did not see through its own API.
+func broadcast(u PolicyUpdate) {
+	if watcher == nil {
+		return
+	}
+	if err := watcher.publish(u); err != nil {
+		log.Printf("⚠️ Failed to publish policy change %s: %v", u.Op, err)
+	}
+}
+
+// Status returns this instance's sync status.
+func (w *PolicyWatcher) Status() SyncStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// ListSyncStatus returns the sync status every instance has reported, most recent first.
+func ListSyncStatus(ctx context.Context) ([]SyncStatus, error) {
+	if watcher == nil {
+		return []SyncStatus{}, nil
+	}
+	statuses := []SyncStatus{}
+	err := watcher.db.SelectContext(ctx, &statuses, `
+		SELECT instance_id, listening, last_sync_at, last_error, updated_at
+		FROM casbin_sync_status
+		ORDER BY updated_at DESC`)
+	return statuses, err
+}
+
+// LocalSyncStatus returns this instance's sync status, and false when no watcher runs.
+func LocalSyncStatus() (SyncStatus, bool) {
+	if watcher == nil {
+		return SyncStatus{Instance: InstanceID()}, false
+	}
+	return watcher.Status(), true
+}
+
+// SetUpdateCallback implements persist.Watcher. Changes from other instances are applied
+// by ApplyPolicyUpdate instead of the callback.
+func (w *PolicyWatcher) SetUpdateCallback(func(string)) error {
+	return nil
+}
+
+// Update implements persist.Watcher by asking other instances to reload every rule.
+func (w *PolicyWatcher) Update() error {
+	return w.publish(PolicyUpdate{Op: UpdateReload})
+}
+
+// UpdateForAddPolicy implements persist.WatcherEx.
+func (w *PolicyWatcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.publish(PolicyUpdate{Op: UpdateAdd, Sec: sec, PType: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemovePolicy implements persist.WatcherEx.
+func (w *PolicyWatcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.publish(PolicyUpdate{Op: UpdateRemove, Sec: sec, PType: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemoveFilteredPolicy implements persist.WatcherEx with a full reload.
+func (w *PolicyWatcher) UpdateForRemoveFilteredPolicy(_, _ string, _ int, _ ...string) error {
+	return w.Update()
+}
+
+// UpdateForSavePolicy implements persist.WatcherEx with a full reload.
+func (w *PolicyWatcher) UpdateForSavePolicy(_ model.Model) error {
+	return w.Update()
+}
+
+// UpdateForAddPolicies implements persist.WatcherEx.
+func (w *PolicyWatcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(PolicyUpdate{Op: UpdateAdd, Sec: sec, PType: ptype, Rules: rules})
+}
+
+// UpdateForRemovePolicies implements persist.WatcherEx.
+func (w *PolicyWatcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(PolicyUpdate{Op: UpdateRemove, Sec: sec, PType: ptype, Rules: rules})
+}
+
+// Close implements persist.Watcher and stops listening.
+func (w *PolicyWatcher) Close() {
+	select {
+	case <-w.done:
+		return
+	default:
+		close(w.done)
+	}
+	_ = w.listener.Close()
+	w.setListening(false)
+}
+
+func (w *PolicyWatcher) publish(u PolicyUpdate) error {
+	u.Instance = w.instance
+	payload, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		payload, _ = json.Marshal(PolicyUpdate{Instance: w.instance, Op: UpdateReload})
+	}
+	_, err = w.db.Exec(`SELECT pg_notify($1, $2)`, PolicyChannel, string(payload))
+	return err
+}
+
+func (w *PolicyWatcher) run() {
+	ticker := time.NewTicker(watcherPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case n := <-w.listener.Notify:
+			if n == nil {
+				// The connection was re-established; notifications may have been missed.
+				w.recordSync(GetEnforcer().LoadPolicy())
+				continue
+			}
+			w.handle(n.Extra)
+		case <-ticker.C:
+			if err := w.listener.Ping(); err != nil {
+				log.Printf("⚠️ Policy watcher ping failed: %v", err)
+			}
+			w.writeStatus()
+		}
+	}
+}
+
+func (w *PolicyWatcher) handle(payload string) {
+	var u PolicyUpdate
+	if err := json.Unmarshal([]byte(payload), &u); err != nil {
+		log.Printf("❌ Ignoring malformed policy notification: %v", err)
+		return
+	}
+	if u.Instance == w.instance {
+		return
+	}
+	err := ApplyPolicyUpdate(GetEnforcer(), u)
+	if err != nil {
+		log.Printf("❌ Failed to apply policy change %s from %s: %v", u.Op, u.Instance, err)
+	}
+	w.recordSync(err)
+}
+
+// ApplyPolicyUpdate applies a change published by another instance to e's in-memory model.
+// The rules are already stored in casbin_rule, so the adapter is not written to.
+func ApplyPolicyUpdate(e *casbin.SyncedEnforcer, u PolicyUpdate) error {
+	switch u.Op {
+	case UpdateReload:
+		return e.LoadPolicy()
+	case UpdateUnits:
+		if hierarchy == nil {
+			return nil
+		}
+		hierarchy.Invalidate()
+		return e.BuildRoleLinks()
+	case UpdateAdd:
+		_, err := addRules(e, u.Sec, u.PType, u.Rules)
+		return err
+	case UpdateRemove:
+		_, err := removeRules(e, u.Sec, u.PType, u.Rules)
+		return err
+	default:
+		return fmt.Errorf("unknown policy update %q", u.Op)
+	}
+}
+
+// addRules adds the rules e does not hold yet to its in-memory model under e's write lock,
+// rebuilding role links for grouping rules, and returns the rules it added.
+func addRules(e *casbin.SyncedEnforcer, sec, ptype string, rules [][]string) ([][]string, error) {
+	e.GetLock().Lock()
+	defer e.GetLock().Unlock()
+	var added [][]string
+	for _, rule := range rules {
+		ok, err := e.GetModel().HasPolicy(sec, ptype, rule)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			added = append(added, rule)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	if err := e.GetModel().AddPolicies(sec, ptype, added); err != nil {
+		return nil, err
+	}
+	if sec == "g" {
+		return added, e.BuildIncrementalRoleLinks(model.PolicyAdd, ptype, added)
+	}
+	return added, nil
+}
+
+// removeRules removes the rules from e's in-memory model under e's write lock, rebuilding
+// role links for grouping rules, and returns the rules it removed.
+func removeRules(e *casbin.SyncedEnforcer, sec, ptype string, rules [][]string) ([][]string, error) {
+	e.GetLock().Lock()
+	defer e.GetLock().Unlock()
+	var removed [][]string
+	for _, rule := range rules {
+		ok, err := e.GetModel().RemovePolicy(sec, ptype, rule)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			removed = append(removed, rule)
+		}
+	}
+	if sec == "g" && len(removed) > 0 {
+		return removed, e.BuildIncrementalRoleLinks(model.PolicyRemove, ptype, removed)
+	}
+	return removed, nil
+}
+
+func (w *PolicyWatcher) listenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventConnected, pq.ListenerEventReconnected:
+		w.setListening(true)
+	case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+		if err != nil {
+			log.Printf("⚠️ Policy watcher connection lost: %v", err)
+		}
+		w.setListening(false)
+	}
+}
+
+func (w *PolicyWatcher) setListening(listening bool) {
+	w.mu.Lock()
+	w.status.Listening = listening
+	w.mu.Unlock()
+}
+
+// recordSync notes the outcome of a (re)load or applied change and writes the status.
+func (w *PolicyWatcher) recordSync(err error) {
+	w.mu.Lock()
+	if err != nil {
+		w.status.LastError = err.Error()
+	} else {
+		now := time.Now().UTC()
+		w.status.LastSyncAt = &now
+		w.status.LastError = ""
+	}
+	w.mu.Unlock()
+	w.writeStatus()
+}
+
+func (w *PolicyWatcher) writeStatus() {
+	s := w.Status()
+	_, err := w.db.Exec(`
+		INSERT INTO casbin_sync_status (instance_id, listening, last_sync_at, last_error, updated_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (instance_id) DO UPDATE SET
+			listening = EXCLUDED.listening,
+			last_sync_at = EXCLUDED.last_sync_at,
+			last_error = EXCLUDED.last_error,
+			updated_at = now()`,
+		s.Instance, s.Listening, s.LastSyncAt, s.LastError)
+	if err != nil {
+		log.Printf("⚠️ Failed to record policy sync status: %v", err)
+	}
+}
@@ -4,10 +4,13 @@ package admin
 import (
 	"context"
 	"errors"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/nomenarkt/lamina/common/mailer"
 	"github.com/nomenarkt/lamina/common/utils"
+	"github.com/nomenarkt/lamina/internal/audit"
 	"github.com/nomenarkt/lamina/internal/auth"
 	"github.com/nomenarkt/lamina/internal/user"
 )
@@ -16,13 +19,15 @@ import (
 type Service struct {
 	repo   Repo
 	hasher utils.PasswordHasher
+	mailer mailer.Mailer
 }
 
-// NewAdminService creates a new instance of Service.
-func NewAdminService(repo Repo, hasher utils.PasswordHasher) *Service {
+// NewAdminService creates a new instance of Service. Invite emails are handed to m.
+func NewAdminService(repo Repo, hasher utils.PasswordHasher, m mailer.Mailer) *Service {
 	return &Service{
 		repo:   repo,
 		hasher: hasher,
+		mailer: m,
 	}
 }
 
@@ -89,5 +94,19 @@ func (s *Service) InviteUser(ctx context.Context, req CreateUserRequest, _ strin
 		return err
 	}
 
-	return auth.SendConfirmationEmail(req.Email, token, false)
+	if err := auth.SendConfirmationEmail(ctx, s.mailer, req.Email, token, false); err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.Event{
+		Action:     "user.invite",
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(userID, 10),
+		After: map[string]any{
+			"role":              newUser.Role,
+			"user_type":         newUser.UserType,
+			"access_expires_at": newUser.AccessExpiresAt,
+		},
+	})
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/nomenarkt/lamina/common/mailer"
 	"github.com/nomenarkt/lamina/internal/user"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -45,10 +46,21 @@ func (m *MockHasher) HashPassword(password string) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
+// MockMailer is a mock implementation of the mailer.Mailer interface.
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
 func TestInviteUser_Success(t *testing.T) {
 	mockRepo := new(MockAdminRepo)
 	mockHasher := new(MockHasher)
-	service := NewAdminService(mockRepo, mockHasher)
+	mockMailer := new(MockMailer)
+	service := NewAdminService(mockRepo, mockHasher, mockMailer)
 
 	req := CreateUserRequest{Email: "newuser@madagascarairlines.com"}
 
@@ -56,17 +68,21 @@ func TestInviteUser_Success(t *testing.T) {
 	mockRepo.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("FindUserIDByEmail", mock.Anything, req.Email).Return(int64(99), nil)
 	mockRepo.On("SetConfirmationToken", mock.Anything, int64(99), mock.Anything).Return(nil)
+	mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == req.Email && msg.HTMLBody != ""
+	})).Return(nil)
 
 	err := service.InviteUser(context.Background(), req, "admin")
 	assert.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
+	mockMailer.AssertExpectations(t)
 }
 
 func TestInviteUser_EmailExists(t *testing.T) {
 	mockRepo := new(MockAdminRepo)
 	mockHasher := new(MockHasher)
-	service := NewAdminService(mockRepo, mockHasher)
+	service := NewAdminService(mockRepo, mockHasher, new(MockMailer))
 
 	req := CreateUserRequest{Email: "existing@madagascarairlines.com"}
 
@@ -79,7 +95,7 @@ func TestInviteUser_EmailExists(t *testing.T) {
 func TestInviteUser_RepoFails(t *testing.T) {
 	mockRepo := new(MockAdminRepo)
 	mockHasher := new(MockHasher)
-	service := NewAdminService(mockRepo, mockHasher)
+	service := NewAdminService(mockRepo, mockHasher, new(MockMailer))
 
 	req := CreateUserRequest{Email: "dbfail@madagascarairlines.com"}
 
@@ -2,12 +2,18 @@
 package adminaccess
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nomenarkt/lamina/common/database"
 	"github.com/nomenarkt/lamina/internal/access"
+	"github.com/nomenarkt/lamina/internal/audit"
+	"github.com/nomenarkt/lamina/internal/org"
 )
 
 // AssignRoleRequest contains fields to assign a role to a user.
@@ -15,6 +21,7 @@ type AssignRoleRequest struct {
 	UserID    int    `json:"user_id" binding:"required"`
 	Function  string `json:"function" binding:"required"`    // e.g., "planner", "auditor"
 	OrgUnitID int    `json:"org_unit_id" binding:"required"` // domain scope
+	RankID    *int   `json:"rank_id"`                        // optional rank within the function
 }
 
 // PolicyRequest defines the request payload for modifying access policies.
@@ -23,6 +30,45 @@ type PolicyRequest struct {
 	OrgUnitID int    `json:"org_unit_id" binding:"required"` // org domain
 	Object    string `json:"object" binding:"required"`      // e.g., /api/flights
 	Action    string `json:"action" binding:"required"`      // e.g., read/write
+	Effect    string `json:"effect" binding:"omitempty,oneof=allow deny"`
+}
+
+// policyType returns the Casbin policy type the request's effect is stored under.
+// Deny rules exist only in the hierarchical model.
+func (r PolicyRequest) policyType() (string, bool) {
+	if r.Effect != "deny" {
+		return "p", true
+	}
+	return access.DenyPolicyType, access.Hierarchical()
+}
+
+// AccessCheckRequest describes a request to simulate. Subject is a Casbin subject
+// such as user:42, or a function name to check the role itself.
+type AccessCheckRequest struct {
+	Subject   string `json:"subject" binding:"required"`
+	OrgUnitID int    `json:"org_unit_id" binding:"required"`
+	Path      string `json:"path" binding:"required"`   // e.g. /api/v1/flights
+	Method    string `json:"method" binding:"required"` // e.g. GET
+}
+
+// Endpoint is a path and method checked in an access matrix.
+type Endpoint struct {
+	Path   string `json:"path" binding:"required"`
+	Method string `json:"method" binding:"required"`
+}
+
+// AccessMatrixRequest checks every subject against every endpoint in one org unit.
+type AccessMatrixRequest struct {
+	Subjects  []string   `json:"subjects" binding:"required,min=1,max=100,dive,required"`
+	OrgUnitID int        `json:"org_unit_id" binding:"required"`
+	Endpoints []Endpoint `json:"endpoints" binding:"required,min=1,max=100,dive"`
+}
+
+// AccessMatrixResponse holds one row of decisions per subject, one column per endpoint.
+type AccessMatrixResponse struct {
+	Subjects  []string            `json:"subjects"`
+	Endpoints []Endpoint          `json:"endpoints"`
+	Decisions [][]access.Decision `json:"decisions"`
 }
 
 // OrganizationalUnitResponse represents the ID and name of an org unit.
@@ -31,7 +77,24 @@ type OrganizationalUnitResponse struct {
 	Name string `db:"name" json:"name"`
 }
 
+const errDenyNeedsHierarchy = "deny rules require CASBIN_MODEL=hierarchical"
+
+// RoleService writes role assignments to user_functions and the Casbin grouping rules together.
+type RoleService interface {
+	AssignFunction(ctx context.Context, req org.AssignFunctionRequest) (org.UserFunctionDetail, error)
+	RevokeFunction(ctx context.Context, req org.RevokeFunctionRequest) error
+}
+
+// roles handles AssignRole and RemoveRole; main wires it to the org service.
+var roles RoleService
+
+// SetRoleService sets the service used to assign and remove roles.
+func SetRoleService(s RoleService) {
+	roles = s
+}
+
 // AssignRole assigns a role to a user for a given domain.
+// The role must be a function from the catalogue; it is also recorded as a user function.
 func AssignRole(c *gin.Context) {
 	var req AssignRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -39,11 +102,17 @@ func AssignRole(c *gin.Context) {
 		return
 	}
 
-	e := access.GetEnforcer()
-	sub := fmt.Sprintf("user:%d", req.UserID)
-	dom := fmt.Sprintf("orgunit:%d", req.OrgUnitID)
-
-	if _, err := e.AddGroupingPolicy(sub, req.Function, dom); err != nil {
+	_, err := roles.AssignFunction(c.Request.Context(), org.AssignFunctionRequest{
+		UserID:   req.UserID,
+		Function: req.Function,
+		UnitID:   req.OrgUnitID,
+		RankID:   req.RankID,
+	})
+	if errors.Is(err, org.ErrInvalidAssignment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -59,11 +128,16 @@ func RemoveRole(c *gin.Context) {
 		return
 	}
 
-	e := access.GetEnforcer()
-	sub := fmt.Sprintf("user:%d", req.UserID)
-	dom := fmt.Sprintf("orgunit:%d", req.OrgUnitID)
-
-	if _, err := e.RemoveGroupingPolicy(sub, req.Function, dom); err != nil {
+	err := roles.RevokeFunction(c.Request.Context(), org.RevokeFunctionRequest{
+		UserID:   req.UserID,
+		Function: req.Function,
+		UnitID:   req.OrgUnitID,
+	})
+	if errors.Is(err, org.ErrAssignmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -79,14 +153,26 @@ func AddPolicy(c *gin.Context) {
 		return
 	}
 
+	ptype, ok := req.policyType()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errDenyNeedsHierarchy})
+		return
+	}
 	e := access.GetEnforcer()
 	dom := fmt.Sprintf("orgunit:%d", req.OrgUnitID)
 
-	if _, err := e.AddPolicy(req.Role, dom, req.Object, req.Action); err != nil {
+	if _, err := e.AddNamedPolicy(ptype, req.Role, dom, req.Object, req.Action); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	audit.Record(c.Request.Context(), audit.Event{
+		Action:     "policy.add",
+		TargetType: "policy",
+		TargetID:   dom,
+		After:      []string{ptype, req.Role, dom, req.Object, req.Action},
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Policy added"})
 }
 
@@ -98,13 +184,27 @@ func DeletePolicy(c *gin.Context) {
 		return
 	}
 
+	ptype, ok := req.policyType()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errDenyNeedsHierarchy})
+		return
+	}
 	e := access.GetEnforcer()
 	dom := fmt.Sprintf("orgunit:%d", req.OrgUnitID)
 
-	if _, err := e.RemovePolicy(req.Role, dom, req.Object, req.Action); err != nil {
+	removed, err := e.RemoveNamedPolicy(ptype, req.Role, dom, req.Object, req.Action)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if removed {
+		audit.Record(c.Request.Context(), audit.Event{
+			Action:     "policy.remove",
+			TargetType: "policy",
+			TargetID:   dom,
+			Before:     []string{ptype, req.Role, dom, req.Object, req.Action},
+		})
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Policy removed"})
 }
@@ -117,7 +217,131 @@ func ListPolicies(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"policies": policies})
+	if !access.Hierarchical() {
+		c.JSON(http.StatusOK, gin.H{"policies": policies})
+		return
+	}
+	denies, err := e.GetNamedPolicy(access.DenyPolicyType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": policies, "denies": denies})
+}
+
+// ExportPolicies returns every p and g rule as JSON, or as Casbin CSV with ?format=csv.
+func ExportPolicies(c *gin.Context) {
+	set, err := ExportPolicySet()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, set)
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="policies.csv"`)
+		if err := set.WriteCSV(c.Writer); err != nil {
+			log.Printf("❌ Failed to write policy export: %v", err)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+// ImportPolicies replaces the rules with a policy set sent as JSON or, with Content-Type
+// text/csv, in Casbin's CSV format. ?mode=partial limits removals to the domains in the set;
+// ?dry_run=true only reports the additions and removals.
+func ImportPolicies(c *gin.Context) {
+	var set PolicySet
+	if strings.HasPrefix(c.ContentType(), "text/csv") {
+		parsed, err := ParsePolicyCSV(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		set = parsed
+	} else if err := c.ShouldBindJSON(&set); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	report, err := ImportPolicySet(c.Request.Context(), set, c.Query("mode"), dryRun)
+	var invalid *PolicySetError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPolicySet.Error(), "problems": invalid.Problems})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !dryRun && report.Changes() > 0 {
+		audit.Record(c.Request.Context(), audit.Event{
+			Action:     "policy.import",
+			TargetType: "policy",
+			TargetID:   report.Mode,
+			Before:     report.Removed,
+			After:      report.Added,
+		})
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// CheckAccess reports whether a subject may call a path in an org unit, and which policy
+// and role chain decide it. The admin role's bypass of CasbinMiddleware is not simulated.
+func CheckAccess(c *gin.Context) {
+	var req AccessCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	d, err := access.Explain(req.Subject, req.OrgUnitID, req.Path, strings.ToUpper(req.Method))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// CheckAccessMatrix runs CheckAccess for every combination of subjects and endpoints.
+func CheckAccessMatrix(c *gin.Context) {
+	var req AccessMatrixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := AccessMatrixResponse{Subjects: req.Subjects, Endpoints: req.Endpoints, Decisions: make([][]access.Decision, len(req.Subjects))}
+	for i, sub := range req.Subjects {
+		row := make([]access.Decision, len(req.Endpoints))
+		for j, ep := range req.Endpoints {
+			d, err := access.Explain(sub, req.OrgUnitID, ep.Path, strings.ToUpper(ep.Method))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			row[j] = d
+		}
+		resp.Decisions[i] = row
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// PolicySyncStatus reports when this and every other API instance last synchronized policies.
+func PolicySyncStatus(c *gin.Context) {
+	local, watching := access.LocalSyncStatus()
+	instances, err := access.ListSyncStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"instance": local, "watching": watching, "instances": instances})
 }
 
 // ListOrganizationalUnitsHandler returns all org units.
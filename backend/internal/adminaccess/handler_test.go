@@ -2,13 +2,16 @@ package adminaccess
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nomenarkt/lamina/internal/access"
+	"github.com/nomenarkt/lamina/internal/org"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -112,3 +115,283 @@ func TestViewer_CannotAccessPolicies(t *testing.T) {
 
 	assert.Equal(t, http.StatusForbidden, w.Code)
 }
+
+// stubRoles records assignments of the listed function names.
+type stubRoles struct {
+	known    map[string]bool
+	assigned []org.AssignFunctionRequest
+}
+
+func (s *stubRoles) AssignFunction(_ context.Context, req org.AssignFunctionRequest) (org.UserFunctionDetail, error) {
+	if !s.known[req.Function] {
+		return org.UserFunctionDetail{}, fmt.Errorf("%w: unknown function %q", org.ErrInvalidAssignment, req.Function)
+	}
+	s.assigned = append(s.assigned, req)
+	return org.UserFunctionDetail{}, nil
+}
+
+func (s *stubRoles) RevokeFunction(_ context.Context, _ org.RevokeFunctionRequest) error {
+	return org.ErrAssignmentNotFound
+}
+
+func TestAssignRole_GoesThroughRoleService(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	stub := &stubRoles{known: map[string]bool{"planner": true}}
+	SetRoleService(stub)
+	t.Cleanup(func() { SetRoleService(nil) })
+
+	router := gin.New()
+	router.POST("/admin/roles", AssignRole)
+	router.DELETE("/admin/roles", RemoveRole)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/roles", bytes.NewBufferString(`{"user_id":201,"function":"pilot-in-chief","org_unit_id":1}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown function")
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/roles", bytes.NewBufferString(`{"user_id":201,"function":"planner","org_unit_id":1,"rank_id":3}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, stub.assigned, 1)
+	assert.Equal(t, 201, stub.assigned[0].UserID)
+	assert.Equal(t, 1, stub.assigned[0].UnitID)
+	require.NotNil(t, stub.assigned[0].RankID)
+	assert.Equal(t, 3, *stub.assigned[0].RankID)
+
+	req = httptest.NewRequest(http.MethodDelete, "/admin/roles", bytes.NewBufferString(`{"user_id":201,"function":"planner","org_unit_id":2}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// stubCatalogue knows units 1 and 2 (2 below 1) and the planner and auditor functions.
+type stubCatalogue struct{}
+
+func (stubCatalogue) Tree(_ context.Context) ([]*org.UnitNode, error) {
+	child := &org.UnitNode{OrganizationalUnit: org.OrganizationalUnit{ID: 2}}
+	return []*org.UnitNode{{OrganizationalUnit: org.OrganizationalUnit{ID: 1}, Children: []*org.UnitNode{child}}}, nil
+}
+
+func (stubCatalogue) ListFunctions(_ context.Context) ([]org.Function, error) {
+	return []org.Function{{Name: "planner"}, {Name: "auditor"}}, nil
+}
+
+func setupPolicyImportRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	enforcer := access.InitTestEnforcer(t)
+	_, err := enforcer.AddPolicies([][]string{
+		{"planner", "orgunit:1", "/api/crew", "assign"},
+		{"planner", "orgunit:1", "/api/flights", "GET"},
+		{"auditor", "orgunit:2", "/api/crew", "GET"},
+	})
+	require.NoError(t, err)
+
+	SetPolicyCatalogue(stubCatalogue{})
+	t.Cleanup(func() { SetPolicyCatalogue(nil) })
+
+	router := gin.New()
+	router.GET("/admin/policies/export", ExportPolicies)
+	router.POST("/admin/policies/import", ImportPolicies)
+	return router
+}
+
+func TestExportPolicies_CSVRoundTrips(t *testing.T) {
+	router := setupPolicyImportRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/policies/export?format=csv", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "p,auditor,orgunit:2,/api/crew,GET\np,planner,orgunit:1,/api/crew,assign\np,planner,orgunit:1,/api/flights,GET\n", w.Body.String())
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/policies/import?dry_run=true", bytes.NewBufferString(w.Body.String()))
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report ImportReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 0, report.Changes())
+	assert.Equal(t, 3, report.Unchanged)
+}
+
+func TestExportPolicies_DenyRulesRoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enforcer := access.InitHierarchicalTestEnforcer(t, map[int]int{1: 0, 2: 1})
+	_, err := enforcer.AddPolicy("planner", "orgunit:1", "/api/crew", "assign")
+	require.NoError(t, err)
+	_, err = enforcer.AddNamedPolicy(access.DenyPolicyType, "planner", "orgunit:2", "/api/crew", "assign")
+	require.NoError(t, err)
+	SetPolicyCatalogue(stubCatalogue{})
+	t.Cleanup(func() { SetPolicyCatalogue(nil) })
+	router := gin.New()
+	router.GET("/admin/policies/export", ExportPolicies)
+	router.POST("/admin/policies/import", ImportPolicies)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/policies/export?format=csv", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	exported := w.Body.String()
+	assert.Equal(t, "p,planner,orgunit:1,/api/crew,assign\np2,planner,orgunit:2,/api/crew,assign\n", exported)
+
+	// Reimporting the export changes nothing.
+	req := httptest.NewRequest(http.MethodPost, "/admin/policies/import", bytes.NewBufferString(exported))
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var report ImportReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 0, report.Changes())
+	assert.Equal(t, 2, report.Unchanged)
+
+	// A full import without the deny rule removes it, and the grant applies again below orgunit:1.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/policies/import",
+		bytes.NewBufferString(`{"policies":[{"role":"planner","domain":"orgunit:1","object":"/api/crew","action":"assign"}]}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, []PolicyRule{{Role: "planner", Domain: "orgunit:2", Object: "/api/crew", Action: "assign"}}, report.Removed.Denies)
+
+	denies, err := access.GetEnforcer().GetNamedPolicy(access.DenyPolicyType)
+	require.NoError(t, err)
+	assert.Empty(t, denies)
+}
+
+func TestImportPolicies_RejectsDenyRulesWithoutHierarchy(t *testing.T) {
+	router := setupPolicyImportRouter(t)
+	body := `{"denies":[{"role":"planner","domain":"orgunit:1","object":"/api/crew","action":"assign"}]}`
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/policies/import", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "deny rules require CASBIN_MODEL=hierarchical")
+}
+
+func TestImportPolicies_DryRunAndPartialApply(t *testing.T) {
+	router := setupPolicyImportRouter(t)
+	body := `{"policies":[
+		{"role":"planner","domain":"orgunit:1","object":"/api/crew","action":"assign"},
+		{"role":"auditor","domain":"orgunit:1","object":"/api/crew","action":"GET"}]}`
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/policies/import?mode=partial&dry_run=true", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var report ImportReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []PolicyRule{{Role: "auditor", Domain: "orgunit:1", Object: "/api/crew", Action: "GET"}}, report.Added.Policies)
+	assert.Equal(t, []PolicyRule{{Role: "planner", Domain: "orgunit:1", Object: "/api/flights", Action: "GET"}}, report.Removed.Policies)
+	assert.Equal(t, 1, report.Unchanged)
+
+	e := access.GetEnforcer()
+	ok, _ := e.HasPolicy("planner", "orgunit:1", "/api/flights", "GET")
+	assert.True(t, ok, "a dry run must not change the rules")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/policies/import?mode=partial", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, [][]string{
+		{"planner", "orgunit:1", "/api/crew", "assign"},
+		{"auditor", "orgunit:1", "/api/crew", "GET"},
+		{"auditor", "orgunit:2", "/api/crew", "GET"}, // outside the imported domains
+	}, policies)
+}
+
+func TestImportPolicies_RejectsUnknownUnitsAndFunctions(t *testing.T) {
+	router := setupPolicyImportRouter(t)
+	body := `{"policies":[{"role":"pilot-in-chief","domain":"orgunit:9","object":"/api/crew","action":"GET"}],
+		"groupings":[{"subject":"crew:1","role":"planner","domain":"orgunit:x"}]}`
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/policies/import", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp struct {
+		Problems []string `json:"problems"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Problems, 4)
+	assert.Contains(t, w.Body.String(), `unknown function \"pilot-in-chief\"`)
+	assert.Contains(t, w.Body.String(), "organizational unit 9 does not exist")
+
+	policies, err := access.GetEnforcer().GetPolicy()
+	require.NoError(t, err)
+	assert.Len(t, policies, 3)
+}
+
+func TestCheckAccess_ExplainsDecision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enforcer := access.InitTestEnforcer(t)
+	_, err := enforcer.AddPolicy("planner", "orgunit:1", "/api/v1/flights*", "GET")
+	require.NoError(t, err)
+	_, err = enforcer.AddGroupingPolicies([][]string{
+		{"user:201", "chief-planner", "orgunit:1"},
+		{"chief-planner", "planner", "orgunit:1"},
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.POST("/admin/access/check", CheckAccess)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/access/check",
+		bytes.NewBufferString(`{"subject":"user:201","org_unit_id":1,"path":"/api/v1/flights/7","method":"get"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var d access.Decision
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	assert.True(t, d.Allowed)
+	assert.Equal(t, []string{"planner", "orgunit:1", "/api/v1/flights*", "GET"}, d.Policy)
+	assert.Equal(t, []string{"user:201", "chief-planner", "planner"}, d.RoleChain)
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/access/check",
+		bytes.NewBufferString(`{"subject":"user:201","org_unit_id":2,"path":"/api/v1/flights","method":"GET"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	d = access.Decision{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	assert.False(t, d.Allowed)
+	assert.Empty(t, d.Policy)
+	assert.Equal(t, "user:201 holds no role in orgunit:2", d.Reason)
+}
+
+func TestCheckAccessMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enforcer := access.InitTestEnforcer(t)
+	_, err := enforcer.AddPolicy("planner", "orgunit:1", "/api/v1/crew*", "*")
+	require.NoError(t, err)
+	_, err = enforcer.AddGroupingPolicy("user:201", "planner", "orgunit:1")
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.POST("/admin/access/check/matrix", CheckAccessMatrix)
+
+	body := `{"subjects":["user:201","user:999"],"org_unit_id":1,
+		"endpoints":[{"path":"/api/v1/crew/assign","method":"POST"},{"path":"/api/v1/flights","method":"GET"}]}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/access/check/matrix", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AccessMatrixResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Decisions, 2)
+	allowed := [][]bool{}
+	for _, row := range resp.Decisions {
+		var r []bool
+		for _, d := range row {
+			r = append(r, d.Allowed)
+		}
+		allowed = append(allowed, r)
+	}
+	assert.Equal(t, [][]bool{{true, false}, {false, false}}, allowed)
+	assert.Contains(t, resp.Decisions[0][1].Reason, "no policy for planner in orgunit:1")
+}
@@ -0,0 +1,433 @@
+package adminaccess
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nomenarkt/lamina/internal/access"
+	"github.com/nomenarkt/lamina/internal/org"
+	"gorm.io/gorm"
+)
+
+// Import modes: a full set replaces every rule, a partial set only the rules of the
+// org-unit domains it mentions.
+const (
+	ImportFull    = "full"
+	ImportPartial = "partial"
+)
+
+// ErrInvalidPolicySet is wrapped by PolicySetError.
+var ErrInvalidPolicySet = errors.New("invalid policy set")
+
+// PolicySetError lists every problem found in an imported policy set.
+type PolicySetError struct {
+	Problems []string
+}
+
+func (e *PolicySetError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidPolicySet, strings.Join(e.Problems, "; "))
+}
+
+// Is makes errors.Is(err, ErrInvalidPolicySet) match.
+func (e *PolicySetError) Is(target error) bool {
+	return target == ErrInvalidPolicySet
+}
+
+// PolicyRule is a p rule: role may act on object within the org-unit domain. As a p2 deny rule
+// it withholds that grant instead.
+type PolicyRule struct {
+	Role   string `json:"role"`
+	Domain string `json:"domain"` // e.g. orgunit:3
+	Object string `json:"object"`
+	Action string `json:"action"`
+}
+
+// GroupingRule is a g rule: subject holds role within the org-unit domain.
+type GroupingRule struct {
+	Subject string `json:"subject"` // e.g. user:42
+	Role    string `json:"role"`
+	Domain  string `json:"domain"`
+}
+
+// PolicySet is the exported form of casbin_rule, and the payload of an import.
+type PolicySet struct {
+	Policies  []PolicyRule   `json:"policies"`
+	Denies    []PolicyRule   `json:"denies"` // hierarchical model only
+	Groupings []GroupingRule `json:"groupings"`
+}
+
+// ImportReport describes what an import changes, or changed when DryRun is false.
+type ImportReport struct {
+	DryRun    bool      `json:"dry_run"`
+	Mode      string    `json:"mode"`
+	Added     PolicySet `json:"added"`
+	Removed   PolicySet `json:"removed"`
+	Unchanged int       `json:"unchanged"`
+}
+
+// Changes counts the rules added and removed.
+func (r ImportReport) Changes() int {
+	return len(r.Added.Policies) + len(r.Added.Denies) + len(r.Added.Groupings) +
+		len(r.Removed.Policies) + len(r.Removed.Denies) + len(r.Removed.Groupings)
+}
+
+// PolicyCatalogue provides the org units and function names imported rules may refer to.
+type PolicyCatalogue interface {
+	Tree(ctx context.Context) ([]*org.UnitNode, error)
+	ListFunctions(ctx context.Context) ([]org.Function, error)
+}
+
+// catalogue validates imports; main wires it to the org service.
+var catalogue PolicyCatalogue
+
+// SetPolicyCatalogue sets the catalogue used to validate policy imports.
+func SetPolicyCatalogue(c PolicyCatalogue) {
+	catalogue = c
+}
+
+// ExportPolicySet returns every p, p2 and g rule loaded in the enforcer, sorted.
+func ExportPolicySet() (PolicySet, error) {
+	policies, denies, groupings, err := loadedRules()
+	if err != nil {
+		return PolicySet{}, err
+	}
+	return newPolicySet(policies, denies, groupings), nil
+}
+
+// loadedRules returns the enforcer's p, p2 and g rules; p2 exists only in the hierarchical model.
+func loadedRules() (policies, denies, groupings [][]string, err error) {
+	e := access.GetEnforcer()
+	if policies, err = e.GetPolicy(); err != nil {
+		return nil, nil, nil, err
+	}
+	if access.Hierarchical() {
+		if denies, err = e.GetNamedPolicy(access.DenyPolicyType); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if groupings, err = e.GetNamedGroupingPolicy("g"); err != nil {
+		return nil, nil, nil, err
+	}
+	return policies, denies, groupings, nil
+}
+
+func newPolicySet(policies, denies, groupings [][]string) PolicySet {
+	set := PolicySet{Policies: toPolicyRules(policies), Denies: toPolicyRules(denies), Groupings: []GroupingRule{}}
+	for _, g := range sortedRules(groupings) {
+		if len(g) >= 3 {
+			set.Groupings = append(set.Groupings, GroupingRule{Subject: g[0], Role: g[1], Domain: g[2]})
+		}
+	}
+	return set
+}
+
+func toPolicyRules(rules [][]string) []PolicyRule {
+	out := []PolicyRule{}
+	for _, p := range sortedRules(rules) {
+		if len(p) >= 4 {
+			out = append(out, PolicyRule{Role: p[0], Domain: p[1], Object: p[2], Action: p[3]})
+		}
+	}
+	return out
+}
+
+func fromPolicyRules(policies []PolicyRule) [][]string {
+	rules := make([][]string, 0, len(policies))
+	for _, p := range policies {
+		rules = append(rules, []string{p.Role, p.Domain, p.Object, p.Action})
+	}
+	return rules
+}
+
+func (s PolicySet) policyRules() [][]string {
+	return fromPolicyRules(s.Policies)
+}
+
+func (s PolicySet) denyRules() [][]string {
+	return fromPolicyRules(s.Denies)
+}
+
+func (s PolicySet) groupingRules() [][]string {
+	rules := make([][]string, 0, len(s.Groupings))
+	for _, g := range s.Groupings {
+		rules = append(rules, []string{g.Subject, g.Role, g.Domain})
+	}
+	return rules
+}
+
+// WriteCSV writes the set in Casbin's CSV policy format, one "p, ...", "p2, ..." or "g, ..." line per rule.
+func (s PolicySet) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	for _, p := range s.policyRules() {
+		if err := cw.Write(append([]string{"p"}, p...)); err != nil {
+			return err
+		}
+	}
+	for _, d := range s.denyRules() {
+		if err := cw.Write(append([]string{access.DenyPolicyType}, d...)); err != nil {
+			return err
+		}
+	}
+	for _, g := range s.groupingRules() {
+		if err := cw.Write(append([]string{"g"}, g...)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParsePolicyCSV reads Casbin's CSV policy format. Blank lines and lines starting with # are skipped.
+func ParsePolicyCSV(r io.Reader) (PolicySet, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	set := PolicySet{Policies: []PolicyRule{}, Denies: []PolicyRule{}, Groupings: []GroupingRule{}}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return set, nil
+		}
+		if err != nil {
+			return PolicySet{}, fmt.Errorf("%w: %v", ErrInvalidPolicySet, err)
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		line, _ := cr.FieldPos(0)
+		switch {
+		case record[0] == "p" && len(record) == 5:
+			set.Policies = append(set.Policies, PolicyRule{Role: record[1], Domain: record[2], Object: record[3], Action: record[4]})
+		case record[0] == access.DenyPolicyType && len(record) == 5:
+			set.Denies = append(set.Denies, PolicyRule{Role: record[1], Domain: record[2], Object: record[3], Action: record[4]})
+		case record[0] == "g" && len(record) == 4:
+			set.Groupings = append(set.Groupings, GroupingRule{Subject: record[1], Role: record[2], Domain: record[3]})
+		default:
+			return PolicySet{}, fmt.Errorf("%w: line %d: expected \"p, role, domain, object, action\", \"p2, role, domain, object, action\" or \"g, subject, role, domain\"", ErrInvalidPolicySet, line)
+		}
+	}
+}
+
+// ImportPolicySet compares set with the loaded p, p2 and g rules and, unless dryRun is set, applies the
+// difference in one transaction. In full mode rules missing from set are removed; in partial
+// mode only those in the org-unit domains set mentions. Grouping rules are mirrored into
+// user_functions in the same transaction.
+func ImportPolicySet(ctx context.Context, set PolicySet, mode string, dryRun bool) (ImportReport, error) {
+	if mode == "" {
+		mode = ImportFull
+	}
+	if mode != ImportFull && mode != ImportPartial {
+		return ImportReport{}, &PolicySetError{Problems: []string{fmt.Sprintf("unknown mode %q", mode)}}
+	}
+	if err := validatePolicySet(ctx, set); err != nil {
+		return ImportReport{}, err
+	}
+
+	currentPolicies, currentDenies, currentGroupings, err := loadedRules()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var inScope func(domain string) bool
+	if mode == ImportFull {
+		inScope = func(string) bool { return true }
+	} else {
+		domains := map[string]bool{}
+		for _, p := range set.Policies {
+			domains[p.Domain] = true
+		}
+		for _, d := range set.Denies {
+			domains[d.Domain] = true
+		}
+		for _, g := range set.Groupings {
+			domains[g.Domain] = true
+		}
+		inScope = func(domain string) bool { return domains[domain] }
+	}
+
+	addP, removeP, keptP := diffRules(currentPolicies, set.policyRules(), 1, inScope)
+	addD, removeD, keptD := diffRules(currentDenies, set.denyRules(), 1, inScope)
+	addG, removeG, keptG := diffRules(currentGroupings, set.groupingRules(), 2, inScope)
+	report := ImportReport{
+		DryRun:    dryRun,
+		Mode:      mode,
+		Added:     newPolicySet(addP, addD, addG),
+		Removed:   newPolicySet(removeP, removeD, removeG),
+		Unchanged: keptP + keptD + keptG,
+	}
+	changes := access.RuleChanges{
+		AddPolicies: addP, RemovePolicies: removeP,
+		AddDenies: addD, RemoveDenies: removeD,
+		AddGroupings: addG, RemoveGroupings: removeG,
+	}
+	if dryRun || changes.Empty() {
+		return report, nil
+	}
+
+	if err := access.ApplyRuleChanges(ctx, changes, func(tx *gorm.DB) error {
+		return mirrorUserFunctions(tx, addG, removeG)
+	}); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// diffRules returns the wanted rules not yet present, the present rules within scope that
+// are not wanted, and how many wanted rules are already present. domainIndex locates the
+// domain field used for scoping.
+func diffRules(current, wanted [][]string, domainIndex int, inScope func(string) bool) (add, remove [][]string, kept int) {
+	have := make(map[string]bool, len(current))
+	for _, r := range current {
+		have[ruleKey(r)] = true
+	}
+	want := make(map[string]bool, len(wanted))
+	for _, r := range wanted {
+		key := ruleKey(r)
+		if want[key] {
+			continue
+		}
+		want[key] = true
+		if have[key] {
+			kept++
+		} else {
+			add = append(add, r)
+		}
+	}
+	for _, r := range current {
+		if len(r) > domainIndex && inScope(r[domainIndex]) && !want[ruleKey(r)] {
+			remove = append(remove, r)
+		}
+	}
+	return add, remove, kept
+}
+
+// validatePolicySet checks that every rule names a catalogue function and an existing org
+// unit, that grouping subjects are users, and that deny rules are only imported into the
+// hierarchical model.
+func validatePolicySet(ctx context.Context, set PolicySet) error {
+	if catalogue == nil {
+		return errors.New("policy catalogue is not configured")
+	}
+	functions, err := catalogue.ListFunctions(ctx)
+	if err != nil {
+		return err
+	}
+	tree, err := catalogue.Tree(ctx)
+	if err != nil {
+		return err
+	}
+	knownFunctions := make(map[string]bool, len(functions))
+	for _, f := range functions {
+		knownFunctions[f.Name] = true
+	}
+	knownUnits := map[int]bool{}
+	var collect func(nodes []*org.UnitNode)
+	collect = func(nodes []*org.UnitNode) {
+		for _, n := range nodes {
+			knownUnits[n.ID] = true
+			collect(n.Children)
+		}
+	}
+	collect(tree)
+
+	var problems []string
+	checkRole := func(where, role string) {
+		if !knownFunctions[role] {
+			problems = append(problems, fmt.Sprintf("%s: unknown function %q", where, role))
+		}
+	}
+	checkDomain := func(where, domain string) {
+		id, ok := parseIDPrefix(domain, "orgunit:")
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: domain %q is not of the form orgunit:<id>", where, domain))
+		} else if !knownUnits[id] {
+			problems = append(problems, fmt.Sprintf("%s: organizational unit %d does not exist", where, id))
+		}
+	}
+	for i, p := range set.Policies {
+		where := fmt.Sprintf("policies[%d]", i)
+		checkRole(where, p.Role)
+		checkDomain(where, p.Domain)
+		if strings.TrimSpace(p.Object) == "" || strings.TrimSpace(p.Action) == "" {
+			problems = append(problems, where+": object and action are required")
+		}
+	}
+	if len(set.Denies) > 0 && !access.Hierarchical() {
+		problems = append(problems, "denies: "+errDenyNeedsHierarchy)
+	}
+	for i, d := range set.Denies {
+		where := fmt.Sprintf("denies[%d]", i)
+		checkRole(where, d.Role)
+		checkDomain(where, d.Domain)
+		if strings.TrimSpace(d.Object) == "" || strings.TrimSpace(d.Action) == "" {
+			problems = append(problems, where+": object and action are required")
+		}
+	}
+	for i, g := range set.Groupings {
+		where := fmt.Sprintf("groupings[%d]", i)
+		if _, ok := parseIDPrefix(g.Subject, "user:"); !ok {
+			problems = append(problems, fmt.Sprintf("%s: subject %q is not of the form user:<id>", where, g.Subject))
+		}
+		checkRole(where, g.Role)
+		checkDomain(where, g.Domain)
+	}
+	if len(problems) > 0 {
+		return &PolicySetError{Problems: problems}
+	}
+	return nil
+}
+
+// mirrorUserFunctions keeps user_functions in step with imported grouping rules, which
+// are validated to have the form g(user:<id>, function, orgunit:<id>).
+func mirrorUserFunctions(tx *gorm.DB, add, remove [][]string) error {
+	for _, g := range remove {
+		userID, _ := parseIDPrefix(g[0], "user:")
+		unitID, _ := parseIDPrefix(g[2], "orgunit:")
+		if err := tx.Exec(`
+			DELETE FROM user_functions uf
+			USING functions f
+			WHERE f.id = uf.function_id AND uf.user_id = ? AND f.name = ? AND uf.unit_id = ?`,
+			userID, g[1], unitID).Error; err != nil {
+			return err
+		}
+	}
+	for _, g := range add {
+		userID, _ := parseIDPrefix(g[0], "user:")
+		unitID, _ := parseIDPrefix(g[2], "orgunit:")
+		if err := tx.Exec(`
+			INSERT INTO user_functions (user_id, function_id, unit_id)
+			SELECT ?, f.id, ? FROM functions f WHERE f.name = ?
+			ON CONFLICT (user_id, function_id, unit_id) DO NOTHING`,
+			userID, unitID, g[1]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseIDPrefix(s, prefix string) (int, bool) {
+	rest, ok := strings.CutPrefix(s, prefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(rest)
+	return id, err == nil && id > 0
+}
+
+func ruleKey(rule []string) string {
+	return strings.Join(rule, "\x00")
+}
+
+func sortedRules(rules [][]string) [][]string {
+	sorted := append([][]string(nil), rules...)
+	sort.Slice(sorted, func(i, j int) bool { return ruleKey(sorted[i]) < ruleKey(sorted[j]) })
+	return sorted
+}
@@ -19,6 +19,13 @@ func RegisterRoutes(rg *gin.RouterGroup) {
 	admin.POST("/policies", AddPolicy)
 	admin.DELETE("/policies", DeletePolicy)
 	admin.GET("/policies", ListPolicies)
+	admin.GET("/policies/export", ExportPolicies)
+	admin.POST("/policies/import", ImportPolicies)
+
+	// Access decision simulator
+	admin.POST("/access/check", CheckAccess)
+	admin.POST("/access/check/matrix", CheckAccessMatrix)
+	admin.GET("/access/sync", PolicySyncStatus)
 
 	// Organizational unit reference data
 	admin.GET("/organizational_units", ListOrganizationalUnitsHandler)
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/common/utils"
+)
+
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// ContextRequestIDKey is the Gin context key holding the request ID.
+const ContextRequestIDKey = "requestID"
+
+// requestIDPattern limits client-supplied request IDs to something safe to log and store.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFromContext returns the actor stored by WithActor, or the zero value.
+func ActorFromContext(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
+
+// RequestID returns a Gin middleware that keeps the caller's X-Request-ID, or generates one,
+// and echoes it in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id, _ = utils.GenerateSecureToken(8)
+		}
+		c.Set(ContextRequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(WithActor(c.Request.Context(), Actor{IP: c.ClientIP(), RequestID: id}))
+		c.Next()
+	}
+}
+
+// CaptureActor returns a Gin middleware that attributes audit entries recorded with the
+// request context to the authenticated user. It must run after auth.Middleware.
+func CaptureActor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := ActorFromContext(c.Request.Context())
+		actor.IP = c.ClientIP()
+		actor.RequestID = c.GetString(ContextRequestIDKey)
+		if userID, ok := utils.GetUserIDFromContext(c); ok {
+			actor.UserID = &userID
+		}
+		actor.Role = c.GetString("userRole")
+		c.Request = c.Request.WithContext(WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler defines the HTTP handler for reading the audit log.
+type Handler struct {
+	service ServiceInterface
+}
+
+// NewHandler creates a new Handler instance for the audit service.
+func NewHandler(s ServiceInterface) *Handler {
+	return &Handler{service: s}
+}
+
+// List returns a page of audit entries, newest first.
+// GET /admin/audit?actor_id=1&action=policy.add&target_type=user&target_id=7&from=2025-01-01&to=2025-02-01&limit=50&offset=0
+func (h *Handler) List(c *gin.Context) {
+	var filter Filter
+	if v := c.Query("actor_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'actor_id'"})
+			return
+		}
+		filter.ActorID = &id
+	}
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(name); v != "" {
+			t, err := parseDateOrTime(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid '" + name + "' date"})
+				return
+			}
+			*dst = &t
+		}
+	}
+	filter.Action = c.Query("action")
+	filter.TargetType = c.Query("target_type")
+	filter.TargetID = c.Query("target_id")
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	filter.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	page, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("❌ Audit request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func parseDateOrTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
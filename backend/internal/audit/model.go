@@ -0,0 +1,121 @@
+// Package audit keeps an append-only, hash-chained log of administrative actions.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// GenesisHash is the prev_hash of the first entry.
+var GenesisHash = strings.Repeat("0", 64)
+
+// Entry is one recorded action.
+type Entry struct {
+	ID         int64           `db:"id" json:"id"`
+	ActorID    *int64          `db:"actor_id" json:"actor_id"`
+	ActorRole  string          `db:"actor_role" json:"actor_role"`
+	Action     string          `db:"action" json:"action"`
+	TargetType string          `db:"target_type" json:"target_type"`
+	TargetID   string          `db:"target_id" json:"target_id"`
+	Before     json.RawMessage `db:"before" json:"before"`
+	After      json.RawMessage `db:"after" json:"after"`
+	IP         string          `db:"ip" json:"ip"`
+	RequestID  string          `db:"request_id" json:"request_id"`
+	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
+	PrevHash   string          `db:"prev_hash" json:"prev_hash"`
+	Hash       string          `db:"hash" json:"hash"`
+}
+
+// hashedFields fixes the field order and encoding the hash is computed over.
+type hashedFields struct {
+	PrevHash   string          `json:"prev_hash"`
+	ActorID    *int64          `json:"actor_id"`
+	ActorRole  string          `json:"actor_role"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// ComputeHash returns the hex SHA-256 of the entry's fields chained to e.PrevHash.
+// CreatedAt is hashed in UTC at the microsecond precision PostgreSQL stores.
+func ComputeHash(e Entry) string {
+	payload, _ := json.Marshal(hashedFields{
+		PrevHash:   e.PrevHash,
+		ActorID:    e.ActorID,
+		ActorRole:  e.ActorRole,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Before:     nullIfEmpty(e.Before),
+		After:      nullIfEmpty(e.After),
+		IP:         e.IP,
+		RequestID:  e.RequestID,
+		CreatedAt:  e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+func nullIfEmpty(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("null")
+	}
+	return raw
+}
+
+// Event describes an action to record. Before and After are marshalled to JSON;
+// nil means there was no state before (a creation) or after (a deletion). They hold IDs and
+// state only, never names, emails or free text: entries are append-only and outlive erasure.
+type Event struct {
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+}
+
+// Actor identifies who performed a request, and from where.
+type Actor struct {
+	UserID    *int64
+	Role      string
+	IP        string
+	RequestID string
+}
+
+// Filter narrows the audit log. Zero values are ignored.
+type Filter struct {
+	ActorID    *int64
+	Action     string
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// Page is one page of audit entries, newest first.
+type Page struct {
+	Entries []Entry `json:"entries"`
+	Total   int     `json:"total"`
+	Limit   int     `json:"limit"`
+	Offset  int     `json:"offset"`
+}
+
+// VerifyResult reports whether the chain is intact. Head is the hash of the last entry;
+// keeping a copy elsewhere also makes truncation of the tail detectable.
+type VerifyResult struct {
+	Entries  int    `json:"entries"`
+	Valid    bool   `json:"valid"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Head     string `json:"head"`
+}
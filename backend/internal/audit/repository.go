@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository defines the interface for audit log storage.
+type Repository interface {
+	Append(ctx context.Context, e *Entry) error
+	List(ctx context.Context, filter Filter) ([]Entry, int, error)
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]Entry, error)
+}
+
+// auditRepository is a concrete implementation of the Repository interface.
+type auditRepository struct {
+	db *sqlx.DB
+}
+
+// NewRepository returns a new instance of an audit Repository.
+func NewRepository(db *sqlx.DB) Repository {
+	return &auditRepository{db: db}
+}
+
+// appendLockKey serializes appends so that every entry chains to the one before it.
+const appendLockKey = 0x61756469 // "audi"
+
+// entryColumns reads the JSON payloads as text so they hash exactly as they were written.
+const entryColumns = `id, actor_id, actor_role, action, target_type, target_id,
+	COALESCE(before::text, 'null') AS before, COALESCE(after::text, 'null') AS after,
+	ip, request_id, created_at, prev_hash, hash`
+
+// Append chains e to the last entry, fills in its ID, PrevHash and Hash, and stores it.
+func (r *auditRepository) Append(ctx context.Context, e *Entry) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, appendLockKey); err != nil {
+		return err
+	}
+	e.PrevHash = GenesisHash
+	if err := tx.GetContext(ctx, &e.PrevHash, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	e.Hash = ComputeHash(*e)
+
+	if err := tx.GetContext(ctx, &e.ID, `
+		INSERT INTO audit_log (actor_id, actor_role, action, target_type, target_id, before, after,
+		                       ip, request_id, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`,
+		e.ActorID, e.ActorRole, e.Action, e.TargetType, e.TargetID, jsonParam(e.Before), jsonParam(e.After),
+		e.IP, e.RequestID, e.CreatedAt, e.PrevHash, e.Hash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// List returns the entries matching the filter, newest first, and the number of matches.
+func (r *auditRepository) List(ctx context.Context, filter Filter) ([]Entry, int, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.ActorID != nil {
+		add("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM audit_log`+clause, args...); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `SELECT ` + entryColumns + ` FROM audit_log` + clause +
+		fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	entries := []Entry{}
+	err := r.db.SelectContext(ctx, &entries, query, args...)
+	return entries, total, err
+}
+
+// ListAfter returns up to limit entries with an ID above afterID, in chain order.
+func (r *auditRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]Entry, error) {
+	entries := []Entry{}
+	err := r.db.SelectContext(ctx, &entries, `
+		SELECT `+entryColumns+` FROM audit_log WHERE id > $1 ORDER BY id LIMIT $2`, afterID, limit)
+	return entries, err
+}
+
+// jsonParam stores an empty payload as NULL.
+func jsonParam(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
@@ -0,0 +1,9 @@
+package audit
+
+import "github.com/gin-gonic/gin"
+
+// RegisterRoutes sets up the audit log endpoint under the given route group. guard must
+// restrict it to administrators; it is passed in because auth itself records audit events.
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, guard ...gin.HandlerFunc) {
+	rg.GET("/admin/audit", append(guard, h.List)...)
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+	verifyBatchSize  = 1000
+)
+
+// Recorder appends events to the audit log.
+type Recorder interface {
+	Record(ctx context.Context, ev Event) error
+}
+
+// ServiceInterface defines the operations available on the audit log.
+type ServiceInterface interface {
+	Recorder
+	List(ctx context.Context, filter Filter) (Page, error)
+	Verify(ctx context.Context) (VerifyResult, error)
+}
+
+// Service implements ServiceInterface using a data repository.
+type Service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+// NewService creates a new audit Service.
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, now: time.Now}
+}
+
+// Record appends ev, attributed to the actor stored in ctx by CaptureActor.
+func (s *Service) Record(ctx context.Context, ev Event) error {
+	before, err := marshalState(ev.Before)
+	if err != nil {
+		return fmt.Errorf("failed to encode before state: %w", err)
+	}
+	after, err := marshalState(ev.After)
+	if err != nil {
+		return fmt.Errorf("failed to encode after state: %w", err)
+	}
+	actor := ActorFromContext(ctx)
+	e := Entry{
+		ActorID:    actor.UserID,
+		ActorRole:  actor.Role,
+		Action:     ev.Action,
+		TargetType: ev.TargetType,
+		TargetID:   ev.TargetID,
+		Before:     before,
+		After:      after,
+		IP:         actor.IP,
+		RequestID:  actor.RequestID,
+		CreatedAt:  s.now().UTC().Truncate(time.Microsecond),
+	}
+	return s.repo.Append(ctx, &e)
+}
+
+// List returns a page of entries matching the filter, newest first.
+func (s *Service) List(ctx context.Context, filter Filter) (Page, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	entries, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return Page{}, err
+	}
+	return Page{Entries: entries, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// Verify walks the whole chain and reports the first entry whose link or hash does not match.
+func (s *Service) Verify(ctx context.Context) (VerifyResult, error) {
+	result := VerifyResult{Valid: true, Head: GenesisHash}
+	var lastID int64
+	for {
+		entries, err := s.repo.ListAfter(ctx, lastID, verifyBatchSize)
+		if err != nil {
+			return result, err
+		}
+		for _, e := range entries {
+			result.Entries++
+			switch {
+			case e.PrevHash != result.Head:
+				return broken(result, e.ID, "previous hash does not match the preceding entry"), nil
+			case e.Hash != ComputeHash(e):
+				return broken(result, e.ID, "entry content does not match its hash"), nil
+			}
+			result.Head = e.Hash
+			lastID = e.ID
+		}
+		if len(entries) < verifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+func broken(result VerifyResult, id int64, reason string) VerifyResult {
+	result.Valid = false
+	result.BrokenAt = &id
+	result.Reason = reason
+	return result
+}
+
+func marshalState(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// recorder receives the events passed to Record; main wires it to the audit Service.
+var recorder Recorder
+
+// SetRecorder sets the recorder used by Record.
+func SetRecorder(r Recorder) {
+	recorder = r
+}
+
+// Record appends ev to the audit log through the recorder set with SetRecorder.
+// The action has already happened, so failures are logged rather than returned.
+func Record(ctx context.Context, ev Event) {
+	if recorder == nil {
+		return
+	}
+	if err := recorder.Record(ctx, ev); err != nil {
+		log.Printf("❌ Failed to write audit entry %s %s/%s: %v", ev.Action, ev.TargetType, ev.TargetID, err)
+	}
+}
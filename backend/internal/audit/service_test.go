@@ -0,0 +1,116 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nomenarkt/lamina/internal/audit"
+)
+
+// memoryRepo chains entries in memory the way the database repository does.
+type memoryRepo struct {
+	mock.Mock
+	entries []audit.Entry
+}
+
+func (r *memoryRepo) Append(_ context.Context, e *audit.Entry) error {
+	e.PrevHash = audit.GenesisHash
+	if n := len(r.entries); n > 0 {
+		e.PrevHash = r.entries[n-1].Hash
+	}
+	e.ID = int64(len(r.entries) + 1)
+	e.Hash = audit.ComputeHash(*e)
+	r.entries = append(r.entries, *e)
+	return nil
+}
+
+func (r *memoryRepo) List(ctx context.Context, filter audit.Filter) ([]audit.Entry, int, error) {
+	args := r.Called(ctx, filter)
+	return args.Get(0).([]audit.Entry), args.Int(1), args.Error(2)
+}
+
+func (r *memoryRepo) ListAfter(_ context.Context, afterID int64, limit int) ([]audit.Entry, error) {
+	var out []audit.Entry
+	for _, e := range r.entries {
+		if e.ID > afterID && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func recordSome(t *testing.T, svc *audit.Service) {
+	t.Helper()
+	actorID := int64(1)
+	ctx := audit.WithActor(context.Background(), audit.Actor{UserID: &actorID, Role: "admin", IP: "10.0.0.1", RequestID: "req-1"})
+	require.NoError(t, svc.Record(ctx, audit.Event{Action: "user.invite", TargetType: "user", TargetID: "7", After: map[string]any{"email": "a@b.c"}}))
+	require.NoError(t, svc.Record(ctx, audit.Event{Action: "policy.remove", TargetType: "policy", TargetID: "orgunit:2", Before: []string{"planner", "orgunit:2", "/crew", "GET"}}))
+	require.NoError(t, svc.Record(ctx, audit.Event{Action: "crew.unassign_flight", TargetType: "flight", TargetID: "9"}))
+}
+
+func TestService_RecordChainsEntries(t *testing.T) {
+	repo := &memoryRepo{}
+	svc := audit.NewService(repo)
+	recordSome(t, svc)
+
+	require.Len(t, repo.entries, 3)
+	first := repo.entries[0]
+	assert.Equal(t, audit.GenesisHash, first.PrevHash)
+	assert.Equal(t, int64(1), *first.ActorID)
+	assert.Equal(t, "admin", first.ActorRole)
+	assert.Equal(t, "10.0.0.1", first.IP)
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.JSONEq(t, `{"email":"a@b.c"}`, string(first.After))
+	assert.Nil(t, first.Before)
+	assert.Equal(t, first.Hash, repo.entries[1].PrevHash)
+
+	result, err := svc.Verify(context.Background())
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 3, result.Entries)
+	assert.Equal(t, repo.entries[2].Hash, result.Head)
+}
+
+func TestService_VerifyDetectsTampering(t *testing.T) {
+	repo := &memoryRepo{}
+	svc := audit.NewService(repo)
+	recordSome(t, svc)
+
+	repo.entries[1].Before = json.RawMessage(`["viewer","orgunit:2","/crew","GET"]`)
+	result, err := svc.Verify(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	require.NotNil(t, result.BrokenAt)
+	assert.Equal(t, int64(2), *result.BrokenAt)
+	assert.Contains(t, result.Reason, "content")
+}
+
+func TestService_VerifyDetectsRemovedEntry(t *testing.T) {
+	repo := &memoryRepo{}
+	svc := audit.NewService(repo)
+	recordSome(t, svc)
+
+	repo.entries = append(repo.entries[:1], repo.entries[2:]...)
+	result, err := svc.Verify(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(3), *result.BrokenAt)
+	assert.Contains(t, result.Reason, "previous hash")
+}
+
+func TestService_ListClampsLimit(t *testing.T) {
+	repo := &memoryRepo{}
+	svc := audit.NewService(repo)
+	repo.On("List", mock.Anything, audit.Filter{Action: "policy.add", Limit: 500}).Return([]audit.Entry{}, 0, nil)
+
+	page, err := svc.List(context.Background(), audit.Filter{Action: "policy.add", Limit: 10000, Offset: -3})
+	require.NoError(t, err)
+	assert.Equal(t, 500, page.Limit)
+	assert.Equal(t, 0, page.Offset)
+	repo.AssertExpectations(t)
+}
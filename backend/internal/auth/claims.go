@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nomenarkt/lamina/internal/user"
+)
+
+// MemberRole is the department role of users who belong to a department without holding a function in it.
+const MemberRole = "member"
+
+// withAccessClaims fills the Departments and Modules claims of u from its org units,
+// functions, ranks and module entitlements.
+func (s *Service) withAccessClaims(ctx context.Context, u user.User) (user.User, error) {
+	grants, err := s.repo.ListDepartmentGrants(ctx, u.ID)
+	if err != nil {
+		return u, fmt.Errorf("failed to load departments: %w", err)
+	}
+	modules, err := s.repo.ListModules(ctx, u.ID)
+	if err != nil {
+		return u, fmt.Errorf("failed to load modules: %w", err)
+	}
+	u.Departments = BuildDepartmentAccess(grants)
+	u.Modules = modules
+	return u, nil
+}
+
+// BuildDepartmentAccess turns department grants into claims: one entry per department and
+// function held there, with its rank, and a MemberRole entry for departments the user only
+// belongs to.
+func BuildDepartmentAccess(grants []DepartmentGrant) []user.DepartmentAccess {
+	holdsFunction := map[string]bool{}
+	for _, g := range grants {
+		if g.Function != nil {
+			holdsFunction[g.Department] = true
+		}
+	}
+
+	access := []user.DepartmentAccess{}
+	seen := map[user.DepartmentAccess]bool{}
+	for _, g := range grants {
+		entry := user.DepartmentAccess{Name: g.Department, Role: MemberRole}
+		switch {
+		case g.Function != nil:
+			entry.Role = *g.Function
+			if g.Rank != nil {
+				entry.Rank = *g.Rank
+			}
+		case holdsFunction[g.Department]:
+			continue
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			access = append(access, entry)
+		}
+	}
+	return access
+}
@@ -2,19 +2,21 @@ package auth
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"fmt"
 	"html/template"
-	"log"
 	"os"
 	"time"
+
+	"github.com/nomenarkt/lamina/common/mailer"
 )
 
 //go:embed email_templates/confirmation_email.html
 var confirmationTemplateFS embed.FS
 
-// SendConfirmationEmail renders and logs the email body with a confirmation link.
-func SendConfirmationEmail(toEmail, token string, isResend bool) error {
+// SendConfirmationEmail renders the confirmation email and hands it to the mailer.
+func SendConfirmationEmail(ctx context.Context, m mailer.Mailer, toEmail, token string, isResend bool) error {
 	baseURL := os.Getenv("FRONTEND_CONFIRM_URL")
 	if baseURL == "" {
 		baseURL = "http://localhost:3000/confirm"
@@ -41,6 +43,41 @@ func SendConfirmationEmail(toEmail, token string, isResend bool) error {
 		return fmt.Errorf("❌ failed to render email template: %w", err)
 	}
 
-	log.Printf("📧 Confirmation email to %s:\n%s", toEmail, body.String())
-	return nil
+	subject := "Confirm your Lamina account"
+	if isResend {
+		subject = "Your new Lamina confirmation link"
+	}
+	return m.Send(ctx, mailer.Message{To: toEmail, Subject: subject, HTMLBody: body.String()})
+}
+
+//go:embed email_templates/password_reset_email.html
+var passwordResetTemplateFS embed.FS
+
+// SendPasswordResetEmail renders the password reset email and hands it to the mailer.
+func SendPasswordResetEmail(ctx context.Context, m mailer.Mailer, toEmail, token string, validFor time.Duration) error {
+	baseURL := os.Getenv("FRONTEND_RESET_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:3000/reset-password"
+	}
+	link := fmt.Sprintf("%s/%s", baseURL, token)
+
+	tmpl, err := template.ParseFS(passwordResetTemplateFS, "email_templates/password_reset_email.html")
+	if err != nil {
+		return fmt.Errorf("❌ failed to parse embedded email template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, struct {
+		Link         string
+		ValidMinutes int
+		Year         int
+	}{
+		Link:         link,
+		ValidMinutes: int(validFor.Minutes()),
+		Year:         time.Now().Year(),
+	}); err != nil {
+		return fmt.Errorf("❌ failed to render email template: %w", err)
+	}
+
+	return m.Send(ctx, mailer.Message{To: toEmail, Subject: "Reset your Lamina password", HTMLBody: body.String()})
 }
@@ -7,22 +7,25 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
+	"github.com/nomenarkt/lamina/common/mailer"
+	"github.com/nomenarkt/lamina/common/utils"
 )
 
 // RegisterRoutes registers the authentication endpoints for signup and login.
 func RegisterRoutes(router *gin.RouterGroup, db *sqlx.DB, service ServiceInterface) {
 	if service == nil {
-		service = NewService(NewAuthRepository(db))
+		service = NewService(NewAuthRepository(db), mailer.NewOutboxMailer(mailer.NewOutboxRepository(db)))
 	}
 
-	router.POST("/auth/signup", func(c *gin.Context) {
+	router.POST("/auth/signup", captureClientInfo(), func(c *gin.Context) {
 		service.Signup(c)
 	})
 
-	router.POST("/auth/login", func(c *gin.Context) {
+	router.POST("/auth/login", captureClientInfo(), func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -40,6 +43,46 @@ func RegisterRoutes(router *gin.RouterGroup, db *sqlx.DB, service ServiceInterfa
 		c.JSON(http.StatusOK, tokens)
 	})
 
+	router.POST("/auth/refresh", captureClientInfo(), func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tokens, err := service.Refresh(c.Request.Context(), req.RefreshToken)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrRefreshTokenReuse):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token already used, session revoked"})
+			case errors.Is(err, ErrInvalidRefreshToken):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+			default:
+				log.Printf("❌ Refresh failed: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not refresh tokens"})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	})
+
+	router.POST("/auth/logout", func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+			if errors.Is(err, ErrInvalidRefreshToken) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+				return
+			}
+			log.Printf("❌ Logout failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not log out"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+	})
+
 	router.GET("/auth/confirm/:token", func(c *gin.Context) {
 		token := c.Param("token")
 		frontendURL := os.Getenv("FRONTEND_URL")
@@ -70,7 +113,7 @@ func RegisterRoutes(router *gin.RouterGroup, db *sqlx.DB, service ServiceInterfa
 		c.Redirect(http.StatusFound, fmt.Sprintf("%s/email-confirmed", frontendURL))
 	})
 
-	router.POST("/auth/complete-invite", func(c *gin.Context) {
+	router.POST("/auth/complete-invite", captureClientInfo(), func(c *gin.Context) {
 		var req struct {
 			Token string `json:"token" binding:"required"`
 			PasswordPayload
@@ -90,10 +133,51 @@ func RegisterRoutes(router *gin.RouterGroup, db *sqlx.DB, service ServiceInterfa
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"access_token":  resp.AccessToken,
-			"refresh_token": resp.RefreshToken,
-		})
+		c.JSON(http.StatusOK, resp)
+	})
+
+	router.POST("/auth/forgot-password", func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+			return
+		}
+
+		if err := service.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+			log.Printf("❌ Forgot password failed: %v", err)
+		}
+
+		// Same response whether or not the account exists.
+		c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a reset link has been sent"})
+	})
+
+	router.POST("/auth/reset-password", func(c *gin.Context) {
+		var req struct {
+			Token string `json:"token" binding:"required"`
+			PasswordPayload
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+		if err := req.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := service.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+			if errors.Is(err, ErrInvalidResetToken) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("❌ Reset password failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not reset password"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "password updated"})
 	})
 
 	router.POST("/auth/resend-confirmation", func(c *gin.Context) {
@@ -127,3 +211,184 @@ func RegisterRoutes(router *gin.RouterGroup, db *sqlx.DB, service ServiceInterfa
 		c.JSON(http.StatusOK, gin.H{"message": "Confirmation email resent"})
 	})
 }
+
+// RegisterSessionRoutes registers session management endpoints. The router group
+// must already be protected by Middleware so the user and session IDs are set.
+func RegisterSessionRoutes(router *gin.RouterGroup, service SessionServiceInterface) {
+	router.GET("/user/sessions", func(c *gin.Context) {
+		userID, ok := utils.GetUserIDFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		sessions, err := service.ListSessions(c.Request.Context(), userID, c.GetString(ContextSessionIDKey))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list sessions"})
+			return
+		}
+		c.JSON(http.StatusOK, sessions)
+	})
+
+	router.DELETE("/user/sessions/:id", func(c *gin.Context) {
+		userID, ok := utils.GetUserIDFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if err := service.RevokeSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke session"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+	})
+
+	router.DELETE("/admin/users/:id/sessions", RequireRoles("admin"), func(c *gin.Context) {
+		targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			return
+		}
+		if err := service.RevokeAllSessions(c.Request.Context(), targetID); err != nil {
+			log.Printf("❌ RevokeAllSessions failed for user %d: %v", targetID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke sessions"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "all sessions revoked"})
+	})
+}
+
+// RegisterMFALoginRoutes registers the public second-factor login endpoints that
+// accept the mfa_pending token returned by /auth/login.
+func RegisterMFALoginRoutes(router *gin.RouterGroup, service MFAServiceInterface) {
+	router.POST("/auth/mfa/login", captureClientInfo(), func(c *gin.Context) {
+		var req MFALoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tokens, err := service.CompleteMFALogin(c.Request.Context(), req.MFAToken, req.Code)
+		if err != nil {
+			respondMFAError(c, err, "could not complete login")
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	})
+
+	router.POST("/auth/mfa/enroll", func(c *gin.Context) {
+		var req struct {
+			MFAToken string `json:"mfa_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		enrollment, err := service.BeginMFAEnrollmentWithToken(c.Request.Context(), req.MFAToken)
+		if err != nil {
+			respondMFAError(c, err, "could not start mfa enrollment")
+			return
+		}
+		c.JSON(http.StatusOK, enrollment)
+	})
+}
+
+// RegisterMFARoutes registers MFA self-service and admin endpoints. The router group
+// must already be protected by Middleware.
+func RegisterMFARoutes(router *gin.RouterGroup, service MFAServiceInterface) {
+	router.POST("/user/mfa/enroll", func(c *gin.Context) {
+		userID, ok := utils.GetUserIDFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		enrollment, err := service.BeginMFAEnrollment(c.Request.Context(), userID)
+		if err != nil {
+			respondMFAError(c, err, "could not start mfa enrollment")
+			return
+		}
+		c.JSON(http.StatusOK, enrollment)
+	})
+
+	router.POST("/user/mfa/verify", func(c *gin.Context) {
+		userID, ok := utils.GetUserIDFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.ConfirmMFAEnrollment(c.Request.Context(), userID, req.Code); err != nil {
+			respondMFAError(c, err, "could not verify mfa code")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "mfa enabled"})
+	})
+
+	router.POST("/user/mfa/disable", func(c *gin.Context) {
+		userID, ok := utils.GetUserIDFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.DisableMFA(c.Request.Context(), userID, req.Code); err != nil {
+			respondMFAError(c, err, "could not disable mfa")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "mfa disabled"})
+	})
+
+	router.GET("/admin/mfa/requirements", RequireRoles("admin"), func(c *gin.Context) {
+		requirements, err := service.ListMFARoleRequirements(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list mfa requirements"})
+			return
+		}
+		c.JSON(http.StatusOK, requirements)
+	})
+
+	router.PUT("/admin/mfa/requirements", RequireRoles("admin"), func(c *gin.Context) {
+		adminID, ok := utils.GetUserIDFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req MFARoleRequirementRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := service.SetMFARoleRequirement(c.Request.Context(), req.Role, *req.Required, adminID); err != nil {
+			log.Printf("❌ SetMFARoleRequirement failed for role %q: %v", req.Role, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update mfa requirement"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "mfa requirement updated"})
+	})
+}
+
+func respondMFAError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidMFAToken), errors.Is(err, ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrMFALocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrMFAAlreadyEnabled), errors.Is(err, ErrMFANotEnrolled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrMFARequiredByRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ MFA request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
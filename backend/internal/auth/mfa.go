@@ -0,0 +1,289 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nomenarkt/lamina/common/utils"
+	"github.com/nomenarkt/lamina/internal/user"
+)
+
+const (
+	recoveryCodeCount    = 10
+	mfaMaxFailedAttempts = 5
+	mfaLockout           = 15 * time.Minute
+	totpSkew             = 1
+)
+
+var (
+	// ErrInvalidMFAToken is returned when an mfa_pending token is malformed or expired.
+	ErrInvalidMFAToken = errors.New("invalid or expired mfa token")
+	// ErrInvalidMFACode is returned when a TOTP or recovery code does not verify.
+	ErrInvalidMFACode = errors.New("invalid mfa code")
+	// ErrMFANotEnrolled is returned when the user has no TOTP enrollment to act on.
+	ErrMFANotEnrolled = errors.New("mfa not enrolled")
+	// ErrMFAAlreadyEnabled is returned when starting enrollment while MFA is already active.
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+	// ErrMFALocked is returned while code verification is locked after repeated failures.
+	ErrMFALocked = errors.New("too many invalid mfa codes, try again later")
+	// ErrMFARequiredByRole is returned when disabling MFA that the user's role mandates.
+	ErrMFARequiredByRole = errors.New("mfa is mandatory for this role")
+)
+
+// MFAServiceInterface defines TOTP enrollment, second-factor login, and per-role enforcement.
+type MFAServiceInterface interface {
+	BeginMFAEnrollment(ctx context.Context, userID int64) (MFAEnrollment, error)
+	BeginMFAEnrollmentWithToken(ctx context.Context, mfaToken string) (MFAEnrollment, error)
+	ConfirmMFAEnrollment(ctx context.Context, userID int64, code string) error
+	DisableMFA(ctx context.Context, userID int64, code string) error
+	CompleteMFALogin(ctx context.Context, mfaToken, code string) (Response, error)
+	ListMFARoleRequirements(ctx context.Context) ([]MFARoleRequirement, error)
+	SetMFARoleRequirement(ctx context.Context, role string, required bool, updatedBy int64) error
+}
+
+// loginChallenge decides whether a user who passed the password check gets a
+// token pair right away or an mfa_pending token for the second step.
+func (s *Service) loginChallenge(ctx context.Context, u user.User) (Response, error) {
+	enrollment, enrolled, err := s.findMFA(ctx, u.ID)
+	if err != nil {
+		return Response{}, err
+	}
+	if enrolled && enrollment.EnabledAt != nil {
+		token, err := s.generateMFAToken(u.ID)
+		if err != nil {
+			return Response{}, err
+		}
+		return Response{MFARequired: true, MFAToken: token}, nil
+	}
+
+	required, err := s.repo.IsMFARequiredForRole(ctx, u.Role)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to check mfa requirement: %w", err)
+	}
+	if required {
+		token, err := s.generateMFAToken(u.ID)
+		if err != nil {
+			return Response{}, err
+		}
+		return Response{MFAEnrollmentRequired: true, MFAToken: token}, nil
+	}
+
+	return s.issueTokens(ctx, u, "")
+}
+
+// BeginMFAEnrollment generates a new TOTP secret and recovery codes for the user.
+// The enrollment stays pending until a first code is confirmed.
+func (s *Service) BeginMFAEnrollment(ctx context.Context, userID int64) (MFAEnrollment, error) {
+	userRecord, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return MFAEnrollment{}, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	enrollment, enrolled, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	if enrolled && enrollment.EnabledAt != nil {
+		return MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	if err := s.repo.SaveMFAEnrollment(ctx, userID, secret, hashes); err != nil {
+		if errors.Is(err, ErrMFAAlreadyEnabled) {
+			return MFAEnrollment{}, err
+		}
+		return MFAEnrollment{}, fmt.Errorf("failed to store mfa enrollment: %w", err)
+	}
+
+	return MFAEnrollment{
+		Secret:        secret,
+		OTPAuthURI:    utils.TOTPProvisioningURI(mfaIssuer(), userRecord.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// BeginMFAEnrollmentWithToken starts enrollment for a user whose role mandates MFA
+// and who only holds the mfa_pending token returned by Login.
+func (s *Service) BeginMFAEnrollmentWithToken(ctx context.Context, mfaToken string) (MFAEnrollment, error) {
+	claims, err := s.parseMFAToken(mfaToken)
+	if err != nil {
+		return MFAEnrollment{}, ErrInvalidMFAToken
+	}
+	return s.BeginMFAEnrollment(ctx, claims.UserID)
+}
+
+// ConfirmMFAEnrollment enables a pending enrollment once the user proves the authenticator works.
+func (s *Service) ConfirmMFAEnrollment(ctx context.Context, userID int64, code string) error {
+	enrollment, enrolled, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return ErrMFANotEnrolled
+	}
+	if enrollment.EnabledAt != nil {
+		return ErrMFAAlreadyEnabled
+	}
+	return s.verifyMFACode(ctx, enrollment, code)
+}
+
+// DisableMFA removes the user's TOTP enrollment after checking a current code.
+// It is refused when the user's role makes MFA mandatory.
+func (s *Service) DisableMFA(ctx context.Context, userID int64, code string) error {
+	userRecord, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	required, err := s.repo.IsMFARequiredForRole(ctx, userRecord.Role)
+	if err != nil {
+		return fmt.Errorf("failed to check mfa requirement: %w", err)
+	}
+	if required {
+		return ErrMFARequiredByRole
+	}
+
+	enrollment, enrolled, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enrolled || enrollment.EnabledAt == nil {
+		return ErrMFANotEnrolled
+	}
+	if err := s.verifyMFACode(ctx, enrollment, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteMFA(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+	return nil
+}
+
+// CompleteMFALogin exchanges an mfa_pending token and a TOTP or recovery code for a token pair.
+// A valid code for a pending enrollment also enables it, which completes forced enrollment.
+func (s *Service) CompleteMFALogin(ctx context.Context, mfaToken, code string) (Response, error) {
+	claims, err := s.parseMFAToken(mfaToken)
+	if err != nil {
+		return Response{}, ErrInvalidMFAToken
+	}
+
+	userRecord, err := s.repo.FindByID(ctx, claims.UserID)
+	if err != nil || userRecord.Status != "active" {
+		return Response{}, ErrInvalidMFAToken
+	}
+
+	enrollment, enrolled, err := s.findMFA(ctx, userRecord.ID)
+	if err != nil {
+		return Response{}, err
+	}
+	if !enrolled {
+		return Response{}, ErrMFANotEnrolled
+	}
+	if err := s.verifyMFACode(ctx, enrollment, code); err != nil {
+		return Response{}, err
+	}
+
+	return s.issueTokens(ctx, userRecord, "")
+}
+
+// ListMFARoleRequirements returns every role for which MFA is mandatory.
+func (s *Service) ListMFARoleRequirements(ctx context.Context) ([]MFARoleRequirement, error) {
+	return s.repo.ListMFARoleRequirements(ctx)
+}
+
+// SetMFARoleRequirement makes MFA mandatory, or optional again, for a role.
+func (s *Service) SetMFARoleRequirement(ctx context.Context, role string, required bool, updatedBy int64) error {
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return errors.New("role is required")
+	}
+	if err := s.repo.SetMFARoleRequirement(ctx, role, required, updatedBy); err != nil {
+		return err
+	}
+	log.Printf("🔐 MFA requirement for role %q set to %v by user %d", role, required, updatedBy)
+	return nil
+}
+
+// verifyMFACode checks a TOTP code, falling back to recovery codes for enabled enrollments.
+// Failures count towards a temporary lockout.
+func (s *Service) verifyMFACode(ctx context.Context, enrollment UserMFA, code string) error {
+	if enrollment.LockedUntil != nil && time.Now().Before(*enrollment.LockedUntil) {
+		return ErrMFALocked
+	}
+
+	if step, ok := utils.ValidateTOTP(enrollment.Secret, code, time.Now(), totpSkew); ok {
+		accepted, err := s.repo.AcceptMFAStep(ctx, enrollment.UserID, step)
+		if err != nil {
+			return fmt.Errorf("failed to record mfa step: %w", err)
+		}
+		if accepted {
+			return nil
+		}
+	} else if enrollment.EnabledAt != nil {
+		used, err := s.repo.ConsumeRecoveryCode(ctx, enrollment.UserID, HashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			return fmt.Errorf("failed to check recovery code: %w", err)
+		}
+		if used {
+			log.Printf("🔑 Recovery code used by user %d", enrollment.UserID)
+			return nil
+		}
+	}
+
+	if err := s.repo.RecordMFAFailure(ctx, enrollment.UserID, mfaMaxFailedAttempts, mfaLockout); err != nil {
+		log.Printf("❌ Failed to record mfa failure for user %d: %v", enrollment.UserID, err)
+	}
+	return ErrInvalidMFACode
+}
+
+func (s *Service) findMFA(ctx context.Context, userID int64) (UserMFA, bool, error) {
+	enrollment, err := s.repo.FindMFA(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserMFA{}, false, nil
+	}
+	if err != nil {
+		return UserMFA{}, false, fmt.Errorf("failed to load mfa enrollment: %w", err)
+	}
+	return enrollment, true, nil
+}
+
+// generateRecoveryCodes returns codes formatted as "xxxxx-xxxxx" and their stored hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateSecureToken(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func mfaIssuer() string {
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Lamina"
+}
@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strings"
@@ -18,10 +20,21 @@ const (
 	ContextModulesKey     = "modules"
 	ContextDepartmentsKey = "departments"
 	ContextUserRoleKey    = "userRole"
+	ContextSessionIDKey   = "sessionID"
 )
 
+// sessionTouchInterval throttles last-seen updates so every request doesn't write to the database.
+const sessionTouchInterval = time.Minute
+
+// SessionRepo is the subset of Repository the middleware needs to validate sessions.
+type SessionRepo interface {
+	FindSession(ctx context.Context, id string) (Session, error)
+	TouchSession(ctx context.Context, id, ipAddress, userAgent string) error
+}
+
 // Middleware validates JWTs and injects user identity into Gin context.
-func Middleware(repo user.Repo) gin.HandlerFunc {
+// Tokens whose session has been revoked are rejected.
+func Middleware(repo user.Repo, sessions SessionRepo) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -58,6 +71,23 @@ func Middleware(repo user.Repo) gin.HandlerFunc {
 			return
 		}
 
+		if claims.SessionID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+		sess, err := sessions.FindSession(c.Request.Context(), claims.SessionID)
+		if err != nil || sess.RevokedAt != nil || sess.UserID != claims.UserID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session revoked"})
+			c.Abort()
+			return
+		}
+		if time.Since(sess.LastSeenAt) > sessionTouchInterval {
+			if err := sessions.TouchSession(c.Request.Context(), sess.ID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+				log.Printf("⚠️ Failed to update session %s: %v", sess.ID, err)
+			}
+		}
+
 		// Enforce Access Expiration for external users
 		userRecord, err := repo.FindByID(c.Request.Context(), claims.UserID)
 		if err != nil {
@@ -78,6 +108,7 @@ func Middleware(repo user.Repo) gin.HandlerFunc {
 		c.Set(ContextModulesKey, claims.Modules)
 		c.Set(ContextDepartmentsKey, claims.Departments)
 		c.Set(ContextUserRoleKey, claims.Role)
+		c.Set(ContextSessionIDKey, claims.SessionID)
 		c.Next()
 	}
 }
@@ -107,3 +138,52 @@ func RequireRoles(allowedRoles ...string) gin.HandlerFunc {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: insufficient role"})
 	}
 }
+
+// RequireModule returns a Gin middleware that allows access only to users whose token grants
+// the module. Admins are always allowed.
+func RequireModule(module string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(ContextUserRoleKey) == "admin" {
+			c.Next()
+			return
+		}
+		modules, _ := c.Get(ContextModulesKey)
+		granted, _ := modules.([]string)
+		for _, m := range granted {
+			if m == module {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: module not enabled"})
+	}
+}
+
+// RequireDepartmentRole returns a Gin middleware that allows access only to users holding one
+// of the roles in the named department, or any role when none are given. Admins are always allowed.
+func RequireDepartmentRole(department string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(ContextUserRoleKey) == "admin" {
+			c.Next()
+			return
+		}
+		departments, _ := c.Get(ContextDepartmentsKey)
+		granted, _ := departments.([]user.DepartmentAccess)
+		for _, d := range granted {
+			if d.Name != department {
+				continue
+			}
+			if len(roles) == 0 {
+				c.Next()
+				return
+			}
+			for _, role := range roles {
+				if d.Role == role {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: insufficient department role"})
+	}
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubUserRepo satisfies user.Repo for middleware tests; only FindByID is used.
+type stubUserRepo struct {
+	user.Repo
+	u *user.User
+}
+
+func (s stubUserRepo) FindByID(_ context.Context, _ int64) (*user.User, error) {
+	return s.u, nil
+}
+
+func newMiddlewareRouter(t *testing.T, sessions SessionRepo) (*gin.Engine, string) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "mw-secret")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(stubUserRepo{u: &user.User{ID: 5, UserType: "internal"}}, sessions))
+	r.GET("/protected", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"sid": c.GetString(ContextSessionIDKey)})
+	})
+
+	access, _, err := GenerateTokens("mw-secret", "refresh-secret", user.User{ID: 5}, TokenMeta{JTI: "j", FamilyID: "sess-1"})
+	assert.NoError(t, err)
+	return r, access
+}
+
+func TestMiddleware_ActiveSession(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindSession", mock.Anything, "sess-1").Return(Session{ID: "sess-1", UserID: 5, LastSeenAt: time.Now()}, nil)
+
+	r, access := newMiddlewareRouter(t, repo)
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "sess-1")
+	repo.AssertNotCalled(t, "TouchSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMiddleware_RevokedSession(t *testing.T) {
+	revokedAt := time.Now()
+	repo := new(MockAuthRepo)
+	repo.On("FindSession", mock.Anything, "sess-1").Return(Session{ID: "sess-1", UserID: 5, RevokedAt: &revokedAt}, nil)
+
+	r, access := newMiddlewareRouter(t, repo)
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Session revoked")
+}
+
+func TestMiddleware_RejectsMFAPendingToken(t *testing.T) {
+	repo := new(MockAuthRepo)
+
+	r, _ := newMiddlewareRouter(t, repo)
+	pending, err := GenerateMFAToken("mw-secret", 5)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+pending)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	repo.AssertNotCalled(t, "FindSession", mock.Anything, mock.Anything)
+}
+
+func TestRequireModuleAndDepartmentRole_UseTokenClaims(t *testing.T) {
+	t.Setenv("JWT_SECRET", "mw-secret")
+	repo := new(MockAuthRepo)
+	repo.On("FindSession", mock.Anything, "sess-1").Return(Session{ID: "sess-1", UserID: 5, LastSeenAt: time.Now()}, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(stubUserRepo{u: &user.User{ID: 5, UserType: "internal"}}, repo))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/crew", RequireModule("crew"), ok)
+	r.GET("/hr", RequireModule("hr"), ok)
+	r.GET("/ops/planning", RequireDepartmentRole("Flight Ops", "planner"), ok)
+	r.GET("/ops/any", RequireDepartmentRole("Flight Ops"), ok)
+	r.GET("/finance", RequireDepartmentRole("Finance"), ok)
+
+	u := user.User{
+		ID:          5,
+		Role:        "user",
+		Departments: []user.DepartmentAccess{{Name: "Flight Ops", Role: "planner", Rank: "Senior"}},
+		Modules:     []string{"crew"},
+	}
+	access, _, err := GenerateTokens("mw-secret", "refresh-secret", u, TokenMeta{JTI: "j", FamilyID: "sess-1"})
+	assert.NoError(t, err)
+
+	for path, want := range map[string]int{
+		"/crew":         http.StatusOK,
+		"/hr":           http.StatusForbidden,
+		"/ops/planning": http.StatusOK,
+		"/ops/any":      http.StatusOK,
+		"/finance":      http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, path)
+	}
+}
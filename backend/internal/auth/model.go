@@ -4,6 +4,7 @@ package auth
 import (
 	"errors"
 	"strings"
+	"time"
 )
 
 // SignupRequest represents the expected payload for a user signup request.
@@ -19,9 +20,100 @@ type LoginRequest struct {
 }
 
 // Response contains JWT tokens returned upon successful authentication.
+// When a second factor is needed the tokens are empty and MFAToken must be
+// exchanged together with a TOTP or recovery code.
 type Response struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken           string `json:"access_token"`
+	RefreshToken          string `json:"refresh_token"`
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string `json:"mfa_token,omitempty"`
+}
+
+// RefreshRequest represents the expected payload for exchanging a refresh token.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken is the server-side record of an issued refresh token.
+// Only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID         int64      `db:"id"`
+	JTI        string     `db:"jti"`
+	UserID     int64      `db:"user_id"`
+	FamilyID   string     `db:"family_id"`
+	TokenHash  string     `db:"token_hash"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	UsedAt     *time.Time `db:"used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	ReplacedBy *string    `db:"replaced_by"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// Session is a logged-in device. Its ID is the family ID shared by every
+// refresh token rotated from the same login.
+type Session struct {
+	ID         string     `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"user_id"`
+	Device     string     `db:"device" json:"device"`
+	IPAddress  string     `db:"ip_address" json:"ip_address"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastSeenAt time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	Current    bool       `db:"-" json:"current"`
+}
+
+// UserMFA is the TOTP enrollment of a user. It stays pending until a first code is verified.
+type UserMFA struct {
+	UserID         int64      `db:"user_id"`
+	Secret         string     `db:"secret"`
+	EnabledAt      *time.Time `db:"enabled_at"`
+	LastUsedStep   int64      `db:"last_used_step"`
+	FailedAttempts int        `db:"failed_attempts"`
+	LockedUntil    *time.Time `db:"locked_until"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+// MFAEnrollment is returned once when a user starts TOTP enrollment.
+// Recovery codes are never shown again.
+type MFAEnrollment struct {
+	Secret        string   `json:"secret"`
+	OTPAuthURI    string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFARoleRequirement marks MFA as mandatory for a role.
+type MFARoleRequirement struct {
+	Role      string    `db:"role" json:"role"`
+	UpdatedBy *int64    `db:"updated_by" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// MFACodeRequest carries a TOTP or recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFALoginRequest exchanges an mfa_pending token and a code for a token pair.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFARoleRequirementRequest toggles mandatory MFA for a role.
+type MFARoleRequirementRequest struct {
+	Role     string `json:"role" binding:"required"`
+	Required *bool  `json:"required" binding:"required"`
+}
+
+// DepartmentGrant is one org unit a user belongs to, resolved to its department, with a
+// function the user holds there. Function is nil for plain memberships.
+type DepartmentGrant struct {
+	UnitID     int     `db:"unit_id"`
+	Department string  `db:"department"`
+	Function   *string `db:"function_name"`
+	Rank       *string `db:"rank_name"`
 }
 
 // PasswordPayload is used for password confirmation during signup or invite completion.
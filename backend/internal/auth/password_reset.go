@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nomenarkt/lamina/common/utils"
+)
+
+// ErrInvalidResetToken is returned when a password reset token is unknown, expired, or already used.
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// ForgotPassword issues a single-use reset token and emails it to the user.
+// Unknown or inactive accounts are silently ignored so callers can't probe which emails exist.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	userRecord, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+	if userRecord.Status != "active" {
+		log.Printf("🔒 Password reset requested for non-active user %d, ignoring", userRecord.ID)
+		return nil
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreatePasswordResetToken(ctx, userRecord.ID, HashToken(token), time.Now().Add(s.passwordResetTTL)); err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	return s.notifyPasswordReset(ctx, userRecord.Email, token)
+}
+
+// ResetPassword sets a new password using a reset token and revokes every existing session.
+// The token, password and sessions change together, so a failure leaves the token usable.
+func (s *Service) ResetPassword(ctx context.Context, token string, password string) error {
+	hashed, err := s.hashPassword(password)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	if _, err := s.repo.ResetPasswordWithToken(ctx, HashToken(token), hashed); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) notifyPasswordReset(ctx context.Context, email, token string) error {
+	return SendPasswordResetEmail(ctx, s.mailer, email, token, s.passwordResetTTL)
+}
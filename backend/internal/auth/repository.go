@@ -19,6 +19,29 @@ type Repository interface {
 	MarkUserConfirmed(ctx context.Context, id int64) error
 	SetConfirmationToken(ctx context.Context, userID int64, token string) error
 	UpdatePasswordAndActivate(ctx context.Context, userID int64, hashed string) error
+	FindByID(ctx context.Context, id int64) (user.User, error)
+	CreateRefreshToken(ctx context.Context, rt RefreshToken) error
+	FindRefreshToken(ctx context.Context, jti string) (RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, jti, replacedBy string) (bool, error)
+	CreateSession(ctx context.Context, sess Session) error
+	FindSession(ctx context.Context, id string) (Session, error)
+	TouchSession(ctx context.Context, id, ipAddress, userAgent string) error
+	ListSessionsByUser(ctx context.Context, userID int64) ([]Session, error)
+	RevokeSession(ctx context.Context, id string) error
+	RevokeSessionsByUser(ctx context.Context, userID int64) error
+	CreatePasswordResetToken(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error
+	ResetPasswordWithToken(ctx context.Context, tokenHash string, hashed string) (int64, error)
+	FindMFA(ctx context.Context, userID int64) (UserMFA, error)
+	SaveMFAEnrollment(ctx context.Context, userID int64, secret string, recoveryCodeHashes []string) error
+	AcceptMFAStep(ctx context.Context, userID int64, step int64) (bool, error)
+	RecordMFAFailure(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) error
+	ConsumeRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	DeleteMFA(ctx context.Context, userID int64) error
+	IsMFARequiredForRole(ctx context.Context, role string) (bool, error)
+	ListMFARoleRequirements(ctx context.Context) ([]MFARoleRequirement, error)
+	SetMFARoleRequirement(ctx context.Context, role string, required bool, updatedBy int64) error
+	ListDepartmentGrants(ctx context.Context, userID int64) ([]DepartmentGrant, error)
+	ListModules(ctx context.Context, userID int64) ([]string, error)
 }
 
 // repositoryImpl handles database operations for user authentication.
@@ -115,3 +138,379 @@ func (r *repositoryImpl) UpdatePasswordAndActivate(ctx context.Context, userID i
 	`, hashed, userID)
 	return err
 }
+
+func (r *repositoryImpl) FindByID(ctx context.Context, id int64) (user.User, error) {
+	var u user.User
+	err := r.db.GetContext(ctx, &u, `
+		SELECT id, email, role, status, user_type, created_at
+		FROM users
+		WHERE id = $1
+	`, id)
+	return u, err
+}
+
+func (r *repositoryImpl) CreateRefreshToken(ctx context.Context, rt RefreshToken) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (jti, user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, rt.JTI, rt.UserID, rt.FamilyID, rt.TokenHash, rt.ExpiresAt)
+	return err
+}
+
+func (r *repositoryImpl) FindRefreshToken(ctx context.Context, jti string) (RefreshToken, error) {
+	var rt RefreshToken
+	err := r.db.GetContext(ctx, &rt, `
+		SELECT id, jti, user_id, family_id, token_hash, expires_at, used_at, revoked_at, replaced_by, created_at
+		FROM refresh_tokens
+		WHERE jti = $1
+	`, jti)
+	return rt, err
+}
+
+// MarkRefreshTokenUsed consumes a refresh token exactly once. It reports false
+// when the token was already used or revoked, e.g. by a concurrent request.
+func (r *repositoryImpl) MarkRefreshTokenUsed(ctx context.Context, jti, replacedBy string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET used_at = now(), replaced_by = $2
+		WHERE jti = $1 AND used_at IS NULL AND revoked_at IS NULL
+	`, jti, replacedBy)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *repositoryImpl) CreateSession(ctx context.Context, sess Session) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, device, ip_address, user_agent, created_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, now(), now())
+	`, sess.ID, sess.UserID, sess.Device, sess.IPAddress, sess.UserAgent)
+	return err
+}
+
+func (r *repositoryImpl) FindSession(ctx context.Context, id string) (Session, error) {
+	var sess Session
+	err := r.db.GetContext(ctx, &sess, `
+		SELECT id, user_id, device, ip_address, user_agent, created_at, last_seen_at, revoked_at
+		FROM sessions
+		WHERE id = $1
+	`, id)
+	return sess, err
+}
+
+func (r *repositoryImpl) TouchSession(ctx context.Context, id, ipAddress, userAgent string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET last_seen_at = now(),
+		    ip_address = COALESCE(NULLIF($2, ''), ip_address),
+		    user_agent = COALESCE(NULLIF($3, ''), user_agent)
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id, ipAddress, userAgent)
+	return err
+}
+
+func (r *repositoryImpl) ListSessionsByUser(ctx context.Context, userID int64) ([]Session, error) {
+	var sessions []Session
+	err := r.db.SelectContext(ctx, &sessions, `
+		SELECT id, user_id, device, ip_address, user_agent, created_at, last_seen_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC
+	`, userID)
+	return sessions, err
+}
+
+// RevokeSession revokes a session and every refresh token of its family in one statement.
+func (r *repositoryImpl) RevokeSession(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE sessions SET revoked_at = now()
+			WHERE id = $1 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, id)
+	return err
+}
+
+// RevokeSessionsByUser revokes every session and refresh token held by a user.
+func (r *repositoryImpl) RevokeSessionsByUser(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE sessions SET revoked_at = now()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
+
+// CreatePasswordResetToken stores a new reset token and invalidates any earlier unused ones for the user.
+func (r *repositoryImpl) CreatePasswordResetToken(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens
+		SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResetPasswordWithToken consumes a valid reset token, sets the user's password and revokes
+// their sessions and refresh tokens in one transaction, returning the user ID. It returns
+// sql.ErrNoRows when the token is unknown, expired, or already used.
+func (r *repositoryImpl) ResetPasswordWithToken(ctx context.Context, tokenHash string, hashed string) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var userID int64
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE password_reset_tokens
+		SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id
+	`, tokenHash).Scan(&userID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET password_hash = $1 WHERE id = $2
+	`, hashed, userID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE sessions SET revoked_at = now()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+func (r *repositoryImpl) FindMFA(ctx context.Context, userID int64) (UserMFA, error) {
+	var m UserMFA
+	err := r.db.GetContext(ctx, &m, `
+		SELECT user_id, secret, enabled_at, last_used_step, failed_attempts, locked_until, created_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`, userID)
+	return m, err
+}
+
+// SaveMFAEnrollment stores a new pending TOTP secret and replaces the user's recovery codes.
+// An already enabled enrollment is left untouched.
+func (r *repositoryImpl) SaveMFAEnrollment(ctx context.Context, userID int64, secret string, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO user_mfa (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, failed_attempts = 0,
+		    locked_until = NULL, created_at = now()
+		WHERE user_mfa.enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AcceptMFAStep records a verified TOTP time step, enabling a pending enrollment.
+// It reports false when the step is not newer than the last accepted one (replay).
+func (r *repositoryImpl) AcceptMFAStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_mfa
+		SET last_used_step = $2, failed_attempts = 0, locked_until = NULL,
+		    enabled_at = COALESCE(enabled_at, now())
+		WHERE user_id = $1 AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RecordMFAFailure counts a wrong code and locks verification once maxAttempts is reached.
+func (r *repositoryImpl) RecordMFAFailure(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_mfa
+		SET failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+		    locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN now() + make_interval(secs => $3) ELSE locked_until END
+		WHERE user_id = $1
+	`, userID, maxAttempts, lockout.Seconds())
+	return err
+}
+
+// ConsumeRecoveryCode marks a recovery code as used. It reports false when the code is unknown or spent.
+func (r *repositoryImpl) ConsumeRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE mfa_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteMFA removes the TOTP secret and every recovery code of a user.
+func (r *repositoryImpl) DeleteMFA(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		WITH codes AS (
+			DELETE FROM mfa_recovery_codes WHERE user_id = $1
+		)
+		DELETE FROM user_mfa WHERE user_id = $1
+	`, userID)
+	return err
+}
+
+func (r *repositoryImpl) IsMFARequiredForRole(ctx context.Context, role string) (bool, error) {
+	var required bool
+	err := r.db.GetContext(ctx, &required, `
+		SELECT EXISTS (SELECT 1 FROM mfa_role_requirements WHERE role = $1)
+	`, role)
+	return required, err
+}
+
+func (r *repositoryImpl) ListMFARoleRequirements(ctx context.Context) ([]MFARoleRequirement, error) {
+	requirements := []MFARoleRequirement{}
+	err := r.db.SelectContext(ctx, &requirements, `
+		SELECT role, updated_by, updated_at
+		FROM mfa_role_requirements
+		ORDER BY role
+	`)
+	return requirements, err
+}
+
+func (r *repositoryImpl) SetMFARoleRequirement(ctx context.Context, role string, required bool, updatedBy int64) error {
+	if !required {
+		_, err := r.db.ExecContext(ctx, `DELETE FROM mfa_role_requirements WHERE role = $1`, role)
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO mfa_role_requirements (role, updated_by, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (role) DO UPDATE SET updated_by = EXCLUDED.updated_by, updated_at = now()
+	`, role, updatedBy)
+	return err
+}
+
+// userUnitsCTE lists the units a user belongs to or holds a function in.
+const userUnitsCTE = `
+	user_units AS (
+		SELECT unit_id FROM user_organizational_units WHERE user_id = $1
+		UNION
+		SELECT unit_id FROM user_functions WHERE user_id = $1
+	)`
+
+// ListDepartmentGrants resolves each unit the user belongs to or holds a function in to the
+// nearest department at or above it (the unit itself when there is none), with the functions
+// and ranks held in the unit.
+func (r *repositoryImpl) ListDepartmentGrants(ctx context.Context, userID int64) ([]DepartmentGrant, error) {
+	grants := []DepartmentGrant{}
+	err := r.db.SelectContext(ctx, &grants, `
+		WITH RECURSIVE `+userUnitsCTE+`,
+		chain AS (
+			SELECT ou.id AS start_id, ou.id, ou.name, ou.type, ou.parent_id, 0 AS depth
+			FROM organizational_units ou JOIN user_units uu ON uu.unit_id = ou.id
+			UNION ALL
+			SELECT c.start_id, p.id, p.name, p.type, p.parent_id, c.depth + 1
+			FROM chain c JOIN organizational_units p ON p.id = c.parent_id
+			WHERE c.type <> 'department' AND c.depth < 64
+		),
+		departments AS (
+			SELECT DISTINCT ON (start_id) start_id, name
+			FROM chain
+			ORDER BY start_id, (type = 'department') DESC, depth
+		)
+		SELECT d.start_id AS unit_id, d.name AS department, f.name AS function_name, rk.name AS rank_name
+		FROM departments d
+		LEFT JOIN user_functions uf ON uf.unit_id = d.start_id AND uf.user_id = $1
+		LEFT JOIN functions f ON f.id = uf.function_id
+		LEFT JOIN ranks rk ON rk.id = uf.rank_id
+		ORDER BY d.name, f.name`, userID)
+	return grants, err
+}
+
+// ListModules returns the modules granted to the user through module_entitlements: by a
+// unit the user belongs to (or one above it), by a function the user holds, or by a function
+// held in or below the entitled unit.
+func (r *repositoryImpl) ListModules(ctx context.Context, userID int64) ([]string, error) {
+	modules := []string{}
+	err := r.db.SelectContext(ctx, &modules, `
+		WITH RECURSIVE `+userUnitsCTE+`,
+		ancestors AS (
+			SELECT unit_id AS start_id, unit_id AS id, 0 AS depth FROM user_units
+			UNION ALL
+			SELECT a.start_id, ou.parent_id, a.depth + 1
+			FROM ancestors a JOIN organizational_units ou ON ou.id = a.id
+			WHERE ou.parent_id IS NOT NULL AND a.depth < 64
+		)
+		SELECT DISTINCT e.module
+		FROM module_entitlements e
+		WHERE (e.function_id IS NULL AND e.unit_id IN (SELECT id FROM ancestors))
+		   OR (e.function_id IS NOT NULL AND EXISTS (
+				SELECT 1
+				FROM user_functions uf
+				JOIN ancestors a ON a.start_id = uf.unit_id
+				WHERE uf.user_id = $1
+				  AND uf.function_id = e.function_id
+				  AND (e.unit_id IS NULL OR a.id = e.unit_id)))
+		ORDER BY e.module`, userID)
+	return modules, err
+}
@@ -12,7 +12,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/common/mailer"
 	"github.com/nomenarkt/lamina/common/utils"
+	"github.com/nomenarkt/lamina/internal/audit"
 	"github.com/nomenarkt/lamina/internal/user"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -33,31 +35,51 @@ type ServiceInterface interface {
 	CompleteInvite(ctx context.Context, token string, password string) (Response, error)
 	ConfirmRegistration(ctx context.Context, token string) error
 	ResendConfirmation(ctx context.Context, email string) error
+	Refresh(ctx context.Context, refreshToken string) (Response, error)
+	Logout(ctx context.Context, refreshToken string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
 }
 
 // Service provides the implementation for authentication-related business logic.
 type Service struct {
-	repo            Repository
-	checkPassword   func(raw, hash string) error
-	hashPassword    func(p string) (string, error)
-	generateTokens  func(u user.User) (string, string, error)
-	confirmationTTL time.Duration
+	repo              Repository
+	mailer            mailer.Mailer
+	checkPassword     func(raw, hash string) error
+	hashPassword      func(p string) (string, error)
+	generateTokens    func(u user.User, meta TokenMeta) (string, string, error)
+	parseRefreshToken func(token string) (*RefreshClaims, error)
+	generateMFAToken  func(userID int64) (string, error)
+	parseMFAToken     func(token string) (*MFAClaims, error)
+	confirmationTTL   time.Duration
+	passwordResetTTL  time.Duration
 }
 
-// NewService creates a new instance of the auth Service.
-func NewService(r Repository) *Service {
+// NewService creates a new instance of the auth Service. Emails are handed to m.
+func NewService(r Repository, m mailer.Mailer) *Service {
 	ttlHours := 24
 	if envTTL := os.Getenv("CONFIRMATION_TOKEN_TTL_HOURS"); envTTL != "" {
 		if parsed, err := strconv.Atoi(envTTL); err == nil {
 			ttlHours = parsed
 		}
 	}
+	resetMinutes := 30
+	if envTTL := os.Getenv("PASSWORD_RESET_TTL_MINUTES"); envTTL != "" {
+		if parsed, err := strconv.Atoi(envTTL); err == nil {
+			resetMinutes = parsed
+		}
+	}
 	return &Service{
-		repo:            r,
-		checkPassword:   CheckPasswordHash,
-		hashPassword:    HashPassword,
-		generateTokens:  GenerateTokensFromEnv,
-		confirmationTTL: time.Duration(ttlHours) * time.Hour,
+		repo:              r,
+		mailer:            m,
+		checkPassword:     CheckPasswordHash,
+		hashPassword:      HashPassword,
+		generateTokens:    GenerateTokensFromEnv,
+		parseRefreshToken: ParseRefreshTokenFromEnv,
+		generateMFAToken:  GenerateMFATokenFromEnv,
+		parseMFAToken:     ParseMFATokenFromEnv,
+		confirmationTTL:   time.Duration(ttlHours) * time.Hour,
+		passwordResetTTL:  time.Duration(resetMinutes) * time.Minute,
 	}
 }
 
@@ -113,16 +135,16 @@ func (s *Service) SignupUser(ctx context.Context, req SignupRequest) (Response,
 	if err != nil {
 		return Response{}, fmt.Errorf("failed to issue token: %w", err)
 	}
-	if err := s.notifyUserWithToken(req.Email, token, false); err != nil {
+	if err := s.notifyUserWithToken(ctx, req.Email, token, false); err != nil {
 		return Response{}, fmt.Errorf("failed to send confirmation email: %w", err)
 	}
 
-	access, refresh, err := s.generateTokens(user.User{ID: userID, Email: req.Email, Role: "user"})
+	resp, err := s.issueTokens(ctx, user.User{ID: userID, Email: req.Email, Role: "user"}, "")
 	if err != nil {
 		return Response{}, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
-	return Response{AccessToken: access, RefreshToken: refresh}, nil
+	return resp, nil
 }
 
 // InviteUser allows admins to invite internal/external users with optional access duration.
@@ -170,11 +192,17 @@ func (s *Service) InviteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token generation failed"})
 		return
 	}
-	if err := s.notifyUserWithToken(req.Email, token, false); err != nil {
+	if err := s.notifyUserWithToken(c.Request.Context(), req.Email, token, false); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send invite email"})
 		return
 	}
 
+	audit.Record(c.Request.Context(), audit.Event{
+		Action:     "user.invite",
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(userID, 10),
+		After:      map[string]any{"user_type": req.UserType, "access_expires_at": accessExpires},
+	})
 	c.JSON(http.StatusOK, gin.H{"message": "User invited"})
 }
 
@@ -198,13 +226,13 @@ func (s *Service) ResendConfirmation(ctx context.Context, email string) error {
 	if err != nil {
 		return fmt.Errorf("failed to issue token: %w", err)
 	}
-	if err := s.notifyUserWithToken(userRecord.Email, token, true); err != nil {
+	if err := s.notifyUserWithToken(ctx, userRecord.Email, token, true); err != nil {
 		return fmt.Errorf("failed to send confirmation email: %w", err)
 	}
 	return nil
 }
 
-// CompleteInvite sets the password and activates an invited user.
+// CompleteInvite sets the password and activates an invited user, then signs them in as Login does.
 func (s *Service) CompleteInvite(ctx context.Context, token string, password string) (Response, error) {
 	userRecord, err := s.repo.FindByConfirmationToken(ctx, token)
 	if err != nil {
@@ -225,19 +253,22 @@ func (s *Service) CompleteInvite(ctx context.Context, token string, password str
 		return Response{}, errors.New("failed to activate account")
 	}
 
-	access, refresh, err := s.generateTokens(userRecord)
+	// The new account is signed in the same way Login would, so a role that mandates MFA
+	// gets an enrollment challenge rather than tokens.
+	resp, err := s.loginChallenge(ctx, userRecord)
 	if err != nil {
 		return Response{}, errors.New("failed to generate tokens")
 	}
 
-	return Response{AccessToken: access, RefreshToken: refresh}, nil
+	return resp, nil
 }
 
 // ErrUnconfirmedAccount is returned when the user has not confirmed their email yet.
 var ErrUnconfirmedAccount = errors.New("unconfirmed account")
 
 // Login authenticates a user and returns JWT tokens if the credentials are valid and the account is confirmed.
-// It returns ErrUnconfirmedAccount if the account is still pending.
+// It returns ErrUnconfirmedAccount if the account is still pending. Users with MFA enabled, or whose
+// role mandates it, receive an mfa_pending token instead and finish with CompleteMFALogin.
 func (s *Service) Login(ctx context.Context, req LoginRequest) (Response, error) {
 	userRecord, err := s.repo.FindByEmail(ctx, req.Email)
 	if err != nil {
@@ -250,13 +281,137 @@ func (s *Service) Login(ctx context.Context, req LoginRequest) (Response, error)
 		return Response{}, errors.New("invalid email or password")
 	}
 
-	access, refresh, err := s.generateTokens(userRecord)
+	return s.loginChallenge(ctx, userRecord)
+}
+
+// ErrInvalidRefreshToken is returned when a refresh token is malformed, expired, revoked, or unknown.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReuse is returned when an already rotated refresh token is presented again.
+// The whole token family is revoked when this happens.
+var ErrRefreshTokenReuse = errors.New("refresh token reuse detected")
+
+// Refresh exchanges a valid refresh token for a new access/refresh pair.
+// The presented token is consumed; presenting it again revokes its whole family.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (Response, error) {
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		return Response{}, ErrInvalidRefreshToken
+	}
+
+	stored, err := s.repo.FindRefreshToken(ctx, claims.ID)
+	if err != nil {
+		return Response{}, ErrInvalidRefreshToken
+	}
+	if stored.TokenHash != HashToken(refreshToken) || stored.UserID != claims.UserID {
+		return Response{}, ErrInvalidRefreshToken
+	}
+	if stored.RevokedAt != nil {
+		return Response{}, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return Response{}, s.revokeFamilyOnReuse(ctx, stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return Response{}, ErrInvalidRefreshToken
+	}
+
+	userRecord, err := s.repo.FindByID(ctx, stored.UserID)
+	if err != nil || userRecord.Status != "active" {
+		return Response{}, ErrInvalidRefreshToken
+	}
+
+	meta, err := newTokenMeta(stored.FamilyID)
 	if err != nil {
 		return Response{}, err
 	}
+
+	consumed, err := s.repo.MarkRefreshTokenUsed(ctx, stored.JTI, meta.JTI)
+	if err != nil {
+		return Response{}, err
+	}
+	if !consumed {
+		return Response{}, s.revokeFamilyOnReuse(ctx, stored)
+	}
+
+	client := ClientInfoFromContext(ctx)
+	if err := s.repo.TouchSession(ctx, stored.FamilyID, client.IPAddress, client.UserAgent); err != nil {
+		return Response{}, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return s.issueTokensWithMeta(ctx, userRecord, meta)
+}
+
+func (s *Service) revokeFamilyOnReuse(ctx context.Context, stored RefreshToken) error {
+	log.Printf("🚨 Refresh token reuse detected for user %d (family %s), revoking family", stored.UserID, stored.FamilyID)
+	if err := s.repo.RevokeSession(ctx, stored.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return ErrRefreshTokenReuse
+}
+
+// issueTokens signs a new token pair and records the refresh token server-side.
+// An empty familyID starts a new token family and opens a session for it.
+func (s *Service) issueTokens(ctx context.Context, u user.User, familyID string) (Response, error) {
+	meta, err := newTokenMeta(familyID)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if familyID == "" {
+		client := ClientInfoFromContext(ctx)
+		if err := s.repo.CreateSession(ctx, Session{
+			ID:        meta.FamilyID,
+			UserID:    u.ID,
+			Device:    client.Device,
+			IPAddress: client.IPAddress,
+			UserAgent: client.UserAgent,
+		}); err != nil {
+			return Response{}, fmt.Errorf("failed to create session: %w", err)
+		}
+	}
+
+	return s.issueTokensWithMeta(ctx, u, meta)
+}
+
+func (s *Service) issueTokensWithMeta(ctx context.Context, u user.User, meta TokenMeta) (Response, error) {
+	u, err := s.withAccessClaims(ctx, u)
+	if err != nil {
+		return Response{}, err
+	}
+
+	access, refresh, err := s.generateTokens(u, meta)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if err := s.repo.CreateRefreshToken(ctx, RefreshToken{
+		JTI:       meta.JTI,
+		UserID:    u.ID,
+		FamilyID:  meta.FamilyID,
+		TokenHash: HashToken(refresh),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}); err != nil {
+		return Response{}, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
 	return Response{AccessToken: access, RefreshToken: refresh}, nil
 }
 
+func newTokenMeta(familyID string) (TokenMeta, error) {
+	jti, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return TokenMeta{}, err
+	}
+	if familyID == "" {
+		familyID, err = utils.GenerateSecureToken(16)
+		if err != nil {
+			return TokenMeta{}, err
+		}
+	}
+	return TokenMeta{JTI: jti, FamilyID: familyID}, nil
+}
+
 // ConfirmRegistration confirms a user account using a token sent via email.
 func (s *Service) ConfirmRegistration(ctx context.Context, token string) error {
 	userRecord, err := s.repo.FindByConfirmationToken(ctx, token)
@@ -283,8 +438,8 @@ func (s *Service) issueConfirmationToken(ctx context.Context, userID int64) (str
 	return token, nil
 }
 
-func (s *Service) notifyUserWithToken(email, token string, isResend bool) error {
-	return SendConfirmationEmail(email, token, isResend)
+func (s *Service) notifyUserWithToken(ctx context.Context, email, token string, isResend bool) error {
+	return SendConfirmationEmail(ctx, s.mailer, email, token, isResend)
 }
 
 // HashPassword hashes a password securely using bcrypt.
@@ -2,14 +2,19 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/nomenarkt/lamina/common/mailer"
+	"github.com/nomenarkt/lamina/common/utils"
 	"github.com/nomenarkt/lamina/internal/user"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockAuthRepo struct {
@@ -61,10 +66,149 @@ func (m *MockAuthRepo) UpdatePasswordAndActivate(ctx context.Context, userID int
 	return args.Error(0)
 }
 
+func (m *MockAuthRepo) FindByID(ctx context.Context, id int64) (user.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *MockAuthRepo) CreateRefreshToken(ctx context.Context, rt RefreshToken) error {
+	args := m.Called(ctx, rt)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) FindRefreshToken(ctx context.Context, jti string) (RefreshToken, error) {
+	args := m.Called(ctx, jti)
+	return args.Get(0).(RefreshToken), args.Error(1)
+}
+
+func (m *MockAuthRepo) MarkRefreshTokenUsed(ctx context.Context, jti, replacedBy string) (bool, error) {
+	args := m.Called(ctx, jti, replacedBy)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepo) CreateSession(ctx context.Context, sess Session) error {
+	args := m.Called(ctx, sess)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) FindSession(ctx context.Context, id string) (Session, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Session), args.Error(1)
+}
+
+func (m *MockAuthRepo) TouchSession(ctx context.Context, id, ipAddress, userAgent string) error {
+	args := m.Called(ctx, id, ipAddress, userAgent)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) ListSessionsByUser(ctx context.Context, userID int64) ([]Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]Session), args.Error(1)
+}
+
+func (m *MockAuthRepo) RevokeSession(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) RevokeSessionsByUser(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) CreatePasswordResetToken(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) ResetPasswordWithToken(ctx context.Context, tokenHash string, hashed string) (int64, error) {
+	args := m.Called(ctx, tokenHash, hashed)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAuthRepo) FindMFA(ctx context.Context, userID int64) (UserMFA, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(UserMFA), args.Error(1)
+}
+
+func (m *MockAuthRepo) SaveMFAEnrollment(ctx context.Context, userID int64, secret string, recoveryCodeHashes []string) error {
+	args := m.Called(ctx, userID, secret, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) AcceptMFAStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepo) RecordMFAFailure(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) error {
+	args := m.Called(ctx, userID, maxAttempts, lockout)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) ConsumeRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepo) DeleteMFA(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) IsMFARequiredForRole(ctx context.Context, role string) (bool, error) {
+	args := m.Called(ctx, role)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepo) ListMFARoleRequirements(ctx context.Context) ([]MFARoleRequirement, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]MFARoleRequirement), args.Error(1)
+}
+
+func (m *MockAuthRepo) SetMFARoleRequirement(ctx context.Context, role string, required bool, updatedBy int64) error {
+	args := m.Called(ctx, role, required, updatedBy)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) ListDepartmentGrants(ctx context.Context, userID int64) ([]DepartmentGrant, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]DepartmentGrant), args.Error(1)
+}
+
+func (m *MockAuthRepo) ListModules(ctx context.Context, userID int64) ([]string, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// expectAccessClaims stubs the org lookups made whenever tokens are issued.
+func expectAccessClaims(repo *MockAuthRepo, grants []DepartmentGrant, modules []string) {
+	repo.On("ListDepartmentGrants", mock.Anything, mock.Anything).Return(grants, nil)
+	repo.On("ListModules", mock.Anything, mock.Anything).Return(modules, nil)
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
 func TestLogin_Success(t *testing.T) {
 	repo := new(MockAuthRepo)
-	u := user.User{ID: 1, Email: "test@example.com", PasswordHash: "any", Status: "active"}
+	u := user.User{ID: 1, Email: "test@example.com", PasswordHash: "any", Status: "active", Role: "user"}
 	repo.On("FindByEmail", mock.Anything, "test@example.com").Return(u, nil)
+	repo.On("FindMFA", mock.Anything, int64(1)).Return(UserMFA{}, sql.ErrNoRows)
+	repo.On("IsMFARequiredForRole", mock.Anything, "user").Return(false, nil)
+	repo.On("CreateSession", mock.Anything, mock.MatchedBy(func(sess Session) bool {
+		return sess.UserID == 1 && sess.ID != "" && sess.IPAddress == "10.0.0.1" && sess.Device == "iPhone"
+	})).Return(nil)
+	expectAccessClaims(repo, []DepartmentGrant{}, []string{})
+	repo.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(rt RefreshToken) bool {
+		return rt.UserID == 1 && rt.TokenHash == HashToken("refresh-token") && rt.FamilyID != ""
+	})).Return(nil)
 
 	service := &Service{
 		repo: repo,
@@ -72,12 +216,13 @@ func TestLogin_Success(t *testing.T) {
 			fmt.Println("✅ checkPassword mock called")
 			return nil
 		},
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "access-token", "refresh-token", nil
 		},
 	}
 
-	resp, err := service.Login(context.Background(), LoginRequest{
+	ctx := WithClientInfo(context.Background(), ClientInfo{Device: "iPhone", IPAddress: "10.0.0.1"})
+	resp, err := service.Login(ctx, LoginRequest{
 		Email:    "test@example.com",
 		Password: "whatever",
 	})
@@ -97,7 +242,7 @@ func TestLogin_InvalidPassword(t *testing.T) {
 		checkPassword: func(_, _ string) error {
 			return errors.New("invalid")
 		},
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "", "", nil
 		},
 	}
@@ -118,7 +263,7 @@ func TestLogin_UserNotFound(t *testing.T) {
 	service := &Service{
 		repo:          repo,
 		checkPassword: func(_, _ string) error { return nil },
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "token", "refresh", nil
 		},
 	}
@@ -139,13 +284,21 @@ func TestSignupUser_Success(t *testing.T) {
 	repo.On("IsEmailExists", email).Return(false, nil)
 	repo.On("CreateUserWithType", mock.Anything, (*int)(nil), email, "hashed123", "internal").Return(int64(42), nil)
 	repo.On("SetConfirmationToken", mock.Anything, int64(42), mock.AnythingOfType("string")).Return(nil)
+	repo.On("CreateSession", mock.Anything, mock.AnythingOfType("auth.Session")).Return(nil)
+	expectAccessClaims(repo, []DepartmentGrant{}, []string{})
+	repo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("auth.RefreshToken")).Return(nil)
+	mailerMock := new(MockMailer)
+	mailerMock.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == email && strings.Contains(msg.HTMLBody, "/confirm/")
+	})).Return(nil)
 
 	service := &Service{
-		repo: repo,
+		repo:   repo,
+		mailer: mailerMock,
 		hashPassword: func(_ string) (string, error) {
 			return "hashed123", nil
 		},
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "access-token", "refresh-token", nil
 		},
 	}
@@ -158,6 +311,7 @@ func TestSignupUser_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "access-token", resp.AccessToken)
 	assert.Equal(t, "refresh-token", resp.RefreshToken)
+	mailerMock.AssertExpectations(t)
 }
 
 func TestSignupUser_EmailExists(t *testing.T) {
@@ -171,7 +325,7 @@ func TestSignupUser_EmailExists(t *testing.T) {
 		hashPassword: func(_ string) (string, error) {
 			return "ignored", nil
 		},
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "", "", nil
 		},
 	}
@@ -195,7 +349,7 @@ func TestSignupUser_HashFailure(t *testing.T) {
 		hashPassword: func(_ string) (string, error) {
 			return "", errors.New("hash failed")
 		},
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "", "", nil
 		},
 	}
@@ -221,7 +375,7 @@ func TestSignupUser_TokenFailure(t *testing.T) {
 		hashPassword: func(_ string) (string, error) {
 			return "hashedok", nil
 		},
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "", "", errors.New("token failed")
 		},
 	}
@@ -246,7 +400,7 @@ func TestSignupUser_InvalidDomain(t *testing.T) {
 		hashPassword: func(_ string) (string, error) {
 			return "irrelevant", nil
 		},
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "", "", nil
 		},
 	}
@@ -276,13 +430,18 @@ func TestCompleteInvite_Success(t *testing.T) {
 
 	repo.On("FindByConfirmationToken", mock.Anything, token).Return(testUser, nil)
 	repo.On("UpdatePasswordAndActivate", mock.Anything, userID, hashed).Return(nil)
+	repo.On("FindMFA", mock.Anything, userID).Return(UserMFA{}, sql.ErrNoRows)
+	repo.On("IsMFARequiredForRole", mock.Anything, "").Return(false, nil)
+	repo.On("CreateSession", mock.Anything, mock.AnythingOfType("auth.Session")).Return(nil)
+	expectAccessClaims(repo, []DepartmentGrant{}, []string{})
+	repo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("auth.RefreshToken")).Return(nil)
 
 	service := &Service{
 		repo: repo,
 		hashPassword: func(_ string) (string, error) {
 			return hashed, nil
 		},
-		generateTokens: func(_ user.User) (string, string, error) {
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
 			return "access-token", "refresh-token", nil
 		},
 		confirmationTTL: 24 * time.Hour,
@@ -294,6 +453,35 @@ func TestCompleteInvite_Success(t *testing.T) {
 	assert.Equal(t, "refresh-token", resp.RefreshToken)
 }
 
+func TestCompleteInvite_RoleRequiresEnrollment(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindByConfirmationToken", mock.Anything, "valid-token").Return(user.User{
+		ID:        78,
+		Email:     "admin@madagascarairlines.com",
+		Role:      "admin",
+		Status:    "pending",
+		CreatedAt: time.Now(),
+	}, nil)
+	repo.On("UpdatePasswordAndActivate", mock.Anything, int64(78), "hashedpass").Return(nil)
+	repo.On("FindMFA", mock.Anything, int64(78)).Return(UserMFA{}, sql.ErrNoRows)
+	repo.On("IsMFARequiredForRole", mock.Anything, "admin").Return(true, nil)
+
+	service := &Service{
+		repo:             repo,
+		hashPassword:     func(_ string) (string, error) { return "hashedpass", nil },
+		generateMFAToken: func(_ int64) (string, error) { return "mfa-token", nil },
+		confirmationTTL:  24 * time.Hour,
+	}
+
+	resp, err := service.CompleteInvite(context.Background(), "valid-token", "newpass")
+	assert.NoError(t, err)
+	assert.True(t, resp.MFAEnrollmentRequired)
+	assert.Equal(t, "mfa-token", resp.MFAToken)
+	assert.Empty(t, resp.AccessToken)
+	assert.Empty(t, resp.RefreshToken)
+	repo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+}
+
 func TestConfirmRegistration_Success(t *testing.T) {
 	repo := new(MockAuthRepo)
 	repo.On("FindByConfirmationToken", mock.Anything, "valid-token").Return(user.User{
@@ -311,3 +499,458 @@ func TestConfirmRegistration_Success(t *testing.T) {
 	err := service.ConfirmRegistration(context.Background(), "valid-token")
 	assert.NoError(t, err)
 }
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	repo := new(MockAuthRepo)
+	stored := RefreshToken{
+		JTI:       "old-jti",
+		UserID:    7,
+		FamilyID:  "family-1",
+		TokenHash: HashToken("old-refresh"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	repo.On("FindRefreshToken", mock.Anything, "old-jti").Return(stored, nil)
+	repo.On("FindByID", mock.Anything, int64(7)).Return(user.User{ID: 7, Status: "active"}, nil)
+	repo.On("MarkRefreshTokenUsed", mock.Anything, "old-jti", mock.AnythingOfType("string")).Return(true, nil)
+	repo.On("TouchSession", mock.Anything, "family-1", "", "").Return(nil)
+	expectAccessClaims(repo, []DepartmentGrant{}, []string{})
+	repo.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(rt RefreshToken) bool {
+		return rt.FamilyID == "family-1" && rt.JTI != "old-jti" && rt.TokenHash == HashToken("new-refresh")
+	})).Return(nil)
+
+	service := &Service{
+		repo: repo,
+		parseRefreshToken: func(_ string) (*RefreshClaims, error) {
+			c := &RefreshClaims{UserID: 7, FamilyID: "family-1"}
+			c.ID = "old-jti"
+			return c, nil
+		},
+		generateTokens: func(_ user.User, meta TokenMeta) (string, string, error) {
+			assert.Equal(t, "family-1", meta.FamilyID)
+			return "new-access", "new-refresh", nil
+		},
+	}
+
+	resp, err := service.Refresh(context.Background(), "old-refresh")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-access", resp.AccessToken)
+	assert.Equal(t, "new-refresh", resp.RefreshToken)
+	repo.AssertExpectations(t)
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	repo := new(MockAuthRepo)
+	usedAt := time.Now().Add(-time.Minute)
+	stored := RefreshToken{
+		JTI:       "old-jti",
+		UserID:    7,
+		FamilyID:  "family-1",
+		TokenHash: HashToken("old-refresh"),
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+	repo.On("FindRefreshToken", mock.Anything, "old-jti").Return(stored, nil)
+	repo.On("RevokeSession", mock.Anything, "family-1").Return(nil)
+
+	service := &Service{
+		repo: repo,
+		parseRefreshToken: func(_ string) (*RefreshClaims, error) {
+			c := &RefreshClaims{UserID: 7, FamilyID: "family-1"}
+			c.ID = "old-jti"
+			return c, nil
+		},
+	}
+
+	_, err := service.Refresh(context.Background(), "old-refresh")
+	assert.ErrorIs(t, err, ErrRefreshTokenReuse)
+	repo.AssertCalled(t, "RevokeSession", mock.Anything, "family-1")
+}
+
+func TestRefresh_HashMismatch(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindRefreshToken", mock.Anything, "old-jti").Return(RefreshToken{
+		JTI:       "old-jti",
+		UserID:    7,
+		FamilyID:  "family-1",
+		TokenHash: HashToken("something-else"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	service := &Service{
+		repo: repo,
+		parseRefreshToken: func(_ string) (*RefreshClaims, error) {
+			c := &RefreshClaims{UserID: 7, FamilyID: "family-1"}
+			c.ID = "old-jti"
+			return c, nil
+		},
+	}
+
+	_, err := service.Refresh(context.Background(), "old-refresh")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	repo.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+}
+
+func TestParseRefreshToken_RoundTrip(t *testing.T) {
+	_, refresh, err := GenerateTokens("access-secret", "refresh-secret", user.User{ID: 5}, TokenMeta{JTI: "jti-1", FamilyID: "fam-1"})
+	assert.NoError(t, err)
+
+	claims, err := ParseRefreshToken("refresh-secret", refresh)
+	assert.NoError(t, err)
+	assert.Equal(t, "jti-1", claims.ID)
+	assert.Equal(t, "fam-1", claims.FamilyID)
+	assert.Equal(t, int64(5), claims.UserID)
+
+	_, err = ParseRefreshToken("access-secret", refresh)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestLogout_RevokesSession(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindRefreshToken", mock.Anything, "jti-1").Return(RefreshToken{
+		JTI:       "jti-1",
+		FamilyID:  "family-1",
+		TokenHash: HashToken("refresh"),
+	}, nil)
+	repo.On("RevokeSession", mock.Anything, "family-1").Return(nil)
+
+	service := &Service{
+		repo: repo,
+		parseRefreshToken: func(_ string) (*RefreshClaims, error) {
+			c := &RefreshClaims{FamilyID: "family-1"}
+			c.ID = "jti-1"
+			return c, nil
+		},
+	}
+
+	err := service.Logout(context.Background(), "refresh")
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestRevokeSession_OtherUsersSession(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindSession", mock.Anything, "sess-9").Return(Session{ID: "sess-9", UserID: 99}, nil)
+
+	service := &Service{repo: repo}
+
+	err := service.RevokeSession(context.Background(), 1, "sess-9")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	repo.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+}
+
+func TestListSessions_FlagsCurrent(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("ListSessionsByUser", mock.Anything, int64(1)).Return([]Session{{ID: "a"}, {ID: "b"}}, nil)
+
+	service := &Service{repo: repo}
+
+	sessions, err := service.ListSessions(context.Background(), 1, "b")
+	assert.NoError(t, err)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+}
+
+func TestForgotPassword_UnknownEmailIsSilent(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindByEmail", mock.Anything, "ghost@example.com").Return(user.User{}, errors.New("not found"))
+
+	service := &Service{repo: repo, passwordResetTTL: 30 * time.Minute}
+
+	err := service.ForgotPassword(context.Background(), "ghost@example.com")
+	assert.NoError(t, err)
+	repo.AssertNotCalled(t, "CreatePasswordResetToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestForgotPassword_StoresHashedToken(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindByEmail", mock.Anything, "crew@example.com").Return(user.User{ID: 3, Email: "crew@example.com", Status: "active"}, nil)
+	repo.On("CreatePasswordResetToken", mock.Anything, int64(3), mock.MatchedBy(func(h string) bool {
+		return len(h) == 64
+	}), mock.AnythingOfType("time.Time")).Return(nil)
+	mailerMock := new(MockMailer)
+	mailerMock.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == "crew@example.com" && strings.Contains(msg.HTMLBody, "reset-password/")
+	})).Return(nil)
+
+	service := &Service{repo: repo, mailer: mailerMock, passwordResetTTL: 30 * time.Minute}
+
+	err := service.ForgotPassword(context.Background(), "crew@example.com")
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	mailerMock.AssertExpectations(t)
+}
+
+func TestResetPassword_RevokesSessions(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("ResetPasswordWithToken", mock.Anything, HashToken("reset-token"), "new-hash").Return(int64(3), nil)
+
+	service := &Service{
+		repo:         repo,
+		hashPassword: func(_ string) (string, error) { return "new-hash", nil },
+	}
+
+	err := service.ResetPassword(context.Background(), "reset-token", "newpassword")
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("ResetPasswordWithToken", mock.Anything, HashToken("used-token"), "new-hash").Return(int64(0), sql.ErrNoRows)
+
+	service := &Service{
+		repo:         repo,
+		hashPassword: func(_ string) (string, error) { return "new-hash", nil },
+	}
+
+	err := service.ResetPassword(context.Background(), "used-token", "newpassword")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestResetPassword_StorageFailureIsNotAnInvalidToken(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("ResetPasswordWithToken", mock.Anything, HashToken("reset-token"), "new-hash").Return(int64(0), errors.New("connection reset"))
+
+	service := &Service{
+		repo:         repo,
+		hashPassword: func(_ string) (string, error) { return "new-hash", nil },
+	}
+
+	err := service.ResetPassword(context.Background(), "reset-token", "newpassword")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestLogin_MFAEnabledReturnsPendingToken(t *testing.T) {
+	repo := new(MockAuthRepo)
+	enabledAt := time.Now().Add(-time.Hour)
+	repo.On("FindByEmail", mock.Anything, "pilot@example.com").Return(user.User{ID: 4, Status: "active", Role: "user"}, nil)
+	repo.On("FindMFA", mock.Anything, int64(4)).Return(UserMFA{UserID: 4, Secret: "ABC", EnabledAt: &enabledAt}, nil)
+
+	service := &Service{
+		repo:             repo,
+		checkPassword:    func(_, _ string) error { return nil },
+		generateMFAToken: func(id int64) (string, error) { return fmt.Sprintf("mfa-%d", id), nil },
+	}
+
+	resp, err := service.Login(context.Background(), LoginRequest{Email: "pilot@example.com", Password: "pw"})
+	assert.NoError(t, err)
+	assert.True(t, resp.MFARequired)
+	assert.Equal(t, "mfa-4", resp.MFAToken)
+	assert.Empty(t, resp.AccessToken)
+	repo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+}
+
+func TestLogin_RoleRequiresEnrollment(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindByEmail", mock.Anything, "admin@example.com").Return(user.User{ID: 2, Status: "active", Role: "admin"}, nil)
+	repo.On("FindMFA", mock.Anything, int64(2)).Return(UserMFA{}, sql.ErrNoRows)
+	repo.On("IsMFARequiredForRole", mock.Anything, "admin").Return(true, nil)
+
+	service := &Service{
+		repo:             repo,
+		checkPassword:    func(_, _ string) error { return nil },
+		generateMFAToken: func(_ int64) (string, error) { return "mfa-token", nil },
+	}
+
+	resp, err := service.Login(context.Background(), LoginRequest{Email: "admin@example.com", Password: "pw"})
+	assert.NoError(t, err)
+	assert.True(t, resp.MFAEnrollmentRequired)
+	assert.False(t, resp.MFARequired)
+	assert.Equal(t, "mfa-token", resp.MFAToken)
+}
+
+func TestBeginMFAEnrollment_ReturnsSecretAndRecoveryCodes(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindByID", mock.Anything, int64(3)).Return(user.User{ID: 3, Email: "crew@example.com"}, nil)
+	repo.On("FindMFA", mock.Anything, int64(3)).Return(UserMFA{}, sql.ErrNoRows)
+	repo.On("SaveMFAEnrollment", mock.Anything, int64(3), mock.AnythingOfType("string"), mock.MatchedBy(func(h []string) bool {
+		return len(h) == recoveryCodeCount
+	})).Return(nil)
+
+	service := &Service{repo: repo}
+
+	enrollment, err := service.BeginMFAEnrollment(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, enrollment.Secret)
+	assert.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/")
+	assert.Contains(t, enrollment.OTPAuthURI, "secret="+enrollment.Secret)
+	assert.Len(t, enrollment.RecoveryCodes, recoveryCodeCount)
+	repo.AssertExpectations(t)
+}
+
+func TestBeginMFAEnrollment_AlreadyEnabled(t *testing.T) {
+	repo := new(MockAuthRepo)
+	enabledAt := time.Now()
+	repo.On("FindByID", mock.Anything, int64(3)).Return(user.User{ID: 3}, nil)
+	repo.On("FindMFA", mock.Anything, int64(3)).Return(UserMFA{UserID: 3, EnabledAt: &enabledAt}, nil)
+
+	service := &Service{repo: repo}
+
+	_, err := service.BeginMFAEnrollment(context.Background(), 3)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+	repo.AssertNotCalled(t, "SaveMFAEnrollment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCompleteMFALogin_ValidCodeIssuesTokens(t *testing.T) {
+	secret, _ := utils.GenerateTOTPSecret()
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+
+	repo := new(MockAuthRepo)
+	repo.On("FindByID", mock.Anything, int64(4)).Return(user.User{ID: 4, Status: "active"}, nil)
+	repo.On("FindMFA", mock.Anything, int64(4)).Return(UserMFA{UserID: 4, Secret: secret}, nil)
+	repo.On("AcceptMFAStep", mock.Anything, int64(4), mock.AnythingOfType("int64")).Return(true, nil)
+	repo.On("CreateSession", mock.Anything, mock.Anything).Return(nil)
+	expectAccessClaims(repo, []DepartmentGrant{}, []string{})
+	repo.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+	service := &Service{
+		repo:          repo,
+		parseMFAToken: func(_ string) (*MFAClaims, error) { return &MFAClaims{UserID: 4}, nil },
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
+			return "access", "refresh", nil
+		},
+	}
+
+	resp, err := service.CompleteMFALogin(context.Background(), "mfa-token", code)
+	assert.NoError(t, err)
+	assert.Equal(t, "access", resp.AccessToken)
+	repo.AssertExpectations(t)
+}
+
+func TestCompleteMFALogin_ReplayedCodeRejected(t *testing.T) {
+	secret, _ := utils.GenerateTOTPSecret()
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	enabledAt := time.Now().Add(-time.Hour)
+
+	repo := new(MockAuthRepo)
+	repo.On("FindByID", mock.Anything, int64(4)).Return(user.User{ID: 4, Status: "active"}, nil)
+	repo.On("FindMFA", mock.Anything, int64(4)).Return(UserMFA{UserID: 4, Secret: secret, EnabledAt: &enabledAt}, nil)
+	repo.On("AcceptMFAStep", mock.Anything, int64(4), mock.AnythingOfType("int64")).Return(false, nil)
+	repo.On("RecordMFAFailure", mock.Anything, int64(4), mfaMaxFailedAttempts, mfaLockout).Return(nil)
+
+	service := &Service{
+		repo:          repo,
+		parseMFAToken: func(_ string) (*MFAClaims, error) { return &MFAClaims{UserID: 4}, nil },
+	}
+
+	_, err := service.CompleteMFALogin(context.Background(), "mfa-token", code)
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+	repo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+}
+
+func TestCompleteMFALogin_RecoveryCode(t *testing.T) {
+	secret, _ := utils.GenerateTOTPSecret()
+	enabledAt := time.Now().Add(-time.Hour)
+
+	repo := new(MockAuthRepo)
+	repo.On("FindByID", mock.Anything, int64(4)).Return(user.User{ID: 4, Status: "active"}, nil)
+	repo.On("FindMFA", mock.Anything, int64(4)).Return(UserMFA{UserID: 4, Secret: secret, EnabledAt: &enabledAt}, nil)
+	repo.On("ConsumeRecoveryCode", mock.Anything, int64(4), HashToken("abcde12345")).Return(true, nil)
+	repo.On("CreateSession", mock.Anything, mock.Anything).Return(nil)
+	expectAccessClaims(repo, []DepartmentGrant{}, []string{})
+	repo.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+	service := &Service{
+		repo:          repo,
+		parseMFAToken: func(_ string) (*MFAClaims, error) { return &MFAClaims{UserID: 4}, nil },
+		generateTokens: func(_ user.User, _ TokenMeta) (string, string, error) {
+			return "access", "refresh", nil
+		},
+	}
+
+	_, err := service.CompleteMFALogin(context.Background(), "mfa-token", "ABCDE-12345")
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestCompleteMFALogin_Locked(t *testing.T) {
+	lockedUntil := time.Now().Add(10 * time.Minute)
+
+	repo := new(MockAuthRepo)
+	repo.On("FindByID", mock.Anything, int64(4)).Return(user.User{ID: 4, Status: "active"}, nil)
+	repo.On("FindMFA", mock.Anything, int64(4)).Return(UserMFA{UserID: 4, Secret: "ABC", LockedUntil: &lockedUntil}, nil)
+
+	service := &Service{
+		repo:          repo,
+		parseMFAToken: func(_ string) (*MFAClaims, error) { return &MFAClaims{UserID: 4}, nil },
+	}
+
+	_, err := service.CompleteMFALogin(context.Background(), "mfa-token", "123456")
+	assert.ErrorIs(t, err, ErrMFALocked)
+}
+
+func TestDisableMFA_RequiredByRole(t *testing.T) {
+	repo := new(MockAuthRepo)
+	repo.On("FindByID", mock.Anything, int64(2)).Return(user.User{ID: 2, Role: "admin"}, nil)
+	repo.On("IsMFARequiredForRole", mock.Anything, "admin").Return(true, nil)
+
+	service := &Service{repo: repo}
+
+	err := service.DisableMFA(context.Background(), 2, "123456")
+	assert.ErrorIs(t, err, ErrMFARequiredByRole)
+	repo.AssertNotCalled(t, "DeleteMFA", mock.Anything, mock.Anything)
+}
+
+func TestParseMFAToken_RejectsAccessToken(t *testing.T) {
+	access, _, err := GenerateTokens("secret", "refresh-secret", user.User{ID: 9}, TokenMeta{JTI: "j", FamilyID: "f"})
+	assert.NoError(t, err)
+
+	_, err = ParseMFAToken("secret", access)
+	assert.ErrorIs(t, err, ErrInvalidMFAToken)
+
+	pending, err := GenerateMFAToken("secret", 9)
+	assert.NoError(t, err)
+	claims, err := ParseMFAToken("secret", pending)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), claims.UserID)
+}
+
+func TestBuildDepartmentAccess(t *testing.T) {
+	planner, auditor, senior := "planner", "auditor", "Senior"
+	grants := []DepartmentGrant{
+		{UnitID: 3, Department: "Flight Ops", Function: &planner, Rank: &senior},
+		{UnitID: 4, Department: "Flight Ops"},                                    // membership in a section of Flight Ops
+		{UnitID: 5, Department: "Flight Ops", Function: &planner, Rank: &senior}, // same function in another section
+		{UnitID: 6, Department: "Ground Ops"},
+		{UnitID: 7, Department: "Quality", Function: &auditor},
+	}
+
+	assert.Equal(t, []user.DepartmentAccess{
+		{Name: "Flight Ops", Role: "planner", Rank: "Senior"},
+		{Name: "Ground Ops", Role: MemberRole},
+		{Name: "Quality", Role: "auditor"},
+	}, BuildDepartmentAccess(grants))
+}
+
+func TestRefresh_RecomputesAccessClaims(t *testing.T) {
+	repo := new(MockAuthRepo)
+	stored := RefreshToken{JTI: "old-jti", UserID: 7, FamilyID: "family-1", TokenHash: HashToken("old-refresh"), ExpiresAt: time.Now().Add(time.Hour)}
+	planner := "planner"
+	repo.On("FindRefreshToken", mock.Anything, "old-jti").Return(stored, nil)
+	repo.On("FindByID", mock.Anything, int64(7)).Return(user.User{ID: 7, Status: "active"}, nil)
+	repo.On("MarkRefreshTokenUsed", mock.Anything, "old-jti", mock.AnythingOfType("string")).Return(true, nil)
+	repo.On("TouchSession", mock.Anything, "family-1", "", "").Return(nil)
+	repo.On("ListDepartmentGrants", mock.Anything, int64(7)).Return([]DepartmentGrant{{UnitID: 2, Department: "Flight Ops", Function: &planner}}, nil)
+	repo.On("ListModules", mock.Anything, int64(7)).Return([]string{"crew", "flights"}, nil)
+	repo.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil)
+
+	var issued user.User
+	service := &Service{
+		repo: repo,
+		parseRefreshToken: func(_ string) (*RefreshClaims, error) {
+			c := &RefreshClaims{UserID: 7, FamilyID: "family-1"}
+			c.ID = "old-jti"
+			return c, nil
+		},
+		generateTokens: func(u user.User, _ TokenMeta) (string, string, error) {
+			issued = u
+			return "new-access", "new-refresh", nil
+		},
+	}
+
+	_, err := service.Refresh(context.Background(), "old-refresh")
+	require.NoError(t, err)
+	assert.Equal(t, []user.DepartmentAccess{{Name: "Flight Ops", Role: "planner"}}, issued.Departments)
+	assert.Equal(t, []string{"crew", "flights"}, issued.Modules)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrSessionNotFound is returned when a session does not exist or belongs to another user.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionServiceInterface defines the session management operations exposed over HTTP.
+type SessionServiceInterface interface {
+	ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]Session, error)
+	RevokeSession(ctx context.Context, userID int64, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID int64) error
+}
+
+// ClientInfo describes the device a token pair is issued to.
+type ClientInfo struct {
+	Device    string
+	IPAddress string
+	UserAgent string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo stores the caller's device details on the context for session bookkeeping.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the device details stored by WithClientInfo, if any.
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// captureClientInfo records IP, user agent, and the optional X-Device-Name header on the request context.
+func captureClientInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := ClientInfo{
+			Device:    c.GetHeader("X-Device-Name"),
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+		c.Request = c.Request.WithContext(WithClientInfo(c.Request.Context(), info))
+		c.Next()
+	}
+}
+
+// Logout revokes the session the given refresh token belongs to.
+func (s *Service) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+
+	stored, err := s.repo.FindRefreshToken(ctx, claims.ID)
+	if err != nil || stored.TokenHash != HashToken(refreshToken) {
+		return ErrInvalidRefreshToken
+	}
+
+	return s.repo.RevokeSession(ctx, stored.FamilyID)
+}
+
+// ListSessions returns the user's active sessions, flagging the one making the request.
+func (s *Service) ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]Session, error) {
+	sessions, err := s.repo.ListSessionsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes one of the user's own sessions.
+func (s *Service) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	sess, err := s.repo.FindSession(ctx, sessionID)
+	if err != nil || sess.UserID != userID {
+		return ErrSessionNotFound
+	}
+	if err := s.repo.RevokeSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes every session of a user, e.g. after a compromise.
+func (s *Service) RevokeAllSessions(ctx context.Context, userID int64) error {
+	if err := s.repo.RevokeSessionsByUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
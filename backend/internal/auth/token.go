@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"os"
 	"time"
 
@@ -8,6 +11,17 @@ import (
 	"github.com/nomenarkt/lamina/internal/user"
 )
 
+// AccessTokenTTL is how long an access token stays valid.
+const AccessTokenTTL = 15 * time.Minute
+
+// RefreshTokenTTL is how long a refresh token stays valid.
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+// MFATokenTTL is how long an mfa_pending token can be exchanged for a token pair.
+const MFATokenTTL = 5 * time.Minute
+
+const mfaPendingPurpose = "mfa_pending"
+
 // Claims = JWT payload returned to frontend.
 type Claims struct {
 	UserID      int64                   `json:"userID"`
@@ -15,20 +29,46 @@ type Claims struct {
 	Departments []user.DepartmentAccess `json:"departments"`
 	Modules     []string                `json:"modules"`
 	Role        string                  `json:"role"`
+	SessionID   string                  `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// RefreshClaims = JWT payload of a refresh token. The registered "jti" claim
+// identifies the stored token row; FamilyID links every rotation of a login.
+type RefreshClaims struct {
+	UserID   int64  `json:"userID"`
+	FamilyID string `json:"fid"`
+	jwt.RegisteredClaims
+}
+
+// MFAClaims = JWT payload of an mfa_pending token. It carries no session ID,
+// so Middleware never accepts it as an access token.
+type MFAClaims struct {
+	UserID  int64  `json:"userID"`
+	Purpose string `json:"purpose"`
 	jwt.RegisteredClaims
 }
 
+// TokenMeta carries the identifiers embedded in a newly issued token pair.
+// FamilyID doubles as the session ID carried by the access token.
+type TokenMeta struct {
+	JTI      string
+	FamilyID string
+}
+
 // GenerateTokens returns access + refresh tokens for a user.
-func GenerateTokens(secret, refreshSecret string, u user.User) (string, string, error) {
+func GenerateTokens(secret, refreshSecret string, u user.User, meta TokenMeta) (string, string, error) {
+	now := time.Now()
 	claims := Claims{
 		UserID:      u.ID,
 		Email:       u.Email,
 		Departments: u.Departments,
 		Modules:     u.Modules,
 		Role:        u.Role,
+		SessionID:   meta.FamilyID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)), //Add(24 * time.Hour))
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
 		},
 	}
 
@@ -38,9 +78,14 @@ func GenerateTokens(secret, refreshSecret string, u user.User) (string, string,
 		return "", "", err
 	}
 
-	refresh := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
+	refresh := jwt.NewWithClaims(jwt.SigningMethodHS256, RefreshClaims{
+		UserID:   u.ID,
+		FamilyID: meta.FamilyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        meta.JTI,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshTokenTTL)),
+		},
 	})
 	refreshToken, err := refresh.SignedString([]byte(refreshSecret))
 	if err != nil {
@@ -51,6 +96,77 @@ func GenerateTokens(secret, refreshSecret string, u user.User) (string, string,
 }
 
 // GenerateTokensFromEnv uses env vars for signing keys.
-func GenerateTokensFromEnv(u user.User) (string, string, error) {
-	return GenerateTokens(os.Getenv("JWT_SECRET"), os.Getenv("JWT_REFRESH_SECRET"), u)
+func GenerateTokensFromEnv(u user.User, meta TokenMeta) (string, string, error) {
+	return GenerateTokens(os.Getenv("JWT_SECRET"), os.Getenv("JWT_REFRESH_SECRET"), u, meta)
+}
+
+// ParseRefreshToken validates a refresh token signature and expiry and returns its claims.
+func ParseRefreshToken(refreshSecret, tokenString string) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(refreshSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidRefreshToken
+	}
+	if claims.ID == "" || claims.FamilyID == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
+
+// ParseRefreshTokenFromEnv uses JWT_REFRESH_SECRET to validate a refresh token.
+func ParseRefreshTokenFromEnv(tokenString string) (*RefreshClaims, error) {
+	return ParseRefreshToken(os.Getenv("JWT_REFRESH_SECRET"), tokenString)
+}
+
+// GenerateMFAToken signs a short-lived mfa_pending token for a user who passed the password step.
+func GenerateMFAToken(secret string, userID int64) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, MFAClaims{
+		UserID:  userID,
+		Purpose: mfaPendingPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFATokenTTL)),
+		},
+	})
+	return token.SignedString([]byte(secret))
+}
+
+// GenerateMFATokenFromEnv uses JWT_SECRET to sign an mfa_pending token.
+func GenerateMFATokenFromEnv(userID int64) (string, error) {
+	return GenerateMFAToken(os.Getenv("JWT_SECRET"), userID)
+}
+
+// ParseMFAToken validates an mfa_pending token and returns its claims.
+func ParseMFAToken(secret, tokenString string) (*MFAClaims, error) {
+	claims := &MFAClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidMFAToken
+	}
+	if claims.Purpose != mfaPendingPurpose || claims.UserID == 0 {
+		return nil, ErrInvalidMFAToken
+	}
+	return claims, nil
+}
+
+// ParseMFATokenFromEnv uses JWT_SECRET to validate an mfa_pending token.
+func ParseMFATokenFromEnv(tokenString string) (*MFAClaims, error) {
+	return ParseMFAToken(os.Getenv("JWT_SECRET"), tokenString)
+}
+
+// HashToken returns the hex-encoded SHA-256 digest stored in place of a raw token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,355 @@
+package crew
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rule identifiers reported in a Violation.
+const (
+	RuleMaxFDP            = "max_fdp"
+	RuleMinRest           = "min_rest"
+	RuleDuty7Days         = "duty_7d"
+	RuleDuty28Days        = "duty_28d"
+	RuleFlightTime28Days  = "flight_time_28d"
+	RuleFlightTime365Days = "flight_time_365d"
+)
+
+// Measure selects what a cumulative limit adds up.
+type Measure string
+
+const (
+	// MeasureDuty sums duty time from check-in to checkout.
+	MeasureDuty Measure = "duty"
+	// MeasureFlightTime sums block time of sectors operated in function.
+	MeasureFlightTime Measure = "flight_time"
+)
+
+// FDPBand gives the maximum flight duty period for duties reporting at or after
+// Start (time of day in the rule set's location) until the next band.
+type FDPBand struct {
+	Start time.Duration
+	Max   time.Duration
+}
+
+// CumulativeLimit caps the duty or flight time within any rolling window.
+type CumulativeLimit struct {
+	Rule    string
+	Measure Measure
+	Window  time.Duration
+	Max     time.Duration
+}
+
+// RuleSet describes one regulation profile for flight time limitations (FTL).
+type RuleSet struct {
+	Code            string
+	Name            string
+	Location        *time.Location // reference time zone for FDP bands
+	FDPBands        []FDPBand
+	FreeSectors     int           // sectors covered by the band value
+	SectorReduction time.Duration // reduction for each further sector
+	MinFDP          time.Duration // floor after sector reductions
+	MinRest         time.Duration // rest is also at least as long as the preceding duty
+	Cumulative      []CumulativeLimit
+}
+
+// EASARuleSet returns limits modelled on EASA ORO.FTL.205/235/210 for acclimatised crew at home base.
+func EASARuleSet() RuleSet {
+	return RuleSet{
+		Code:     "EASA",
+		Name:     "EASA ORO.FTL",
+		Location: time.UTC,
+		FDPBands: []FDPBand{
+			{Start: 0, Max: 11 * time.Hour},
+			{Start: hm(5, 0), Max: hm(12, 0)},
+			{Start: hm(5, 15), Max: hm(12, 15)},
+			{Start: hm(5, 30), Max: hm(12, 30)},
+			{Start: hm(5, 45), Max: hm(12, 45)},
+			{Start: hm(6, 0), Max: hm(13, 0)},
+			{Start: hm(13, 30), Max: hm(12, 45)},
+			{Start: hm(14, 0), Max: hm(12, 30)},
+			{Start: hm(14, 30), Max: hm(12, 15)},
+			{Start: hm(15, 0), Max: hm(12, 0)},
+			{Start: hm(15, 30), Max: hm(11, 45)},
+			{Start: hm(16, 0), Max: hm(11, 30)},
+			{Start: hm(16, 30), Max: hm(11, 15)},
+			{Start: hm(17, 0), Max: hm(11, 0)},
+		},
+		FreeSectors:     2,
+		SectorReduction: 30 * time.Minute,
+		MinFDP:          9 * time.Hour,
+		MinRest:         12 * time.Hour,
+		Cumulative: []CumulativeLimit{
+			{Rule: RuleDuty7Days, Measure: MeasureDuty, Window: 7 * 24 * time.Hour, Max: 60 * time.Hour},
+			{Rule: RuleDuty28Days, Measure: MeasureDuty, Window: 28 * 24 * time.Hour, Max: 190 * time.Hour},
+			{Rule: RuleFlightTime28Days, Measure: MeasureFlightTime, Window: 28 * 24 * time.Hour, Max: 100 * time.Hour},
+			{Rule: RuleFlightTime365Days, Measure: MeasureFlightTime, Window: 365 * 24 * time.Hour, Max: 1000 * time.Hour},
+		},
+	}
+}
+
+// ruleSets lists the regulation profiles selectable with FTL_RULE_SET.
+var ruleSets = map[string]func() RuleSet{
+	"EASA": EASARuleSet,
+}
+
+// RuleSetFromEnv returns the profile named by FTL_RULE_SET (default EASA), with FDP bands
+// evaluated in FTL_TIMEZONE (default UTC).
+func RuleSetFromEnv() RuleSet {
+	code := strings.ToUpper(strings.TrimSpace(os.Getenv("FTL_RULE_SET")))
+	build, ok := ruleSets[code]
+	if !ok {
+		if code != "" {
+			log.Printf("⚠️ Unknown FTL_RULE_SET %q, using EASA", code)
+		}
+		build = EASARuleSet
+	}
+	rs := build()
+
+	if tz := os.Getenv("FTL_TIMEZONE"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			log.Printf("⚠️ Invalid FTL_TIMEZONE %q: %v", tz, err)
+		} else {
+			rs.Location = loc
+		}
+	}
+	return rs
+}
+
+// DutySector is one crew assignment as seen by the FTL engine.
+type DutySector struct {
+	AssignmentID int64     `db:"id"`
+	FlightID     int64     `db:"flight_id"`
+	InFunction   bool      `db:"in_function"`
+	CheckinTime  time.Time `db:"checkin_time"`
+	CheckoutTime time.Time `db:"checkout_time"`
+	BlockOff     time.Time `db:"block_off"`
+	BlockOn      time.Time `db:"block_on"`
+}
+
+// DutyPeriod groups sectors whose check-in/checkout windows overlap or touch.
+type DutyPeriod struct {
+	Start   time.Time
+	End     time.Time
+	Sectors []DutySector
+}
+
+// Violation explains why an assignment breaches a rule.
+type Violation struct {
+	Rule          string    `json:"rule"`
+	Message       string    `json:"message"`
+	LimitMinutes  int       `json:"limit_minutes"`
+	ActualMinutes int       `json:"actual_minutes"`
+	PeriodStart   time.Time `json:"period_start"`
+	PeriodEnd     time.Time `json:"period_end"`
+}
+
+// Lookback is how far around a candidate assignment existing duties must be loaded.
+func (rs RuleSet) Lookback() time.Duration {
+	lookback := 2 * rs.MinRest
+	for _, limit := range rs.Cumulative {
+		if limit.Window > lookback {
+			lookback = limit.Window
+		}
+	}
+	return lookback
+}
+
+// MaxFDP returns the maximum flight duty period for a duty reporting at start with the given sectors.
+func (rs RuleSet) MaxFDP(start time.Time, sectors int) time.Duration {
+	if len(rs.FDPBands) == 0 {
+		return 0
+	}
+	local := start.In(rs.location())
+	tod := hm(local.Hour(), local.Minute())
+
+	limit := rs.FDPBands[len(rs.FDPBands)-1].Max
+	for _, band := range rs.FDPBands {
+		if band.Start <= tod {
+			limit = band.Max
+		}
+	}
+	if extra := sectors - rs.FreeSectors; extra > 0 {
+		limit -= time.Duration(extra) * rs.SectorReduction
+	}
+	if limit < rs.MinFDP {
+		limit = rs.MinFDP
+	}
+	return limit
+}
+
+// RequiredRest returns the minimum rest after a duty period.
+func (rs RuleSet) RequiredRest(d DutyPeriod) time.Duration {
+	if duty := d.End.Sub(d.Start); duty > rs.MinRest {
+		return duty
+	}
+	return rs.MinRest
+}
+
+// Evaluate checks a candidate assignment against the crew member's existing assignments.
+func (rs RuleSet) Evaluate(existing []DutySector, candidate DutySector) []Violation {
+	duties := BuildDutyPeriods(append(append([]DutySector{}, existing...), candidate))
+
+	idx := -1
+	for i, d := range duties {
+		for _, s := range d.Sectors {
+			if s == candidate {
+				idx = i
+			}
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+	duty := duties[idx]
+
+	var violations []Violation
+	if limit, actual := rs.MaxFDP(duty.Start, duty.sectorCount()), duty.End.Sub(duty.Start); actual > limit {
+		violations = append(violations, violation(RuleMaxFDP,
+			fmt.Sprintf("flight duty period of %s exceeds the %s maximum for %d sector(s)", fmtDuration(actual), fmtDuration(limit), duty.sectorCount()),
+			limit, actual, duty.Start, duty.End))
+	}
+
+	if idx > 0 {
+		prev := duties[idx-1]
+		if limit, actual := rs.RequiredRest(prev), duty.Start.Sub(prev.End); actual < limit {
+			violations = append(violations, violation(RuleMinRest,
+				fmt.Sprintf("rest of %s before duty is shorter than the required %s", fmtDuration(actual), fmtDuration(limit)),
+				limit, actual, prev.End, duty.Start))
+		}
+	}
+	if idx+1 < len(duties) {
+		next := duties[idx+1]
+		if limit, actual := rs.RequiredRest(duty), next.Start.Sub(duty.End); actual < limit {
+			violations = append(violations, violation(RuleMinRest,
+				fmt.Sprintf("rest of %s after duty is shorter than the required %s", fmtDuration(actual), fmtDuration(limit)),
+				limit, actual, duty.End, next.Start))
+		}
+	}
+
+	for _, limit := range rs.Cumulative {
+		if v, ok := rs.checkCumulative(limit, duties, idx); ok {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// checkCumulative evaluates every rolling window, ending at a duty end, that covers the
+// candidate duty and reports the worst breach.
+func (rs RuleSet) checkCumulative(limit CumulativeLimit, duties []DutyPeriod, idx int) (Violation, bool) {
+	var (
+		worst    time.Duration
+		worstEnd time.Time
+	)
+	for j := idx; j < len(duties) && duties[j].End.Sub(duties[idx].Start) <= limit.Window; j++ {
+		end := duties[j].End
+		start := end.Add(-limit.Window)
+		var total time.Duration
+		for _, d := range duties {
+			if limit.Measure == MeasureFlightTime {
+				for _, s := range d.Sectors {
+					if s.InFunction {
+						total += overlap(s.BlockOff, s.BlockOn, start, end)
+					}
+				}
+				continue
+			}
+			total += overlap(d.Start, d.End, start, end)
+		}
+		if total > limit.Max && total > worst {
+			worst, worstEnd = total, end
+		}
+	}
+	if worst == 0 {
+		return Violation{}, false
+	}
+
+	what := "duty"
+	if limit.Measure == MeasureFlightTime {
+		what = "flight time"
+	}
+	return violation(limit.Rule,
+		fmt.Sprintf("%s of %s within %d days exceeds the %s limit", what, fmtDuration(worst), int(limit.Window.Hours()/24), fmtDuration(limit.Max)),
+		limit.Max, worst, worstEnd.Add(-limit.Window), worstEnd), true
+}
+
+// BuildDutyPeriods sorts sectors and merges those whose check-in/checkout windows overlap or touch.
+func BuildDutyPeriods(sectors []DutySector) []DutyPeriod {
+	sorted := append([]DutySector{}, sectors...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CheckinTime.Before(sorted[j].CheckinTime) })
+
+	var duties []DutyPeriod
+	for _, s := range sorted {
+		if n := len(duties); n > 0 && !s.CheckinTime.After(duties[n-1].End) {
+			last := &duties[n-1]
+			last.Sectors = append(last.Sectors, s)
+			if s.CheckoutTime.After(last.End) {
+				last.End = s.CheckoutTime
+			}
+			continue
+		}
+		duties = append(duties, DutyPeriod{Start: s.CheckinTime, End: s.CheckoutTime, Sectors: []DutySector{s}})
+	}
+	return duties
+}
+
+func (d DutyPeriod) sectorCount() int {
+	n := 0
+	for _, s := range d.Sectors {
+		if s.InFunction {
+			n++
+		}
+	}
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+func (rs RuleSet) location() *time.Location {
+	if rs.Location == nil {
+		return time.UTC
+	}
+	return rs.Location
+}
+
+func violation(rule, msg string, limit, actual time.Duration, start, end time.Time) Violation {
+	return Violation{
+		Rule:          rule,
+		Message:       msg,
+		LimitMinutes:  int(limit.Minutes()),
+		ActualMinutes: int(actual.Minutes()),
+		PeriodStart:   start,
+		PeriodEnd:     end,
+	}
+}
+
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	if aStart.Before(bStart) {
+		aStart = bStart
+	}
+	if aEnd.After(bEnd) {
+		aEnd = bEnd
+	}
+	if !aEnd.After(aStart) {
+		return 0
+	}
+	return aEnd.Sub(aStart)
+}
+
+func hm(h, m int) time.Duration {
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+}
+
+func fmtDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + fmtDuration(-d)
+	}
+	return fmt.Sprintf("%dh%02d", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,101 @@
+package crew_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nomenarkt/lamina/internal/crew"
+	"github.com/stretchr/testify/assert"
+)
+
+func sector(id int64, checkin time.Time, duty, block time.Duration) crew.DutySector {
+	return crew.DutySector{
+		AssignmentID: id, FlightID: id, InFunction: true,
+		CheckinTime: checkin, CheckoutTime: checkin.Add(duty),
+		BlockOff: checkin.Add(time.Hour), BlockOn: checkin.Add(time.Hour + block),
+	}
+}
+
+func TestRuleSet_MaxFDP_BandsAndSectors(t *testing.T) {
+	rs := crew.EASARuleSet()
+	day := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 13*time.Hour, rs.MaxFDP(day.Add(8*time.Hour), 2))
+	assert.Equal(t, 11*time.Hour, rs.MaxFDP(day.Add(22*time.Hour), 1))
+	assert.Equal(t, 12*time.Hour+15*time.Minute, rs.MaxFDP(day.Add(5*time.Hour+20*time.Minute), 1))
+	assert.Equal(t, 11*time.Hour+30*time.Minute, rs.MaxFDP(day.Add(8*time.Hour), 5))
+	assert.Equal(t, 9*time.Hour, rs.MaxFDP(day.Add(2*time.Hour), 10))
+}
+
+func TestRuleSet_MaxFDP_UsesReferenceTimeZone(t *testing.T) {
+	rs := crew.EASARuleSet()
+	rs.Location = time.FixedZone("EAT", 3*60*60)
+
+	// 03:30 UTC is 06:30 local time.
+	assert.Equal(t, 13*time.Hour, rs.MaxFDP(time.Date(2025, 3, 3, 3, 30, 0, 0, time.UTC), 1))
+}
+
+func TestRuleSet_Evaluate_MaxFDPAcrossMergedSectors(t *testing.T) {
+	rs := crew.EASARuleSet()
+	start := time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)
+
+	existing := []crew.DutySector{sector(1, start, 7*time.Hour, 2*time.Hour)}
+	candidate := sector(0, start.Add(7*time.Hour), 7*time.Hour, 2*time.Hour)
+
+	violations := rs.Evaluate(existing, candidate)
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, crew.RuleMaxFDP, violations[0].Rule)
+		assert.Equal(t, 13*60, violations[0].LimitMinutes)
+		assert.Equal(t, 14*60, violations[0].ActualMinutes)
+	}
+}
+
+func TestRuleSet_Evaluate_RestAfterCandidate(t *testing.T) {
+	rs := crew.EASARuleSet()
+	start := time.Date(2025, 3, 3, 6, 0, 0, 0, time.UTC)
+
+	existing := []crew.DutySector{sector(1, start.Add(24*time.Hour), 8*time.Hour, 4*time.Hour)}
+	candidate := sector(0, start.Add(6*time.Hour), 8*time.Hour, 4*time.Hour) // ends 20:00, next duty 06:00
+
+	violations := rs.Evaluate(existing, candidate)
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, crew.RuleMinRest, violations[0].Rule)
+		assert.Equal(t, 10*60, violations[0].ActualMinutes)
+	}
+}
+
+func TestRuleSet_Evaluate_CumulativeDutyAndFlightTime(t *testing.T) {
+	rs := crew.EASARuleSet()
+	start := time.Date(2025, 3, 1, 6, 0, 0, 0, time.UTC)
+
+	// Six 10h duties on consecutive days reach the 60h limit over 7 days.
+	var existing []crew.DutySector
+	for i := 0; i < 6; i++ {
+		existing = append(existing, sector(int64(i+1), start.AddDate(0, 0, i), 10*time.Hour, 8*time.Hour))
+	}
+	candidate := sector(0, start.AddDate(0, 0, 6), 10*time.Hour, 8*time.Hour)
+
+	violations := rs.Evaluate(existing, candidate)
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, crew.RuleDuty7Days, violations[0].Rule)
+		assert.Equal(t, 70*60, violations[0].ActualMinutes)
+	}
+
+	candidate.InFunction = false
+	rs.Cumulative = []crew.CumulativeLimit{{Rule: crew.RuleFlightTime28Days, Measure: crew.MeasureFlightTime, Window: 28 * 24 * time.Hour, Max: 40 * time.Hour}}
+	violations = rs.Evaluate(existing, candidate)
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, crew.RuleFlightTime28Days, violations[0].Rule)
+		assert.Equal(t, 48*60, violations[0].ActualMinutes) // positioning does not count as flight time
+	}
+}
+
+func TestRuleSet_Evaluate_LegalRoster(t *testing.T) {
+	rs := crew.EASARuleSet()
+	start := time.Date(2025, 3, 3, 6, 0, 0, 0, time.UTC)
+
+	existing := []crew.DutySector{sector(1, start, 9*time.Hour, 5*time.Hour)}
+	candidate := sector(0, start.Add(24*time.Hour), 9*time.Hour, 5*time.Hour)
+
+	assert.Empty(t, rs.Evaluate(existing, candidate))
+}
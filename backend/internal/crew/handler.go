@@ -2,12 +2,16 @@
 package crew
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/common/utils"
 )
 
 // Handler defines the HTTP handler for crew operations.
@@ -18,12 +22,18 @@ type Handler struct {
 // AssignCrewRequest represents the expected JSON payload for crew assignment.
 type AssignCrewRequest struct {
 	FlightNumber string `json:"flight_number"`
-	CrewID       int64  `json:"crew_id"`
-	CrewRole     string `json:"crew_role"`
-	InFunction   bool   `json:"in_function"`
-	PickupTime   string `json:"pickup_time"`
-	CheckinTime  string `json:"checkin_time"`
-	CheckoutTime string `json:"checkout_time"`
+	// FlightDate is the operating date (YYYY-MM-DD); a flight number recurs on every date it operates.
+	FlightDate string `json:"flight_date"`
+	// DepartureCode picks the leg when the flight has several on that date.
+	DepartureCode string `json:"departure_code"`
+	CrewID        int64  `json:"crew_id"`
+	CrewRole      string `json:"crew_role"`
+	InFunction    bool   `json:"in_function"`
+	PickupTime    string `json:"pickup_time"`
+	CheckinTime   string `json:"checkin_time"`
+	CheckoutTime  string `json:"checkout_time"`
+	// OverrideReason lets a supervisor assign crew despite FTL violations.
+	OverrideReason string `json:"override_reason"`
 }
 
 // NewHandler creates a new Handler instance for the crew service.
@@ -45,9 +55,17 @@ func (h *Handler) AssignCrew(c *gin.Context) {
 		return
 	}
 
-	flightID, err := h.service.ResolveFlightID(c.Request.Context(), req.FlightNumber)
+	if _, err := time.Parse("2006-01-02", req.FlightDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "flight_date must be YYYY-MM-DD"})
+		return
+	}
+	flightID, err := h.service.ResolveFlightID(c.Request.Context(), FlightRef{
+		Number:        strings.TrimSpace(req.FlightNumber),
+		Date:          req.FlightDate,
+		DepartureCode: strings.ToUpper(strings.TrimSpace(req.DepartureCode)),
+	})
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Flight not found"})
+		respondAssignError(c, err)
 		return
 	}
 
@@ -61,14 +79,71 @@ func (h *Handler) AssignCrew(c *gin.Context) {
 		CheckoutTime: parseTime(req.CheckoutTime),
 	}
 
-	err = h.service.AssignCrew(c.Request.Context(), ca)
+	if req.OverrideReason == "" {
+		if err := h.service.AssignCrew(c.Request.Context(), ca); err != nil {
+			respondAssignError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"message": "Crew assigned"})
+		return
+	}
+
+	if !canOverrideFTL(c.GetString("userRole")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only supervisors can override flight time limitations"})
+		return
+	}
+	supervisorID, _ := c.Get("userID")
+	id, _ := supervisorID.(int64)
+
+	override, err := h.service.AssignCrewWithOverride(c.Request.Context(), ca, req.OverrideReason, id)
 	if err != nil {
-		log.Printf("❌ AssignCrew error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondAssignError(c, err)
 		return
 	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Crew assigned", "override": override})
+}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Crew assigned"})
+// canOverrideFTL reports whether a role is listed in FTL_OVERRIDE_ROLES (default "admin,supervisor").
+func canOverrideFTL(role string) bool {
+	roles := os.Getenv("FTL_OVERRIDE_ROLES")
+	if roles == "" {
+		roles = "admin,supervisor"
+	}
+	for _, r := range strings.Split(roles, ",") {
+		if role != "" && strings.TrimSpace(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+func respondAssignError(c *gin.Context, err error) {
+	var (
+		illegal     *LegalityError
+		unqualified *QualificationError
+	)
+	switch {
+	case errors.As(err, &unqualified):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":                  ErrUnqualified.Error(),
+			"missing_qualifications": unqualified.Missing,
+		})
+	case errors.As(err, &illegal):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      ErrIllegalAssignment.Error(),
+			"rule_set":   illegal.RuleSet,
+			"violations": illegal.Violations,
+		})
+	case errors.Is(err, ErrInvalidAssignment), errors.Is(err, ErrOverrideReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrFlightNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flight not found"})
+	case errors.Is(err, ErrAmbiguousFlight):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ AssignCrew error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not assign crew"})
+	}
 }
 
 // GetCrewByFlight retrieves assigned crew for a flight.
@@ -127,3 +202,99 @@ func (h *Handler) GetCrewDetailsByFlight(c *gin.Context) {
 
 	c.JSON(http.StatusOK, result)
 }
+
+// GetMyRoster returns the caller's roster as an iCalendar file.
+// GET /crew/me/roster.ics
+func (h *Handler) GetMyRoster(c *gin.Context) {
+	userID, ok := utils.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	calendar, err := h.service.RosterCalendar(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Roster export failed for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not build roster"})
+		return
+	}
+	writeCalendar(c, calendar)
+}
+
+// GetRosterFeed serves a roster through its secret subscription URL; the ".ics" suffix is optional.
+// GET /crew/roster/:token
+func (h *Handler) GetRosterFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	calendar, err := h.service.RosterCalendarByToken(c.Request.Context(), token)
+	if errors.Is(err, ErrInvalidRosterToken) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Roster feed not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Roster feed failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not build roster"})
+		return
+	}
+	writeCalendar(c, calendar)
+}
+
+// CreateRosterFeed issues a new secret subscription URL, revoking the previous one.
+// POST /crew/me/roster/feed
+func (h *Handler) CreateRosterFeed(c *gin.Context) {
+	userID, ok := utils.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	token, err := h.service.CreateRosterFeedToken(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Roster feed token failed for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create roster feed"})
+		return
+	}
+
+	url := rosterFeedURL(c, token)
+	c.JSON(http.StatusCreated, gin.H{
+		"url":        url,
+		"webcal_url": "webcal://" + strings.SplitN(url, "://", 2)[1],
+	})
+}
+
+// RevokeRosterFeed disables the caller's subscription URL.
+// DELETE /crew/me/roster/feed
+func (h *Handler) RevokeRosterFeed(c *gin.Context) {
+	userID, ok := utils.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.service.RevokeRosterFeedToken(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke roster feed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Roster feed revoked"})
+}
+
+func writeCalendar(c *gin.Context, calendar string) {
+	c.Header("Content-Disposition", `inline; filename="roster.ics"`)
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
+
+// rosterFeedURL builds the public feed URL next to the current /crew/me/roster/feed route.
+// API_PUBLIC_URL overrides the scheme and host seen by the server, e.g. behind a proxy.
+func rosterFeedURL(c *gin.Context, token string) string {
+	base := strings.TrimSuffix(c.Request.URL.Path, "/me/roster/feed") + "/roster/" + token + ".ics"
+	if origin := strings.TrimSuffix(os.Getenv("API_PUBLIC_URL"), "/"); origin != "" {
+		return origin + base
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + base
+}
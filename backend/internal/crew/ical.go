@@ -0,0 +1,158 @@
+package crew
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	icalTimeLayout = "20060102T150405Z"
+	icalProdID     = "-//Lamina//Crew Roster//EN"
+	icalLineLimit  = 75
+)
+
+// sequenceEpoch anchors event SEQUENCE numbers, which count minutes since this instant
+// so that every change to an assignment or its flight yields a higher sequence.
+var sequenceEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// RosterEventUID returns the stable iCalendar UID of an assignment.
+func RosterEventUID(assignmentID int64) string {
+	return fmt.Sprintf("crew-assignment-%d@lamina", assignmentID)
+}
+
+// BuildRosterCalendar renders roster entries as an RFC 5545 VCALENDAR.
+// Deleted assignments and cancelled flights are published with STATUS:CANCELLED.
+func BuildRosterCalendar(name string, entries []RosterEntry, now time.Time) string {
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:"+icalProdID)
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(name))
+
+	for _, e := range entries {
+		start, end := e.eventBounds()
+		if start == nil || end == nil || !end.After(*start) {
+			continue
+		}
+
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, "UID:"+RosterEventUID(e.AssignmentID))
+		writeICalLine(&b, "DTSTAMP:"+now.UTC().Format(icalTimeLayout))
+		writeICalLine(&b, "LAST-MODIFIED:"+e.LastModified.UTC().Format(icalTimeLayout))
+		writeICalLine(&b, fmt.Sprintf("SEQUENCE:%d", sequenceOf(e.LastModified)))
+		writeICalLine(&b, "DTSTART:"+start.UTC().Format(icalTimeLayout))
+		writeICalLine(&b, "DTEND:"+end.UTC().Format(icalTimeLayout))
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(e.summary()))
+		if e.DepartureCode != "" {
+			writeICalLine(&b, "LOCATION:"+escapeICalText(e.DepartureCode))
+		}
+		writeICalLine(&b, "DESCRIPTION:"+escapeICalText(e.description()))
+		if e.Cancelled {
+			writeICalLine(&b, "STATUS:CANCELLED")
+		} else {
+			writeICalLine(&b, "STATUS:CONFIRMED")
+		}
+		if !e.InFunction {
+			writeICalLine(&b, "CATEGORIES:MEP")
+			writeICalLine(&b, "X-LAMINA-MEP:TRUE")
+		}
+		if e.CrewRole != "" {
+			writeICalLine(&b, "X-LAMINA-CREW-ROLE:"+escapeICalText(e.CrewRole))
+		}
+		writeICalLine(&b, "TRANSP:OPAQUE")
+		writeICalLine(&b, "END:VEVENT")
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// eventBounds spans the duty from pickup (or check-in, or departure) to checkout (or arrival).
+func (e RosterEntry) eventBounds() (*time.Time, *time.Time) {
+	return firstTime(e.PickupTime, e.CheckinTime, e.ScheduledDeparture), firstTime(e.CheckoutTime, e.ScheduledArrival)
+}
+
+func (e RosterEntry) summary() string {
+	parts := []string{}
+	if e.Cancelled {
+		parts = append(parts, "CANCELLED")
+	}
+	if !e.InFunction {
+		parts = append(parts, "MEP")
+	}
+	flight := e.FlightNumber
+	if flight == "" {
+		flight = "Duty"
+	}
+	parts = append(parts, flight)
+	if e.DepartureCode != "" && e.ArrivalCode != "" {
+		parts = append(parts, e.DepartureCode+"-"+e.ArrivalCode)
+	}
+	if e.CrewRole != "" {
+		parts = append(parts, "("+e.CrewRole+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (e RosterEntry) description() string {
+	lines := []string{}
+	add := func(label string, t *time.Time) {
+		if t != nil {
+			lines = append(lines, fmt.Sprintf("%s: %s UTC", label, t.UTC().Format("2006-01-02 15:04")))
+		}
+	}
+	add("Pickup", e.PickupTime)
+	add("Check-in", e.CheckinTime)
+	add("Departure", e.ScheduledDeparture)
+	add("Arrival", e.ScheduledArrival)
+	add("Checkout", e.CheckoutTime)
+	if e.CrewRole != "" {
+		lines = append(lines, "Role: "+e.CrewRole)
+	}
+	if !e.InFunction {
+		lines = append(lines, "MEP: positioning, not in function")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func firstTime(candidates ...*time.Time) *time.Time {
+	for _, t := range candidates {
+		if t != nil && !t.IsZero() {
+			return t
+		}
+	}
+	return nil
+}
+
+func sequenceOf(modified time.Time) int64 {
+	if modified.Before(sequenceEpoch) {
+		return 0
+	}
+	return int64(modified.Sub(sequenceEpoch) / time.Minute)
+}
+
+// escapeICalText escapes TEXT values per RFC 5545 §3.3.11.
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICalLine writes a content line folded at 75 octets and terminated by CRLF,
+// taking care not to split UTF-8 sequences.
+func writeICalLine(b *strings.Builder, line string) {
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icalLineLimit - 1 // continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,79 @@
+package crew_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nomenarkt/lamina/internal/crew"
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestBuildRosterCalendar_Events(t *testing.T) {
+	day := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	entries := []crew.RosterEntry{
+		{
+			AssignmentID: 11, CrewRole: "CDB", InFunction: true,
+			PickupTime: ptr(day.Add(4*time.Hour + 15*time.Minute)), CheckinTime: ptr(day.Add(5 * time.Hour)),
+			CheckoutTime: ptr(day.Add(8 * time.Hour)),
+			FlightNumber: "MD700", DepartureCode: "TNR", ArrivalCode: "NOS",
+			ScheduledDeparture: ptr(day.Add(6 * time.Hour)), ScheduledArrival: ptr(day.Add(7*time.Hour + 15*time.Minute)),
+			LastModified: day,
+		},
+		{
+			AssignmentID: 12, CrewRole: "OPL", InFunction: false,
+			CheckinTime: ptr(day.Add(10 * time.Hour)), CheckoutTime: ptr(day.Add(13 * time.Hour)),
+			FlightNumber: "MD701", DepartureCode: "NOS", ArrivalCode: "TNR",
+			Cancelled: true, LastModified: day.Add(time.Hour),
+		},
+	}
+
+	cal := crew.BuildRosterCalendar("Lamina roster", entries, day)
+
+	assert.True(t, strings.HasPrefix(cal, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(cal, "END:VCALENDAR\r\n"))
+	assert.Equal(t, 2, strings.Count(cal, "BEGIN:VEVENT"))
+	assert.Contains(t, cal, "UID:crew-assignment-11@lamina\r\n")
+	assert.Contains(t, cal, "DTSTART:20250303T041500Z\r\n")
+	assert.Contains(t, cal, "DTEND:20250303T080000Z\r\n")
+	assert.Contains(t, cal, "SUMMARY:MD700 TNR-NOS (CDB)\r\n")
+	assert.Contains(t, strings.ReplaceAll(cal, "\r\n ", ""), `Departure: 2025-03-03 06:00 UTC\n`)
+
+	assert.Contains(t, cal, "SUMMARY:CANCELLED MEP MD701 NOS-TNR (OPL)\r\n")
+	assert.Contains(t, cal, "STATUS:CANCELLED\r\n")
+	assert.Contains(t, cal, "X-LAMINA-MEP:TRUE\r\n")
+
+	for _, line := range strings.Split(cal, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+}
+
+func TestBuildRosterCalendar_SequenceIncreasesWithChanges(t *testing.T) {
+	day := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	entry := crew.RosterEntry{AssignmentID: 1, InFunction: true, CheckinTime: ptr(day), CheckoutTime: ptr(day.Add(time.Hour)), LastModified: day}
+
+	before := crew.BuildRosterCalendar("r", []crew.RosterEntry{entry}, day)
+	entry.LastModified = day.Add(5 * time.Minute)
+	after := crew.BuildRosterCalendar("r", []crew.RosterEntry{entry}, day)
+
+	seq := func(cal string) string {
+		i := strings.Index(cal, "SEQUENCE:")
+		return cal[i : i+strings.Index(cal[i:], "\r\n")]
+	}
+	assert.NotEqual(t, seq(before), seq(after))
+	assert.Contains(t, after, "UID:crew-assignment-1@lamina")
+}
+
+func TestBuildRosterCalendar_EscapesAndFolds(t *testing.T) {
+	day := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	name := "Équipage; roster, " + strings.Repeat("é", 60)
+
+	cal := crew.BuildRosterCalendar(name, nil, day)
+
+	assert.Contains(t, cal, `X-WR-CALNAME:Équipage\; roster\, `)
+	assert.Contains(t, cal, "\r\n ")
+	unfolded := strings.ReplaceAll(cal, "\r\n ", "")
+	assert.Contains(t, unfolded, strings.Repeat("é", 60))
+}
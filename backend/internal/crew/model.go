@@ -16,6 +16,14 @@ type Assignment struct {
 	CreatedAt    time.Time `db:"created_at"`    // Record creation time
 }
 
+// FlightRef identifies a flight by its unique key: flight number, operating date (YYYY-MM-DD)
+// and departure station. The station may be left empty when only one leg matches.
+type FlightRef struct {
+	Number        string
+	Date          string
+	DepartureCode string
+}
+
 // AssignmentDetail represents enriched crew assignment info with flight and crew metadata.
 type AssignmentDetail struct {
 	ID            int       `db:"id" json:"id"`                         // Assignment ID
@@ -31,3 +39,41 @@ type AssignmentDetail struct {
 	CrewName      string    `db:"crew_name" json:"crew_name"`           // Full name of crew member
 	CrewEmail     string    `db:"crew_email" json:"crew_email"`         // Crew email address
 }
+
+// FTLOverride records a supervisor accepting an assignment that breaches flight time limitations.
+type FTLOverride struct {
+	ID           int64       `db:"id" json:"id"`
+	AssignmentID int64       `db:"assignment_id" json:"assignment_id"`
+	CrewID       int         `db:"crew_id" json:"crew_id"`
+	RuleSet      string      `db:"rule_set" json:"rule_set"`
+	Violations   []Violation `db:"-" json:"violations"`
+	Reason       string      `db:"reason" json:"reason"`
+	OverriddenBy int64       `db:"overridden_by" json:"overridden_by"`
+	CreatedAt    time.Time   `db:"created_at" json:"created_at"`
+}
+
+// RosterEntry is one crew assignment as published in a roster calendar feed.
+// Times are nullable because assignments may be stored without pickup or flight details.
+type RosterEntry struct {
+	AssignmentID       int64      `db:"assignment_id"`
+	CrewRole           string     `db:"crew_role"`
+	InFunction         bool       `db:"in_function"`
+	PickupTime         *time.Time `db:"pickup_time"`
+	CheckinTime        *time.Time `db:"checkin_time"`
+	CheckoutTime       *time.Time `db:"checkout_time"`
+	FlightNumber       string     `db:"flight_number"`
+	DepartureCode      string     `db:"departure_code"`
+	ArrivalCode        string     `db:"arrival_code"`
+	ScheduledDeparture *time.Time `db:"scheduled_departure"`
+	ScheduledArrival   *time.Time `db:"scheduled_arrival"`
+	Cancelled          bool       `db:"cancelled"`     // assignment deleted or flight cancelled
+	LastModified       time.Time  `db:"last_modified"` // drives the event SEQUENCE
+}
+
+// MissingQualification is a qualification the crew member lacks for a role on a flight.
+type MissingQualification struct {
+	Code      string     `db:"code" json:"code"`
+	Name      string     `db:"name" json:"name"`
+	Reason    string     `db:"reason" json:"reason"`         // missing, expired, not_yet_valid or aircraft_type_unknown
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at"` // of the most recent record, if any
+}
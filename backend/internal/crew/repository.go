@@ -3,6 +3,8 @@ package crew
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -12,20 +14,79 @@ type Repository interface {
 	Create(ctx context.Context, ca *Assignment) error
 	GetByFlightID(ctx context.Context, flightID int64) ([]Assignment, error)
 	DeleteByFlightID(ctx context.Context, flightID int64) error
-	GetFlightIDByNumber(ctx context.Context, flightNumber string) (int64, error)
+	FindFlightIDs(ctx context.Context, ref FlightRef) ([]int64, error)
 	GetDetailedByFlightID(ctx context.Context, flightID int64) ([]AssignmentDetail, error)
+	GetFlightBlockTimes(ctx context.Context, flightID int64) (time.Time, time.Time, error)
+	ListDutySectors(ctx context.Context, crewID int, from, to time.Time) ([]DutySector, error)
+	CreateWithOverride(ctx context.Context, ca *Assignment, o *FTLOverride) error
+	ListRosterEntries(ctx context.Context, crewID int64, since time.Time) ([]RosterEntry, error)
+	SaveRosterFeedToken(ctx context.Context, userID int64, tokenHash string) error
+	FindUserByRosterFeedToken(ctx context.Context, tokenHash string) (int64, error)
+	DeleteRosterFeedToken(ctx context.Context, userID int64) error
+	ListMissingQualifications(ctx context.Context, crewID int, flightID int64, role string) ([]MissingQualification, error)
+	// WithCrewLock runs fn in a transaction holding an exclusive lock on the crew member, passing
+	// a Repository bound to that transaction. fn's error rolls the transaction back.
+	WithCrewLock(ctx context.Context, crewID int, fn func(repo Repository) error) error
+}
+
+// queryer is the part of sqlx shared by *sqlx.DB and *sqlx.Tx.
+type queryer interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
 }
 
 // crewRepository is a concrete implementation of the Repository interface.
 type crewRepository struct {
 	db *sqlx.DB
+	tx *sqlx.Tx // set inside WithCrewLock
 }
 
+// crewLockClass namespaces the per-crew-member advisory locks taken by WithCrewLock.
+const crewLockClass = 0x63726577 // "crew"
+
 // NewRepository returns a new instance of a crew Repository.
 func NewRepository(db *sqlx.DB) Repository {
 	return &crewRepository{db: db}
 }
 
+// q returns the transaction inside WithCrewLock and the database otherwise.
+func (r *crewRepository) q() queryer {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
+// withTx runs fn in the surrounding WithCrewLock transaction, or in a new one.
+func (r *crewRepository) withTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithCrewLock serialises assignments of one crew member, so the duties and qualifications
+// checked by fn cannot change before its assignment is stored.
+func (r *crewRepository) WithCrewLock(ctx context.Context, crewID int, fn func(repo Repository) error) error {
+	return r.withTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, crewLockClass, crewID); err != nil {
+			return err
+		}
+		return fn(&crewRepository{db: r.db, tx: tx})
+	})
+}
+
 // Create inserts a new Assignment into the database.
 func (r *crewRepository) Create(ctx context.Context, ca *Assignment) error {
 	query := `
@@ -33,7 +94,7 @@ func (r *crewRepository) Create(ctx context.Context, ca *Assignment) error {
 		VALUES (:flight_id, :crew_id, :crew_role, :in_function, :pickup_time, :checkin_time, :checkout_time)
 		RETURNING id
 	`
-	stmt, err := r.db.PrepareNamedContext(ctx, query)
+	stmt, err := r.q().PrepareNamedContext(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -43,7 +104,7 @@ func (r *crewRepository) Create(ctx context.Context, ca *Assignment) error {
 // GetByFlightID returns all crew assignments for a specific flight.
 func (r *crewRepository) GetByFlightID(ctx context.Context, flightID int64) ([]Assignment, error) {
 	var assignments []Assignment
-	err := r.db.SelectContext(ctx, &assignments, `
+	err := r.q().SelectContext(ctx, &assignments, `
 		SELECT * FROM crew_assignments
 		WHERE flight_id = $1
 		ORDER BY pickup_time ASC
@@ -53,18 +114,24 @@ func (r *crewRepository) GetByFlightID(ctx context.Context, flightID int64) ([]A
 
 // DeleteByFlightID removes all crew assignments for a specific flight.
 func (r *crewRepository) DeleteByFlightID(ctx context.Context, flightID int64) error {
-	_, err := r.db.ExecContext(ctx, `
+	_, err := r.q().ExecContext(ctx, `
 		DELETE FROM crew_assignments
 		WHERE flight_id = $1
 	`, flightID)
 	return err
 }
 
-// GetFlightIDByNumber returns the internal ID of a flight given its flight number.
-func (r *crewRepository) GetFlightIDByNumber(ctx context.Context, flightNumber string) (int64, error) {
-	var id int64
-	err := r.db.GetContext(ctx, &id, `SELECT id FROM flights WHERE flight_number = $1`, flightNumber)
-	return id, err
+// FindFlightIDs returns the IDs of the flights matching ref, at most two: enough to tell a
+// unique match from an ambiguous one.
+func (r *crewRepository) FindFlightIDs(ctx context.Context, ref FlightRef) ([]int64, error) {
+	var ids []int64
+	err := r.q().SelectContext(ctx, &ids, `
+		SELECT id FROM flights
+		WHERE flight_number = $1 AND flight_date = $2 AND ($3 = '' OR departure_code = $3)
+		ORDER BY id
+		LIMIT 2
+	`, ref.Number, ref.Date, ref.DepartureCode)
+	return ids, err
 }
 
 // GetDetailedByFlightID returns enriched crew assignment data for a flight.
@@ -83,6 +150,155 @@ func (r *crewRepository) GetDetailedByFlightID(ctx context.Context, flightID int
 		ORDER BY ca.pickup_time ASC
 	`
 
-	err := r.db.SelectContext(ctx, &result, query, flightID)
+	err := r.q().SelectContext(ctx, &result, query, flightID)
 	return result, err
 }
+
+// GetFlightBlockTimes returns a flight's off- and on-block times, actual when recorded and scheduled otherwise.
+func (r *crewRepository) GetFlightBlockTimes(ctx context.Context, flightID int64) (time.Time, time.Time, error) {
+	var times struct {
+		BlockOff time.Time `db:"block_off"`
+		BlockOn  time.Time `db:"block_on"`
+	}
+	err := r.q().GetContext(ctx, &times, `
+		SELECT COALESCE(actual_departure, scheduled_departure) AS block_off,
+		       COALESCE(actual_arrival, scheduled_arrival) AS block_on
+		FROM flights
+		WHERE id = $1
+	`, flightID)
+	return times.BlockOff, times.BlockOn, err
+}
+
+// ListDutySectors returns a crew member's assignments on non-cancelled flights overlapping [from, to).
+func (r *crewRepository) ListDutySectors(ctx context.Context, crewID int, from, to time.Time) ([]DutySector, error) {
+	var sectors []DutySector
+	err := r.q().SelectContext(ctx, &sectors, `
+		SELECT ca.id, ca.flight_id, ca.in_function, ca.checkin_time, ca.checkout_time,
+		       COALESCE(f.actual_departure, f.scheduled_departure) AS block_off,
+		       COALESCE(f.actual_arrival, f.scheduled_arrival) AS block_on
+		FROM crew_assignments ca
+		JOIN flights f ON ca.flight_id = f.id
+		WHERE ca.crew_id = $1
+		  AND f.status <> 'cancelled'
+		  AND ca.checkin_time IS NOT NULL AND ca.checkout_time IS NOT NULL
+		  AND ca.checkout_time > $2 AND ca.checkin_time < $3
+		ORDER BY ca.checkin_time ASC
+	`, crewID, from, to)
+	return sectors, err
+}
+
+// CreateWithOverride inserts an assignment together with the supervisor override that allowed it.
+func (r *crewRepository) CreateWithOverride(ctx context.Context, ca *Assignment, o *FTLOverride) error {
+	violations, err := json.Marshal(o.Violations)
+	if err != nil {
+		return err
+	}
+
+	return r.withTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &ca.ID, `
+			INSERT INTO crew_assignments (flight_id, crew_id, crew_role, in_function, pickup_time, checkin_time, checkout_time)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+		`, ca.FlightID, ca.CrewID, ca.CrewRole, ca.InFunction, ca.PickupTime, ca.CheckinTime, ca.CheckoutTime); err != nil {
+			return err
+		}
+
+		o.AssignmentID = ca.ID
+		if err := tx.QueryRowxContext(ctx, `
+			INSERT INTO ftl_overrides (assignment_id, crew_id, rule_set, violations, reason, overridden_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, created_at
+		`, o.AssignmentID, o.CrewID, o.RuleSet, violations, o.Reason, o.OverriddenBy).Scan(&o.ID, &o.CreatedAt); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// ListRosterEntries returns a crew member's assignments ending after since, together with
+// assignments deleted after since, ordered by check-in.
+func (r *crewRepository) ListRosterEntries(ctx context.Context, crewID int64, since time.Time) ([]RosterEntry, error) {
+	var entries []RosterEntry
+	err := r.q().SelectContext(ctx, &entries, `
+		SELECT ca.id AS assignment_id, ca.crew_role, COALESCE(ca.in_function, TRUE) AS in_function,
+		       ca.pickup_time, ca.checkin_time, ca.checkout_time,
+		       f.flight_number, f.departure_code, f.arrival_code,
+		       f.scheduled_departure, f.scheduled_arrival,
+		       f.status = 'cancelled' AS cancelled,
+		       GREATEST(COALESCE(ca.created_at, f.updated_at), f.updated_at) AS last_modified
+		FROM crew_assignments ca
+		JOIN flights f ON ca.flight_id = f.id
+		WHERE ca.crew_id = $1
+		  AND COALESCE(ca.checkout_time, f.scheduled_arrival) >= $2
+		UNION ALL
+		SELECT c.assignment_id, COALESCE(c.crew_role, ''), COALESCE(c.in_function, TRUE),
+		       c.pickup_time, c.checkin_time, c.checkout_time,
+		       COALESCE(c.flight_number, ''), COALESCE(c.departure_code, ''), COALESCE(c.arrival_code, ''),
+		       NULL, NULL,
+		       TRUE, c.cancelled_at
+		FROM crew_assignment_cancellations c
+		WHERE c.crew_id = $1
+		  AND c.cancelled_at >= $2
+		ORDER BY checkin_time ASC NULLS LAST
+	`, crewID, since)
+	return entries, err
+}
+
+// SaveRosterFeedToken stores the hash of a user's feed token, replacing any previous one.
+func (r *crewRepository) SaveRosterFeedToken(ctx context.Context, userID int64, tokenHash string) error {
+	_, err := r.q().ExecContext(ctx, `
+		INSERT INTO roster_feed_tokens (user_id, token_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now()
+	`, userID, tokenHash)
+	return err
+}
+
+// FindUserByRosterFeedToken returns the user owning a feed token hash.
+func (r *crewRepository) FindUserByRosterFeedToken(ctx context.Context, tokenHash string) (int64, error) {
+	var userID int64
+	err := r.q().GetContext(ctx, &userID, `SELECT user_id FROM roster_feed_tokens WHERE token_hash = $1`, tokenHash)
+	return userID, err
+}
+
+// DeleteRosterFeedToken revokes a user's feed token.
+func (r *crewRepository) DeleteRosterFeedToken(ctx context.Context, userID int64) error {
+	_, err := r.q().ExecContext(ctx, `DELETE FROM roster_feed_tokens WHERE user_id = $1`, userID)
+	return err
+}
+
+// ListMissingQualifications returns the qualification types required for a crew role on a flight
+// that the crew member does not hold valid on the flight date. Types tied to an aircraft type
+// apply only to flights of that type; while the flight's aircraft type is unknown every such type
+// is reported as aircraft_type_unknown, since the right rating cannot be checked.
+func (r *crewRepository) ListMissingQualifications(ctx context.Context, crewID int, flightID int64, role string) ([]MissingQualification, error) {
+	missing := []MissingQualification{}
+	err := r.q().SelectContext(ctx, &missing, `
+		SELECT qt.code, qt.name,
+		       CASE
+		           WHEN qt.aircraft_type IS NOT NULL AND f.aircraft_type IS NULL THEN 'aircraft_type_unknown'
+		           WHEN uq.id IS NULL THEN 'missing'
+		           WHEN uq.issued_at > f.flight_date THEN 'not_yet_valid'
+		           ELSE 'expired'
+		       END AS reason,
+		       uq.expires_at
+		FROM flights f
+		JOIN qualification_types qt
+		  ON UPPER($3) = ANY(qt.required_for_roles)
+		 AND (qt.aircraft_type IS NULL OR f.aircraft_type IS NULL OR qt.aircraft_type = f.aircraft_type)
+		LEFT JOIN LATERAL (
+		    SELECT uq.id, uq.issued_at, uq.expires_at
+		    FROM user_qualifications uq
+		    WHERE uq.user_id = $1 AND uq.qualification_type_id = qt.id
+		    ORDER BY (uq.issued_at <= f.flight_date AND (uq.expires_at IS NULL OR uq.expires_at >= f.flight_date)) DESC,
+		             uq.expires_at DESC NULLS FIRST
+		    LIMIT 1
+		) uq ON TRUE
+		WHERE f.id = $2
+		  AND ((qt.aircraft_type IS NOT NULL AND f.aircraft_type IS NULL)
+		       OR NOT COALESCE(uq.issued_at <= f.flight_date AND (uq.expires_at IS NULL OR uq.expires_at >= f.flight_date), FALSE))
+		ORDER BY qt.code
+	`, crewID, flightID, role)
+	return missing, err
+}
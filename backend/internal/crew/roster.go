@@ -0,0 +1,68 @@
+package crew
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nomenarkt/lamina/common/utils"
+)
+
+// rosterHistory is how far back finished and cancelled assignments stay in a feed.
+const rosterHistory = 30 * 24 * time.Hour
+
+const rosterCalendarName = "Lamina roster"
+
+// ErrInvalidRosterToken is returned for unknown or revoked feed tokens.
+var ErrInvalidRosterToken = errors.New("invalid roster feed token")
+
+// RosterCalendar renders a crew member's roster as iCalendar data.
+func (s *crewService) RosterCalendar(ctx context.Context, crewID int64) (string, error) {
+	entries, err := s.repo.ListRosterEntries(ctx, crewID, time.Now().Add(-rosterHistory))
+	if err != nil {
+		return "", fmt.Errorf("failed to load roster: %w", err)
+	}
+	return BuildRosterCalendar(rosterCalendarName, entries, time.Now()), nil
+}
+
+// RosterCalendarByToken renders the roster of the user owning a feed subscription token.
+func (s *crewService) RosterCalendarByToken(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", ErrInvalidRosterToken
+	}
+	userID, err := s.repo.FindUserByRosterFeedToken(ctx, hashFeedToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidRosterToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve feed token: %w", err)
+	}
+	return s.RosterCalendar(ctx, userID)
+}
+
+// CreateRosterFeedToken issues a new feed subscription token, invalidating the previous one.
+// Only its hash is stored, so the token is returned once.
+func (s *crewService) CreateRosterFeedToken(ctx context.Context, userID int64) (string, error) {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.SaveRosterFeedToken(ctx, userID, hashFeedToken(token)); err != nil {
+		return "", fmt.Errorf("failed to store feed token: %w", err)
+	}
+	return token, nil
+}
+
+// RevokeRosterFeedToken disables the user's feed subscription URL.
+func (s *crewService) RevokeRosterFeedToken(ctx context.Context, userID int64) error {
+	return s.repo.DeleteRosterFeedToken(ctx, userID)
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -4,10 +4,23 @@ package crew
 import "github.com/gin-gonic/gin"
 
 // RegisterRoutes sets up the HTTP endpoints for crew management under the given route group.
-func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
-	crewGroup := rg.Group("/crew")
+// The scope middleware (e.g. access.CasbinMiddleware) guards crew operations; a user's own
+// roster stays reachable without an org-unit policy.
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, scope ...gin.HandlerFunc) {
+	crewGroup := rg.Group("/crew", scope...)
 	crewGroup.POST("/assign", h.AssignCrew)
 	crewGroup.GET("/flight/:flight_id", h.GetCrewByFlight)
 	crewGroup.DELETE("/flight/:flight_id", h.RemoveCrewByFlight)
 	crewGroup.GET("/flight/:flight_id/details", h.GetCrewDetailsByFlight)
+
+	me := rg.Group("/crew/me")
+	me.GET("/roster.ics", h.GetMyRoster)
+	me.POST("/roster/feed", h.CreateRosterFeed)
+	me.DELETE("/roster/feed", h.RevokeRosterFeed)
+}
+
+// RegisterPublicRoutes sets up endpoints authenticated by a secret in the URL instead of a JWT,
+// so calendar apps can subscribe to roster feeds.
+func RegisterPublicRoutes(rg *gin.RouterGroup, h *Handler) {
+	rg.GET("/crew/roster/:token", h.GetRosterFeed)
 }
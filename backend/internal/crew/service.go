@@ -1,30 +1,200 @@
 // Package crew contains business logic and service interfaces for crew assignment operations.
 package crew
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/nomenarkt/lamina/internal/audit"
+)
+
+var (
+	// ErrInvalidAssignment is returned when check-in/checkout times are missing or out of order.
+	ErrInvalidAssignment = errors.New("invalid crew assignment")
+	// ErrFlightNotFound is returned when the assigned flight does not exist.
+	ErrFlightNotFound = errors.New("flight not found")
+	// ErrAmbiguousFlight is returned when a flight reference matches more than one leg.
+	ErrAmbiguousFlight = errors.New("flight matches several legs; give the departure station")
+	// ErrIllegalAssignment is matched by LegalityError.
+	ErrIllegalAssignment = errors.New("assignment breaches flight time limitations")
+	// ErrOverrideReasonRequired is returned when an FTL override has no reason.
+	ErrOverrideReasonRequired = errors.New("override reason is required")
+	// ErrUnqualified is matched by QualificationError.
+	ErrUnqualified = errors.New("crew member lacks a valid qualification for this role")
+)
+
+// QualificationError lists the qualifications a crew member lacks for the assigned role.
+type QualificationError struct {
+	Missing []MissingQualification
+}
+
+func (e *QualificationError) Error() string {
+	codes := make([]string, 0, len(e.Missing))
+	for _, m := range e.Missing {
+		codes = append(codes, m.Code+" ("+m.Reason+")")
+	}
+	return fmt.Sprintf("%s: %s", ErrUnqualified, strings.Join(codes, ", "))
+}
+
+// Is lets errors.Is match a QualificationError against ErrUnqualified.
+func (e *QualificationError) Is(target error) bool {
+	return target == ErrUnqualified
+}
+
+// LegalityError lists the FTL rules an assignment would breach.
+type LegalityError struct {
+	RuleSet    string
+	Violations []Violation
+}
+
+func (e *LegalityError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s", ErrIllegalAssignment, strings.Join(rules, ", "))
+}
+
+// Is lets errors.Is match a LegalityError against ErrIllegalAssignment.
+func (e *LegalityError) Is(target error) bool {
+	return target == ErrIllegalAssignment
+}
 
 // ServiceInterface defines the operations for assigning and retrieving crew information.
 type ServiceInterface interface {
 	AssignCrew(ctx context.Context, assignment *Assignment) error
+	AssignCrewWithOverride(ctx context.Context, assignment *Assignment, reason string, supervisorID int64) (*FTLOverride, error)
 	GetCrewByFlight(ctx context.Context, flightID int64) ([]Assignment, error)
 	RemoveCrewByFlight(ctx context.Context, flightID int64) error
-	ResolveFlightID(ctx context.Context, flightNumber string) (int64, error)
+	ResolveFlightID(ctx context.Context, ref FlightRef) (int64, error)
 	GetDetailedCrewByFlight(ctx context.Context, flightID int64) ([]AssignmentDetail, error)
+	RosterCalendar(ctx context.Context, crewID int64) (string, error)
+	RosterCalendarByToken(ctx context.Context, token string) (string, error)
+	CreateRosterFeedToken(ctx context.Context, userID int64) (string, error)
+	RevokeRosterFeedToken(ctx context.Context, userID int64) error
 }
 
 // crewService implements the ServiceInterface using a data repository.
 type crewService struct {
-	repo Repository
+	repo  Repository
+	rules RuleSet
 }
 
-// NewService creates a new instance of ServiceInterface using the provided repository.
-func NewService(repo Repository) ServiceInterface {
-	return &crewService{repo: repo}
+// NewService creates a new instance of ServiceInterface using the provided repository
+// and flight time limitation rules.
+func NewService(repo Repository, rules RuleSet) ServiceInterface {
+	return &crewService{repo: repo, rules: rules}
 }
 
-// AssignCrew stores a new crew assignment in the database.
+// AssignCrew stores a new crew assignment after checking qualifications and the FTL rules.
+// Unqualified crew is rejected with a *QualificationError, illegal duties with a *LegalityError.
+// The checks and the insert hold a lock on the crew member, so concurrent assignments cannot
+// both pass against the same duties.
 func (s *crewService) AssignCrew(ctx context.Context, assignment *Assignment) error {
-	return s.repo.Create(ctx, assignment)
+	return s.repo.WithCrewLock(ctx, assignment.CrewID, func(repo Repository) error {
+		violations, err := s.checkLegality(ctx, repo, assignment)
+		if err != nil {
+			return err
+		}
+		if err := s.checkQualifications(ctx, repo, assignment); err != nil {
+			return err
+		}
+		if len(violations) > 0 {
+			return &LegalityError{RuleSet: s.rules.Code, Violations: violations}
+		}
+		return repo.Create(ctx, assignment)
+	})
+}
+
+// AssignCrewWithOverride stores an assignment even if it breaches the FTL rules, recording
+// the supervisor and reason. It returns the recorded override, or nil when the assignment was legal.
+// Missing qualifications cannot be overridden.
+func (s *crewService) AssignCrewWithOverride(ctx context.Context, assignment *Assignment, reason string, supervisorID int64) (*FTLOverride, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrOverrideReasonRequired
+	}
+
+	var override *FTLOverride
+	err := s.repo.WithCrewLock(ctx, assignment.CrewID, func(repo Repository) error {
+		violations, err := s.checkLegality(ctx, repo, assignment)
+		if err != nil {
+			return err
+		}
+		if err := s.checkQualifications(ctx, repo, assignment); err != nil {
+			return err
+		}
+		if len(violations) == 0 {
+			return repo.Create(ctx, assignment)
+		}
+
+		override = &FTLOverride{
+			CrewID:       assignment.CrewID,
+			RuleSet:      s.rules.Code,
+			Violations:   violations,
+			Reason:       reason,
+			OverriddenBy: supervisorID,
+		}
+		return repo.CreateWithOverride(ctx, assignment, override)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if override != nil {
+		log.Printf("⚠️ FTL override by user %d for crew %d on flight %d: %s", supervisorID, assignment.CrewID, assignment.FlightID, reason)
+	}
+	return override, nil
+}
+
+// checkQualifications requires valid qualifications on the flight date for crew in function.
+// Positioning (MEP) crew do not act in their role and are not checked.
+func (s *crewService) checkQualifications(ctx context.Context, repo Repository, a *Assignment) error {
+	if !a.InFunction {
+		return nil
+	}
+	missing, err := repo.ListMissingQualifications(ctx, a.CrewID, a.FlightID, a.CrewRole)
+	if err != nil {
+		return fmt.Errorf("failed to check qualifications: %w", err)
+	}
+	if len(missing) > 0 {
+		return &QualificationError{Missing: missing}
+	}
+	return nil
+}
+
+// checkLegality evaluates a new assignment against the crew member's surrounding duties.
+func (s *crewService) checkLegality(ctx context.Context, repo Repository, a *Assignment) ([]Violation, error) {
+	if a.CheckinTime.IsZero() || a.CheckoutTime.IsZero() || !a.CheckoutTime.After(a.CheckinTime) {
+		return nil, fmt.Errorf("%w: checkout_time must be after checkin_time", ErrInvalidAssignment)
+	}
+
+	blockOff, blockOn, err := repo.GetFlightBlockTimes(ctx, a.FlightID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFlightNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load flight: %w", err)
+	}
+
+	lookback := s.rules.Lookback()
+	existing, err := repo.ListDutySectors(ctx, a.CrewID, a.CheckinTime.Add(-lookback), a.CheckoutTime.Add(lookback))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load crew duties: %w", err)
+	}
+
+	return s.rules.Evaluate(existing, DutySector{
+		FlightID:     a.FlightID,
+		InFunction:   a.InFunction,
+		CheckinTime:  a.CheckinTime,
+		CheckoutTime: a.CheckoutTime,
+		BlockOff:     blockOff,
+		BlockOn:      blockOn,
+	}), nil
 }
 
 // GetCrewByFlight returns a list of crew assignments for a given flight ID.
@@ -33,13 +203,45 @@ func (s *crewService) GetCrewByFlight(ctx context.Context, flightID int64) ([]As
 }
 
 // RemoveCrewByFlight removes all crew assignments associated with a given flight ID.
+// The removed assignments are recorded in the audit log.
 func (s *crewService) RemoveCrewByFlight(ctx context.Context, flightID int64) error {
-	return s.repo.DeleteByFlightID(ctx, flightID)
+	before, err := s.repo.GetDetailedByFlightID(ctx, flightID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteByFlightID(ctx, flightID); err != nil {
+		return err
+	}
+	removed := make([]map[string]any, len(before))
+	for i, a := range before {
+		// The detail carries the crew member's name and email, which the audit log must not keep.
+		removed[i] = map[string]any{"id": a.ID, "crew_id": a.CrewID, "crew_role": a.CrewRole, "in_function": a.InFunction}
+	}
+	audit.Record(ctx, audit.Event{
+		Action:     "crew.unassign_flight",
+		TargetType: "flight",
+		TargetID:   strconv.FormatInt(flightID, 10),
+		Before:     removed,
+	})
+	return nil
 }
 
-// ResolveFlightID looks up the internal flight ID based on a flight number.
-func (s *crewService) ResolveFlightID(ctx context.Context, flightNumber string) (int64, error) {
-	return s.repo.GetFlightIDByNumber(ctx, flightNumber)
+// ResolveFlightID looks up the internal ID of the flight ref identifies. A flight number is
+// reused every operating day, so a reference matching several flights is rejected rather than
+// resolved to any one of them.
+func (s *crewService) ResolveFlightID(ctx context.Context, ref FlightRef) (int64, error) {
+	ids, err := s.repo.FindFlightIDs(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+	switch len(ids) {
+	case 0:
+		return 0, ErrFlightNotFound
+	case 1:
+		return ids[0], nil
+	default:
+		return 0, ErrAmbiguousFlight
+	}
 }
 
 // GetDetailedCrewByFlight retrieves detailed crew information for a specific flight.
@@ -2,8 +2,12 @@ package crew_test
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/nomenarkt/lamina/internal/audit"
 	"github.com/nomenarkt/lamina/internal/crew"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -12,6 +16,7 @@ import (
 // MockCrewRepo implements the crew.Repository interface
 type MockCrewRepo struct {
 	mock.Mock
+	locked []int // crew IDs passed to WithCrewLock
 }
 
 func (m *MockCrewRepo) Create(ctx context.Context, assignment *crew.Assignment) error {
@@ -29,9 +34,9 @@ func (m *MockCrewRepo) DeleteByFlightID(ctx context.Context, flightID int64) err
 	return args.Error(0)
 }
 
-func (m *MockCrewRepo) GetFlightIDByNumber(ctx context.Context, flightNumber string) (int64, error) {
-	args := m.Called(ctx, flightNumber)
-	return args.Get(0).(int64), args.Error(1)
+func (m *MockCrewRepo) FindFlightIDs(ctx context.Context, ref crew.FlightRef) ([]int64, error) {
+	args := m.Called(ctx, ref)
+	return args.Get(0).([]int64), args.Error(1)
 }
 
 func (m *MockCrewRepo) GetDetailedByFlightID(ctx context.Context, flightID int64) ([]crew.AssignmentDetail, error) {
@@ -39,28 +44,210 @@ func (m *MockCrewRepo) GetDetailedByFlightID(ctx context.Context, flightID int64
 	return args.Get(0).([]crew.AssignmentDetail), args.Error(1)
 }
 
+func (m *MockCrewRepo) GetFlightBlockTimes(ctx context.Context, flightID int64) (time.Time, time.Time, error) {
+	args := m.Called(ctx, flightID)
+	return args.Get(0).(time.Time), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *MockCrewRepo) ListDutySectors(ctx context.Context, crewID int, from, to time.Time) ([]crew.DutySector, error) {
+	args := m.Called(ctx, crewID, from, to)
+	return args.Get(0).([]crew.DutySector), args.Error(1)
+}
+
+func (m *MockCrewRepo) CreateWithOverride(ctx context.Context, ca *crew.Assignment, o *crew.FTLOverride) error {
+	args := m.Called(ctx, ca, o)
+	return args.Error(0)
+}
+
+func (m *MockCrewRepo) ListRosterEntries(ctx context.Context, crewID int64, since time.Time) ([]crew.RosterEntry, error) {
+	args := m.Called(ctx, crewID, since)
+	return args.Get(0).([]crew.RosterEntry), args.Error(1)
+}
+
+func (m *MockCrewRepo) SaveRosterFeedToken(ctx context.Context, userID int64, tokenHash string) error {
+	args := m.Called(ctx, userID, tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockCrewRepo) FindUserByRosterFeedToken(ctx context.Context, tokenHash string) (int64, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCrewRepo) DeleteRosterFeedToken(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockCrewRepo) WithCrewLock(_ context.Context, crewID int, fn func(repo crew.Repository) error) error {
+	m.locked = append(m.locked, crewID)
+	return fn(m)
+}
+
+func (m *MockCrewRepo) ListMissingQualifications(ctx context.Context, crewID int, flightID int64, role string) ([]crew.MissingQualification, error) {
+	args := m.Called(ctx, crewID, flightID, role)
+	return args.Get(0).([]crew.MissingQualification), args.Error(1)
+}
+
 func TestService_AssignCrew_Success(t *testing.T) {
 	repo := new(MockCrewRepo)
-	service := crew.NewService(repo)
+	service := crew.NewService(repo, crew.EASARuleSet())
 
+	checkin := time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC)
 	assignment := &crew.Assignment{
-		FlightID:   1001,
-		CrewID:     3199,
-		CrewRole:   "CDB",
-		InFunction: true,
+		FlightID:     1001,
+		CrewID:       3199,
+		CrewRole:     "CDB",
+		InFunction:   true,
+		CheckinTime:  checkin,
+		CheckoutTime: checkin.Add(3 * time.Hour),
 	}
 
+	repo.On("GetFlightBlockTimes", mock.Anything, int64(1001)).Return(checkin.Add(time.Hour), checkin.Add(2*time.Hour+15*time.Minute), nil)
+	repo.On("ListDutySectors", mock.Anything, 3199, mock.Anything, mock.Anything).Return([]crew.DutySector{}, nil)
+	repo.On("ListMissingQualifications", mock.Anything, 3199, int64(1001), "CDB").Return([]crew.MissingQualification{}, nil)
 	repo.On("Create", mock.Anything, assignment).Return(nil)
 
 	err := service.AssignCrew(context.Background(), assignment)
 
 	assert.NoError(t, err)
 	repo.AssertCalled(t, "Create", mock.Anything, assignment)
+	assert.Equal(t, []int{3199}, repo.locked)
+}
+
+// previousDuty is a 10h duty ending at 20:00 UTC on 2 March 2025.
+func previousDuty() crew.DutySector {
+	start := time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)
+	return crew.DutySector{
+		AssignmentID: 7, FlightID: 900, InFunction: true,
+		CheckinTime: start, CheckoutTime: start.Add(10 * time.Hour),
+		BlockOff: start.Add(time.Hour), BlockOn: start.Add(9 * time.Hour),
+	}
+}
+
+func TestService_AssignCrew_RejectsInsufficientRest(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	checkin := time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC) // 9h after the previous duty
+	assignment := &crew.Assignment{FlightID: 1001, CrewID: 3199, InFunction: true, CheckinTime: checkin, CheckoutTime: checkin.Add(3 * time.Hour)}
+
+	repo.On("GetFlightBlockTimes", mock.Anything, int64(1001)).Return(checkin.Add(time.Hour), checkin.Add(2*time.Hour), nil)
+	repo.On("ListDutySectors", mock.Anything, 3199, mock.Anything, mock.Anything).Return([]crew.DutySector{previousDuty()}, nil)
+	repo.On("ListMissingQualifications", mock.Anything, 3199, int64(1001), "").Return([]crew.MissingQualification{}, nil)
+
+	err := service.AssignCrew(context.Background(), assignment)
+
+	assert.ErrorIs(t, err, crew.ErrIllegalAssignment)
+	var illegal *crew.LegalityError
+	if assert.ErrorAs(t, err, &illegal) {
+		assert.Equal(t, "EASA", illegal.RuleSet)
+		assert.Len(t, illegal.Violations, 1)
+		assert.Equal(t, crew.RuleMinRest, illegal.Violations[0].Rule)
+		assert.Equal(t, 12*60, illegal.Violations[0].LimitMinutes)
+		assert.Equal(t, 9*60, illegal.Violations[0].ActualMinutes)
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_AssignCrewWithOverride_RecordsReason(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	checkin := time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC)
+	assignment := &crew.Assignment{FlightID: 1001, CrewID: 3199, InFunction: true, CheckinTime: checkin, CheckoutTime: checkin.Add(3 * time.Hour)}
+
+	repo.On("GetFlightBlockTimes", mock.Anything, int64(1001)).Return(checkin.Add(time.Hour), checkin.Add(2*time.Hour), nil)
+	repo.On("ListDutySectors", mock.Anything, 3199, mock.Anything, mock.Anything).Return([]crew.DutySector{previousDuty()}, nil)
+	repo.On("ListMissingQualifications", mock.Anything, 3199, int64(1001), "").Return([]crew.MissingQualification{}, nil)
+	repo.On("CreateWithOverride", mock.Anything, assignment, mock.MatchedBy(func(o *crew.FTLOverride) bool {
+		return o.Reason == "AOG recovery" && o.OverriddenBy == 42 && o.RuleSet == "EASA" && len(o.Violations) == 1
+	})).Return(nil)
+
+	override, err := service.AssignCrewWithOverride(context.Background(), assignment, " AOG recovery ", 42)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, override)
+	repo.AssertExpectations(t)
+}
+
+func TestService_AssignCrew_RejectsMissingQualification(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	checkin := time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC)
+	assignment := &crew.Assignment{FlightID: 1001, CrewID: 3199, CrewRole: "OPL", InFunction: true, CheckinTime: checkin, CheckoutTime: checkin.Add(3 * time.Hour)}
+	expired := time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)
+
+	repo.On("GetFlightBlockTimes", mock.Anything, int64(1001)).Return(checkin.Add(time.Hour), checkin.Add(2*time.Hour), nil)
+	repo.On("ListDutySectors", mock.Anything, 3199, mock.Anything, mock.Anything).Return([]crew.DutySector{}, nil)
+	repo.On("ListMissingQualifications", mock.Anything, 3199, int64(1001), "OPL").Return([]crew.MissingQualification{
+		{Code: "TR-AT7", Name: "ATR 72 type rating", Reason: "expired", ExpiresAt: &expired},
+	}, nil)
+
+	err := service.AssignCrew(context.Background(), assignment)
+
+	assert.ErrorIs(t, err, crew.ErrUnqualified)
+	var unqualified *crew.QualificationError
+	if assert.ErrorAs(t, err, &unqualified) {
+		assert.Equal(t, "TR-AT7", unqualified.Missing[0].Code)
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	// Missing qualifications cannot be overridden.
+	_, err = service.AssignCrewWithOverride(context.Background(), assignment, "AOG recovery", 42)
+	assert.ErrorIs(t, err, crew.ErrUnqualified)
+}
+
+func TestService_AssignCrew_RejectsUnknownAircraftType(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	checkin := time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC)
+	assignment := &crew.Assignment{FlightID: 1002, CrewID: 3199, CrewRole: "OPL", InFunction: true, CheckinTime: checkin, CheckoutTime: checkin.Add(3 * time.Hour)}
+
+	// Flight 1002 has no aircraft type, so its type ratings cannot be checked.
+	repo.On("GetFlightBlockTimes", mock.Anything, int64(1002)).Return(checkin.Add(time.Hour), checkin.Add(2*time.Hour), nil)
+	repo.On("ListDutySectors", mock.Anything, 3199, mock.Anything, mock.Anything).Return([]crew.DutySector{}, nil)
+	repo.On("ListMissingQualifications", mock.Anything, 3199, int64(1002), "OPL").Return([]crew.MissingQualification{
+		{Code: "TR-AT7", Name: "ATR 72 type rating", Reason: "aircraft_type_unknown"},
+	}, nil)
+
+	err := service.AssignCrew(context.Background(), assignment)
+
+	assert.ErrorIs(t, err, crew.ErrUnqualified)
+	assert.Contains(t, err.Error(), "TR-AT7 (aircraft_type_unknown)")
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_AssignCrew_PositioningSkipsQualifications(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	checkin := time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC)
+	assignment := &crew.Assignment{FlightID: 1001, CrewID: 3199, CrewRole: "OPL", InFunction: false, CheckinTime: checkin, CheckoutTime: checkin.Add(3 * time.Hour)}
+
+	repo.On("GetFlightBlockTimes", mock.Anything, int64(1001)).Return(checkin.Add(time.Hour), checkin.Add(2*time.Hour), nil)
+	repo.On("ListDutySectors", mock.Anything, 3199, mock.Anything, mock.Anything).Return([]crew.DutySector{}, nil)
+	repo.On("Create", mock.Anything, assignment).Return(nil)
+
+	assert.NoError(t, service.AssignCrew(context.Background(), assignment))
+	repo.AssertNotCalled(t, "ListMissingQualifications", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_AssignCrew_InvalidTimes(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	checkin := time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC)
+	err := service.AssignCrew(context.Background(), &crew.Assignment{FlightID: 1, CrewID: 1, CheckinTime: checkin, CheckoutTime: checkin})
+
+	assert.ErrorIs(t, err, crew.ErrInvalidAssignment)
 }
 
 func TestService_GetCrewByFlight_Success(t *testing.T) {
 	repo := new(MockCrewRepo)
-	service := crew.NewService(repo)
+	service := crew.NewService(repo, crew.EASARuleSet())
 
 	expected := []crew.Assignment{
 		{ID: 1, FlightID: 1001, CrewID: 3199, CrewRole: "CDB"},
@@ -77,38 +264,104 @@ func TestService_GetCrewByFlight_Success(t *testing.T) {
 
 func TestService_RemoveCrewByFlight_Success(t *testing.T) {
 	repo := new(MockCrewRepo)
-	service := crew.NewService(repo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	var events []audit.Event
+	audit.SetRecorder(recorderFunc(func(ev audit.Event) { events = append(events, ev) }))
+	t.Cleanup(func() { audit.SetRecorder(nil) })
 
+	repo.On("GetDetailedByFlightID", mock.Anything, int64(1001)).Return([]crew.AssignmentDetail{
+		{ID: 5, CrewID: 42, CrewRole: "CDB", InFunction: true, CrewName: "Rabe Hery", CrewEmail: "hery@example.com"},
+	}, nil)
 	repo.On("DeleteByFlightID", mock.Anything, int64(1001)).Return(nil)
 
 	err := service.RemoveCrewByFlight(context.Background(), 1001)
 
 	assert.NoError(t, err)
 	repo.AssertCalled(t, "DeleteByFlightID", mock.Anything, int64(1001))
+
+	// The audit log keeps the crew IDs, not their names and emails.
+	assert.Len(t, events, 1)
+	payload, err := json.Marshal(events[0].Before)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":5,"crew_id":42,"crew_role":"CDB","in_function":true}]`, string(payload))
+}
+
+type recorderFunc func(ev audit.Event)
+
+func (f recorderFunc) Record(_ context.Context, ev audit.Event) error {
+	f(ev)
+	return nil
 }
 
 func TestService_ResolveFlightID_Success(t *testing.T) {
 	repo := new(MockCrewRepo)
-	service := crew.NewService(repo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+	ref := crew.FlightRef{Number: "MD710", Date: "2025-03-03"}
 
-	repo.On("GetFlightIDByNumber", mock.Anything, "MD710").Return(int64(1), nil)
+	repo.On("FindFlightIDs", mock.Anything, ref).Return([]int64{1}, nil)
 
-	flightID, err := service.ResolveFlightID(context.Background(), "MD710")
+	flightID, err := service.ResolveFlightID(context.Background(), ref)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), flightID)
-	repo.AssertCalled(t, "GetFlightIDByNumber", mock.Anything, "MD710")
+	repo.AssertCalled(t, "FindFlightIDs", mock.Anything, ref)
 }
 
 func TestService_ResolveFlightID_NotFound(t *testing.T) {
 	repo := new(MockCrewRepo)
-	service := crew.NewService(repo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+	ref := crew.FlightRef{Number: "UNKNOWN", Date: "2025-03-03"}
 
-	repo.On("GetFlightIDByNumber", mock.Anything, "UNKNOWN").Return(int64(0), assert.AnError)
+	repo.On("FindFlightIDs", mock.Anything, ref).Return([]int64{}, nil)
 
-	flightID, err := service.ResolveFlightID(context.Background(), "UNKNOWN")
+	flightID, err := service.ResolveFlightID(context.Background(), ref)
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, crew.ErrFlightNotFound)
 	assert.Equal(t, int64(0), flightID)
-	repo.AssertCalled(t, "GetFlightIDByNumber", mock.Anything, "UNKNOWN")
+}
+
+func TestService_ResolveFlightID_RejectsAmbiguousMatch(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+	ref := crew.FlightRef{Number: "MD710", Date: "2025-03-03"}
+
+	// A multi-leg flight shares its number and date across legs.
+	repo.On("FindFlightIDs", mock.Anything, ref).Return([]int64{1, 2}, nil)
+
+	flightID, err := service.ResolveFlightID(context.Background(), ref)
+
+	assert.ErrorIs(t, err, crew.ErrAmbiguousFlight)
+	assert.Equal(t, int64(0), flightID)
+}
+
+func TestService_RosterFeedToken_RoundTrip(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	var storedHash string
+	repo.On("SaveRosterFeedToken", mock.Anything, int64(5), mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { storedHash = args.String(2) }).Return(nil)
+
+	token, err := service.CreateRosterFeedToken(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Len(t, token, 64)
+	assert.NotEqual(t, token, storedHash)
+
+	repo.On("FindUserByRosterFeedToken", mock.Anything, storedHash).Return(int64(5), nil)
+	repo.On("ListRosterEntries", mock.Anything, int64(5), mock.Anything).Return([]crew.RosterEntry{}, nil)
+
+	calendar, err := service.RosterCalendarByToken(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Contains(t, calendar, "BEGIN:VCALENDAR")
+}
+
+func TestService_RosterCalendarByToken_Unknown(t *testing.T) {
+	repo := new(MockCrewRepo)
+	service := crew.NewService(repo, crew.EASARuleSet())
+
+	repo.On("FindUserByRosterFeedToken", mock.Anything, mock.Anything).Return(int64(0), sql.ErrNoRows)
+
+	_, err := service.RosterCalendarByToken(context.Background(), "nope")
+	assert.ErrorIs(t, err, crew.ErrInvalidRosterToken)
 }
@@ -0,0 +1,228 @@
+// Package flight handles HTTP endpoints for the flight schedule.
+package flight
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize caps the size of an uploaded SSIM file.
+const maxImportSize = 10 << 20
+
+// Handler defines the HTTP handler for flight operations.
+type Handler struct {
+	service ServiceInterface
+}
+
+// NewHandler creates a new Handler instance for the flight service.
+func NewHandler(s ServiceInterface) *Handler {
+	return &Handler{service: s}
+}
+
+// CreateFlight schedules a new flight.
+// POST /flights
+func (h *Handler) CreateFlight(c *gin.Context) {
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+
+	f, err := h.service.CreateFlight(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, f)
+}
+
+// ListFlights lists flights filtered by date range, station, registration and status.
+// GET /flights?from=2025-01-01&to=2025-01-02&station=TNR&registration=5R-MJA
+func (h *Handler) ListFlights(c *gin.Context) {
+	var filter ListFilter
+
+	if v := c.Query("from"); v != "" {
+		t, err := parseDateOrTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' date"})
+			return
+		}
+		filter.From = &t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseDateOrTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' date"})
+			return
+		}
+		filter.To = &t
+	}
+	filter.Station = c.Query("station")
+	filter.Registration = c.Query("registration")
+	filter.Status = c.Query("status")
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	filter.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	flights, err := h.service.ListFlights(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, flights)
+}
+
+// GetFlight returns a single flight.
+// GET /flights/:id
+func (h *Handler) GetFlight(c *gin.Context) {
+	id, ok := flightIDParam(c)
+	if !ok {
+		return
+	}
+	f, err := h.service.GetFlight(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// UpdateFlight reschedules a flight.
+// PUT /flights/:id
+func (h *Handler) UpdateFlight(c *gin.Context) {
+	id, ok := flightIDParam(c)
+	if !ok {
+		return
+	}
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	f, err := h.service.UpdateSchedule(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// RecordActuals records actual out/in times, delay reason and load.
+// PATCH /flights/:id/actuals
+func (h *Handler) RecordActuals(c *gin.Context) {
+	id, ok := flightIDParam(c)
+	if !ok {
+		return
+	}
+	var req ActualsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	f, err := h.service.RecordActuals(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// CancelFlight cancels a flight.
+// POST /flights/:id/cancel
+func (h *Handler) CancelFlight(c *gin.Context) {
+	id, ok := flightIDParam(c)
+	if !ok {
+		return
+	}
+	var req CancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cancellation reason is required"})
+		return
+	}
+	f, err := h.service.CancelFlight(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// ImportSSIM imports a schedule from an SSIM file, sent either as multipart field "file" or as the raw body.
+// POST /flights/import?dry_run=true
+func (h *Handler) ImportSSIM(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			if isTooLarge(err) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "SSIM file is too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "SSIM file is required"})
+			return
+		}
+		file, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "SSIM file could not be read"})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	report, err := h.service.ImportSSIM(c.Request.Context(), body, dryRun)
+	if err != nil {
+		if isTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "SSIM file is too large"})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func isTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+func flightIDParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flight ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseDateOrTime accepts either a calendar date (YYYY-MM-DD, UTC midnight) or an RFC 3339 timestamp.
+func parseDateOrTime(v string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidFlight):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrFlightNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flight not found"})
+	case errors.Is(err, ErrFlightCancelled), errors.Is(err, ErrDuplicateFlight):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Flight request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
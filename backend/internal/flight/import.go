@@ -0,0 +1,212 @@
+package flight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ImportReport summarises an SSIM import. In dry-run mode nothing is written and
+// Created/Updated describe the diff against the existing schedule. Removed lists the
+// scheduled flights within the file's date range that it does not contain; an import
+// leaves them as they are, to be cancelled by hand if they no longer operate.
+type ImportReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Records   int               `json:"records"` // type 3 records accepted
+	Flights   int               `json:"flights"` // dated flights expanded from them
+	Created   []FlightChange    `json:"created"`
+	Updated   []FlightChange    `json:"updated"`
+	Removed   []FlightChange    `json:"removed"`
+	Unchanged int               `json:"unchanged"`
+	Rejected  []ImportRejection `json:"rejected"`
+}
+
+// FlightChange is one dated flight added, rescheduled or dropped by an import.
+type FlightChange struct {
+	FlightNumber       string        `json:"flight_number"`
+	FlightDate         string        `json:"flight_date"`
+	DepartureCode      string        `json:"departure_code"`
+	ArrivalCode        string        `json:"arrival_code"`
+	ScheduledDeparture time.Time     `json:"scheduled_departure"`
+	ScheduledArrival   time.Time     `json:"scheduled_arrival"`
+	Changes            []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is a single field that differs from the existing schedule.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type flightKey struct {
+	number    string
+	date      string
+	departure string
+}
+
+func keyOf(f Flight) flightKey {
+	return flightKey{number: f.FlightNumber, date: f.FlightDate.Format("2006-01-02"), departure: f.DepartureCode}
+}
+
+// ImportSSIM expands SSIM type 3 records into dated flights and upserts them on
+// flight number, flight date and departure station. With dryRun set it only reports the diff.
+// Flights the file drops are reported but never cancelled or deleted.
+func (s *flightService) ImportSSIM(ctx context.Context, r io.Reader, dryRun bool) (ImportReport, error) {
+	report := ImportReport{DryRun: dryRun, Created: []FlightChange{}, Updated: []FlightChange{}, Removed: []FlightChange{}}
+
+	legs, rejected, err := ParseSSIM(r)
+	if err != nil {
+		return report, err
+	}
+	report.Rejected = rejected
+
+	var (
+		incoming []Flight
+		seen     = map[flightKey]int{}
+	)
+	for _, leg := range legs {
+		flights, err := expandLeg(leg)
+		if err != nil {
+			report.Rejected = append(report.Rejected, ImportRejection{Line: leg.Line, Reason: err.Error()})
+			continue
+		}
+
+		duplicate := 0
+		for _, f := range flights {
+			if line, ok := seen[keyOf(f)]; ok {
+				duplicate = line
+				break
+			}
+		}
+		if duplicate != 0 {
+			report.Rejected = append(report.Rejected, ImportRejection{
+				Line:   leg.Line,
+				Reason: fmt.Sprintf("overlaps flights already defined on line %d", duplicate),
+			})
+			continue
+		}
+		for _, f := range flights {
+			seen[keyOf(f)] = leg.Line
+		}
+
+		report.Records++
+		incoming = append(incoming, flights...)
+	}
+	report.Flights = len(incoming)
+	if len(incoming) == 0 {
+		return report, nil
+	}
+
+	from, to := incoming[0].FlightDate, incoming[0].FlightDate
+	for _, f := range incoming {
+		if f.FlightDate.Before(from) {
+			from = f.FlightDate
+		}
+		if f.FlightDate.After(to) {
+			to = f.FlightDate
+		}
+	}
+	existing, err := s.repo.ListByFlightDates(ctx, from, to)
+	if err != nil {
+		return report, fmt.Errorf("failed to load existing schedule: %w", err)
+	}
+	current := make(map[flightKey]Flight, len(existing))
+	for _, f := range existing {
+		current[keyOf(f)] = f
+	}
+
+	var writes []Flight
+	for _, f := range incoming {
+		change := changeOf(f)
+		old, ok := current[keyOf(f)]
+		if !ok {
+			report.Created = append(report.Created, change)
+			writes = append(writes, f)
+			continue
+		}
+		change.Changes = diffSchedule(old, f)
+		if len(change.Changes) == 0 {
+			report.Unchanged++
+			continue
+		}
+		report.Updated = append(report.Updated, change)
+		writes = append(writes, f)
+	}
+	for _, f := range existing {
+		if _, ok := seen[keyOf(f)]; !ok && f.Status != StatusCancelled {
+			report.Removed = append(report.Removed, changeOf(f))
+		}
+	}
+
+	if dryRun || len(writes) == 0 {
+		return report, nil
+	}
+	if err := s.repo.UpsertSchedules(ctx, writes); err != nil {
+		return report, fmt.Errorf("failed to apply schedule: %w", err)
+	}
+	return report, nil
+}
+
+func changeOf(f Flight) FlightChange {
+	return FlightChange{
+		FlightNumber:       f.FlightNumber,
+		FlightDate:         f.FlightDate.Format("2006-01-02"),
+		DepartureCode:      f.DepartureCode,
+		ArrivalCode:        f.ArrivalCode,
+		ScheduledDeparture: f.ScheduledDeparture,
+		ScheduledArrival:   f.ScheduledArrival,
+	}
+}
+
+// expandLeg turns a leg record into validated dated flights.
+func expandLeg(leg SSIMLeg) ([]Flight, error) {
+	dates := leg.FlightDates()
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("no operating dates between %s and %s", leg.PeriodFrom.Format("2006-01-02"), leg.PeriodTo.Format("2006-01-02"))
+	}
+
+	flights := make([]Flight, 0, len(dates))
+	for _, date := range dates {
+		f := Flight{Status: StatusScheduled}
+		if err := applySchedule(&f, leg.Schedule(date)); err != nil {
+			return nil, err
+		}
+		f.FlightDate = date
+		flights = append(flights, f)
+	}
+	return flights, nil
+}
+
+func diffSchedule(old, updated Flight) []FieldChange {
+	var changes []FieldChange
+	if old.ArrivalCode != updated.ArrivalCode {
+		changes = append(changes, FieldChange{Field: "arrival_code", From: old.ArrivalCode, To: updated.ArrivalCode})
+	}
+	if from, to := stringOrEmpty(old.AircraftType), stringOrEmpty(updated.AircraftType); from != to {
+		changes = append(changes, FieldChange{Field: "aircraft_type", From: from, To: to})
+	}
+	if !old.ScheduledDeparture.Equal(updated.ScheduledDeparture) {
+		changes = append(changes, FieldChange{
+			Field: "scheduled_departure",
+			From:  old.ScheduledDeparture.UTC().Format(time.RFC3339),
+			To:    updated.ScheduledDeparture.UTC().Format(time.RFC3339),
+		})
+	}
+	if !old.ScheduledArrival.Equal(updated.ScheduledArrival) {
+		changes = append(changes, FieldChange{
+			Field: "scheduled_arrival",
+			From:  old.ScheduledArrival.UTC().Format(time.RFC3339),
+			To:    updated.ScheduledArrival.UTC().Format(time.RFC3339),
+		})
+	}
+	return changes
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
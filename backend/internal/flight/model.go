@@ -0,0 +1,69 @@
+// Package flight defines data structures for the flight schedule.
+package flight
+
+import "time"
+
+// Flight statuses.
+const (
+	StatusScheduled = "scheduled"
+	StatusCancelled = "cancelled"
+)
+
+// Flight is a single dated flight leg. Times are stored in UTC.
+type Flight struct {
+	ID                 int64      `db:"id" json:"id"`
+	FlightNumber       string     `db:"flight_number" json:"flight_number"`             // e.g. MD700
+	FlightDate         time.Time  `db:"flight_date" json:"flight_date"`                 // Operating date of the flight
+	DepartureCode      string     `db:"departure_code" json:"departure_code"`           // IATA station (e.g. TNR)
+	ArrivalCode        string     `db:"arrival_code" json:"arrival_code"`               // IATA station
+	ScheduledDeparture time.Time  `db:"scheduled_departure" json:"scheduled_departure"` // STD
+	ScheduledArrival   time.Time  `db:"scheduled_arrival" json:"scheduled_arrival"`     // STA
+	ActualDeparture    *time.Time `db:"actual_departure" json:"actual_departure"`       // Off-blocks (out)
+	ActualArrival      *time.Time `db:"actual_arrival" json:"actual_arrival"`           // On-blocks (in)
+	DelayReason        *string    `db:"delay_reason" json:"delay_reason"`
+	PlannedLoad        *int       `db:"planned_load" json:"planned_load"`
+	ActualLoad         *int       `db:"actual_load" json:"actual_load"`
+	Registration       *string    `db:"airplane_immatriculation" json:"registration"` // e.g. 5R-MJA
+	AircraftType       *string    `db:"aircraft_type" json:"aircraft_type"`           // IATA type code, e.g. AT7
+	Status             string     `db:"status" json:"status"`
+	CancelledAt        *time.Time `db:"cancelled_at" json:"cancelled_at,omitempty"`
+	CancelReason       *string    `db:"cancel_reason" json:"cancel_reason,omitempty"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// ScheduleRequest is the payload for creating or rescheduling a flight.
+type ScheduleRequest struct {
+	FlightNumber       string    `json:"flight_number" binding:"required"`
+	DepartureCode      string    `json:"departure_code" binding:"required"`
+	ArrivalCode        string    `json:"arrival_code" binding:"required"`
+	ScheduledDeparture time.Time `json:"scheduled_departure" binding:"required"`
+	ScheduledArrival   time.Time `json:"scheduled_arrival" binding:"required"`
+	PlannedLoad        *int      `json:"planned_load"`
+	Registration       *string   `json:"registration"`
+	AircraftType       *string   `json:"aircraft_type"`
+}
+
+// ActualsRequest records what actually happened on the day. Omitted fields are left unchanged.
+type ActualsRequest struct {
+	ActualDeparture *time.Time `json:"actual_departure"`
+	ActualArrival   *time.Time `json:"actual_arrival"`
+	DelayReason     *string    `json:"delay_reason"`
+	ActualLoad      *int       `json:"actual_load"`
+}
+
+// CancelRequest is the payload for cancelling a flight.
+type CancelRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ListFilter narrows the flight list. Zero values are ignored.
+type ListFilter struct {
+	From         *time.Time // scheduled departure at or after
+	To           *time.Time // scheduled departure before
+	Station      string     // departure or arrival station
+	Registration string
+	Status       string
+	Limit        int
+	Offset       int
+}
@@ -0,0 +1,201 @@
+// Package flight implements repository interfaces for the flight schedule.
+package flight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Repository defines the interface for interacting with flight storage.
+type Repository interface {
+	Create(ctx context.Context, f *Flight) error
+	GetByID(ctx context.Context, id int64) (Flight, error)
+	List(ctx context.Context, filter ListFilter) ([]Flight, error)
+	UpdateSchedule(ctx context.Context, f *Flight) error
+	ListByFlightDates(ctx context.Context, from, to time.Time) ([]Flight, error)
+	UpsertSchedules(ctx context.Context, flights []Flight) error
+	UpdateActuals(ctx context.Context, f *Flight) error
+	Cancel(ctx context.Context, id int64, reason string) error
+}
+
+// flightRepository is a concrete implementation of the Repository interface.
+type flightRepository struct {
+	db *sqlx.DB
+}
+
+// NewRepository returns a new instance of a flight Repository.
+func NewRepository(db *sqlx.DB) Repository {
+	return &flightRepository{db: db}
+}
+
+const flightColumns = `
+	id, flight_number, flight_date, departure_code, arrival_code,
+	scheduled_departure, scheduled_arrival, actual_departure, actual_arrival,
+	delay_reason, planned_load, actual_load, airplane_immatriculation, aircraft_type,
+	status, cancelled_at, cancel_reason, created_at, updated_at`
+
+// Create inserts a new scheduled flight and fills in its generated fields.
+func (r *flightRepository) Create(ctx context.Context, f *Flight) error {
+	query := `
+		INSERT INTO flights (flight_number, flight_date, departure_code, arrival_code, scheduled_departure,
+		                     scheduled_arrival, planned_load, airplane_immatriculation, aircraft_type)
+		VALUES (:flight_number, :flight_date, :departure_code, :arrival_code, :scheduled_departure,
+		        :scheduled_arrival, :planned_load, :airplane_immatriculation, :aircraft_type)
+		RETURNING ` + flightColumns
+	stmt, err := r.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+	return mapUniqueViolation(stmt.GetContext(ctx, f, f))
+}
+
+// GetByID returns a single flight.
+func (r *flightRepository) GetByID(ctx context.Context, id int64) (Flight, error) {
+	var f Flight
+	err := r.db.GetContext(ctx, &f, `SELECT `+flightColumns+` FROM flights WHERE id = $1`, id)
+	return f, err
+}
+
+// List returns flights matching the filter, ordered by scheduled departure.
+func (r *flightRepository) List(ctx context.Context, filter ListFilter) ([]Flight, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.From != nil {
+		add("scheduled_departure >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("scheduled_departure < $%d", *filter.To)
+	}
+	if filter.Station != "" {
+		args = append(args, filter.Station)
+		where = append(where, fmt.Sprintf("(departure_code = $%d OR arrival_code = $%d)", len(args), len(args)))
+	}
+	if filter.Registration != "" {
+		add("airplane_immatriculation = $%d", filter.Registration)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+
+	query := `SELECT ` + flightColumns + ` FROM flights`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY scheduled_departure ASC, id ASC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	flights := []Flight{}
+	err := r.db.SelectContext(ctx, &flights, query, args...)
+	return flights, err
+}
+
+// UpdateSchedule overwrites the planned fields of a flight.
+func (r *flightRepository) UpdateSchedule(ctx context.Context, f *Flight) error {
+	_, err := r.db.NamedExecContext(ctx, `
+		UPDATE flights
+		SET flight_number = :flight_number,
+		    flight_date = :flight_date,
+		    departure_code = :departure_code,
+		    arrival_code = :arrival_code,
+		    scheduled_departure = :scheduled_departure,
+		    scheduled_arrival = :scheduled_arrival,
+		    planned_load = :planned_load,
+		    airplane_immatriculation = :airplane_immatriculation,
+		    aircraft_type = :aircraft_type,
+		    updated_at = now()
+		WHERE id = :id
+	`, f)
+	return mapUniqueViolation(err)
+}
+
+// UpdateActuals stores actual out/in times, delay reason and load.
+func (r *flightRepository) UpdateActuals(ctx context.Context, f *Flight) error {
+	_, err := r.db.NamedExecContext(ctx, `
+		UPDATE flights
+		SET actual_departure = :actual_departure,
+		    actual_arrival = :actual_arrival,
+		    delay_reason = :delay_reason,
+		    actual_load = :actual_load,
+		    updated_at = now()
+		WHERE id = :id
+	`, f)
+	return err
+}
+
+// Cancel marks a flight as cancelled. Crew assignments are kept for the record.
+func (r *flightRepository) Cancel(ctx context.Context, id int64, reason string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE flights
+		SET status = 'cancelled', cancelled_at = now(), cancel_reason = $2, updated_at = now()
+		WHERE id = $1
+	`, id, reason)
+	return err
+}
+
+// ListByFlightDates returns every flight whose operating date falls within [from, to].
+func (r *flightRepository) ListByFlightDates(ctx context.Context, from, to time.Time) ([]Flight, error) {
+	flights := []Flight{}
+	err := r.db.SelectContext(ctx, &flights, `
+		SELECT `+flightColumns+`
+		FROM flights
+		WHERE flight_date BETWEEN $1 AND $2
+		ORDER BY flight_date, flight_number, scheduled_departure
+	`, from, to)
+	return flights, err
+}
+
+// UpsertSchedules inserts or reschedules flights keyed on flight number, flight date
+// and departure station, all in one transaction. Actuals and status are left untouched.
+func (r *flightRepository) UpsertSchedules(ctx context.Context, flights []Flight) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareNamedContext(ctx, `
+		INSERT INTO flights (flight_number, flight_date, departure_code, arrival_code,
+		                     scheduled_departure, scheduled_arrival, aircraft_type)
+		VALUES (:flight_number, :flight_date, :departure_code, :arrival_code,
+		        :scheduled_departure, :scheduled_arrival, :aircraft_type)
+		ON CONFLICT (flight_number, flight_date, departure_code) DO UPDATE
+		SET arrival_code = EXCLUDED.arrival_code,
+		    scheduled_departure = EXCLUDED.scheduled_departure,
+		    scheduled_arrival = EXCLUDED.scheduled_arrival,
+		    aircraft_type = EXCLUDED.aircraft_type,
+		    updated_at = now()
+	`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for i := range flights {
+		if _, err := stmt.ExecContext(ctx, &flights[i]); err != nil {
+			return fmt.Errorf("upsert %s on %s: %w", flights[i].FlightNumber, flights[i].FlightDate.Format("2006-01-02"), err)
+		}
+	}
+	return tx.Commit()
+}
+
+func mapUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicateFlight
+	}
+	return err
+}
@@ -0,0 +1,17 @@
+// Package flight defines route registration for the flight schedule APIs.
+package flight
+
+import "github.com/gin-gonic/gin"
+
+// RegisterRoutes sets up the HTTP endpoints for flight management under the given route group,
+// guarded by the optional scope middleware (e.g. access.CasbinMiddleware).
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, scope ...gin.HandlerFunc) {
+	flightGroup := rg.Group("/flights", scope...)
+	flightGroup.POST("", h.CreateFlight)
+	flightGroup.GET("", h.ListFlights)
+	flightGroup.POST("/import", h.ImportSSIM)
+	flightGroup.GET("/:id", h.GetFlight)
+	flightGroup.PUT("/:id", h.UpdateFlight)
+	flightGroup.PATCH("/:id/actuals", h.RecordActuals)
+	flightGroup.POST("/:id/cancel", h.CancelFlight)
+}
@@ -0,0 +1,268 @@
+// Package flight contains business logic for managing the flight schedule.
+package flight
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrFlightNotFound is returned when no flight matches the given ID.
+	ErrFlightNotFound = errors.New("flight not found")
+	// ErrFlightCancelled is returned when modifying a cancelled flight.
+	ErrFlightCancelled = errors.New("flight is cancelled")
+	// ErrDuplicateFlight is returned when the flight number already departs that station on that date.
+	ErrDuplicateFlight = errors.New("flight already exists for this date and departure station")
+	// ErrInvalidFlight wraps every validation failure so handlers can map it to 400.
+	ErrInvalidFlight = errors.New("invalid flight")
+)
+
+var (
+	flightNumberPattern = regexp.MustCompile(`^([A-Z][A-Z0-9]|[0-9][A-Z])[0-9]{1,4}[A-Z]?$`)
+	stationPattern      = regexp.MustCompile(`^[A-Z]{3}$`)
+	registrationPattern = regexp.MustCompile(`^[A-Z0-9]{1,2}-?[A-Z0-9]{1,5}$`)
+	aircraftTypePattern = regexp.MustCompile(`^[A-Z0-9]{3}$`)
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 500
+)
+
+// ServiceInterface defines the operations available on the flight schedule.
+type ServiceInterface interface {
+	CreateFlight(ctx context.Context, req ScheduleRequest) (Flight, error)
+	GetFlight(ctx context.Context, id int64) (Flight, error)
+	ListFlights(ctx context.Context, filter ListFilter) ([]Flight, error)
+	UpdateSchedule(ctx context.Context, id int64, req ScheduleRequest) (Flight, error)
+	RecordActuals(ctx context.Context, id int64, req ActualsRequest) (Flight, error)
+	CancelFlight(ctx context.Context, id int64, reason string) (Flight, error)
+	ImportSSIM(ctx context.Context, r io.Reader, dryRun bool) (ImportReport, error)
+}
+
+// flightService implements the ServiceInterface using a data repository.
+type flightService struct {
+	repo Repository
+}
+
+// NewService creates a new instance of ServiceInterface using the provided repository.
+func NewService(repo Repository) ServiceInterface {
+	return &flightService{repo: repo}
+}
+
+// CreateFlight validates and stores a new scheduled flight.
+func (s *flightService) CreateFlight(ctx context.Context, req ScheduleRequest) (Flight, error) {
+	f := Flight{Status: StatusScheduled}
+	if err := applySchedule(&f, req); err != nil {
+		return Flight{}, err
+	}
+	if err := s.repo.Create(ctx, &f); err != nil {
+		return Flight{}, err
+	}
+	return f, nil
+}
+
+// GetFlight returns a flight by ID.
+func (s *flightService) GetFlight(ctx context.Context, id int64) (Flight, error) {
+	f, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Flight{}, ErrFlightNotFound
+	}
+	return f, err
+}
+
+// ListFlights returns flights matching the filter. Station and registration are matched case-insensitively.
+func (s *flightService) ListFlights(ctx context.Context, filter ListFilter) ([]Flight, error) {
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return nil, fmt.Errorf("%w: 'to' must be after 'from'", ErrInvalidFlight)
+	}
+	if filter.Station != "" {
+		filter.Station = strings.ToUpper(strings.TrimSpace(filter.Station))
+		if !stationPattern.MatchString(filter.Station) {
+			return nil, fmt.Errorf("%w: station must be a 3-letter IATA code", ErrInvalidFlight)
+		}
+	}
+	if filter.Registration != "" {
+		filter.Registration = strings.ToUpper(strings.TrimSpace(filter.Registration))
+	}
+	if filter.Status != "" && filter.Status != StatusScheduled && filter.Status != StatusCancelled {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidFlight, filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.List(ctx, filter)
+}
+
+// UpdateSchedule replaces the planned fields of a flight that is not cancelled.
+func (s *flightService) UpdateSchedule(ctx context.Context, id int64, req ScheduleRequest) (Flight, error) {
+	f, err := s.GetFlight(ctx, id)
+	if err != nil {
+		return Flight{}, err
+	}
+	if f.Status == StatusCancelled {
+		return Flight{}, ErrFlightCancelled
+	}
+	previousDeparture, previousDate := f.ScheduledDeparture, f.FlightDate
+	if err := applySchedule(&f, req); err != nil {
+		return Flight{}, err
+	}
+	// Keep the offset between flight date and departure day (e.g. legs after midnight).
+	if !previousDate.IsZero() {
+		f.FlightDate = previousDate.AddDate(0, 0, daysBetween(previousDeparture, f.ScheduledDeparture))
+	}
+	if err := s.repo.UpdateSchedule(ctx, &f); err != nil {
+		return Flight{}, err
+	}
+	return f, nil
+}
+
+// RecordActuals stores actual out/in times, delay reason and load reported by ops.
+func (s *flightService) RecordActuals(ctx context.Context, id int64, req ActualsRequest) (Flight, error) {
+	f, err := s.GetFlight(ctx, id)
+	if err != nil {
+		return Flight{}, err
+	}
+	if f.Status == StatusCancelled {
+		return Flight{}, ErrFlightCancelled
+	}
+
+	if req.ActualDeparture != nil {
+		t := req.ActualDeparture.UTC()
+		f.ActualDeparture = &t
+	}
+	if req.ActualArrival != nil {
+		t := req.ActualArrival.UTC()
+		f.ActualArrival = &t
+	}
+	if req.DelayReason != nil {
+		reason := strings.TrimSpace(*req.DelayReason)
+		if reason == "" {
+			f.DelayReason = nil
+		} else {
+			f.DelayReason = &reason
+		}
+	}
+	if req.ActualLoad != nil {
+		if *req.ActualLoad < 0 {
+			return Flight{}, fmt.Errorf("%w: actual load cannot be negative", ErrInvalidFlight)
+		}
+		f.ActualLoad = req.ActualLoad
+	}
+
+	if f.ActualArrival != nil && f.ActualDeparture == nil {
+		return Flight{}, fmt.Errorf("%w: actual arrival requires an actual departure", ErrInvalidFlight)
+	}
+	if f.ActualArrival != nil && !f.ActualArrival.After(*f.ActualDeparture) {
+		return Flight{}, fmt.Errorf("%w: actual arrival must be after actual departure", ErrInvalidFlight)
+	}
+
+	if err := s.repo.UpdateActuals(ctx, &f); err != nil {
+		return Flight{}, err
+	}
+	return f, nil
+}
+
+// CancelFlight cancels a scheduled flight with a reason.
+func (s *flightService) CancelFlight(ctx context.Context, id int64, reason string) (Flight, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return Flight{}, fmt.Errorf("%w: cancellation reason is required", ErrInvalidFlight)
+	}
+	f, err := s.GetFlight(ctx, id)
+	if err != nil {
+		return Flight{}, err
+	}
+	if f.Status == StatusCancelled {
+		return Flight{}, ErrFlightCancelled
+	}
+	if err := s.repo.Cancel(ctx, id, reason); err != nil {
+		return Flight{}, err
+	}
+	return s.GetFlight(ctx, id)
+}
+
+// applySchedule validates a schedule request and copies it onto the flight.
+func applySchedule(f *Flight, req ScheduleRequest) error {
+	number := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(req.FlightNumber), " ", ""))
+	if !flightNumberPattern.MatchString(number) {
+		return fmt.Errorf("%w: flight number %q must be an airline designator followed by 1-4 digits", ErrInvalidFlight, req.FlightNumber)
+	}
+
+	dep := strings.ToUpper(strings.TrimSpace(req.DepartureCode))
+	arr := strings.ToUpper(strings.TrimSpace(req.ArrivalCode))
+	if !stationPattern.MatchString(dep) {
+		return fmt.Errorf("%w: departure code %q is not a 3-letter IATA code", ErrInvalidFlight, req.DepartureCode)
+	}
+	if !stationPattern.MatchString(arr) {
+		return fmt.Errorf("%w: arrival code %q is not a 3-letter IATA code", ErrInvalidFlight, req.ArrivalCode)
+	}
+	if dep == arr {
+		return fmt.Errorf("%w: departure and arrival stations must differ", ErrInvalidFlight)
+	}
+
+	std := req.ScheduledDeparture.UTC()
+	sta := req.ScheduledArrival.UTC()
+	if !sta.After(std) {
+		return fmt.Errorf("%w: scheduled arrival must be after scheduled departure", ErrInvalidFlight)
+	}
+	if sta.Sub(std) > 24*time.Hour {
+		return fmt.Errorf("%w: block time cannot exceed 24 hours", ErrInvalidFlight)
+	}
+
+	if req.PlannedLoad != nil && *req.PlannedLoad < 0 {
+		return fmt.Errorf("%w: planned load cannot be negative", ErrInvalidFlight)
+	}
+
+	var registration *string
+	if req.Registration != nil && strings.TrimSpace(*req.Registration) != "" {
+		reg := strings.ToUpper(strings.TrimSpace(*req.Registration))
+		if !registrationPattern.MatchString(reg) {
+			return fmt.Errorf("%w: registration %q is not valid", ErrInvalidFlight, *req.Registration)
+		}
+		registration = &reg
+	}
+
+	var aircraftType *string
+	if req.AircraftType != nil && strings.TrimSpace(*req.AircraftType) != "" {
+		t := strings.ToUpper(strings.TrimSpace(*req.AircraftType))
+		if !aircraftTypePattern.MatchString(t) {
+			return fmt.Errorf("%w: aircraft type %q is not a 3-character IATA code", ErrInvalidFlight, *req.AircraftType)
+		}
+		aircraftType = &t
+	}
+
+	f.FlightNumber = number
+	f.FlightDate = utcDate(std)
+	f.DepartureCode = dep
+	f.ArrivalCode = arr
+	f.ScheduledDeparture = std
+	f.ScheduledArrival = sta
+	f.PlannedLoad = req.PlannedLoad
+	f.Registration = registration
+	f.AircraftType = aircraftType
+	return nil
+}
+
+// daysBetween returns the number of UTC calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	return int(utcDate(b).Sub(utcDate(a)).Hours() / 24)
+}
+
+// utcDate truncates t to midnight of its UTC calendar day.
+func utcDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,280 @@
+package flight_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nomenarkt/lamina/internal/flight"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockFlightRepo implements the flight.Repository interface
+type MockFlightRepo struct {
+	mock.Mock
+}
+
+func (m *MockFlightRepo) Create(ctx context.Context, f *flight.Flight) error {
+	args := m.Called(ctx, f)
+	return args.Error(0)
+}
+
+func (m *MockFlightRepo) GetByID(ctx context.Context, id int64) (flight.Flight, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightRepo) List(ctx context.Context, filter flight.ListFilter) ([]flight.Flight, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightRepo) UpdateSchedule(ctx context.Context, f *flight.Flight) error {
+	args := m.Called(ctx, f)
+	return args.Error(0)
+}
+
+func (m *MockFlightRepo) UpdateActuals(ctx context.Context, f *flight.Flight) error {
+	args := m.Called(ctx, f)
+	return args.Error(0)
+}
+
+func (m *MockFlightRepo) Cancel(ctx context.Context, id int64, reason string) error {
+	args := m.Called(ctx, id, reason)
+	return args.Error(0)
+}
+
+func (m *MockFlightRepo) ListByFlightDates(ctx context.Context, from, to time.Time) ([]flight.Flight, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightRepo) UpsertSchedules(ctx context.Context, flights []flight.Flight) error {
+	args := m.Called(ctx, flights)
+	return args.Error(0)
+}
+
+func validSchedule() flight.ScheduleRequest {
+	std := time.Date(2025, 3, 1, 6, 0, 0, 0, time.UTC)
+	reg := "5r-mja"
+	return flight.ScheduleRequest{
+		FlightNumber:       "md 700",
+		DepartureCode:      "tnr",
+		ArrivalCode:        "NOS",
+		ScheduledDeparture: std,
+		ScheduledArrival:   std.Add(75 * time.Minute),
+		Registration:       &reg,
+	}
+}
+
+func TestService_CreateFlight_NormalizesCodes(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(f *flight.Flight) bool {
+		return f.FlightNumber == "MD700" && f.DepartureCode == "TNR" && f.ArrivalCode == "NOS" &&
+			*f.Registration == "5R-MJA" && f.Status == flight.StatusScheduled
+	})).Return(nil)
+
+	_, err := service.CreateFlight(context.Background(), validSchedule())
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestService_CreateFlight_Validation(t *testing.T) {
+	cases := map[string]func(r *flight.ScheduleRequest){
+		"non-IATA station":       func(r *flight.ScheduleRequest) { r.DepartureCode = "FMMI" },
+		"same stations":          func(r *flight.ScheduleRequest) { r.ArrivalCode = "TNR" },
+		"arrival before depart":  func(r *flight.ScheduleRequest) { r.ScheduledArrival = r.ScheduledDeparture.Add(-time.Minute) },
+		"arrival equals depart":  func(r *flight.ScheduleRequest) { r.ScheduledArrival = r.ScheduledDeparture },
+		"bad flight number":      func(r *flight.ScheduleRequest) { r.FlightNumber = "700" },
+		"negative planned load":  func(r *flight.ScheduleRequest) { n := -1; r.PlannedLoad = &n },
+		"malformed registration": func(r *flight.ScheduleRequest) { s := "5R MJA!"; r.Registration = &s },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			repo := new(MockFlightRepo)
+			service := flight.NewService(repo)
+
+			req := validSchedule()
+			mutate(&req)
+
+			_, err := service.CreateFlight(context.Background(), req)
+			assert.ErrorIs(t, err, flight.ErrInvalidFlight)
+			repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_GetFlight_NotFound(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	repo.On("GetByID", mock.Anything, int64(9)).Return(flight.Flight{}, sql.ErrNoRows)
+
+	_, err := service.GetFlight(context.Background(), 9)
+	assert.ErrorIs(t, err, flight.ErrFlightNotFound)
+}
+
+func TestService_ListFlights_NormalizesFilter(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	repo.On("List", mock.Anything, flight.ListFilter{Station: "TNR", Registration: "5R-MJA", Limit: 100}).
+		Return([]flight.Flight{{ID: 1}}, nil)
+
+	flights, err := service.ListFlights(context.Background(), flight.ListFilter{Station: "tnr", Registration: "5r-mja"})
+	assert.NoError(t, err)
+	assert.Len(t, flights, 1)
+}
+
+func TestService_RecordActuals(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	existing := flight.Flight{ID: 3, Status: flight.StatusScheduled}
+	out := time.Date(2025, 3, 1, 6, 20, 0, 0, time.UTC)
+	in := out.Add(80 * time.Minute)
+	reason := "  ATC slot "
+
+	repo.On("GetByID", mock.Anything, int64(3)).Return(existing, nil)
+	repo.On("UpdateActuals", mock.Anything, mock.MatchedBy(func(f *flight.Flight) bool {
+		return f.ActualDeparture.Equal(out) && f.ActualArrival.Equal(in) && *f.DelayReason == "ATC slot"
+	})).Return(nil)
+
+	_, err := service.RecordActuals(context.Background(), 3, flight.ActualsRequest{
+		ActualDeparture: &out,
+		ActualArrival:   &in,
+		DelayReason:     &reason,
+	})
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestService_RecordActuals_InBeforeOut(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	out := time.Date(2025, 3, 1, 6, 20, 0, 0, time.UTC)
+	in := out.Add(-time.Minute)
+	repo.On("GetByID", mock.Anything, int64(3)).Return(flight.Flight{ID: 3, Status: flight.StatusScheduled, ActualDeparture: &out}, nil)
+
+	_, err := service.RecordActuals(context.Background(), 3, flight.ActualsRequest{ActualArrival: &in})
+	assert.ErrorIs(t, err, flight.ErrInvalidFlight)
+	repo.AssertNotCalled(t, "UpdateActuals", mock.Anything, mock.Anything)
+}
+
+func TestService_CancelFlight_AlreadyCancelled(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	repo.On("GetByID", mock.Anything, int64(4)).Return(flight.Flight{ID: 4, Status: flight.StatusCancelled}, nil)
+
+	_, err := service.CancelFlight(context.Background(), 4, "weather")
+	assert.ErrorIs(t, err, flight.ErrFlightCancelled)
+	repo.AssertNotCalled(t, "Cancel", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_ImportSSIM_DryRunReportsDiff(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	input := carrierRecord('U', "31MAR25") + "\n" +
+		legRecord(" 700", "03MAR25", "04MAR25", "12     ", "TNR", "0600", "+0300", "NOS", "0715", "+0300")
+
+	day1 := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	atr72 := "AT7"
+	repo.On("ListByFlightDates", mock.Anything, day1, day2).Return([]flight.Flight{{
+		ID: 1, FlightNumber: "MD700", FlightDate: day1, DepartureCode: "TNR", ArrivalCode: "NOS", AircraftType: &atr72,
+		ScheduledDeparture: day1.Add(5 * time.Hour), ScheduledArrival: day1.Add(6*time.Hour + 15*time.Minute),
+	}}, nil)
+
+	report, err := service.ImportSSIM(context.Background(), strings.NewReader(input), true)
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Records)
+	assert.Equal(t, 2, report.Flights)
+	assert.Len(t, report.Created, 1)
+	assert.Equal(t, "2025-03-04", report.Created[0].FlightDate)
+	if assert.Len(t, report.Updated, 1) {
+		assert.Len(t, report.Updated[0].Changes, 2)
+		assert.Equal(t, "scheduled_departure", report.Updated[0].Changes[0].Field)
+	}
+	repo.AssertNotCalled(t, "UpsertSchedules", mock.Anything, mock.Anything)
+}
+
+func TestService_ImportSSIM_ReportsFlightsMissingFromFile(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	input := carrierRecord('U', "31MAR25") + "\n" +
+		legRecord(" 700", "03MAR25", "04MAR25", "12     ", "TNR", "0600", "+0300", "NOS", "0715", "+0300")
+
+	day1 := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	atr72 := "AT7"
+	repo.On("ListByFlightDates", mock.Anything, day1, day2).Return([]flight.Flight{
+		{
+			ID: 1, FlightNumber: "MD700", FlightDate: day1, DepartureCode: "TNR", ArrivalCode: "NOS", AircraftType: &atr72,
+			ScheduledDeparture: day1.Add(6 * time.Hour), ScheduledArrival: day1.Add(7*time.Hour + 15*time.Minute), Status: flight.StatusScheduled,
+		},
+		{
+			ID: 2, FlightNumber: "MD702", FlightDate: day2, DepartureCode: "TNR", ArrivalCode: "TMM",
+			ScheduledDeparture: day2.Add(9 * time.Hour), ScheduledArrival: day2.Add(10 * time.Hour), Status: flight.StatusScheduled,
+		},
+		{
+			ID: 3, FlightNumber: "MD704", FlightDate: day2, DepartureCode: "TNR", ArrivalCode: "DIE",
+			ScheduledDeparture: day2.Add(11 * time.Hour), ScheduledArrival: day2.Add(13 * time.Hour), Status: flight.StatusCancelled,
+		},
+	}, nil)
+	repo.On("UpsertSchedules", mock.Anything, mock.Anything).Return(nil)
+
+	report, err := service.ImportSSIM(context.Background(), strings.NewReader(input), false)
+	assert.NoError(t, err)
+	if assert.Len(t, report.Removed, 1) {
+		assert.Equal(t, "MD702", report.Removed[0].FlightNumber)
+		assert.Equal(t, "2025-03-04", report.Removed[0].FlightDate)
+	}
+	// Dropped flights are reported, not cancelled: only the new MD700 on 4 March is written.
+	repo.AssertCalled(t, "UpsertSchedules", mock.Anything, mock.MatchedBy(func(flights []flight.Flight) bool {
+		return len(flights) == 1 && flights[0].FlightNumber == "MD700" && flights[0].FlightDate.Equal(day2)
+	}))
+	repo.AssertNotCalled(t, "Cancel", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_ImportSSIM_UpsertsChangedFlights(t *testing.T) {
+	repo := new(MockFlightRepo)
+	service := flight.NewService(repo)
+
+	input := strings.Join([]string{
+		carrierRecord('U', "31MAR25"),
+		legRecord(" 700", "03MAR25", "04MAR25", "12     ", "TNR", "0600", "+0300", "NOS", "0715", "+0300"),
+		legRecord(" 700", "04MAR25", "04MAR25", "12     ", "TNR", "0800", "+0300", "NOS", "0915", "+0300"),
+	}, "\n")
+
+	day1 := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	atr72 := "AT7"
+	repo.On("ListByFlightDates", mock.Anything, day1, day2).Return([]flight.Flight{{
+		ID: 1, FlightNumber: "MD700", FlightDate: day1, DepartureCode: "TNR", ArrivalCode: "NOS", AircraftType: &atr72,
+		ScheduledDeparture: day1.Add(6 * time.Hour), ScheduledArrival: day1.Add(7*time.Hour + 15*time.Minute),
+	}}, nil)
+	repo.On("UpsertSchedules", mock.Anything, mock.MatchedBy(func(flights []flight.Flight) bool {
+		return len(flights) == 1 && flights[0].FlightDate.Equal(day2) && flights[0].Status == flight.StatusScheduled
+	})).Return(nil)
+
+	report, err := service.ImportSSIM(context.Background(), strings.NewReader(input), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Len(t, report.Created, 1)
+	if assert.Len(t, report.Rejected, 1) {
+		assert.Equal(t, 3, report.Rejected[0].Line)
+		assert.Contains(t, report.Rejected[0].Reason, "line 2")
+	}
+	repo.AssertExpectations(t)
+}
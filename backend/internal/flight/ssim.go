@@ -0,0 +1,283 @@
+package flight
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ssimRecordLength is the fixed width of every SSIM Chapter 7 record.
+const ssimRecordLength = 200
+
+// maxSSIMPeriodDays bounds how far a single record may be expanded.
+const maxSSIMPeriodDays = 400
+
+// SSIMLeg is a parsed SSIM type 3 (flight leg) record.
+type SSIMLeg struct {
+	Line              int
+	FlightNumber      string // airline designator + number + operational suffix, e.g. MD700
+	LegSequence       int
+	PeriodFrom        time.Time
+	PeriodTo          time.Time
+	Days              [7]bool // Monday..Sunday
+	Fortnightly       bool
+	DepartureStation  string
+	ArrivalStation    string
+	DepartureTime     time.Duration // aircraft STD, from midnight
+	ArrivalTime       time.Duration // aircraft STA, from midnight
+	DepartureOffset   time.Duration // UTC/local time variation
+	ArrivalOffset     time.Duration
+	DepartureDayShift int // date variation relative to the flight date
+	ArrivalDayShift   int
+	AircraftType      string
+	LocalTime         bool // times are local (carrier record time mode "L")
+}
+
+// ImportRejection describes an input line that could not be imported.
+type ImportRejection struct {
+	Line   int    `json:"line"`
+	Record string `json:"record"`
+	Reason string `json:"reason"`
+}
+
+// ParseSSIM reads SSIM type 2 and type 3 records. Header (1), segment (4), trailer (5)
+// and zero filler records are skipped; malformed records are returned as rejections.
+func ParseSSIM(r io.Reader) ([]SSIMLeg, []ImportRejection, error) {
+	var (
+		legs     []SSIMLeg
+		rejected []ImportRejection
+		local    bool
+		validTo  time.Time
+		lineNo   int
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 64*1024)
+	for scanner.Scan() {
+		lineNo++
+		raw := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		if len(raw) > ssimRecordLength {
+			rejected = append(rejected, ImportRejection{Line: lineNo, Record: raw, Reason: "record longer than 200 characters"})
+			continue
+		}
+		rec := raw + strings.Repeat(" ", ssimRecordLength-len(raw))
+
+		switch rec[0] {
+		case '0', '1', '4', '5':
+			continue
+		case '2':
+			switch rec[1] {
+			case 'U':
+				local = false
+			case 'L':
+				local = true
+			default:
+				rejected = append(rejected, ImportRejection{Line: lineNo, Record: raw, Reason: "carrier record time mode must be U or L"})
+				continue
+			}
+			validTo = time.Time{}
+			if to, open, err := parseSSIMDate(rec[21:28]); err == nil && !open {
+				validTo = to
+			}
+		case '3':
+			leg, err := parseSSIMLeg(rec, local, validTo)
+			if err != nil {
+				rejected = append(rejected, ImportRejection{Line: lineNo, Record: raw, Reason: err.Error()})
+				continue
+			}
+			leg.Line = lineNo
+			legs = append(legs, leg)
+		default:
+			rejected = append(rejected, ImportRejection{Line: lineNo, Record: raw, Reason: fmt.Sprintf("unknown record type %q", rec[0])})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read SSIM input: %w", err)
+	}
+	return legs, rejected, nil
+}
+
+func parseSSIMLeg(rec string, local bool, carrierValidTo time.Time) (SSIMLeg, error) {
+	leg := SSIMLeg{LocalTime: local}
+
+	airline := strings.TrimSpace(rec[2:5])
+	if airline == "" {
+		return leg, errors.New("missing airline designator")
+	}
+	number, err := strconv.Atoi(strings.TrimSpace(rec[5:9]))
+	if err != nil || number <= 0 {
+		return leg, fmt.Errorf("invalid flight number %q", rec[5:9])
+	}
+	leg.FlightNumber = airline + strconv.Itoa(number)
+	if suffix := rec[1]; suffix != ' ' {
+		leg.FlightNumber += string(suffix)
+	}
+	leg.LegSequence, _ = strconv.Atoi(strings.TrimSpace(rec[11:13]))
+
+	from, open, err := parseSSIMDate(rec[14:21])
+	if err != nil || open {
+		return leg, fmt.Errorf("invalid period start %q", rec[14:21])
+	}
+	to, open, err := parseSSIMDate(rec[21:28])
+	if err != nil {
+		return leg, fmt.Errorf("invalid period end %q", rec[21:28])
+	}
+	if open {
+		if carrierValidTo.IsZero() {
+			return leg, errors.New("open-ended period without a carrier validity end date")
+		}
+		to = carrierValidTo
+	}
+	if to.Before(from) {
+		return leg, errors.New("period end is before period start")
+	}
+	if to.Sub(from) > maxSSIMPeriodDays*24*time.Hour {
+		return leg, fmt.Errorf("period of operation exceeds %d days", maxSSIMPeriodDays)
+	}
+	leg.PeriodFrom, leg.PeriodTo = from, to
+
+	operating := false
+	for i := 0; i < 7; i++ {
+		switch c := rec[28+i]; c {
+		case ' ':
+		case byte('1' + i):
+			leg.Days[i] = true
+			operating = true
+		default:
+			return leg, fmt.Errorf("invalid days of operation %q", rec[28:35])
+		}
+	}
+	if !operating {
+		return leg, errors.New("no days of operation")
+	}
+
+	switch rec[35] {
+	case ' ', '1':
+	case '2':
+		leg.Fortnightly = true
+	default:
+		return leg, fmt.Errorf("unsupported frequency rate %q", rec[35])
+	}
+
+	leg.DepartureStation = strings.TrimSpace(rec[36:39])
+	leg.ArrivalStation = strings.TrimSpace(rec[54:57])
+
+	if leg.DepartureTime, err = parseSSIMTime(rec[43:47]); err != nil {
+		return leg, fmt.Errorf("invalid departure time %q", rec[43:47])
+	}
+	if leg.ArrivalTime, err = parseSSIMTime(rec[57:61]); err != nil {
+		return leg, fmt.Errorf("invalid arrival time %q", rec[57:61])
+	}
+	if leg.DepartureOffset, err = parseSSIMOffset(rec[47:52]); err != nil {
+		return leg, fmt.Errorf("invalid departure UTC variation %q", rec[47:52])
+	}
+	if leg.ArrivalOffset, err = parseSSIMOffset(rec[65:70]); err != nil {
+		return leg, fmt.Errorf("invalid arrival UTC variation %q", rec[65:70])
+	}
+	leg.AircraftType = strings.TrimSpace(rec[72:75])
+
+	if leg.DepartureDayShift, err = parseSSIMDateVariation(rec[192]); err != nil {
+		return leg, err
+	}
+	if leg.ArrivalDayShift, err = parseSSIMDateVariation(rec[193]); err != nil {
+		return leg, err
+	}
+
+	return leg, nil
+}
+
+// FlightDates returns every flight date on which the leg operates.
+func (l SSIMLeg) FlightDates() []time.Time {
+	var dates []time.Time
+	for d := l.PeriodFrom; !d.After(l.PeriodTo); d = d.AddDate(0, 0, 1) {
+		weekday := (int(d.Weekday()) + 6) % 7 // Monday = 0
+		if !l.Days[weekday] {
+			continue
+		}
+		if l.Fortnightly && (int(d.Sub(l.PeriodFrom).Hours()/24)/7)%2 != 0 {
+			continue
+		}
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// Schedule returns the schedule request for the leg on a given flight date, in UTC.
+func (l SSIMLeg) Schedule(flightDate time.Time) ScheduleRequest {
+	std := flightDate.AddDate(0, 0, l.DepartureDayShift).Add(l.DepartureTime)
+	sta := flightDate.AddDate(0, 0, l.ArrivalDayShift).Add(l.ArrivalTime)
+	if l.LocalTime {
+		std = std.Add(-l.DepartureOffset)
+		sta = sta.Add(-l.ArrivalOffset)
+	}
+	req := ScheduleRequest{
+		FlightNumber:       l.FlightNumber,
+		DepartureCode:      l.DepartureStation,
+		ArrivalCode:        l.ArrivalStation,
+		ScheduledDeparture: std,
+		ScheduledArrival:   sta,
+	}
+	if l.AircraftType != "" {
+		aircraftType := l.AircraftType
+		req.AircraftType = &aircraftType
+	}
+	return req
+}
+
+// parseSSIMDate parses DDMMMYY. "00XXX00" denotes an open-ended period.
+func parseSSIMDate(v string) (time.Time, bool, error) {
+	if v == "00XXX00" {
+		return time.Time{}, true, nil
+	}
+	t, err := time.Parse("02Jan06", v)
+	return t, false, err
+}
+
+func parseSSIMTime(v string) (time.Duration, error) {
+	if len(v) != 4 {
+		return 0, errors.New("time must be HHMM")
+	}
+	h, err1 := strconv.Atoi(v[:2])
+	m, err2 := strconv.Atoi(v[2:])
+	if err1 != nil || err2 != nil || h > 23 || m > 59 || h < 0 || m < 0 {
+		return 0, errors.New("time must be HHMM")
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func parseSSIMOffset(v string) (time.Duration, error) {
+	if strings.TrimSpace(v) == "" {
+		return 0, nil
+	}
+	if len(v) != 5 || (v[0] != '+' && v[0] != '-') {
+		return 0, errors.New("variation must be ±HHMM")
+	}
+	d, err := parseSSIMTime(v[1:])
+	if err != nil {
+		return 0, err
+	}
+	if v[0] == '-' {
+		d = -d
+	}
+	return d, nil
+}
+
+func parseSSIMDateVariation(c byte) (int, error) {
+	switch {
+	case c == ' ':
+		return 0, nil
+	case c == 'A':
+		return -1, nil
+	case c >= '0' && c <= '9':
+		return int(c - '0'), nil
+	default:
+		return 0, fmt.Errorf("invalid date variation %q", c)
+	}
+}
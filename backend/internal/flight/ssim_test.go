@@ -0,0 +1,95 @@
+package flight_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nomenarkt/lamina/internal/flight"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ssimRecord lays out fields at their SSIM column offsets (0-based) in a 200-character record.
+func ssimRecord(fields map[int]string) string {
+	rec := []byte(strings.Repeat(" ", 200))
+	for pos, v := range fields {
+		copy(rec[pos:], v)
+	}
+	return string(rec)
+}
+
+func carrierRecord(mode byte, validTo string) string {
+	return ssimRecord(map[int]string{0: "2", 1: string(mode), 2: "MD ", 14: "01MAR25", 21: validTo})
+}
+
+func legRecord(number, from, to, days, dep, std, depVar, arr, sta, arrVar string) string {
+	return ssimRecord(map[int]string{
+		0: "3", 2: "MD ", 5: number, 9: "01", 11: "01", 13: "J",
+		14: from, 21: to, 28: days,
+		36: dep, 39: std, 43: std, 47: depVar,
+		54: arr, 57: sta, 61: sta, 65: arrVar,
+		72: "AT7",
+	})
+}
+
+func TestParseSSIM_ExpandsDaysOfOperation(t *testing.T) {
+	input := strings.Join([]string{
+		ssimRecord(map[int]string{0: "1", 1: "AIRLINE STANDARD SCHEDULE DATA SET"}),
+		carrierRecord('U', "31MAR25"),
+		legRecord(" 700", "03MAR25", "16MAR25", "1 3    ", "TNR", "0600", "+0300", "NOS", "0715", "+0300"),
+		ssimRecord(map[int]string{0: "5"}),
+	}, "\n")
+
+	legs, rejected, err := flight.ParseSSIM(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Empty(t, rejected)
+	require.Len(t, legs, 1)
+
+	leg := legs[0]
+	assert.Equal(t, "MD700", leg.FlightNumber)
+	assert.Equal(t, 3, leg.Line)
+
+	dates := leg.FlightDates()
+	require.Len(t, dates, 4) // Mondays and Wednesdays in the first two weeks of March 2025
+	assert.Equal(t, time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC), dates[0])
+	assert.Equal(t, time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC), dates[3])
+
+	sched := leg.Schedule(dates[0])
+	assert.Equal(t, time.Date(2025, 3, 3, 6, 0, 0, 0, time.UTC), sched.ScheduledDeparture)
+	assert.Equal(t, time.Date(2025, 3, 3, 7, 15, 0, 0, time.UTC), sched.ScheduledArrival)
+}
+
+func TestParseSSIM_LocalTimesAndOpenPeriod(t *testing.T) {
+	input := carrierRecord('L', "10MAR25") + "\n" +
+		legRecord(" 701", "03MAR25", "00XXX00", "1234567", "NOS", "0900", "+0300", "TNR", "1015", "+0300")
+
+	legs, rejected, err := flight.ParseSSIM(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Empty(t, rejected)
+	require.Len(t, legs, 1)
+
+	assert.Len(t, legs[0].FlightDates(), 8)
+	sched := legs[0].Schedule(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 3, 3, 6, 0, 0, 0, time.UTC), sched.ScheduledDeparture)
+}
+
+func TestParseSSIM_RejectsMalformedRecords(t *testing.T) {
+	input := strings.Join([]string{
+		carrierRecord('U', "31MAR25"),
+		legRecord(" 700", "03MAR25", "16MAR25", "1 3    ", "TNR", "2600", "+0300", "NOS", "0715", "+0300"),
+		legRecord(" 702", "16MAR25", "03MAR25", "1234567", "TNR", "0600", "+0300", "NOS", "0715", "+0300"),
+		legRecord(" 703", "03MAR25", "16MAR25", "9      ", "TNR", "0600", "+0300", "NOS", "0715", "+0300"),
+		"X unknown",
+	}, "\n")
+
+	legs, rejected, err := flight.ParseSSIM(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Empty(t, legs)
+	require.Len(t, rejected, 4)
+	assert.Equal(t, 2, rejected[0].Line)
+	assert.Contains(t, rejected[0].Reason, "departure time")
+	assert.Contains(t, rejected[1].Reason, "before period start")
+	assert.Contains(t, rejected[2].Reason, "days of operation")
+	assert.Contains(t, rejected[3].Reason, "unknown record type")
+}
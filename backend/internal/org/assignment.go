@@ -0,0 +1,175 @@
+package org
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/nomenarkt/lamina/internal/audit"
+)
+
+var (
+	// ErrInvalidAssignment is wrapped by user function validation errors.
+	ErrInvalidAssignment = errors.New("invalid function assignment")
+	// ErrAssignmentNotFound is returned when revoking a function the user does not hold in the unit.
+	ErrAssignmentNotFound = errors.New("user does not hold this function in the unit")
+)
+
+// AssignFunction grants a function to a user in a unit, or changes the rank of an existing
+// grant. The user_functions row and the Casbin grouping rule are written together.
+func (s *orgService) AssignFunction(ctx context.Context, req AssignFunctionRequest) (UserFunctionDetail, error) {
+	name := strings.ToLower(strings.TrimSpace(req.Function))
+	f, err := s.repo.GetFunctionByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserFunctionDetail{}, fmt.Errorf("%w: unknown function %q", ErrInvalidAssignment, name)
+	}
+	if err != nil {
+		return UserFunctionDetail{}, err
+	}
+	if _, err := s.repo.GetUnit(ctx, req.UnitID); errors.Is(err, sql.ErrNoRows) {
+		return UserFunctionDetail{}, fmt.Errorf("%w: organizational unit %d does not exist", ErrInvalidAssignment, req.UnitID)
+	} else if err != nil {
+		return UserFunctionDetail{}, err
+	}
+	if req.RankID != nil {
+		r, err := s.repo.GetRank(ctx, *req.RankID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && r.FunctionID != f.ID) {
+			return UserFunctionDetail{}, fmt.Errorf("%w: rank %d is not a rank of %s", ErrInvalidAssignment, *req.RankID, f.Name)
+		}
+		if err != nil {
+			return UserFunctionDetail{}, err
+		}
+	}
+
+	uf := UserFunction{UserID: req.UserID, FunctionID: f.ID, UnitID: req.UnitID, RankID: req.RankID}
+	var before any
+	if prev, err := s.repo.GetUserFunction(ctx, uf.UserID, uf.FunctionID, uf.UnitID); err == nil {
+		before = prev.UserFunction
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return UserFunctionDetail{}, err
+	}
+	if err := s.repo.AssignUserFunction(ctx, uf, f.Name); err != nil {
+		return UserFunctionDetail{}, err
+	}
+	if err := s.rules.LinkRole(uf.UserID, f.Name, uf.UnitID); err != nil {
+		// The rule is stored; the enforcer picks it up on its next reload.
+		log.Printf("⚠️ Failed to load role %s of user %d in unit %d: %v", f.Name, uf.UserID, uf.UnitID, err)
+	}
+	detail, err := s.repo.GetUserFunction(ctx, uf.UserID, uf.FunctionID, uf.UnitID)
+	if err != nil {
+		return UserFunctionDetail{}, err
+	}
+	audit.Record(ctx, audit.Event{
+		Action:     "user_function.assign",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(uf.UserID),
+		Before:     before,
+		After:      detail.UserFunction, // IDs only; the detail names the user
+	})
+	return detail, nil
+}
+
+// RevokeFunction withdraws a function from a user in a unit, removing both the
+// user_functions row and the Casbin grouping rule.
+func (s *orgService) RevokeFunction(ctx context.Context, req RevokeFunctionRequest) error {
+	name := strings.ToLower(strings.TrimSpace(req.Function))
+	f, err := s.repo.GetFunctionByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAssignmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	uf := UserFunction{UserID: req.UserID, FunctionID: f.ID, UnitID: req.UnitID}
+	removed, err := s.repo.RevokeUserFunction(ctx, uf, f.Name)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrAssignmentNotFound
+	}
+	if err := s.rules.UnlinkRole(uf.UserID, f.Name, uf.UnitID); err != nil {
+		log.Printf("⚠️ Failed to unload role %s of user %d in unit %d: %v", f.Name, uf.UserID, uf.UnitID, err)
+	}
+	audit.Record(ctx, audit.Event{
+		Action:     "user_function.revoke",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(uf.UserID),
+		Before:     map[string]any{"function": f.Name, "unit_id": uf.UnitID},
+	})
+	return nil
+}
+
+// ListUserFunctions returns the functions a user holds across units.
+func (s *orgService) ListUserFunctions(ctx context.Context, userID int) ([]UserFunctionDetail, error) {
+	return s.repo.ListUserFunctions(ctx, userID)
+}
+
+// ListFunctionMembers returns the users holding a function in a unit.
+func (s *orgService) ListFunctionMembers(ctx context.Context, functionID, unitID int) ([]UserFunctionDetail, error) {
+	if _, err := s.GetFunction(ctx, functionID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getUnit(ctx, unitID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListFunctionMembers(ctx, functionID, unitID)
+}
+
+// Reconcile compares user_functions with the Casbin grouping rules that assign functions to
+// users in units, and unless dryRun is set converges the two: user functions missing a rule
+// get one, rules for an existing user, function and unit get a user function, and rules
+// naming anything that no longer exists are deleted. Other grouping rules are left alone.
+func (s *orgService) Reconcile(ctx context.Context, dryRun bool) (ReconcileReport, error) {
+	report := ReconcileReport{
+		DryRun:            dryRun,
+		AddedToCasbin:     []UserFunctionDetail{},
+		AddedToFunctions:  []RoleLink{},
+		RemovedFromCasbin: []RoleLink{},
+	}
+	grants, err := s.repo.ListAllUserFunctions(ctx)
+	if err != nil {
+		return report, err
+	}
+	links, err := s.repo.ListRoleLinks(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	linked := make(map[[3]int]bool, len(links))
+	for _, l := range links {
+		if l.Resolvable() {
+			linked[[3]int{*l.UserID, *l.FunctionID, *l.UnitID}] = true
+		}
+	}
+	granted := make(map[[3]int]bool, len(grants))
+	for _, g := range grants {
+		key := [3]int{g.UserID, g.FunctionID, g.UnitID}
+		granted[key] = true
+		if !linked[key] {
+			report.AddedToCasbin = append(report.AddedToCasbin, g)
+		}
+	}
+	for _, l := range links {
+		switch {
+		case !l.Resolvable():
+			report.RemovedFromCasbin = append(report.RemovedFromCasbin, l)
+		case !granted[[3]int{*l.UserID, *l.FunctionID, *l.UnitID}]:
+			report.AddedToFunctions = append(report.AddedToFunctions, l)
+		}
+	}
+
+	if dryRun || len(report.AddedToCasbin)+len(report.AddedToFunctions)+len(report.RemovedFromCasbin) == 0 {
+		return report, nil
+	}
+	if err := s.repo.ApplyReconciliation(ctx, report); err != nil {
+		return report, err
+	}
+	audit.Record(ctx, audit.Event{Action: "user_function.reconcile", TargetType: "casbin_rule", After: report})
+	return report, s.rules.ReloadPolicies()
+}
@@ -0,0 +1,142 @@
+package org_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nomenarkt/lamina/internal/audit"
+	"github.com/nomenarkt/lamina/internal/org"
+)
+
+type recorderFunc func(ev audit.Event)
+
+func (f recorderFunc) Record(_ context.Context, ev audit.Event) error {
+	f(ev)
+	return nil
+}
+
+func newRecordingRules() (stubRules, *[]string, *int) {
+	links, reloads := []string{}, 0
+	return stubRules{links: &links, reloads: &reloads}, &links, &reloads
+}
+
+func TestService_AssignFunction_WritesUserFunctionAndRole(t *testing.T) {
+	repo := new(MockOrgRepo)
+	rules, links, _ := newRecordingRules()
+	service := org.NewService(repo, rules)
+	var events []audit.Event
+	audit.SetRecorder(recorderFunc(func(ev audit.Event) { events = append(events, ev) }))
+	t.Cleanup(func() { audit.SetRecorder(nil) })
+
+	uf := org.UserFunction{UserID: 7, FunctionID: 2, UnitID: 3, RankID: intPtr(11)}
+	repo.On("GetFunctionByName", mock.Anything, "planner").Return(org.Function{ID: 2, Name: "planner"}, nil)
+	repo.On("GetUnit", mock.Anything, 3).Return(org.OrganizationalUnit{ID: 3}, nil)
+	repo.On("GetRank", mock.Anything, 11).Return(org.Rank{ID: 11, FunctionID: 2}, nil)
+	repo.On("AssignUserFunction", mock.Anything, uf, "planner").Return(nil)
+	repo.On("GetUserFunction", mock.Anything, 7, 2, 3).Return(org.UserFunctionDetail{
+		UserFunction: uf, UserName: strPtr("Rabe Hery"), UserEmail: "hery@example.com", FunctionName: "planner",
+	}, nil)
+
+	got, err := service.AssignFunction(context.Background(), org.AssignFunctionRequest{UserID: 7, Function: " Planner ", UnitID: 3, RankID: intPtr(11)})
+	require.NoError(t, err)
+	assert.Equal(t, "planner", got.FunctionName)
+	assert.Equal(t, []string{"+7:planner:3"}, *links)
+	repo.AssertExpectations(t)
+
+	// The audit log outlives erasure, so it keeps IDs rather than the user's name and email.
+	require.Len(t, events, 1)
+	payload, err := json.Marshal(events[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(payload), "Rabe Hery")
+	assert.NotContains(t, string(payload), "hery@example.com")
+	assert.Equal(t, uf, events[0].After)
+}
+
+func TestService_AssignFunction_RejectsForeignRankAndUnknownFunction(t *testing.T) {
+	repo := new(MockOrgRepo)
+	rules, links, _ := newRecordingRules()
+	service := org.NewService(repo, rules)
+
+	repo.On("GetFunctionByName", mock.Anything, "planner").Return(org.Function{ID: 2, Name: "planner"}, nil)
+	repo.On("GetFunctionByName", mock.Anything, "ghost").Return(org.Function{}, sql.ErrNoRows)
+	repo.On("GetUnit", mock.Anything, 3).Return(org.OrganizationalUnit{ID: 3}, nil)
+	repo.On("GetRank", mock.Anything, 12).Return(org.Rank{ID: 12, FunctionID: 5}, nil)
+
+	_, err := service.AssignFunction(context.Background(), org.AssignFunctionRequest{UserID: 7, Function: "planner", UnitID: 3, RankID: intPtr(12)})
+	assert.ErrorIs(t, err, org.ErrInvalidAssignment)
+
+	_, err = service.AssignFunction(context.Background(), org.AssignFunctionRequest{UserID: 7, Function: "ghost", UnitID: 3})
+	assert.ErrorIs(t, err, org.ErrInvalidAssignment)
+
+	repo.AssertNotCalled(t, "AssignUserFunction", mock.Anything, mock.Anything, mock.Anything)
+	assert.Empty(t, *links)
+}
+
+func TestService_RevokeFunction(t *testing.T) {
+	repo := new(MockOrgRepo)
+	rules, links, _ := newRecordingRules()
+	service := org.NewService(repo, rules)
+
+	repo.On("GetFunctionByName", mock.Anything, "planner").Return(org.Function{ID: 2, Name: "planner"}, nil)
+	repo.On("RevokeUserFunction", mock.Anything, org.UserFunction{UserID: 7, FunctionID: 2, UnitID: 3}, "planner").Return(true, nil)
+	repo.On("RevokeUserFunction", mock.Anything, org.UserFunction{UserID: 7, FunctionID: 2, UnitID: 4}, "planner").Return(false, nil)
+
+	require.NoError(t, service.RevokeFunction(context.Background(), org.RevokeFunctionRequest{UserID: 7, Function: "planner", UnitID: 3}))
+	assert.Equal(t, []string{"-7:planner:3"}, *links)
+
+	err := service.RevokeFunction(context.Background(), org.RevokeFunctionRequest{UserID: 7, Function: "planner", UnitID: 4})
+	assert.ErrorIs(t, err, org.ErrAssignmentNotFound)
+}
+
+func TestService_Reconcile_ClassifiesDrift(t *testing.T) {
+	grants := []org.UserFunctionDetail{
+		{UserFunction: org.UserFunction{UserID: 1, FunctionID: 2, UnitID: 3}, FunctionName: "planner"}, // in sync
+		{UserFunction: org.UserFunction{UserID: 4, FunctionID: 2, UnitID: 3}, FunctionName: "planner"}, // no rule
+	}
+	links := []org.RoleLink{
+		{Subject: "user:1", Role: "planner", Domain: "orgunit:3", UserID: intPtr(1), FunctionID: intPtr(2), UnitID: intPtr(3)},
+		{Subject: "user:5", Role: "planner", Domain: "orgunit:3", UserID: intPtr(5), FunctionID: intPtr(2), UnitID: intPtr(3)}, // no user function
+		{Subject: "user:6", Role: "retired", Domain: "orgunit:3", UserID: intPtr(6), UnitID: intPtr(3)},                        // unknown function
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		repo := new(MockOrgRepo)
+		rules, _, reloads := newRecordingRules()
+		service := org.NewService(repo, rules)
+		repo.On("ListAllUserFunctions", mock.Anything).Return(grants, nil)
+		repo.On("ListRoleLinks", mock.Anything).Return(links, nil)
+
+		report, err := service.Reconcile(context.Background(), true)
+		require.NoError(t, err)
+		require.Len(t, report.AddedToCasbin, 1)
+		assert.Equal(t, 4, report.AddedToCasbin[0].UserID)
+		require.Len(t, report.AddedToFunctions, 1)
+		assert.Equal(t, "user:5", report.AddedToFunctions[0].Subject)
+		require.Len(t, report.RemovedFromCasbin, 1)
+		assert.Equal(t, "retired", report.RemovedFromCasbin[0].Role)
+		repo.AssertNotCalled(t, "ApplyReconciliation", mock.Anything, mock.Anything)
+		assert.Zero(t, *reloads)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		repo := new(MockOrgRepo)
+		rules, _, reloads := newRecordingRules()
+		service := org.NewService(repo, rules)
+		repo.On("ListAllUserFunctions", mock.Anything).Return(grants, nil)
+		repo.On("ListRoleLinks", mock.Anything).Return(links, nil)
+		repo.On("ApplyReconciliation", mock.Anything, mock.MatchedBy(func(r org.ReconcileReport) bool {
+			return !r.DryRun && len(r.AddedToCasbin) == 1 && len(r.AddedToFunctions) == 1 && len(r.RemovedFromCasbin) == 1
+		})).Return(nil)
+
+		_, err := service.Reconcile(context.Background(), false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, *reloads)
+		repo.AssertExpectations(t)
+	})
+}
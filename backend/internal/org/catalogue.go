@@ -0,0 +1,259 @@
+package org
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrFunctionNotFound is returned when no function matches the given ID or name.
+	ErrFunctionNotFound = errors.New("function not found")
+	// ErrRankNotFound is returned when no rank of the function matches the given ID.
+	ErrRankNotFound = errors.New("rank not found")
+	// ErrDuplicateFunction is returned when a function name is already used.
+	ErrDuplicateFunction = errors.New("function already exists")
+	// ErrDuplicateRank is returned when the function already has a rank with that name.
+	ErrDuplicateRank = errors.New("rank already exists for this function")
+	// ErrInvalidFunction is wrapped by function and rank validation errors.
+	ErrInvalidFunction = errors.New("invalid function")
+	// ErrFunctionInUse is wrapped by FunctionInUseError.
+	ErrFunctionInUse = errors.New("function is still in use")
+	// ErrRankInUse is returned when deleting a rank users still hold.
+	ErrRankInUse = errors.New("rank is still held by users")
+)
+
+// functionNamePattern keeps function names usable as Casbin role names.
+var functionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+// FunctionInUseError explains why a function cannot be deleted or renamed.
+type FunctionInUseError struct {
+	Usage FunctionUsage
+}
+
+func (e *FunctionInUseError) Error() string {
+	var reasons []string
+	if e.Usage.Holders > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d holders", e.Usage.Holders))
+	}
+	if e.Usage.CasbinRules {
+		reasons = append(reasons, "access policies")
+	}
+	return fmt.Sprintf("%s: %s", ErrFunctionInUse, strings.Join(reasons, ", "))
+}
+
+// Is makes errors.Is(err, ErrFunctionInUse) match.
+func (e *FunctionInUseError) Is(target error) bool {
+	return target == ErrFunctionInUse
+}
+
+// ListFunctions returns the function catalogue with each function's ordered ranks.
+func (s *orgService) ListFunctions(ctx context.Context) ([]Function, error) {
+	functions, err := s.repo.ListFunctions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ranks, err := s.repo.ListRanks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byFunction := make(map[int][]Rank, len(functions))
+	for _, r := range ranks {
+		byFunction[r.FunctionID] = append(byFunction[r.FunctionID], r)
+	}
+	for i := range functions {
+		functions[i].Ranks = byFunction[functions[i].ID]
+		if functions[i].Ranks == nil {
+			functions[i].Ranks = []Rank{}
+		}
+	}
+	return functions, nil
+}
+
+// GetFunction returns a function with its ordered ranks.
+func (s *orgService) GetFunction(ctx context.Context, id int) (Function, error) {
+	f, err := s.repo.GetFunction(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Function{}, ErrFunctionNotFound
+	}
+	if err != nil {
+		return Function{}, err
+	}
+	if f.Ranks, err = s.repo.ListFunctionRanks(ctx, id); err != nil {
+		return Function{}, err
+	}
+	return f, nil
+}
+
+// CreateFunction adds a function to the catalogue.
+func (s *orgService) CreateFunction(ctx context.Context, req FunctionRequest) (Function, error) {
+	f := Function{Ranks: []Rank{}}
+	if err := applyFunction(&f, req); err != nil {
+		return Function{}, err
+	}
+	if err := s.repo.CreateFunction(ctx, &f); err != nil {
+		return Function{}, err
+	}
+	return f, nil
+}
+
+// UpdateFunction changes a function's name or description. Functions referenced by
+// access policies keep their name, since the policies refer to it.
+func (s *orgService) UpdateFunction(ctx context.Context, id int, req FunctionRequest) (Function, error) {
+	f, err := s.GetFunction(ctx, id)
+	if err != nil {
+		return Function{}, err
+	}
+	previous := f.Name
+	if err := applyFunction(&f, req); err != nil {
+		return Function{}, err
+	}
+	if f.Name != previous {
+		inRules, err := s.rules.FunctionHasRules(previous)
+		if err != nil {
+			return Function{}, fmt.Errorf("failed to check access policies: %w", err)
+		}
+		if inRules {
+			return Function{}, &FunctionInUseError{Usage: FunctionUsage{CasbinRules: true}}
+		}
+	}
+	if err := s.repo.UpdateFunction(ctx, &f); errors.Is(err, sql.ErrNoRows) {
+		return Function{}, ErrFunctionNotFound
+	} else if err != nil {
+		return Function{}, err
+	}
+	return f, nil
+}
+
+// DeleteFunction removes a function that nobody holds and no policy refers to.
+func (s *orgService) DeleteFunction(ctx context.Context, id int) error {
+	f, err := s.GetFunction(ctx, id)
+	if err != nil {
+		return err
+	}
+	usage, err := s.repo.GetFunctionUsage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if usage.CasbinRules, err = s.rules.FunctionHasRules(f.Name); err != nil {
+		return fmt.Errorf("failed to check access policies: %w", err)
+	}
+	if usage.InUse() {
+		return &FunctionInUseError{Usage: usage}
+	}
+
+	deleted, err := s.repo.DeleteFunction(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		// Someone was given the function since the usage check.
+		usage, err := s.repo.GetFunctionUsage(ctx, id)
+		if err != nil {
+			return err
+		}
+		return &FunctionInUseError{Usage: usage}
+	}
+	return nil
+}
+
+// CreateRank appends a rank at the end of the function's order.
+func (s *orgService) CreateRank(ctx context.Context, functionID int, name string) (Rank, error) {
+	if _, err := s.GetFunction(ctx, functionID); err != nil {
+		return Rank{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Rank{}, fmt.Errorf("%w: rank name is required", ErrInvalidFunction)
+	}
+	r := Rank{FunctionID: functionID, Name: name}
+	if err := s.repo.CreateRank(ctx, &r); err != nil {
+		return Rank{}, err
+	}
+	return r, nil
+}
+
+// RenameRank changes the name of one of a function's ranks.
+func (s *orgService) RenameRank(ctx context.Context, functionID, rankID int, name string) (Rank, error) {
+	r, err := s.getRank(ctx, functionID, rankID)
+	if err != nil {
+		return Rank{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Rank{}, fmt.Errorf("%w: rank name is required", ErrInvalidFunction)
+	}
+	if err := s.repo.RenameRank(ctx, rankID, name); errors.Is(err, sql.ErrNoRows) {
+		return Rank{}, ErrRankNotFound
+	} else if err != nil {
+		return Rank{}, err
+	}
+	r.Name = name
+	return r, nil
+}
+
+// DeleteRank removes a rank nobody holds; the remaining levels close up.
+func (s *orgService) DeleteRank(ctx context.Context, functionID, rankID int) error {
+	r, err := s.getRank(ctx, functionID, rankID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteRank(ctx, r)
+}
+
+// ReorderRanks sets the level of each rank to its position in rankIDs, which must
+// list every rank of the function exactly once.
+func (s *orgService) ReorderRanks(ctx context.Context, functionID int, rankIDs []int) ([]Rank, error) {
+	f, err := s.GetFunction(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[int]bool, len(f.Ranks))
+	for _, r := range f.Ranks {
+		current[r.ID] = true
+	}
+	if len(rankIDs) != len(current) {
+		return nil, fmt.Errorf("%w: expected all %d ranks of the function, got %d", ErrInvalidFunction, len(current), len(rankIDs))
+	}
+	seen := make(map[int]bool, len(rankIDs))
+	for _, id := range rankIDs {
+		if !current[id] {
+			return nil, fmt.Errorf("%w: rank %d does not belong to function %d", ErrInvalidFunction, id, functionID)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("%w: rank %d is listed twice", ErrInvalidFunction, id)
+		}
+		seen[id] = true
+	}
+
+	if err := s.repo.ReorderRanks(ctx, functionID, rankIDs); err != nil {
+		return nil, err
+	}
+	return s.repo.ListFunctionRanks(ctx, functionID)
+}
+
+func (s *orgService) getRank(ctx context.Context, functionID, rankID int) (Rank, error) {
+	r, err := s.repo.GetRank(ctx, rankID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && r.FunctionID != functionID) {
+		return Rank{}, ErrRankNotFound
+	}
+	return r, err
+}
+
+// applyFunction validates a function request and copies it onto the function.
+func applyFunction(f *Function, req FunctionRequest) error {
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if !functionNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: name %q must start with a letter and contain only lowercase letters, digits, '-' or '_'", ErrInvalidFunction, req.Name)
+	}
+	f.Name = name
+	f.Description = nil
+	if req.Description != nil && strings.TrimSpace(*req.Description) != "" {
+		d := strings.TrimSpace(*req.Description)
+		f.Description = &d
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package org_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nomenarkt/lamina/internal/org"
+)
+
+func pilotRanks() []org.Rank {
+	return []org.Rank{
+		{ID: 10, FunctionID: 1, Name: "First Officer", Level: intPtr(1)},
+		{ID: 11, FunctionID: 1, Name: "Captain", Level: intPtr(2)},
+	}
+}
+
+func TestService_ListFunctions_NestsRanks(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("ListFunctions", mock.Anything).Return([]org.Function{{ID: 1, Name: "pilot"}, {ID: 2, Name: "planner"}}, nil)
+	repo.On("ListRanks", mock.Anything).Return(pilotRanks(), nil)
+
+	functions, err := service.ListFunctions(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, functions[0].Ranks, 2)
+	assert.NotNil(t, functions[1].Ranks)
+	assert.Empty(t, functions[1].Ranks)
+}
+
+func TestService_CreateFunction_RequiresRoleSafeName(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	_, err := service.CreateFunction(context.Background(), org.FunctionRequest{Name: "user:1"})
+	assert.ErrorIs(t, err, org.ErrInvalidFunction)
+
+	repo.On("CreateFunction", mock.Anything, mock.MatchedBy(func(f *org.Function) bool {
+		return f.Name == "crew-planner"
+	})).Return(nil)
+	_, err = service.CreateFunction(context.Background(), org.FunctionRequest{Name: " Crew-Planner "})
+	assert.NoError(t, err)
+}
+
+func TestService_UpdateFunction_KeepsNameUsedByPolicies(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{functions: map[string]bool{"planner": true}})
+
+	repo.On("GetFunction", mock.Anything, 2).Return(org.Function{ID: 2, Name: "planner"}, nil)
+	repo.On("ListFunctionRanks", mock.Anything, 2).Return([]org.Rank{}, nil)
+
+	_, err := service.UpdateFunction(context.Background(), 2, org.FunctionRequest{Name: "scheduler"})
+	assert.ErrorIs(t, err, org.ErrFunctionInUse)
+
+	description := "Builds the crew roster"
+	repo.On("UpdateFunction", mock.Anything, mock.Anything).Return(nil)
+	f, err := service.UpdateFunction(context.Background(), 2, org.FunctionRequest{Name: "planner", Description: &description})
+	assert.NoError(t, err)
+	assert.Equal(t, description, *f.Description)
+}
+
+func TestService_DeleteFunction_InUse(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetFunction", mock.Anything, 1).Return(org.Function{ID: 1, Name: "pilot"}, nil)
+	repo.On("ListFunctionRanks", mock.Anything, 1).Return(pilotRanks(), nil)
+	repo.On("GetFunctionUsage", mock.Anything, 1).Return(org.FunctionUsage{Holders: 12}, nil)
+
+	err := service.DeleteFunction(context.Background(), 1)
+	var inUse *org.FunctionInUseError
+	require.ErrorAs(t, err, &inUse)
+	assert.Equal(t, 12, inUse.Usage.Holders)
+	repo.AssertNotCalled(t, "DeleteFunction", mock.Anything, mock.Anything)
+}
+
+func TestService_ReorderRanks_RequiresEveryRankOnce(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetFunction", mock.Anything, 1).Return(org.Function{ID: 1, Name: "pilot"}, nil)
+	repo.On("ListFunctionRanks", mock.Anything, 1).Return(pilotRanks(), nil)
+
+	for _, ids := range [][]int{{11}, {11, 11}, {11, 99}} {
+		_, err := service.ReorderRanks(context.Background(), 1, ids)
+		assert.ErrorIs(t, err, org.ErrInvalidFunction, "%v", ids)
+	}
+	repo.AssertNotCalled(t, "ReorderRanks", mock.Anything, mock.Anything, mock.Anything)
+
+	repo.On("ReorderRanks", mock.Anything, 1, []int{11, 10}).Return(nil)
+	_, err := service.ReorderRanks(context.Background(), 1, []int{11, 10})
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestService_RenameRank_OfOtherFunction(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetRank", mock.Anything, 10).Return(pilotRanks()[0], nil)
+
+	_, err := service.RenameRank(context.Background(), 2, 10, "Senior First Officer")
+	assert.ErrorIs(t, err, org.ErrRankNotFound)
+}
@@ -0,0 +1,58 @@
+package org_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nomenarkt/lamina/internal/org"
+)
+
+func TestService_CreateModuleEntitlement_Validates(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+	ctx := context.Background()
+
+	_, err := service.CreateModuleEntitlement(ctx, org.ModuleEntitlementRequest{Module: "crew"}, 1)
+	assert.ErrorIs(t, err, org.ErrInvalidEntitlement)
+
+	_, err = service.CreateModuleEntitlement(ctx, org.ModuleEntitlementRequest{Module: "Crew Planning!", UnitID: intPtr(2)}, 1)
+	assert.ErrorIs(t, err, org.ErrInvalidEntitlement)
+
+	repo.On("GetFunction", mock.Anything, 9).Return(org.Function{}, sql.ErrNoRows)
+	_, err = service.CreateModuleEntitlement(ctx, org.ModuleEntitlementRequest{Module: "crew", FunctionID: intPtr(9)}, 1)
+	assert.ErrorIs(t, err, org.ErrInvalidEntitlement)
+
+	repo.AssertNotCalled(t, "CreateModuleEntitlement", mock.Anything, mock.Anything)
+}
+
+func TestService_CreateModuleEntitlement_FunctionInUnit(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetFunction", mock.Anything, 2).Return(org.Function{ID: 2, Name: "planner"}, nil)
+	repo.On("GetUnit", mock.Anything, 3).Return(org.OrganizationalUnit{ID: 3}, nil)
+	repo.On("CreateModuleEntitlement", mock.Anything, mock.MatchedBy(func(e *org.ModuleEntitlement) bool {
+		return e.Module == "crew" && *e.FunctionID == 2 && *e.UnitID == 3 && *e.CreatedBy == 1
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*org.ModuleEntitlement).ID = 14
+	}).Return(nil)
+	repo.On("GetModuleEntitlement", mock.Anything, 14).Return(org.ModuleEntitlement{ID: 14, Module: "crew"}, nil)
+
+	e, err := service.CreateModuleEntitlement(context.Background(), org.ModuleEntitlementRequest{Module: " Crew ", FunctionID: intPtr(2), UnitID: intPtr(3)}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 14, e.ID)
+	repo.AssertExpectations(t)
+}
+
+func TestService_DeleteModuleEntitlement_NotFound(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("DeleteModuleEntitlement", mock.Anything, 5).Return(sql.ErrNoRows)
+	assert.ErrorIs(t, service.DeleteModuleEntitlement(context.Background(), 5), org.ErrEntitlementNotFound)
+}
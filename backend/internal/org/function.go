@@ -2,8 +2,27 @@
 package org
 
 // Function represents a functional unit or responsibility in an organization.
+// Its name doubles as the Casbin role granted to holders.
 type Function struct {
-	ID          int     `db:"id"`
-	Name        string  `db:"name"`
-	Description *string `db:"description"`
+	ID          int     `db:"id" json:"id"`
+	Name        string  `db:"name" json:"name"`
+	Description *string `db:"description" json:"description"`
+	Ranks       []Rank  `db:"-" json:"ranks"` // ordered by level
+}
+
+// FunctionRequest is the payload for creating or updating a function.
+type FunctionRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+}
+
+// FunctionUsage lists what still references a function and prevents its deletion.
+type FunctionUsage struct {
+	Holders     int  `db:"holders" json:"holders"`
+	CasbinRules bool `db:"-" json:"casbin_rules"`
+}
+
+// InUse reports whether anything references the function.
+func (u FunctionUsage) InUse() bool {
+	return u.Holders > 0 || u.CasbinRules
 }
@@ -0,0 +1,472 @@
+package org
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/common/utils"
+)
+
+// Handler defines the HTTP handler for organization structure operations.
+type Handler struct {
+	service ServiceInterface
+}
+
+// NewHandler creates a new Handler instance for the org service.
+func NewHandler(s ServiceInterface) *Handler {
+	return &Handler{service: s}
+}
+
+// GetTree returns all units as nested JSON.
+// GET /organizational_units/tree
+func (h *Handler) GetTree(c *gin.Context) {
+	tree, err := h.service.Tree(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// GetUnit returns a unit with its ancestors and subtree.
+// GET /organizational_units/:id
+func (h *Handler) GetUnit(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	unit, err := h.service.GetUnit(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, unit)
+}
+
+// GetAncestors lists the units above a unit, root first.
+// GET /organizational_units/:id/ancestors
+func (h *Handler) GetAncestors(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	units, err := h.service.Ancestors(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, units)
+}
+
+// GetDescendants lists every unit below a unit.
+// GET /organizational_units/:id/descendants
+func (h *Handler) GetDescendants(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	units, err := h.service.Descendants(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, units)
+}
+
+// CreateUnit adds an organizational unit.
+// POST /organizational_units
+func (h *Handler) CreateUnit(c *gin.Context) {
+	var req CreateUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	createdBy, _ := utils.GetUserIDFromContext(c)
+	unit, err := h.service.CreateUnit(c.Request.Context(), req, createdBy)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, unit)
+}
+
+// RenameUnit changes a unit's name.
+// PATCH /organizational_units/:id
+func (h *Handler) RenameUnit(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var req RenameUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	unit, err := h.service.RenameUnit(c.Request.Context(), id, req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, unit)
+}
+
+// MoveUnit re-parents a unit with its subtree.
+// POST /organizational_units/:id/move
+func (h *Handler) MoveUnit(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var req MoveUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	unit, err := h.service.MoveUnit(c.Request.Context(), id, req.ParentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, unit)
+}
+
+// DeleteUnit removes a unit that has no sub-units, members or access policies.
+// DELETE /organizational_units/:id
+func (h *Handler) DeleteUnit(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteUnit(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListFunctions returns the function catalogue with ranks.
+// GET /functions
+func (h *Handler) ListFunctions(c *gin.Context) {
+	functions, err := h.service.ListFunctions(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, functions)
+}
+
+// GetFunction returns a function with its ranks.
+// GET /functions/:id
+func (h *Handler) GetFunction(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	f, err := h.service.GetFunction(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// CreateFunction adds a function to the catalogue.
+// POST /functions
+func (h *Handler) CreateFunction(c *gin.Context) {
+	var req FunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	f, err := h.service.CreateFunction(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, f)
+}
+
+// UpdateFunction changes a function's name or description.
+// PUT /functions/:id
+func (h *Handler) UpdateFunction(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var req FunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	f, err := h.service.UpdateFunction(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// DeleteFunction removes a function that is not in use.
+// DELETE /functions/:id
+func (h *Handler) DeleteFunction(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteFunction(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateRank appends a rank to a function.
+// POST /functions/:id/ranks
+func (h *Handler) CreateRank(c *gin.Context) {
+	functionID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var req RankRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	r, err := h.service.CreateRank(c.Request.Context(), functionID, req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, r)
+}
+
+// ReorderRanks sets the order of a function's ranks.
+// PUT /functions/:id/ranks
+func (h *Handler) ReorderRanks(c *gin.Context) {
+	functionID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var req ReorderRanksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	ranks, err := h.service.ReorderRanks(c.Request.Context(), functionID, req.RankIDs)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ranks)
+}
+
+// RenameRank changes a rank's name.
+// PATCH /functions/:id/ranks/:rank_id
+func (h *Handler) RenameRank(c *gin.Context) {
+	functionID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	rankID, ok := idParam(c, "rank_id")
+	if !ok {
+		return
+	}
+	var req RankRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	r, err := h.service.RenameRank(c.Request.Context(), functionID, rankID, req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// DeleteRank removes a rank nobody holds.
+// DELETE /functions/:id/ranks/:rank_id
+func (h *Handler) DeleteRank(c *gin.Context) {
+	functionID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	rankID, ok := idParam(c, "rank_id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteRank(c.Request.Context(), functionID, rankID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListUserFunctions returns the functions a user holds. Users may read their own; admins anyone's.
+// GET /user_functions/users/:user_id
+func (h *Handler) ListUserFunctions(c *gin.Context) {
+	userID, ok := idParam(c, "user_id")
+	if !ok {
+		return
+	}
+	if !canReadUser(c, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+	functions, err := h.service.ListUserFunctions(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, functions)
+}
+
+// ListFunctionMembers returns the users holding a function in a unit.
+// GET /functions/:id/units/:unit_id/members
+func (h *Handler) ListFunctionMembers(c *gin.Context) {
+	functionID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	unitID, ok := idParam(c, "unit_id")
+	if !ok {
+		return
+	}
+	members, err := h.service.ListFunctionMembers(c.Request.Context(), functionID, unitID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// AssignFunction grants a function to a user in a unit, or changes its rank.
+// POST /user_functions
+func (h *Handler) AssignFunction(c *gin.Context) {
+	var req AssignFunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	uf, err := h.service.AssignFunction(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, uf)
+}
+
+// RevokeFunction withdraws a function from a user in a unit.
+// DELETE /user_functions
+func (h *Handler) RevokeFunction(c *gin.Context) {
+	var req RevokeFunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	if err := h.service.RevokeFunction(c.Request.Context(), req); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Reconcile reports, and unless dry_run=true fixes, drift between user functions and Casbin.
+// POST /user_functions/reconcile
+func (h *Handler) Reconcile(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+	report, err := h.service.Reconcile(c.Request.Context(), dryRun)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ListModuleEntitlements returns every module grant.
+// GET /module_entitlements
+func (h *Handler) ListModuleEntitlements(c *gin.Context) {
+	entitlements, err := h.service.ListModuleEntitlements(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entitlements)
+}
+
+// CreateModuleEntitlement grants a module to a function and/or unit.
+// POST /module_entitlements
+func (h *Handler) CreateModuleEntitlement(c *gin.Context) {
+	var req ModuleEntitlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	createdBy, _ := utils.GetUserIDFromContext(c)
+	e, err := h.service.CreateModuleEntitlement(c.Request.Context(), req, createdBy)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, e)
+}
+
+// DeleteModuleEntitlement withdraws a module grant.
+// DELETE /module_entitlements/:id
+func (h *Handler) DeleteModuleEntitlement(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteModuleEntitlement(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// canReadUser allows admins to read anyone's functions and users their own.
+func canReadUser(c *gin.Context, userID int) bool {
+	if c.GetString("userRole") == "admin" {
+		return true
+	}
+	caller, ok := utils.GetUserIDFromContext(c)
+	return ok && caller == int64(userID)
+}
+
+func idParam(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondError(c *gin.Context, err error) {
+	var (
+		unitInUse     *UnitInUseError
+		functionInUse *FunctionInUseError
+	)
+	switch {
+	case errors.As(err, &unitInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "usage": unitInUse.Usage})
+	case errors.As(err, &functionInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "usage": functionInUse.Usage})
+	case errors.Is(err, ErrInvalidUnit), errors.Is(err, ErrInvalidFunction), errors.Is(err, ErrInvalidAssignment),
+		errors.Is(err, ErrInvalidEntitlement):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUnitNotFound), errors.Is(err, ErrFunctionNotFound), errors.Is(err, ErrRankNotFound),
+		errors.Is(err, ErrAssignmentNotFound), errors.Is(err, ErrEntitlementNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUnitCycle), errors.Is(err, ErrDuplicateFunction), errors.Is(err, ErrDuplicateRank),
+		errors.Is(err, ErrRankInUse), errors.Is(err, ErrDuplicateEntitlement):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Org request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
@@ -0,0 +1,24 @@
+// Package org defines organization-level domain entities and relationships.
+package org
+
+import "time"
+
+// ModuleEntitlement grants an application module to holders of a function, members of a
+// unit and the units below it, or, with both set, holders of the function in that subtree.
+type ModuleEntitlement struct {
+	ID           int       `db:"id" json:"id"`
+	Module       string    `db:"module" json:"module"`
+	FunctionID   *int      `db:"function_id" json:"function_id"`
+	FunctionName *string   `db:"function_name" json:"function_name"`
+	UnitID       *int      `db:"unit_id" json:"unit_id"`
+	UnitName     *string   `db:"unit_name" json:"unit_name"`
+	CreatedBy    *int      `db:"created_by" json:"created_by"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// ModuleEntitlementRequest is the payload for granting a module.
+type ModuleEntitlementRequest struct {
+	Module     string `json:"module" binding:"required"`
+	FunctionID *int   `json:"function_id"`
+	UnitID     *int   `json:"unit_id"`
+}
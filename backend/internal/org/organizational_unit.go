@@ -3,12 +3,72 @@ package org
 
 import "time"
 
+// Organizational unit types, from the top of the hierarchy down.
+const (
+	UnitTypeDirection  = "direction"
+	UnitTypeDepartment = "department"
+	UnitTypeService    = "service"
+	UnitTypeSection    = "section"
+)
+
+// unitLevels ranks unit types; a unit's parent must have a lower level.
+var unitLevels = map[string]int{
+	UnitTypeDirection:  1,
+	UnitTypeDepartment: 2,
+	UnitTypeService:    3,
+	UnitTypeSection:    4,
+}
+
 // OrganizationalUnit represents a business or functional unit within the organization.
 type OrganizationalUnit struct {
-	ID        int       `db:"id"`
-	Name      string    `db:"name"`
-	Type      string    `db:"type"` // direction, department, service, section
-	ParentID  *int      `db:"parent_id"`
-	CreatedBy *int      `db:"created_by"`
-	CreatedAt time.Time `db:"created_at"`
+	ID        int       `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Type      string    `db:"type" json:"type"` // direction, department, service, section
+	ParentID  *int      `db:"parent_id" json:"parent_id"`
+	CreatedBy *int      `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Depth     int       `db:"depth" json:"depth,omitempty"` // distance from the queried unit in ancestor/descendant lists
+}
+
+// UnitNode is an organizational unit with its nested sub-units.
+type UnitNode struct {
+	OrganizationalUnit
+	Children []*UnitNode `json:"children"`
+}
+
+// UnitDetail is a unit with the path from the root and its subtree.
+type UnitDetail struct {
+	OrganizationalUnit
+	Ancestors []OrganizationalUnit `json:"ancestors"` // root first, parent last
+	Children  []*UnitNode          `json:"children"`
+}
+
+// CreateUnitRequest is the payload for creating an organizational unit.
+type CreateUnitRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Type     string `json:"type" binding:"required"`
+	ParentID *int   `json:"parent_id"`
+}
+
+// RenameUnitRequest is the payload for renaming an organizational unit.
+type RenameUnitRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MoveUnitRequest is the payload for re-parenting an organizational unit; a null parent makes it a root.
+type MoveUnitRequest struct {
+	ParentID *int `json:"parent_id"`
+}
+
+// UnitUsage lists what still references a unit and prevents its deletion.
+type UnitUsage struct {
+	Children     int  `db:"children" json:"children"`
+	Members      int  `db:"members" json:"members"`
+	Functions    int  `db:"functions" json:"functions"`
+	CasbinDomain bool `db:"-" json:"casbin_domain"`
+}
+
+// InUse reports whether anything references the unit.
+func (u UnitUsage) InUse() bool {
+	return u.Children > 0 || u.Members > 0 || u.Functions > 0 || u.CasbinDomain
 }
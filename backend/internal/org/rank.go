@@ -3,8 +3,18 @@ package org
 
 // Rank represents the crew rank within an organization.
 type Rank struct {
-	ID         int    `db:"id"`
-	FunctionID int    `db:"function_id"`
-	Name       string `db:"name"`
-	Level      *int   `db:"level"`
+	ID         int    `db:"id" json:"id"`
+	FunctionID int    `db:"function_id" json:"function_id"`
+	Name       string `db:"name" json:"name"`
+	Level      *int   `db:"level" json:"level"` // position within the function, starting at 1
+}
+
+// RankRequest is the payload for creating or renaming a rank.
+type RankRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ReorderRanksRequest lists every rank of a function in its new order.
+type ReorderRanksRequest struct {
+	RankIDs []int `json:"rank_ids" binding:"required"`
 }
@@ -0,0 +1,561 @@
+package org
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nomenarkt/lamina/internal/access"
+)
+
+// Repository defines the interface for organization data access.
+type Repository interface {
+	ListUnits(ctx context.Context) ([]OrganizationalUnit, error)
+	GetUnit(ctx context.Context, id int) (OrganizationalUnit, error)
+	CreateUnit(ctx context.Context, u *OrganizationalUnit) error
+	RenameUnit(ctx context.Context, id int, name string) error
+	MoveUnit(ctx context.Context, id int, parentID *int) (bool, error)
+	ListAncestors(ctx context.Context, id int) ([]OrganizationalUnit, error)
+	ListDescendants(ctx context.Context, id int) ([]OrganizationalUnit, error)
+	GetUnitUsage(ctx context.Context, id int) (UnitUsage, error)
+	DeleteUnit(ctx context.Context, id int) (bool, error)
+
+	ListFunctions(ctx context.Context) ([]Function, error)
+	GetFunction(ctx context.Context, id int) (Function, error)
+	GetFunctionByName(ctx context.Context, name string) (Function, error)
+	CreateFunction(ctx context.Context, f *Function) error
+	UpdateFunction(ctx context.Context, f *Function) error
+	GetFunctionUsage(ctx context.Context, id int) (FunctionUsage, error)
+	DeleteFunction(ctx context.Context, id int) (bool, error)
+	ListRanks(ctx context.Context) ([]Rank, error)
+	ListFunctionRanks(ctx context.Context, functionID int) ([]Rank, error)
+	GetRank(ctx context.Context, id int) (Rank, error)
+	CreateRank(ctx context.Context, r *Rank) error
+	RenameRank(ctx context.Context, id int, name string) error
+	DeleteRank(ctx context.Context, r Rank) error
+	ReorderRanks(ctx context.Context, functionID int, rankIDs []int) error
+
+	ListUserFunctions(ctx context.Context, userID int) ([]UserFunctionDetail, error)
+	ListFunctionMembers(ctx context.Context, functionID, unitID int) ([]UserFunctionDetail, error)
+	ListAllUserFunctions(ctx context.Context) ([]UserFunctionDetail, error)
+	GetUserFunction(ctx context.Context, userID, functionID, unitID int) (UserFunctionDetail, error)
+	AssignUserFunction(ctx context.Context, uf UserFunction, function string) error
+	RevokeUserFunction(ctx context.Context, uf UserFunction, function string) (bool, error)
+	ListRoleLinks(ctx context.Context) ([]RoleLink, error)
+	ApplyReconciliation(ctx context.Context, report ReconcileReport) error
+
+	ListModuleEntitlements(ctx context.Context) ([]ModuleEntitlement, error)
+	GetModuleEntitlement(ctx context.Context, id int) (ModuleEntitlement, error)
+	CreateModuleEntitlement(ctx context.Context, e *ModuleEntitlement) error
+	DeleteModuleEntitlement(ctx context.Context, id int) error
+}
+
+// orgRepository is a concrete implementation of the Repository interface.
+type orgRepository struct {
+	db *sqlx.DB
+}
+
+// NewRepository returns a new instance of an org Repository.
+func NewRepository(db *sqlx.DB) Repository {
+	return &orgRepository{db: db}
+}
+
+const unitColumns = `id, name, type, parent_id, created_by, created_at`
+
+// ListUnits returns every organizational unit ordered by name.
+func (r *orgRepository) ListUnits(ctx context.Context) ([]OrganizationalUnit, error) {
+	units := []OrganizationalUnit{}
+	err := r.db.SelectContext(ctx, &units, `SELECT `+unitColumns+` FROM organizational_units ORDER BY name, id`)
+	return units, err
+}
+
+// GetUnit returns a single organizational unit.
+func (r *orgRepository) GetUnit(ctx context.Context, id int) (OrganizationalUnit, error) {
+	var u OrganizationalUnit
+	err := r.db.GetContext(ctx, &u, `SELECT `+unitColumns+` FROM organizational_units WHERE id = $1`, id)
+	return u, err
+}
+
+// CreateUnit inserts an organizational unit and fills in its generated fields.
+func (r *orgRepository) CreateUnit(ctx context.Context, u *OrganizationalUnit) error {
+	return r.db.GetContext(ctx, u, `
+		INSERT INTO organizational_units (name, type, parent_id, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+unitColumns,
+		u.Name, u.Type, u.ParentID, u.CreatedBy)
+}
+
+// RenameUnit changes the name of a unit. It returns sql.ErrNoRows if the unit does not exist.
+func (r *orgRepository) RenameUnit(ctx context.Context, id int, name string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE organizational_units SET name = $2 WHERE id = $1`, id, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// MoveUnit re-parents a unit. The update is skipped, and false returned, when the new
+// parent is the unit itself or one of its descendants, so concurrent moves cannot form a cycle.
+func (r *orgRepository) MoveUnit(ctx context.Context, id int, parentID *int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM organizational_units WHERE id = $1
+			UNION
+			SELECT ou.id FROM organizational_units ou JOIN subtree s ON ou.parent_id = s.id
+		)
+		UPDATE organizational_units
+		SET parent_id = $2
+		WHERE id = $1
+		  AND ($2::int IS NULL OR $2::int NOT IN (SELECT id FROM subtree))`, id, parentID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListAncestors returns the units above id, root first. Depth counts the steps up from id.
+func (r *orgRepository) ListAncestors(ctx context.Context, id int) ([]OrganizationalUnit, error) {
+	units := []OrganizationalUnit{}
+	err := r.db.SelectContext(ctx, &units, `
+		WITH RECURSIVE ancestors AS (
+			SELECT parent.id, parent.name, parent.type, parent.parent_id, parent.created_by, parent.created_at, 1 AS depth
+			FROM organizational_units child
+			JOIN organizational_units parent ON parent.id = child.parent_id
+			WHERE child.id = $1
+			UNION ALL
+			SELECT ou.id, ou.name, ou.type, ou.parent_id, ou.created_by, ou.created_at, a.depth + 1
+			FROM organizational_units ou
+			JOIN ancestors a ON ou.id = a.parent_id
+			WHERE a.depth < 64
+		)
+		SELECT `+unitColumns+`, depth FROM ancestors ORDER BY depth DESC`, id)
+	return units, err
+}
+
+// ListDescendants returns every unit below id, breadth first. Depth counts the steps down from id.
+func (r *orgRepository) ListDescendants(ctx context.Context, id int) ([]OrganizationalUnit, error) {
+	units := []OrganizationalUnit{}
+	err := r.db.SelectContext(ctx, &units, `
+		WITH RECURSIVE descendants AS (
+			SELECT id, name, type, parent_id, created_by, created_at, 1 AS depth
+			FROM organizational_units
+			WHERE parent_id = $1
+			UNION ALL
+			SELECT ou.id, ou.name, ou.type, ou.parent_id, ou.created_by, ou.created_at, d.depth + 1
+			FROM organizational_units ou
+			JOIN descendants d ON ou.parent_id = d.id
+			WHERE d.depth < 64
+		)
+		SELECT `+unitColumns+`, depth FROM descendants ORDER BY depth, name, id`, id)
+	return units, err
+}
+
+// GetUnitUsage counts the sub-units, members and function holders of a unit.
+func (r *orgRepository) GetUnitUsage(ctx context.Context, id int) (UnitUsage, error) {
+	var usage UnitUsage
+	err := r.db.GetContext(ctx, &usage, `
+		SELECT
+			(SELECT COUNT(*) FROM organizational_units WHERE parent_id = $1) AS children,
+			(SELECT COUNT(*) FROM user_organizational_units WHERE unit_id = $1) AS members,
+			(SELECT COUNT(*) FROM user_functions WHERE unit_id = $1) AS functions`, id)
+	return usage, err
+}
+
+// DeleteUnit removes a unit that has no sub-units, members or function holders.
+// It returns false if the unit does not exist or is still referenced.
+func (r *orgRepository) DeleteUnit(ctx context.Context, id int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM organizational_units ou
+		WHERE ou.id = $1
+		  AND NOT EXISTS (SELECT 1 FROM organizational_units c WHERE c.parent_id = ou.id)
+		  AND NOT EXISTS (SELECT 1 FROM user_organizational_units m WHERE m.unit_id = ou.id)
+		  AND NOT EXISTS (SELECT 1 FROM user_functions f WHERE f.unit_id = ou.id)`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListFunctions returns the function catalogue ordered by name, without ranks.
+func (r *orgRepository) ListFunctions(ctx context.Context) ([]Function, error) {
+	functions := []Function{}
+	err := r.db.SelectContext(ctx, &functions, `SELECT id, name, description FROM functions ORDER BY name`)
+	return functions, err
+}
+
+// GetFunction returns a single function, without ranks.
+func (r *orgRepository) GetFunction(ctx context.Context, id int) (Function, error) {
+	var f Function
+	err := r.db.GetContext(ctx, &f, `SELECT id, name, description FROM functions WHERE id = $1`, id)
+	return f, err
+}
+
+// GetFunctionByName returns the function with the given name, without ranks.
+func (r *orgRepository) GetFunctionByName(ctx context.Context, name string) (Function, error) {
+	var f Function
+	err := r.db.GetContext(ctx, &f, `SELECT id, name, description FROM functions WHERE name = $1`, name)
+	return f, err
+}
+
+// CreateFunction inserts a function and fills in its ID.
+func (r *orgRepository) CreateFunction(ctx context.Context, f *Function) error {
+	err := r.db.GetContext(ctx, &f.ID, `
+		INSERT INTO functions (name, description) VALUES ($1, $2) RETURNING id`,
+		f.Name, f.Description)
+	return mapUniqueViolation(err, ErrDuplicateFunction)
+}
+
+// UpdateFunction overwrites a function's name and description.
+func (r *orgRepository) UpdateFunction(ctx context.Context, f *Function) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE functions SET name = $2, description = $3 WHERE id = $1`,
+		f.ID, f.Name, f.Description)
+	if err != nil {
+		return mapUniqueViolation(err, ErrDuplicateFunction)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// GetFunctionUsage counts the user function assignments of a function.
+func (r *orgRepository) GetFunctionUsage(ctx context.Context, id int) (FunctionUsage, error) {
+	var usage FunctionUsage
+	err := r.db.GetContext(ctx, &usage, `
+		SELECT (SELECT COUNT(*) FROM user_functions WHERE function_id = $1) AS holders`, id)
+	return usage, err
+}
+
+// DeleteFunction removes a function nobody holds, together with its ranks.
+// It returns false if the function does not exist or is still held.
+func (r *orgRepository) DeleteFunction(ctx context.Context, id int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM functions f
+		WHERE f.id = $1
+		  AND NOT EXISTS (SELECT 1 FROM user_functions uf WHERE uf.function_id = f.id)`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+const rankColumns = `id, function_id, name, level`
+
+// ListRanks returns every rank grouped by function and ordered by level.
+func (r *orgRepository) ListRanks(ctx context.Context) ([]Rank, error) {
+	ranks := []Rank{}
+	err := r.db.SelectContext(ctx, &ranks, `SELECT `+rankColumns+` FROM ranks ORDER BY function_id, level NULLS LAST, name`)
+	return ranks, err
+}
+
+// ListFunctionRanks returns the ranks of one function ordered by level.
+func (r *orgRepository) ListFunctionRanks(ctx context.Context, functionID int) ([]Rank, error) {
+	ranks := []Rank{}
+	err := r.db.SelectContext(ctx, &ranks, `
+		SELECT `+rankColumns+` FROM ranks WHERE function_id = $1 ORDER BY level NULLS LAST, name`, functionID)
+	return ranks, err
+}
+
+// GetRank returns a single rank.
+func (r *orgRepository) GetRank(ctx context.Context, id int) (Rank, error) {
+	var rank Rank
+	err := r.db.GetContext(ctx, &rank, `SELECT `+rankColumns+` FROM ranks WHERE id = $1`, id)
+	return rank, err
+}
+
+// CreateRank appends a rank after the function's last level and fills in its ID and level.
+func (r *orgRepository) CreateRank(ctx context.Context, rank *Rank) error {
+	err := r.db.GetContext(ctx, rank, `
+		INSERT INTO ranks (function_id, name, level)
+		SELECT $1, $2, COALESCE(MAX(level), 0) + 1 FROM ranks WHERE function_id = $1
+		RETURNING `+rankColumns,
+		rank.FunctionID, rank.Name)
+	return mapUniqueViolation(err, ErrDuplicateRank)
+}
+
+// RenameRank changes a rank's name. It returns sql.ErrNoRows if the rank does not exist.
+func (r *orgRepository) RenameRank(ctx context.Context, id int, name string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE ranks SET name = $2 WHERE id = $1`, id, name)
+	if err != nil {
+		return mapUniqueViolation(err, ErrDuplicateRank)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// DeleteRank removes a rank and closes the gap in its function's levels.
+func (r *orgRepository) DeleteRank(ctx context.Context, rank Rank) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ranks WHERE id = $1`, rank.ID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrRankInUse
+		}
+		return err
+	}
+	if err := renumberRanks(ctx, tx, rank.FunctionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReorderRanks assigns levels 1..n to rankIDs, which must be all ranks of the function.
+func (r *orgRepository) ReorderRanks(ctx context.Context, functionID int, rankIDs []int) error {
+	ids := make(pq.Int64Array, len(rankIDs))
+	for i, id := range rankIDs {
+		ids[i] = int64(id)
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE ranks r
+		SET level = o.position
+		FROM unnest($2::int[]) WITH ORDINALITY AS o(id, position)
+		WHERE r.id = o.id AND r.function_id = $1`, functionID, ids)
+	return err
+}
+
+// renumberRanks makes a function's levels contiguous from 1, keeping their order.
+func renumberRanks(ctx context.Context, tx *sqlx.Tx, functionID int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE ranks r
+		SET level = o.position
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY level NULLS LAST, name) AS position
+			FROM ranks WHERE function_id = $1
+		) o
+		WHERE r.id = o.id`, functionID)
+	return err
+}
+
+const userFunctionSelect = `
+	SELECT uf.user_id, uf.function_id, uf.unit_id, uf.rank_id,
+	       u.full_name AS user_name, u.email AS user_email,
+	       f.name AS function_name, ou.name AS unit_name, rk.name AS rank_name
+	FROM user_functions uf
+	JOIN users u ON u.id = uf.user_id
+	JOIN functions f ON f.id = uf.function_id
+	JOIN organizational_units ou ON ou.id = uf.unit_id
+	LEFT JOIN ranks rk ON rk.id = uf.rank_id`
+
+// ListUserFunctions returns the functions a user holds, by unit and function name.
+func (r *orgRepository) ListUserFunctions(ctx context.Context, userID int) ([]UserFunctionDetail, error) {
+	functions := []UserFunctionDetail{}
+	err := r.db.SelectContext(ctx, &functions, userFunctionSelect+`
+		WHERE uf.user_id = $1 ORDER BY ou.name, f.name`, userID)
+	return functions, err
+}
+
+// ListFunctionMembers returns the holders of a function in a unit, highest rank first.
+func (r *orgRepository) ListFunctionMembers(ctx context.Context, functionID, unitID int) ([]UserFunctionDetail, error) {
+	members := []UserFunctionDetail{}
+	err := r.db.SelectContext(ctx, &members, userFunctionSelect+`
+		WHERE uf.function_id = $1 AND uf.unit_id = $2
+		ORDER BY rk.level NULLS LAST, u.full_name, u.email`, functionID, unitID)
+	return members, err
+}
+
+// ListAllUserFunctions returns every user function assignment.
+func (r *orgRepository) ListAllUserFunctions(ctx context.Context) ([]UserFunctionDetail, error) {
+	functions := []UserFunctionDetail{}
+	err := r.db.SelectContext(ctx, &functions, userFunctionSelect+`
+		ORDER BY uf.user_id, uf.unit_id, f.name`)
+	return functions, err
+}
+
+// GetUserFunction returns a single user function assignment.
+func (r *orgRepository) GetUserFunction(ctx context.Context, userID, functionID, unitID int) (UserFunctionDetail, error) {
+	var uf UserFunctionDetail
+	err := r.db.GetContext(ctx, &uf, userFunctionSelect+`
+		WHERE uf.user_id = $1 AND uf.function_id = $2 AND uf.unit_id = $3`, userID, functionID, unitID)
+	return uf, err
+}
+
+// AssignUserFunction stores a user function, updating the rank if it already exists, together
+// with the Casbin grouping rule g(user:<id>, function, orgunit:<id>) in one transaction.
+func (r *orgRepository) AssignUserFunction(ctx context.Context, uf UserFunction, function string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_functions (user_id, function_id, unit_id, rank_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, function_id, unit_id) DO UPDATE SET rank_id = EXCLUDED.rank_id`,
+		uf.UserID, uf.FunctionID, uf.UnitID, uf.RankID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return fmt.Errorf("%w: user %d does not exist", ErrInvalidAssignment, uf.UserID)
+		}
+		return err
+	}
+	if err := insertRoleLink(ctx, tx, uf.UserID, function, uf.UnitID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeUserFunction deletes a user function and its Casbin grouping rule in one transaction.
+// It returns false if neither existed.
+func (r *orgRepository) RevokeUserFunction(ctx context.Context, uf UserFunction, function string) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM user_functions WHERE user_id = $1 AND function_id = $2 AND unit_id = $3`,
+		uf.UserID, uf.FunctionID, uf.UnitID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	links, err := deleteRoleLink(ctx, tx, access.Subject(int64(uf.UserID)), function, access.Domain(uf.UnitID))
+	if err != nil {
+		return false, err
+	}
+	return rows+links > 0, tx.Commit()
+}
+
+// ListRoleLinks returns the grouping rules that assign a function to a user in an org unit,
+// resolved against the users, functions and units they name.
+func (r *orgRepository) ListRoleLinks(ctx context.Context) ([]RoleLink, error) {
+	links := []RoleLink{}
+	err := r.db.SelectContext(ctx, &links, `
+		SELECT cr.v0 AS subject, cr.v1 AS role, cr.v2 AS domain,
+		       u.id AS user_id, f.id AS function_id, ou.id AS unit_id
+		FROM `+casbinRuleTable+` cr
+		LEFT JOIN users u ON 'user:' || u.id = cr.v0
+		LEFT JOIN functions f ON f.name = cr.v1
+		LEFT JOIN organizational_units ou ON 'orgunit:' || ou.id = cr.v2
+		WHERE cr.ptype = 'g' AND cr.v0 LIKE 'user:%' AND cr.v2 LIKE 'orgunit:%'
+		ORDER BY cr.v0, cr.v2, cr.v1`)
+	return links, err
+}
+
+// ApplyReconciliation writes the fixes listed in a reconcile report in one transaction.
+// User functions recreated from Casbin rules have no rank.
+func (r *orgRepository) ApplyReconciliation(ctx context.Context, report ReconcileReport) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, uf := range report.AddedToCasbin {
+		if err := insertRoleLink(ctx, tx, uf.UserID, uf.FunctionName, uf.UnitID); err != nil {
+			return err
+		}
+	}
+	for _, link := range report.AddedToFunctions {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_functions (user_id, function_id, unit_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, function_id, unit_id) DO NOTHING`,
+			*link.UserID, *link.FunctionID, *link.UnitID); err != nil {
+			return err
+		}
+	}
+	for _, link := range report.RemovedFromCasbin {
+		if _, err := deleteRoleLink(ctx, tx, link.Subject, link.Role, link.Domain); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// casbinRuleTable is the table the access package's gorm adapter keeps Casbin rules in.
+const casbinRuleTable = "casbin_rule"
+
+// insertRoleLink adds g(user:<id>, function, orgunit:<id>) unless it is already stored.
+// Unused rule columns are empty strings, as the gorm adapter writes them.
+func insertRoleLink(ctx context.Context, tx *sqlx.Tx, userID int, function string, unitID int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO `+casbinRuleTable+` (ptype, v0, v1, v2, v3, v4, v5)
+		SELECT 'g', $1, $2, $3, '', '', ''
+		WHERE NOT EXISTS (
+			SELECT 1 FROM `+casbinRuleTable+` WHERE ptype = 'g' AND v0 = $1 AND v1 = $2 AND v2 = $3
+		)`, access.Subject(int64(userID)), function, access.Domain(unitID))
+	return err
+}
+
+func deleteRoleLink(ctx context.Context, tx *sqlx.Tx, sub, role, dom string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM `+casbinRuleTable+` WHERE ptype = 'g' AND v0 = $1 AND v1 = $2 AND v2 = $3`,
+		sub, role, dom)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const entitlementSelect = `
+	SELECT e.id, e.module, e.function_id, f.name AS function_name, e.unit_id, ou.name AS unit_name,
+	       e.created_by, e.created_at
+	FROM module_entitlements e
+	LEFT JOIN functions f ON f.id = e.function_id
+	LEFT JOIN organizational_units ou ON ou.id = e.unit_id`
+
+// ListModuleEntitlements returns every module grant ordered by module.
+func (r *orgRepository) ListModuleEntitlements(ctx context.Context) ([]ModuleEntitlement, error) {
+	entitlements := []ModuleEntitlement{}
+	err := r.db.SelectContext(ctx, &entitlements, entitlementSelect+` ORDER BY e.module, f.name, ou.name`)
+	return entitlements, err
+}
+
+// GetModuleEntitlement returns a single module grant.
+func (r *orgRepository) GetModuleEntitlement(ctx context.Context, id int) (ModuleEntitlement, error) {
+	var e ModuleEntitlement
+	err := r.db.GetContext(ctx, &e, entitlementSelect+` WHERE e.id = $1`, id)
+	return e, err
+}
+
+// CreateModuleEntitlement inserts a module grant and fills in its ID and creation time.
+func (r *orgRepository) CreateModuleEntitlement(ctx context.Context, e *ModuleEntitlement) error {
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO module_entitlements (module, function_id, unit_id, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		e.Module, e.FunctionID, e.UnitID, e.CreatedBy).Scan(&e.ID, &e.CreatedAt)
+	return mapUniqueViolation(err, ErrDuplicateEntitlement)
+}
+
+// DeleteModuleEntitlement removes a module grant. It returns sql.ErrNoRows if it does not exist.
+func (r *orgRepository) DeleteModuleEntitlement(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM module_entitlements WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+func mapUniqueViolation(err error, duplicate error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return duplicate
+	}
+	return err
+}
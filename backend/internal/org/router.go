@@ -0,0 +1,47 @@
+package org
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/internal/auth"
+)
+
+// RegisterRoutes sets up the HTTP endpoints for the organization structure and the function
+// catalogue, and the assignment of functions to users, under the given route group. Any authenticated
+// user may read the structure and catalogue, and their own functions; changes and module
+// entitlements are admin-only.
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
+	manage := auth.RequireRoles("admin")
+
+	units := rg.Group("/organizational_units")
+	units.GET("/tree", h.GetTree)
+	units.GET("/:id", h.GetUnit)
+	units.GET("/:id/ancestors", h.GetAncestors)
+	units.GET("/:id/descendants", h.GetDescendants)
+	units.POST("", manage, h.CreateUnit)
+	units.PATCH("/:id", manage, h.RenameUnit)
+	units.POST("/:id/move", manage, h.MoveUnit)
+	units.DELETE("/:id", manage, h.DeleteUnit)
+
+	functions := rg.Group("/functions")
+	functions.GET("", h.ListFunctions)
+	functions.GET("/:id", h.GetFunction)
+	functions.POST("", manage, h.CreateFunction)
+	functions.PUT("/:id", manage, h.UpdateFunction)
+	functions.DELETE("/:id", manage, h.DeleteFunction)
+	functions.POST("/:id/ranks", manage, h.CreateRank)
+	functions.PUT("/:id/ranks", manage, h.ReorderRanks)
+	functions.PATCH("/:id/ranks/:rank_id", manage, h.RenameRank)
+	functions.DELETE("/:id/ranks/:rank_id", manage, h.DeleteRank)
+	functions.GET("/:id/units/:unit_id/members", h.ListFunctionMembers)
+
+	assignments := rg.Group("/user_functions")
+	assignments.GET("/users/:user_id", h.ListUserFunctions)
+	assignments.POST("", manage, h.AssignFunction)
+	assignments.DELETE("", manage, h.RevokeFunction)
+	assignments.POST("/reconcile", manage, h.Reconcile)
+
+	entitlements := rg.Group("/module_entitlements", manage)
+	entitlements.GET("", h.ListModuleEntitlements)
+	entitlements.POST("", h.CreateModuleEntitlement)
+	entitlements.DELETE("/:id", h.DeleteModuleEntitlement)
+}
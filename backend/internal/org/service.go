@@ -0,0 +1,299 @@
+package org
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+var (
+	// ErrUnitNotFound is returned when no organizational unit matches the given ID.
+	ErrUnitNotFound = errors.New("organizational unit not found")
+	// ErrInvalidUnit is wrapped by validation errors.
+	ErrInvalidUnit = errors.New("invalid organizational unit")
+	// ErrUnitCycle is returned when moving a unit under itself or one of its descendants.
+	ErrUnitCycle = errors.New("a unit cannot be moved under itself or its descendants")
+	// ErrUnitInUse is wrapped by UnitInUseError.
+	ErrUnitInUse = errors.New("organizational unit is still in use")
+)
+
+// UnitInUseError explains why a unit cannot be deleted.
+type UnitInUseError struct {
+	Usage UnitUsage
+}
+
+func (e *UnitInUseError) Error() string {
+	var reasons []string
+	if e.Usage.Children > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d sub-units", e.Usage.Children))
+	}
+	if e.Usage.Members > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d members", e.Usage.Members))
+	}
+	if e.Usage.Functions > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d function assignments", e.Usage.Functions))
+	}
+	if e.Usage.CasbinDomain {
+		reasons = append(reasons, "access policies")
+	}
+	return fmt.Sprintf("%s: %s", ErrUnitInUse, strings.Join(reasons, ", "))
+}
+
+// Is makes errors.Is(err, ErrUnitInUse) match.
+func (e *UnitInUseError) Is(target error) bool {
+	return target == ErrUnitInUse
+}
+
+// AccessRules reports whether access-control rules refer to a unit or function, and keeps
+// the loaded role links in step with grouping rules the repository writes and with the
+// unit tree rules may be inherited along.
+type AccessRules interface {
+	UnitHasRules(unitID int) (bool, error)
+	FunctionHasRules(name string) (bool, error)
+	LinkRole(userID int, function string, unitID int) error
+	UnlinkRole(userID int, function string, unitID int) error
+	ReloadPolicies() error
+	UnitsChanged() error
+}
+
+// ServiceInterface defines the operations available on the organization structure.
+type ServiceInterface interface {
+	Tree(ctx context.Context) ([]*UnitNode, error)
+	GetUnit(ctx context.Context, id int) (UnitDetail, error)
+	Ancestors(ctx context.Context, id int) ([]OrganizationalUnit, error)
+	Descendants(ctx context.Context, id int) ([]OrganizationalUnit, error)
+	CreateUnit(ctx context.Context, req CreateUnitRequest, createdBy int64) (OrganizationalUnit, error)
+	RenameUnit(ctx context.Context, id int, name string) (OrganizationalUnit, error)
+	MoveUnit(ctx context.Context, id int, parentID *int) (OrganizationalUnit, error)
+	DeleteUnit(ctx context.Context, id int) error
+
+	ListFunctions(ctx context.Context) ([]Function, error)
+	GetFunction(ctx context.Context, id int) (Function, error)
+	CreateFunction(ctx context.Context, req FunctionRequest) (Function, error)
+	UpdateFunction(ctx context.Context, id int, req FunctionRequest) (Function, error)
+	DeleteFunction(ctx context.Context, id int) error
+	CreateRank(ctx context.Context, functionID int, name string) (Rank, error)
+	RenameRank(ctx context.Context, functionID, rankID int, name string) (Rank, error)
+	DeleteRank(ctx context.Context, functionID, rankID int) error
+	ReorderRanks(ctx context.Context, functionID int, rankIDs []int) ([]Rank, error)
+
+	AssignFunction(ctx context.Context, req AssignFunctionRequest) (UserFunctionDetail, error)
+	RevokeFunction(ctx context.Context, req RevokeFunctionRequest) error
+	ListUserFunctions(ctx context.Context, userID int) ([]UserFunctionDetail, error)
+	ListFunctionMembers(ctx context.Context, functionID, unitID int) ([]UserFunctionDetail, error)
+	Reconcile(ctx context.Context, dryRun bool) (ReconcileReport, error)
+
+	ListModuleEntitlements(ctx context.Context) ([]ModuleEntitlement, error)
+	CreateModuleEntitlement(ctx context.Context, req ModuleEntitlementRequest, createdBy int64) (ModuleEntitlement, error)
+	DeleteModuleEntitlement(ctx context.Context, id int) error
+}
+
+// orgService implements the ServiceInterface using a data repository.
+type orgService struct {
+	repo  Repository
+	rules AccessRules
+}
+
+// NewService creates a new instance of ServiceInterface. rules guards deletion of units
+// and functions that access policies still refer to, and receives function assignments.
+func NewService(repo Repository, rules AccessRules) ServiceInterface {
+	return &orgService{repo: repo, rules: rules}
+}
+
+// Tree returns the whole organization as nested root units.
+func (s *orgService) Tree(ctx context.Context) ([]*UnitNode, error) {
+	units, err := s.repo.ListUnits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(units, nil), nil
+}
+
+// GetUnit returns a unit with its ancestors and nested descendants.
+func (s *orgService) GetUnit(ctx context.Context, id int) (UnitDetail, error) {
+	u, err := s.getUnit(ctx, id)
+	if err != nil {
+		return UnitDetail{}, err
+	}
+	ancestors, err := s.repo.ListAncestors(ctx, id)
+	if err != nil {
+		return UnitDetail{}, err
+	}
+	descendants, err := s.repo.ListDescendants(ctx, id)
+	if err != nil {
+		return UnitDetail{}, err
+	}
+	return UnitDetail{OrganizationalUnit: u, Ancestors: ancestors, Children: BuildTree(descendants, &id)}, nil
+}
+
+// Ancestors returns the units above id, root first.
+func (s *orgService) Ancestors(ctx context.Context, id int) ([]OrganizationalUnit, error) {
+	if _, err := s.getUnit(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAncestors(ctx, id)
+}
+
+// Descendants returns every unit below id, breadth first.
+func (s *orgService) Descendants(ctx context.Context, id int) ([]OrganizationalUnit, error) {
+	if _, err := s.getUnit(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDescendants(ctx, id)
+}
+
+// CreateUnit adds a unit below an optional parent of a higher level.
+func (s *orgService) CreateUnit(ctx context.Context, req CreateUnitRequest, createdBy int64) (OrganizationalUnit, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return OrganizationalUnit{}, fmt.Errorf("%w: name is required", ErrInvalidUnit)
+	}
+	unitType := strings.ToLower(strings.TrimSpace(req.Type))
+	if _, ok := unitLevels[unitType]; !ok {
+		return OrganizationalUnit{}, fmt.Errorf("%w: unknown type %q", ErrInvalidUnit, req.Type)
+	}
+	if err := s.checkParent(ctx, unitType, req.ParentID); err != nil {
+		return OrganizationalUnit{}, err
+	}
+
+	u := OrganizationalUnit{Name: name, Type: unitType, ParentID: req.ParentID}
+	if createdBy != 0 {
+		creator := int(createdBy)
+		u.CreatedBy = &creator
+	}
+	if err := s.repo.CreateUnit(ctx, &u); err != nil {
+		return OrganizationalUnit{}, err
+	}
+	s.unitsChanged()
+	return u, nil
+}
+
+// RenameUnit changes a unit's name.
+func (s *orgService) RenameUnit(ctx context.Context, id int, name string) (OrganizationalUnit, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return OrganizationalUnit{}, fmt.Errorf("%w: name is required", ErrInvalidUnit)
+	}
+	if err := s.repo.RenameUnit(ctx, id, name); errors.Is(err, sql.ErrNoRows) {
+		return OrganizationalUnit{}, ErrUnitNotFound
+	} else if err != nil {
+		return OrganizationalUnit{}, err
+	}
+	return s.getUnit(ctx, id)
+}
+
+// MoveUnit re-parents a unit together with its subtree.
+func (s *orgService) MoveUnit(ctx context.Context, id int, parentID *int) (OrganizationalUnit, error) {
+	u, err := s.getUnit(ctx, id)
+	if err != nil {
+		return OrganizationalUnit{}, err
+	}
+	if parentID != nil && *parentID == id {
+		return OrganizationalUnit{}, ErrUnitCycle
+	}
+	if err := s.checkParent(ctx, u.Type, parentID); err != nil {
+		return OrganizationalUnit{}, err
+	}
+	moved, err := s.repo.MoveUnit(ctx, id, parentID)
+	if err != nil {
+		return OrganizationalUnit{}, err
+	}
+	if !moved {
+		return OrganizationalUnit{}, ErrUnitCycle
+	}
+	s.unitsChanged()
+	return s.getUnit(ctx, id)
+}
+
+// DeleteUnit removes a unit nothing refers to any more.
+func (s *orgService) DeleteUnit(ctx context.Context, id int) error {
+	if _, err := s.getUnit(ctx, id); err != nil {
+		return err
+	}
+	usage, err := s.repo.GetUnitUsage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if usage.CasbinDomain, err = s.rules.UnitHasRules(id); err != nil {
+		return fmt.Errorf("failed to check access policies: %w", err)
+	}
+	if usage.InUse() {
+		return &UnitInUseError{Usage: usage}
+	}
+
+	deleted, err := s.repo.DeleteUnit(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		// A member or sub-unit was added since the usage check.
+		usage, err := s.repo.GetUnitUsage(ctx, id)
+		if err != nil {
+			return err
+		}
+		return &UnitInUseError{Usage: usage}
+	}
+	s.unitsChanged()
+	return nil
+}
+
+// unitsChanged tells the access rules that the tree changed.
+func (s *orgService) unitsChanged() {
+	if err := s.rules.UnitsChanged(); err != nil {
+		log.Printf("⚠️ Failed to refresh the organizational unit tree for access rules: %v", err)
+	}
+}
+
+func (s *orgService) getUnit(ctx context.Context, id int) (OrganizationalUnit, error) {
+	u, err := s.repo.GetUnit(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return OrganizationalUnit{}, ErrUnitNotFound
+	}
+	return u, err
+}
+
+// checkParent verifies that the parent exists and sits at a higher level than unitType.
+func (s *orgService) checkParent(ctx context.Context, unitType string, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	parent, err := s.repo.GetUnit(ctx, *parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: parent unit %d does not exist", ErrInvalidUnit, *parentID)
+	}
+	if err != nil {
+		return err
+	}
+	if unitLevels[parent.Type] >= unitLevels[unitType] {
+		return fmt.Errorf("%w: a %s cannot be placed under a %s", ErrInvalidUnit, unitType, parent.Type)
+	}
+	return nil
+}
+
+// BuildTree nests units under their parents, returning the children of root
+// (the top-level units when root is nil). Units whose parent is missing from
+// the list are treated as top-level only when root is nil.
+func BuildTree(units []OrganizationalUnit, root *int) []*UnitNode {
+	nodes := make(map[int]*UnitNode, len(units))
+	for _, u := range units {
+		nodes[u.ID] = &UnitNode{OrganizationalUnit: u, Children: []*UnitNode{}}
+	}
+
+	top := []*UnitNode{}
+	for _, u := range units {
+		node := nodes[u.ID]
+		switch {
+		case root != nil && u.ParentID != nil && *u.ParentID == *root:
+			top = append(top, node)
+		case u.ParentID != nil && nodes[*u.ParentID] != nil:
+			parent := nodes[*u.ParentID]
+			parent.Children = append(parent.Children, node)
+		case root == nil:
+			top = append(top, node)
+		}
+	}
+	return top
+}
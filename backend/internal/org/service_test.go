@@ -0,0 +1,340 @@
+package org_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nomenarkt/lamina/internal/org"
+)
+
+type MockOrgRepo struct {
+	mock.Mock
+}
+
+func (m *MockOrgRepo) ListUnits(ctx context.Context) ([]org.OrganizationalUnit, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]org.OrganizationalUnit), args.Error(1)
+}
+
+func (m *MockOrgRepo) GetUnit(ctx context.Context, id int) (org.OrganizationalUnit, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(org.OrganizationalUnit), args.Error(1)
+}
+
+func (m *MockOrgRepo) CreateUnit(ctx context.Context, u *org.OrganizationalUnit) error {
+	return m.Called(ctx, u).Error(0)
+}
+
+func (m *MockOrgRepo) RenameUnit(ctx context.Context, id int, name string) error {
+	return m.Called(ctx, id, name).Error(0)
+}
+
+func (m *MockOrgRepo) MoveUnit(ctx context.Context, id int, parentID *int) (bool, error) {
+	args := m.Called(ctx, id, parentID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrgRepo) ListAncestors(ctx context.Context, id int) ([]org.OrganizationalUnit, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]org.OrganizationalUnit), args.Error(1)
+}
+
+func (m *MockOrgRepo) ListDescendants(ctx context.Context, id int) ([]org.OrganizationalUnit, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]org.OrganizationalUnit), args.Error(1)
+}
+
+func (m *MockOrgRepo) GetUnitUsage(ctx context.Context, id int) (org.UnitUsage, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(org.UnitUsage), args.Error(1)
+}
+
+func (m *MockOrgRepo) DeleteUnit(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrgRepo) ListFunctions(ctx context.Context) ([]org.Function, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]org.Function), args.Error(1)
+}
+
+func (m *MockOrgRepo) GetFunction(ctx context.Context, id int) (org.Function, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(org.Function), args.Error(1)
+}
+
+func (m *MockOrgRepo) GetFunctionByName(ctx context.Context, name string) (org.Function, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(org.Function), args.Error(1)
+}
+
+func (m *MockOrgRepo) CreateFunction(ctx context.Context, f *org.Function) error {
+	return m.Called(ctx, f).Error(0)
+}
+
+func (m *MockOrgRepo) UpdateFunction(ctx context.Context, f *org.Function) error {
+	return m.Called(ctx, f).Error(0)
+}
+
+func (m *MockOrgRepo) GetFunctionUsage(ctx context.Context, id int) (org.FunctionUsage, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(org.FunctionUsage), args.Error(1)
+}
+
+func (m *MockOrgRepo) DeleteFunction(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrgRepo) ListRanks(ctx context.Context) ([]org.Rank, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]org.Rank), args.Error(1)
+}
+
+func (m *MockOrgRepo) ListFunctionRanks(ctx context.Context, functionID int) ([]org.Rank, error) {
+	args := m.Called(ctx, functionID)
+	return args.Get(0).([]org.Rank), args.Error(1)
+}
+
+func (m *MockOrgRepo) GetRank(ctx context.Context, id int) (org.Rank, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(org.Rank), args.Error(1)
+}
+
+func (m *MockOrgRepo) CreateRank(ctx context.Context, r *org.Rank) error {
+	return m.Called(ctx, r).Error(0)
+}
+
+func (m *MockOrgRepo) RenameRank(ctx context.Context, id int, name string) error {
+	return m.Called(ctx, id, name).Error(0)
+}
+
+func (m *MockOrgRepo) DeleteRank(ctx context.Context, r org.Rank) error {
+	return m.Called(ctx, r).Error(0)
+}
+
+func (m *MockOrgRepo) ReorderRanks(ctx context.Context, functionID int, rankIDs []int) error {
+	return m.Called(ctx, functionID, rankIDs).Error(0)
+}
+
+func (m *MockOrgRepo) ListUserFunctions(ctx context.Context, userID int) ([]org.UserFunctionDetail, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]org.UserFunctionDetail), args.Error(1)
+}
+
+func (m *MockOrgRepo) ListFunctionMembers(ctx context.Context, functionID, unitID int) ([]org.UserFunctionDetail, error) {
+	args := m.Called(ctx, functionID, unitID)
+	return args.Get(0).([]org.UserFunctionDetail), args.Error(1)
+}
+
+func (m *MockOrgRepo) ListAllUserFunctions(ctx context.Context) ([]org.UserFunctionDetail, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]org.UserFunctionDetail), args.Error(1)
+}
+
+func (m *MockOrgRepo) GetUserFunction(ctx context.Context, userID, functionID, unitID int) (org.UserFunctionDetail, error) {
+	args := m.Called(ctx, userID, functionID, unitID)
+	return args.Get(0).(org.UserFunctionDetail), args.Error(1)
+}
+
+func (m *MockOrgRepo) AssignUserFunction(ctx context.Context, uf org.UserFunction, function string) error {
+	return m.Called(ctx, uf, function).Error(0)
+}
+
+func (m *MockOrgRepo) RevokeUserFunction(ctx context.Context, uf org.UserFunction, function string) (bool, error) {
+	args := m.Called(ctx, uf, function)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrgRepo) ListRoleLinks(ctx context.Context) ([]org.RoleLink, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]org.RoleLink), args.Error(1)
+}
+
+func (m *MockOrgRepo) ApplyReconciliation(ctx context.Context, report org.ReconcileReport) error {
+	return m.Called(ctx, report).Error(0)
+}
+
+func (m *MockOrgRepo) ListModuleEntitlements(ctx context.Context) ([]org.ModuleEntitlement, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]org.ModuleEntitlement), args.Error(1)
+}
+
+func (m *MockOrgRepo) GetModuleEntitlement(ctx context.Context, id int) (org.ModuleEntitlement, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(org.ModuleEntitlement), args.Error(1)
+}
+
+func (m *MockOrgRepo) CreateModuleEntitlement(ctx context.Context, e *org.ModuleEntitlement) error {
+	return m.Called(ctx, e).Error(0)
+}
+
+func (m *MockOrgRepo) DeleteModuleEntitlement(ctx context.Context, id int) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+// stubRules reports Casbin rules for the listed units and function names,
+// and records the role links loaded into and out of the enforcer and tree changes.
+type stubRules struct {
+	units       map[int]bool
+	functions   map[string]bool
+	links       *[]string
+	reloads     *int
+	treeChanges *int
+}
+
+func (s stubRules) UnitHasRules(unitID int) (bool, error) {
+	return s.units[unitID], nil
+}
+
+func (s stubRules) FunctionHasRules(name string) (bool, error) {
+	return s.functions[name], nil
+}
+
+func (s stubRules) LinkRole(userID int, function string, unitID int) error {
+	*s.links = append(*s.links, fmt.Sprintf("+%d:%s:%d", userID, function, unitID))
+	return nil
+}
+
+func (s stubRules) UnlinkRole(userID int, function string, unitID int) error {
+	*s.links = append(*s.links, fmt.Sprintf("-%d:%s:%d", userID, function, unitID))
+	return nil
+}
+
+func (s stubRules) ReloadPolicies() error {
+	*s.reloads++
+	return nil
+}
+
+func (s stubRules) UnitsChanged() error {
+	if s.treeChanges != nil {
+		*s.treeChanges++
+	}
+	return nil
+}
+
+func intPtr(v int) *int { return &v }
+
+func strPtr(v string) *string { return &v }
+
+func TestBuildTree_NestsUnits(t *testing.T) {
+	units := []org.OrganizationalUnit{
+		{ID: 1, Name: "Operations", Type: org.UnitTypeDirection},
+		{ID: 2, Name: "Flight Ops", Type: org.UnitTypeDepartment, ParentID: intPtr(1)},
+		{ID: 3, Name: "Crew Planning", Type: org.UnitTypeService, ParentID: intPtr(2)},
+		{ID: 4, Name: "Finance", Type: org.UnitTypeDirection},
+	}
+
+	tree := org.BuildTree(units, nil)
+	require.Len(t, tree, 2)
+	assert.Equal(t, "Operations", tree[0].Name)
+	require.Len(t, tree[0].Children, 1)
+	require.Len(t, tree[0].Children[0].Children, 1)
+	assert.Equal(t, 3, tree[0].Children[0].Children[0].ID)
+	assert.Empty(t, tree[1].Children)
+
+	subtree := org.BuildTree(units[1:3], intPtr(1))
+	require.Len(t, subtree, 1)
+	assert.Equal(t, 2, subtree[0].ID)
+}
+
+func TestService_CreateUnit_EnforcesTypeOrdering(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetUnit", mock.Anything, 3).Return(org.OrganizationalUnit{ID: 3, Type: org.UnitTypeService}, nil)
+
+	_, err := service.CreateUnit(context.Background(), org.CreateUnitRequest{Name: "Cargo", Type: "department", ParentID: intPtr(3)}, 1)
+	assert.ErrorIs(t, err, org.ErrInvalidUnit)
+	repo.AssertNotCalled(t, "CreateUnit", mock.Anything, mock.Anything)
+}
+
+func TestService_CreateUnit_UnderHigherLevelParent(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetUnit", mock.Anything, 1).Return(org.OrganizationalUnit{ID: 1, Type: org.UnitTypeDirection}, nil)
+	repo.On("CreateUnit", mock.Anything, mock.MatchedBy(func(u *org.OrganizationalUnit) bool {
+		return u.Name == "Cargo" && u.Type == org.UnitTypeSection && *u.ParentID == 1 && *u.CreatedBy == 9
+	})).Return(nil)
+
+	_, err := service.CreateUnit(context.Background(), org.CreateUnitRequest{Name: " Cargo ", Type: "Section", ParentID: intPtr(1)}, 9)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestService_MoveUnit_PreventsCycles(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetUnit", mock.Anything, 1).Return(org.OrganizationalUnit{ID: 1, Type: org.UnitTypeDepartment}, nil)
+	repo.On("GetUnit", mock.Anything, 5).Return(org.OrganizationalUnit{ID: 5, Type: org.UnitTypeDirection, ParentID: intPtr(1)}, nil)
+
+	_, err := service.MoveUnit(context.Background(), 1, intPtr(1))
+	assert.ErrorIs(t, err, org.ErrUnitCycle)
+
+	// Rows created before type ordering was enforced may nest a direction under a department;
+	// the repository still refuses to move a unit under its own descendant.
+	repo.On("MoveUnit", mock.Anything, 1, intPtr(5)).Return(false, nil)
+	_, err = service.MoveUnit(context.Background(), 1, intPtr(5))
+	assert.ErrorIs(t, err, org.ErrUnitCycle)
+}
+
+func TestService_MoveUnit_ToRoot(t *testing.T) {
+	repo := new(MockOrgRepo)
+	treeChanges := 0
+	service := org.NewService(repo, stubRules{treeChanges: &treeChanges})
+
+	repo.On("GetUnit", mock.Anything, 2).Return(org.OrganizationalUnit{ID: 2, Type: org.UnitTypeDepartment}, nil)
+	repo.On("MoveUnit", mock.Anything, 2, (*int)(nil)).Return(true, nil)
+
+	_, err := service.MoveUnit(context.Background(), 2, nil)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	assert.Equal(t, 1, treeChanges)
+}
+
+func TestService_DeleteUnit_BlockedByMembersAndPolicies(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{units: map[int]bool{4: true}})
+
+	repo.On("GetUnit", mock.Anything, 4).Return(org.OrganizationalUnit{ID: 4, Type: org.UnitTypeSection}, nil)
+	repo.On("GetUnitUsage", mock.Anything, 4).Return(org.UnitUsage{Members: 2}, nil)
+
+	err := service.DeleteUnit(context.Background(), 4)
+	assert.ErrorIs(t, err, org.ErrUnitInUse)
+	var inUse *org.UnitInUseError
+	if assert.ErrorAs(t, err, &inUse) {
+		assert.Equal(t, 2, inUse.Usage.Members)
+		assert.True(t, inUse.Usage.CasbinDomain)
+	}
+	repo.AssertNotCalled(t, "DeleteUnit", mock.Anything, mock.Anything)
+}
+
+func TestService_DeleteUnit_Unused(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetUnit", mock.Anything, 6).Return(org.OrganizationalUnit{ID: 6}, nil)
+	repo.On("GetUnitUsage", mock.Anything, 6).Return(org.UnitUsage{}, nil)
+	repo.On("DeleteUnit", mock.Anything, 6).Return(true, nil)
+
+	assert.NoError(t, service.DeleteUnit(context.Background(), 6))
+}
+
+func TestService_GetUnit_NotFound(t *testing.T) {
+	repo := new(MockOrgRepo)
+	service := org.NewService(repo, stubRules{})
+
+	repo.On("GetUnit", mock.Anything, 99).Return(org.OrganizationalUnit{}, sql.ErrNoRows)
+
+	_, err := service.GetUnit(context.Background(), 99)
+	assert.ErrorIs(t, err, org.ErrUnitNotFound)
+}
@@ -3,8 +3,58 @@ package org
 
 // UserFunction associates a user with a specific function within the organization.
 type UserFunction struct {
-	UserID     int  `db:"user_id"`
-	FunctionID int  `db:"function_id"`
-	UnitID     int  `db:"unit_id"`
-	RankID     *int `db:"rank_id"`
+	UserID     int  `db:"user_id" json:"user_id"`
+	FunctionID int  `db:"function_id" json:"function_id"`
+	UnitID     int  `db:"unit_id" json:"unit_id"`
+	RankID     *int `db:"rank_id" json:"rank_id"`
+}
+
+// UserFunctionDetail is a user function with the names of the user, function, unit and rank.
+type UserFunctionDetail struct {
+	UserFunction
+	UserName     *string `db:"user_name" json:"user_name"`
+	UserEmail    string  `db:"user_email" json:"user_email"`
+	FunctionName string  `db:"function_name" json:"function_name"`
+	UnitName     string  `db:"unit_name" json:"unit_name"`
+	RankName     *string `db:"rank_name" json:"rank_name"`
+}
+
+// AssignFunctionRequest grants a function, optionally at a rank, to a user in an org unit.
+type AssignFunctionRequest struct {
+	UserID   int    `json:"user_id" binding:"required"`
+	Function string `json:"function" binding:"required"` // function name, i.e. the Casbin role
+	UnitID   int    `json:"unit_id" binding:"required"`
+	RankID   *int   `json:"rank_id"`
+}
+
+// RevokeFunctionRequest withdraws a function from a user in an org unit.
+type RevokeFunctionRequest struct {
+	UserID   int    `json:"user_id" binding:"required"`
+	Function string `json:"function" binding:"required"`
+	UnitID   int    `json:"unit_id" binding:"required"`
+}
+
+// RoleLink is a Casbin grouping rule g(user:<id>, function, orgunit:<id>) as stored in casbin_rule,
+// with the IDs it resolves to. IDs are nil when the user, function or unit does not exist.
+type RoleLink struct {
+	Subject    string `db:"subject" json:"subject"`
+	Role       string `db:"role" json:"role"`
+	Domain     string `db:"domain" json:"domain"`
+	UserID     *int   `db:"user_id" json:"user_id"`
+	FunctionID *int   `db:"function_id" json:"function_id"`
+	UnitID     *int   `db:"unit_id" json:"unit_id"`
+}
+
+// Resolvable reports whether the rule refers to an existing user, function and unit.
+func (l RoleLink) Resolvable() bool {
+	return l.UserID != nil && l.FunctionID != nil && l.UnitID != nil
+}
+
+// ReconcileReport lists the drift found between user_functions and the Casbin grouping rules.
+// Unless DryRun is set, the drift has been fixed.
+type ReconcileReport struct {
+	DryRun            bool                 `json:"dry_run"`
+	AddedToCasbin     []UserFunctionDetail `json:"added_to_casbin"`     // user functions without a rule
+	AddedToFunctions  []RoleLink           `json:"added_to_functions"`  // rules without a user function
+	RemovedFromCasbin []RoleLink           `json:"removed_from_casbin"` // rules naming unknown users, functions or units
 }
@@ -0,0 +1,157 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/nomenarkt/lamina/internal/audit"
+	"github.com/nomenarkt/lamina/internal/user"
+)
+
+// auditPageSize is the number of audit entries read per query when collecting an export.
+const auditPageSize = 500
+
+// exportFile is one JSON document of an export archive.
+type exportFile struct {
+	name    string
+	records int
+	body    any
+}
+
+// WriteExport writes the personal data covered by an approved, unexpired export request to w as
+// a ZIP archive of JSON files. The data is collected before anything is written, so a failed
+// lookup leaves w untouched.
+func (s *Service) WriteExport(ctx context.Context, id int64, w io.Writer) error {
+	r, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if r.Kind != KindExport || r.Status != StatusApproved || r.ExpiresAt == nil || !s.now().Before(*r.ExpiresAt) {
+		return ErrExportUnavailable
+	}
+
+	files, err := s.collect(ctx, r.UserID)
+	if err != nil {
+		return err
+	}
+	manifest := Manifest{RequestID: r.ID, UserID: r.UserID, GeneratedAt: s.now().UTC(), Files: map[string]int{}}
+	for _, f := range files {
+		manifest.Files[f.name] = f.records
+	}
+	files = append([]exportFile{{name: "manifest.json", body: manifest}}, files...)
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: manifest.GeneratedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.body); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.Event{
+		Action:     "data_request.download",
+		TargetType: "data_request",
+		TargetID:   strconv.FormatInt(r.ID, 10),
+		After:      manifest,
+	})
+	return nil
+}
+
+func (s *Service) collect(ctx context.Context, userID int64) ([]exportFile, error) {
+	u, err := s.profiles.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	children, err := s.profiles.ListChildren(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	functions, err := s.repo.ListFunctions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	units, err := s.repo.ListUnits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.repo.ListAssignments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	qualifications, err := s.repo.ListQualifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := s.repo.List(ctx, Filter{UserID: &userID})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.auditEntries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return []exportFile{
+		{name: "user.json", records: 1, body: profileOf(u)},
+		{name: "children.json", records: len(children), body: children},
+		{name: "functions.json", records: len(functions), body: functions},
+		{name: "org_units.json", records: len(units), body: units},
+		{name: "crew_assignments.json", records: len(assignments), body: assignments},
+		{name: "qualifications.json", records: len(qualifications), body: qualifications},
+		{name: "data_requests.json", records: len(requests), body: requests},
+		{name: "audit_log.json", records: len(entries), body: entries},
+	}, nil
+}
+
+// auditEntries returns every audit entry about the user, newest first.
+func (s *Service) auditEntries(ctx context.Context, userID int64) ([]audit.Entry, error) {
+	entries := []audit.Entry{}
+	filter := audit.Filter{TargetType: "user", TargetID: strconv.FormatInt(userID, 10), Limit: auditPageSize}
+	for {
+		page, total, err := s.auditLog.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page...)
+		if len(page) == 0 || len(entries) >= total {
+			return entries, nil
+		}
+		filter.Offset += len(page)
+	}
+}
+
+func profileOf(u *user.User) Profile {
+	return Profile{
+		ID:                    u.ID,
+		Email:                 u.Email,
+		FullName:              u.FullName,
+		Role:                  u.Role,
+		Status:                u.Status,
+		UserType:              u.UserType,
+		EmployeeID:            u.EmployeeID,
+		Phone:                 u.Phone,
+		Address:               u.Address,
+		Sex:                   u.Sex,
+		Birthday:              u.Birthday,
+		MaritalStatus:         u.MaritalStatus,
+		SpouseName:            u.SpouseName,
+		HasChildren:           u.HasChildren,
+		NumberOfChildren:      u.NumberOfChildren,
+		NationalID:            u.NationalID,
+		EmergencyContactName:  u.EmergencyContactName,
+		EmergencyContactPhone: u.EmergencyContactPhone,
+		AccessExpiresAt:       u.AccessExpiresAt,
+		CreatedAt:             u.CreatedAt,
+	}
+}
@@ -0,0 +1,206 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/common/utils"
+)
+
+// Handler defines the HTTP handler for data subject requests.
+type Handler struct {
+	service ServiceInterface
+}
+
+// NewHandler creates a new Handler instance for the privacy service.
+func NewHandler(s ServiceInterface) *Handler {
+	return &Handler{service: s}
+}
+
+// CreateOwn files a request about the caller.
+// POST /user/data-requests
+func (h *Handler) CreateOwn(c *gin.Context) {
+	userID, ok := utils.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	req.UserID = userID
+	r, err := h.service.Create(c.Request.Context(), req, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, r)
+}
+
+// ListOwn lists the caller's requests.
+// GET /user/data-requests
+func (h *Handler) ListOwn(c *gin.Context) {
+	userID, ok := utils.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	requests, err := h.service.List(c.Request.Context(), Filter{UserID: &userID})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+// DownloadOwn sends the ZIP archive of one of the caller's approved exports.
+// GET /user/data-requests/:id/export
+func (h *Handler) DownloadOwn(c *gin.Context) {
+	userID, ok := utils.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	r, err := h.service.Get(c.Request.Context(), id)
+	if err == nil && r.UserID != userID {
+		err = ErrRequestNotFound
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.download(c, r)
+}
+
+// List lists requests for review.
+// GET /admin/data-requests?status=pending&kind=erasure&user_id=7
+func (h *Handler) List(c *gin.Context) {
+	f := Filter{Status: c.Query("status"), Kind: c.Query("kind")}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'user_id'"})
+			return
+		}
+		f.UserID = &id
+	}
+	requests, err := h.service.List(c.Request.Context(), f)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+// Create files a request on a user's behalf, e.g. for someone who has left and can no longer sign in.
+// POST /admin/data-requests
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	adminID, _ := utils.GetUserIDFromContext(c)
+	r, err := h.service.Create(c.Request.Context(), req, adminID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, r)
+}
+
+// Approve approves a pending request; erasures are carried out immediately.
+// POST /admin/data-requests/:id/approve
+func (h *Handler) Approve(c *gin.Context) {
+	h.review(c, h.service.Approve)
+}
+
+// Reject rejects a pending request.
+// POST /admin/data-requests/:id/reject
+func (h *Handler) Reject(c *gin.Context) {
+	h.review(c, h.service.Reject)
+}
+
+// Download sends the ZIP archive of an approved export, to pass on to the user.
+// GET /admin/data-requests/:id/export
+func (h *Handler) Download(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	r, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.download(c, r)
+}
+
+func (h *Handler) review(c *gin.Context, decide func(ctx context.Context, id, reviewerID int64, note string) (Request, error)) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+	var body ReviewRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+			return
+		}
+	}
+	reviewerID, _ := utils.GetUserIDFromContext(c)
+	r, err := decide(c.Request.Context(), id, reviewerID, body.Note)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+func (h *Handler) download(c *gin.Context, r Request) {
+	var buf bytes.Buffer
+	if err := h.service.WriteExport(c.Request.Context(), r.ID, &buf); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="personal-data-%d-%d.zip"`, r.UserID, r.ID))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+func idParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrSelfReview):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRequestNotFound), errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDuplicateRequest), errors.Is(err, ErrNotPending), errors.Is(err, ErrExportUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Data subject request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
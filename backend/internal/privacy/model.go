@@ -0,0 +1,137 @@
+// Package privacy handles data subject requests: personal data exports and erasures that an
+// administrator approves before they are carried out.
+package privacy
+
+import "time"
+
+// Request kinds.
+const (
+	KindExport  = "export"
+	KindErasure = "erasure"
+)
+
+// Request statuses. Approved exports can be downloaded until they expire; approving an erasure
+// carries it out and completes the request.
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusCompleted = "completed"
+)
+
+// ErasedStatus is the users.status of an anonymised user; they can no longer sign in.
+const ErasedStatus = "erased"
+
+// Request is a data subject request.
+type Request struct {
+	ID          int64      `db:"id" json:"id"`
+	UserID      int64      `db:"user_id" json:"user_id"`
+	Kind        string     `db:"kind" json:"kind"`
+	Status      string     `db:"status" json:"status"`
+	Reason      string     `db:"reason" json:"reason"`
+	RequestedBy *int64     `db:"requested_by" json:"requested_by"`
+	ReviewedBy  *int64     `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewNote  string     `db:"review_note" json:"review_note,omitempty"`
+	ReviewedAt  *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreateRequest is the payload for filing a request. UserID is only read from administrators
+// filing on someone's behalf; users always file for themselves.
+type CreateRequest struct {
+	UserID int64  `json:"user_id"`
+	Kind   string `json:"kind" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// ReviewRequest is the payload for approving or rejecting a request.
+type ReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// Filter narrows the request list. Zero values are ignored.
+type Filter struct {
+	UserID *int64
+	Kind   string
+	Status string
+}
+
+// Profile is the user row as exported. Credentials and tokens are left out.
+type Profile struct {
+	ID                    int64      `json:"id"`
+	Email                 string     `json:"email"`
+	FullName              *string    `json:"full_name"`
+	Role                  string     `json:"role"`
+	Status                string     `json:"status"`
+	UserType              string     `json:"user_type"`
+	EmployeeID            *int       `json:"employee_id"`
+	Phone                 *string    `json:"phone"`
+	Address               *string    `json:"address"`
+	Sex                   *string    `json:"sex"`
+	Birthday              *string    `json:"birthday"`
+	MaritalStatus         *bool      `json:"marital_status"`
+	SpouseName            *string    `json:"spouse_name"`
+	HasChildren           *bool      `json:"has_children"`
+	NumberOfChildren      *int       `json:"number_of_children"`
+	NationalID            *string    `json:"national_id"`
+	EmergencyContactName  *string    `json:"emergency_contact_name"`
+	EmergencyContactPhone *string    `json:"emergency_contact_phone"`
+	AccessExpiresAt       *time.Time `json:"access_expires_at"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+// FunctionRecord is a function the user holds in an org unit.
+type FunctionRecord struct {
+	Function string  `db:"function" json:"function"`
+	UnitID   int64   `db:"unit_id" json:"unit_id"`
+	Unit     string  `db:"unit" json:"unit"`
+	Rank     *string `db:"rank" json:"rank"`
+}
+
+// UnitRecord is an org unit the user belongs to.
+type UnitRecord struct {
+	ID   int64  `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+	Type string `db:"type" json:"type"`
+}
+
+// AssignmentRecord is a flight the user was rostered on.
+type AssignmentRecord struct {
+	ID            int64      `db:"id" json:"id"`
+	FlightID      *int64     `db:"flight_id" json:"flight_id"`
+	FlightNumber  *string    `db:"flight_number" json:"flight_number"`
+	FlightDate    *string    `db:"flight_date" json:"flight_date"`
+	DepartureCode *string    `db:"departure_code" json:"departure_code"`
+	ArrivalCode   *string    `db:"arrival_code" json:"arrival_code"`
+	CrewRole      string     `db:"crew_role" json:"crew_role"`
+	InFunction    *bool      `db:"in_function" json:"in_function"`
+	PickupTime    *time.Time `db:"pickup_time" json:"pickup_time"`
+	CheckinTime   *time.Time `db:"checkin_time" json:"checkin_time"`
+	CheckoutTime  *time.Time `db:"checkout_time" json:"checkout_time"`
+	CreatedAt     *time.Time `db:"created_at" json:"created_at"`
+}
+
+// QualificationRecord is a qualification the user holds.
+type QualificationRecord struct {
+	ID               int64      `db:"id" json:"id"`
+	Code             string     `db:"code" json:"code"`
+	Name             string     `db:"name" json:"name"`
+	Reference        *string    `db:"reference" json:"reference"`
+	IssuingAuthority *string    `db:"issuing_authority" json:"issuing_authority"`
+	IssuedAt         string     `db:"issued_at" json:"issued_at"`
+	ExpiresAt        *string    `db:"expires_at" json:"expires_at"`
+	Notes            *string    `db:"notes" json:"notes"`
+	DocumentName     *string    `db:"document_name" json:"document_name"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        *time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Manifest describes the files of an export archive.
+type Manifest struct {
+	RequestID   int64          `json:"request_id"`
+	UserID      int64          `json:"user_id"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Files       map[string]int `json:"files"` // file name → number of records
+}
@@ -0,0 +1,271 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/nomenarkt/lamina/internal/access"
+)
+
+// Repository defines the storage of data subject requests and the personal data they cover.
+type Repository interface {
+	Create(ctx context.Context, r *Request) error
+	Get(ctx context.Context, id int64) (Request, error)
+	List(ctx context.Context, f Filter) ([]Request, error)
+	// Review moves a pending request to status. It returns false if the request was no longer pending.
+	Review(ctx context.Context, id int64, status string, reviewerID int64, note string, expiresAt *time.Time) (bool, error)
+	// Erase anonymises the request's user and completes the request in one transaction, returning
+	// the storage key of the user's profile picture, if any. It returns false if the request was
+	// no longer pending.
+	Erase(ctx context.Context, r Request, reviewerID int64, note string) (pictureKey *string, ok bool, err error)
+
+	ListFunctions(ctx context.Context, userID int64) ([]FunctionRecord, error)
+	ListUnits(ctx context.Context, userID int64) ([]UnitRecord, error)
+	ListAssignments(ctx context.Context, userID int64) ([]AssignmentRecord, error)
+	ListQualifications(ctx context.Context, userID int64) ([]QualificationRecord, error)
+}
+
+// privacyRepository is a concrete implementation of the Repository interface.
+type privacyRepository struct {
+	db *sqlx.DB
+}
+
+// NewRepository returns a new instance of a privacy Repository.
+func NewRepository(db *sqlx.DB) Repository {
+	return &privacyRepository{db: db}
+}
+
+const requestColumns = `id, user_id, kind, status, reason, requested_by, reviewed_by, review_note,
+	reviewed_at, expires_at, completed_at, created_at`
+
+// Create stores a new pending request and fills in its ID, status and creation time.
+func (r *privacyRepository) Create(ctx context.Context, req *Request) error {
+	err := r.db.GetContext(ctx, req, `
+		INSERT INTO data_subject_requests (user_id, kind, reason, requested_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+requestColumns,
+		req.UserID, req.Kind, req.Reason, req.RequestedBy)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23503":
+			return ErrUserNotFound
+		case "23505":
+			return ErrDuplicateRequest
+		}
+	}
+	return err
+}
+
+// Get returns a request by ID.
+func (r *privacyRepository) Get(ctx context.Context, id int64) (Request, error) {
+	var req Request
+	err := r.db.GetContext(ctx, &req, `SELECT `+requestColumns+` FROM data_subject_requests WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Request{}, ErrRequestNotFound
+	}
+	return req, err
+}
+
+// List returns the requests matching the filter, newest first.
+func (r *privacyRepository) List(ctx context.Context, f Filter) ([]Request, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.UserID != nil {
+		add("user_id = $%d", *f.UserID)
+	}
+	if f.Kind != "" {
+		add("kind = $%d", f.Kind)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	query := `SELECT ` + requestColumns + ` FROM data_subject_requests`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	requests := []Request{}
+	err := r.db.SelectContext(ctx, &requests, query, args...)
+	return requests, err
+}
+
+// Review moves a pending request to status.
+func (r *privacyRepository) Review(ctx context.Context, id int64, status string, reviewerID int64, note string, expiresAt *time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE data_subject_requests
+		SET status = $2, reviewed_by = $3, review_note = $4, reviewed_at = now(), expires_at = $5
+		WHERE id = $1 AND status = 'pending'
+	`, id, status, reviewerID, note, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Erase anonymises the user in place rather than deleting the row, so crew assignments and
+// other references keep pointing at it. Credentials, sessions, dependents, memberships,
+// qualifications and undelivered email go; flight history stays. The audit log is append-only
+// and is left as is.
+func (r *privacyRepository) Erase(ctx context.Context, req Request, reviewerID int64, note string) (*string, bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE data_subject_requests
+		SET status = 'completed', reviewed_by = $2, review_note = $3, reviewed_at = now(), completed_at = now()
+		WHERE id = $1 AND status = 'pending'
+	`, req.ID, reviewerID, note)
+	if err != nil {
+		return nil, false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return nil, false, err
+	}
+
+	var old struct {
+		Email      string  `db:"email"`
+		PictureKey *string `db:"profile_picture_key"`
+	}
+	if err := tx.GetContext(ctx, &old, `
+		SELECT email, profile_picture_key FROM users WHERE id = $1 FOR UPDATE
+	`, req.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, ErrUserNotFound
+		}
+		return nil, false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET
+			email = 'erased-' || id || '@erased.invalid',
+			full_name = 'Erased user',
+			password_hash = '',
+			status = $2,
+			confirmation_token = NULL,
+			company_id = NULL,
+			phone = NULL,
+			address = NULL,
+			profile_picture_url = NULL,
+			profile_picture_key = NULL,
+			sex = NULL,
+			birthday = NULL,
+			marital_status = NULL,
+			spouse_name = NULL,
+			has_children = FALSE,
+			number_of_children = 0,
+			national_id = NULL,
+			national_id_bidx = NULL,
+			emergency_contact_name = NULL,
+			emergency_contact_phone = NULL
+		WHERE id = $1
+	`, req.UserID, ErasedStatus); err != nil {
+		return nil, false, err
+	}
+
+	for _, table := range []string{
+		"user_children",
+		"sessions", // refresh tokens go with their session
+		"refresh_tokens",
+		"password_reset_tokens",
+		"user_mfa",
+		"mfa_recovery_codes",
+		"roster_feed_tokens",
+		"user_qualifications",
+		"user_organizational_units",
+		"user_functions",
+	} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, req.UserID); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM casbin_rule WHERE ptype = 'g' AND v0 = $1
+	`, access.Subject(req.UserID)); err != nil {
+		return nil, false, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_outbox WHERE recipient = $1`, old.Email); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return old.PictureKey, true, nil
+}
+
+// ListFunctions returns the functions the user holds, by unit.
+func (r *privacyRepository) ListFunctions(ctx context.Context, userID int64) ([]FunctionRecord, error) {
+	records := []FunctionRecord{}
+	err := r.db.SelectContext(ctx, &records, `
+		SELECT f.name AS function, ou.id AS unit_id, ou.name AS unit, rk.name AS rank
+		FROM user_functions uf
+		JOIN functions f ON f.id = uf.function_id
+		JOIN organizational_units ou ON ou.id = uf.unit_id
+		LEFT JOIN ranks rk ON rk.id = uf.rank_id
+		WHERE uf.user_id = $1
+		ORDER BY ou.name, f.name
+	`, userID)
+	return records, err
+}
+
+// ListUnits returns the org units the user is a member of.
+func (r *privacyRepository) ListUnits(ctx context.Context, userID int64) ([]UnitRecord, error) {
+	records := []UnitRecord{}
+	err := r.db.SelectContext(ctx, &records, `
+		SELECT ou.id, ou.name, ou.type
+		FROM user_organizational_units uou
+		JOIN organizational_units ou ON ou.id = uou.unit_id
+		WHERE uou.user_id = $1
+		ORDER BY ou.name, ou.id
+	`, userID)
+	return records, err
+}
+
+// ListAssignments returns the flights the user was rostered on, oldest first.
+func (r *privacyRepository) ListAssignments(ctx context.Context, userID int64) ([]AssignmentRecord, error) {
+	records := []AssignmentRecord{}
+	err := r.db.SelectContext(ctx, &records, `
+		SELECT ca.id, ca.flight_id, f.flight_number, f.flight_date::text AS flight_date,
+		       f.departure_code, f.arrival_code, ca.crew_role, ca.in_function,
+		       ca.pickup_time, ca.checkin_time, ca.checkout_time, ca.created_at
+		FROM crew_assignments ca
+		LEFT JOIN flights f ON f.id = ca.flight_id
+		WHERE ca.crew_id = $1
+		ORDER BY ca.checkin_time NULLS LAST, ca.id
+	`, userID)
+	return records, err
+}
+
+// ListQualifications returns the user's qualifications.
+func (r *privacyRepository) ListQualifications(ctx context.Context, userID int64) ([]QualificationRecord, error) {
+	records := []QualificationRecord{}
+	err := r.db.SelectContext(ctx, &records, `
+		SELECT uq.id, qt.code, qt.name, uq.reference, uq.issuing_authority,
+		       uq.issued_at::text AS issued_at, uq.expires_at::text AS expires_at,
+		       uq.notes, uq.document_name, uq.created_at, uq.updated_at
+		FROM user_qualifications uq
+		JOIN qualification_types qt ON qt.id = uq.qualification_type_id
+		WHERE uq.user_id = $1
+		ORDER BY uq.issued_at, uq.id
+	`, userID)
+	return records, err
+}
@@ -0,0 +1,22 @@
+package privacy
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/internal/auth"
+)
+
+// RegisterRoutes sets up the data subject request endpoints under the given route group.
+// Users file and download their own requests; administrators review them.
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
+	own := rg.Group("/user/data-requests")
+	own.GET("", h.ListOwn)
+	own.POST("", h.CreateOwn)
+	own.GET("/:id/export", h.DownloadOwn)
+
+	admin := rg.Group("/admin/data-requests", auth.RequireRoles("admin"))
+	admin.GET("", h.List)
+	admin.POST("", h.Create)
+	admin.POST("/:id/approve", h.Approve)
+	admin.POST("/:id/reject", h.Reject)
+	admin.GET("/:id/export", h.Download)
+}
@@ -0,0 +1,232 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nomenarkt/lamina/common/storage"
+	"github.com/nomenarkt/lamina/internal/audit"
+	"github.com/nomenarkt/lamina/internal/user"
+)
+
+var (
+	// ErrRequestNotFound is returned when no request matches the given ID, or it belongs to someone else.
+	ErrRequestNotFound = errors.New("data subject request not found")
+	// ErrUserNotFound is returned when filing a request for an unknown user.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrDuplicateRequest is returned when the user already has a pending request of the same kind.
+	ErrDuplicateRequest = errors.New("a request of this kind is already pending")
+	// ErrInvalidRequest is wrapped by validation errors.
+	ErrInvalidRequest = errors.New("invalid data subject request")
+	// ErrNotPending is returned when reviewing a request that was already approved or rejected.
+	ErrNotPending = errors.New("request is no longer pending")
+	// ErrSelfReview is returned when an administrator reviews a request about themselves.
+	ErrSelfReview = errors.New("requests about yourself must be reviewed by another administrator")
+	// ErrExportUnavailable is returned when downloading an export that is not approved or has expired.
+	ErrExportUnavailable = errors.New("export is not available")
+)
+
+// defaultExportTTL is how long an approved export can be downloaded.
+const defaultExportTTL = 7 * 24 * time.Hour
+
+// ExportTTLFromEnv reads DATA_EXPORT_TTL, e.g. "72h"; the default is 7 days.
+func ExportTTLFromEnv() time.Duration {
+	if v := os.Getenv("DATA_EXPORT_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️ Invalid DATA_EXPORT_TTL %q, using %s", v, defaultExportTTL)
+	}
+	return defaultExportTTL
+}
+
+// Profiles reads the user row and dependents, decrypted; user.Repo implements it.
+type Profiles interface {
+	FindByID(ctx context.Context, id int64) (*user.User, error)
+	ListChildren(ctx context.Context, userID int64) ([]user.Child, error)
+}
+
+// AuditLog lists audit entries; audit.Repository implements it.
+type AuditLog interface {
+	List(ctx context.Context, filter audit.Filter) ([]audit.Entry, int, error)
+}
+
+// RoleIndex reloads the Casbin policies after an erasure removed a user's grouping rules;
+// access.PolicyIndex implements it.
+type RoleIndex interface {
+	ReloadPolicies() error
+}
+
+// ServiceInterface defines the data subject request operations.
+type ServiceInterface interface {
+	Create(ctx context.Context, req CreateRequest, requestedBy int64) (Request, error)
+	Get(ctx context.Context, id int64) (Request, error)
+	List(ctx context.Context, f Filter) ([]Request, error)
+	Approve(ctx context.Context, id, reviewerID int64, note string) (Request, error)
+	Reject(ctx context.Context, id, reviewerID int64, note string) (Request, error)
+	WriteExport(ctx context.Context, id int64, w io.Writer) error
+}
+
+// Service implements ServiceInterface.
+type Service struct {
+	repo      Repository
+	profiles  Profiles
+	auditLog  AuditLog
+	roles     RoleIndex
+	pictures  storage.Storage
+	exportTTL time.Duration
+	now       func() time.Time
+}
+
+// NewService creates a new privacy Service.
+func NewService(repo Repository, profiles Profiles, auditLog AuditLog, roles RoleIndex, exportTTL time.Duration) *Service {
+	return &Service{repo: repo, profiles: profiles, auditLog: auditLog, roles: roles, exportTTL: exportTTL, now: time.Now}
+}
+
+// SetPictureStorage configures the storage profile pictures are deleted from on erasure.
+func (s *Service) SetPictureStorage(st storage.Storage) {
+	s.pictures = st
+}
+
+// Create files a pending request for req.UserID.
+func (s *Service) Create(ctx context.Context, req CreateRequest, requestedBy int64) (Request, error) {
+	if req.Kind != KindExport && req.Kind != KindErasure {
+		return Request{}, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidRequest, KindExport, KindErasure)
+	}
+	if req.UserID <= 0 {
+		return Request{}, fmt.Errorf("%w: user_id is required", ErrInvalidRequest)
+	}
+	r := Request{UserID: req.UserID, Kind: req.Kind, Reason: req.Reason, RequestedBy: &requestedBy}
+	if err := s.repo.Create(ctx, &r); err != nil {
+		return Request{}, err
+	}
+	audit.Record(ctx, audit.Event{
+		Action:     "data_request.create",
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(r.UserID, 10),
+		After:      auditState(r),
+	})
+	return r, nil
+}
+
+// Get returns a request.
+func (s *Service) Get(ctx context.Context, id int64) (Request, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns the requests matching the filter, newest first.
+func (s *Service) List(ctx context.Context, f Filter) ([]Request, error) {
+	return s.repo.List(ctx, f)
+}
+
+// Approve approves a pending request. An export becomes downloadable until it expires; an
+// erasure is carried out straight away.
+func (s *Service) Approve(ctx context.Context, id, reviewerID int64, note string) (Request, error) {
+	r, err := s.pendingForReview(ctx, id, reviewerID)
+	if err != nil {
+		return Request{}, err
+	}
+
+	if r.Kind == KindExport {
+		expires := s.now().Add(s.exportTTL)
+		if ok, err := s.repo.Review(ctx, id, StatusApproved, reviewerID, note, &expires); err != nil {
+			return Request{}, err
+		} else if !ok {
+			return Request{}, ErrNotPending
+		}
+		return s.reviewed(ctx, r, "data_request.approve")
+	}
+
+	pictureKey, ok, err := s.repo.Erase(ctx, r, reviewerID, note)
+	if err != nil {
+		return Request{}, err
+	}
+	if !ok {
+		return Request{}, ErrNotPending
+	}
+	if s.roles != nil {
+		if err := s.roles.ReloadPolicies(); err != nil {
+			// The rules are deleted; the enforcer drops them on its next reload.
+			log.Printf("⚠️ Failed to reload policies after erasing user %d: %v", r.UserID, err)
+		}
+	}
+	if pictureKey != nil && s.pictures != nil {
+		for _, k := range user.PictureObjectKeys(*pictureKey) {
+			if err := s.pictures.Delete(ctx, k); err != nil {
+				log.Printf("⚠️ Could not delete profile picture object %s: %v", k, err)
+			}
+		}
+	}
+	audit.Record(ctx, audit.Event{
+		Action:     "user.erase",
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(r.UserID, 10),
+		After:      map[string]any{"status": ErasedStatus, "request_id": r.ID},
+	})
+	return s.reviewed(ctx, r, "data_request.approve")
+}
+
+// Reject closes a pending request without acting on it.
+func (s *Service) Reject(ctx context.Context, id, reviewerID int64, note string) (Request, error) {
+	r, err := s.pendingForReview(ctx, id, reviewerID)
+	if err != nil {
+		return Request{}, err
+	}
+	if ok, err := s.repo.Review(ctx, id, StatusRejected, reviewerID, note, nil); err != nil {
+		return Request{}, err
+	} else if !ok {
+		return Request{}, ErrNotPending
+	}
+	return s.reviewed(ctx, r, "data_request.reject")
+}
+
+func (s *Service) pendingForReview(ctx context.Context, id, reviewerID int64) (Request, error) {
+	r, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Request{}, err
+	}
+	if r.Status != StatusPending {
+		return Request{}, ErrNotPending
+	}
+	if r.UserID == reviewerID {
+		return Request{}, ErrSelfReview
+	}
+	return r, nil
+}
+
+// reviewed reloads a request after review and records the decision.
+func (s *Service) reviewed(ctx context.Context, before Request, action string) (Request, error) {
+	after, err := s.repo.Get(ctx, before.ID)
+	if err != nil {
+		return Request{}, err
+	}
+	audit.Record(ctx, audit.Event{
+		Action:     action,
+		TargetType: "data_request",
+		TargetID:   strconv.FormatInt(before.ID, 10),
+		Before:     auditState(before),
+		After:      auditState(after),
+	})
+	return after, nil
+}
+
+// auditState is what the audit log keeps of a request: the reason and review note are free text
+// and may hold personal details, so they are left out.
+func auditState(r Request) map[string]any {
+	return map[string]any{
+		"id":           r.ID,
+		"user_id":      r.UserID,
+		"kind":         r.Kind,
+		"status":       r.Status,
+		"requested_by": r.RequestedBy,
+		"reviewed_by":  r.ReviewedBy,
+		"expires_at":   r.ExpiresAt,
+		"completed_at": r.CompletedAt,
+	}
+}
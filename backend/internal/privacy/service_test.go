@@ -0,0 +1,252 @@
+package privacy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nomenarkt/lamina/common/storage"
+	"github.com/nomenarkt/lamina/internal/audit"
+	"github.com/nomenarkt/lamina/internal/privacy"
+	"github.com/nomenarkt/lamina/internal/user"
+)
+
+type MockPrivacyRepo struct {
+	mock.Mock
+}
+
+func (m *MockPrivacyRepo) Create(ctx context.Context, r *privacy.Request) error {
+	return m.Called(ctx, r).Error(0)
+}
+
+func (m *MockPrivacyRepo) Get(ctx context.Context, id int64) (privacy.Request, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(privacy.Request), args.Error(1)
+}
+
+func (m *MockPrivacyRepo) List(ctx context.Context, f privacy.Filter) ([]privacy.Request, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]privacy.Request), args.Error(1)
+}
+
+func (m *MockPrivacyRepo) Review(ctx context.Context, id int64, status string, reviewerID int64, note string, expiresAt *time.Time) (bool, error) {
+	args := m.Called(ctx, id, status, reviewerID, note, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPrivacyRepo) Erase(ctx context.Context, r privacy.Request, reviewerID int64, note string) (*string, bool, error) {
+	args := m.Called(ctx, r, reviewerID, note)
+	key, _ := args.Get(0).(*string)
+	return key, args.Bool(1), args.Error(2)
+}
+
+func (m *MockPrivacyRepo) ListFunctions(ctx context.Context, userID int64) ([]privacy.FunctionRecord, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]privacy.FunctionRecord), args.Error(1)
+}
+
+func (m *MockPrivacyRepo) ListUnits(ctx context.Context, userID int64) ([]privacy.UnitRecord, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]privacy.UnitRecord), args.Error(1)
+}
+
+func (m *MockPrivacyRepo) ListAssignments(ctx context.Context, userID int64) ([]privacy.AssignmentRecord, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]privacy.AssignmentRecord), args.Error(1)
+}
+
+func (m *MockPrivacyRepo) ListQualifications(ctx context.Context, userID int64) ([]privacy.QualificationRecord, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]privacy.QualificationRecord), args.Error(1)
+}
+
+type fakeProfiles struct {
+	user     user.User
+	children []user.Child
+}
+
+func (f *fakeProfiles) FindByID(_ context.Context, _ int64) (*user.User, error) {
+	u := f.user
+	return &u, nil
+}
+
+func (f *fakeProfiles) ListChildren(_ context.Context, _ int64) ([]user.Child, error) {
+	return f.children, nil
+}
+
+// fakeAuditLog serves entries in pages like audit.Repository.
+type fakeAuditLog struct {
+	entries []audit.Entry
+	filters []audit.Filter
+}
+
+func (f *fakeAuditLog) List(_ context.Context, filter audit.Filter) ([]audit.Entry, int, error) {
+	f.filters = append(f.filters, filter)
+	end := filter.Offset + filter.Limit
+	if end > len(f.entries) {
+		end = len(f.entries)
+	}
+	return f.entries[filter.Offset:end], len(f.entries), nil
+}
+
+type fakeRoles struct{ reloads int }
+
+func (f *fakeRoles) ReloadPolicies() error {
+	f.reloads++
+	return nil
+}
+
+func newService(repo *MockPrivacyRepo, profiles *fakeProfiles, auditLog *fakeAuditLog, roles *fakeRoles) *privacy.Service {
+	return privacy.NewService(repo, profiles, auditLog, roles, 48*time.Hour)
+}
+
+func TestService_CreateValidatesKind(t *testing.T) {
+	repo := new(MockPrivacyRepo)
+	svc := newService(repo, &fakeProfiles{}, &fakeAuditLog{}, &fakeRoles{})
+
+	_, err := svc.Create(context.Background(), privacy.CreateRequest{UserID: 7, Kind: "delete"}, 7)
+	assert.ErrorIs(t, err, privacy.ErrInvalidRequest)
+
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(r *privacy.Request) bool {
+		return r.UserID == 7 && r.Kind == privacy.KindErasure && *r.RequestedBy == 1
+	})).Return(nil)
+	r, err := svc.Create(context.Background(), privacy.CreateRequest{UserID: 7, Kind: privacy.KindErasure, Reason: "left the company"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "left the company", r.Reason)
+	repo.AssertExpectations(t)
+}
+
+func TestService_ApproveExportSetsExpiry(t *testing.T) {
+	repo := new(MockPrivacyRepo)
+	svc := newService(repo, &fakeProfiles{}, &fakeAuditLog{}, &fakeRoles{})
+	pending := privacy.Request{ID: 3, UserID: 7, Kind: privacy.KindExport, Status: privacy.StatusPending}
+	approved := pending
+	approved.Status = privacy.StatusApproved
+
+	repo.On("Get", mock.Anything, int64(3)).Return(pending, nil).Once()
+	repo.On("Review", mock.Anything, int64(3), privacy.StatusApproved, int64(1), "ok", mock.MatchedBy(func(exp *time.Time) bool {
+		return exp != nil && time.Until(*exp) > 47*time.Hour && time.Until(*exp) <= 48*time.Hour
+	})).Return(true, nil)
+	repo.On("Get", mock.Anything, int64(3)).Return(approved, nil).Once()
+
+	r, err := svc.Approve(context.Background(), 3, 1, "ok")
+	require.NoError(t, err)
+	assert.Equal(t, privacy.StatusApproved, r.Status)
+	repo.AssertExpectations(t)
+}
+
+func TestService_ReviewGuards(t *testing.T) {
+	repo := new(MockPrivacyRepo)
+	svc := newService(repo, &fakeProfiles{}, &fakeAuditLog{}, &fakeRoles{})
+	repo.On("Get", mock.Anything, int64(3)).Return(privacy.Request{ID: 3, UserID: 7, Kind: privacy.KindErasure, Status: privacy.StatusPending}, nil)
+	repo.On("Get", mock.Anything, int64(4)).Return(privacy.Request{ID: 4, UserID: 8, Kind: privacy.KindErasure, Status: privacy.StatusRejected}, nil)
+
+	_, err := svc.Approve(context.Background(), 3, 7, "")
+	assert.ErrorIs(t, err, privacy.ErrSelfReview)
+	_, err = svc.Reject(context.Background(), 4, 1, "")
+	assert.ErrorIs(t, err, privacy.ErrNotPending)
+	repo.AssertNotCalled(t, "Erase", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_ApproveErasure(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockPrivacyRepo)
+	roles := &fakeRoles{}
+	svc := newService(repo, &fakeProfiles{}, &fakeAuditLog{}, roles)
+
+	pictures := &storage.LocalStorage{Root: t.TempDir()}
+	svc.SetPictureStorage(pictures)
+	key := "profile-pictures/7/abcd/original.png"
+	for _, k := range user.PictureObjectKeys(key) {
+		require.NoError(t, pictures.Put(ctx, k, strings.NewReader("img"), 3, "image/png"))
+	}
+
+	pending := privacy.Request{ID: 3, UserID: 7, Kind: privacy.KindErasure, Status: privacy.StatusPending}
+	completed := pending
+	completed.Status = privacy.StatusCompleted
+	repo.On("Get", mock.Anything, int64(3)).Return(pending, nil).Once()
+	repo.On("Erase", mock.Anything, pending, int64(1), "confirmed with HR").Return(&key, true, nil)
+	repo.On("Get", mock.Anything, int64(3)).Return(completed, nil).Once()
+
+	r, err := svc.Approve(ctx, 3, 1, "confirmed with HR")
+	require.NoError(t, err)
+	assert.Equal(t, privacy.StatusCompleted, r.Status)
+	assert.Equal(t, 1, roles.reloads, "the erased user's grouping rules are dropped")
+	for _, k := range user.PictureObjectKeys(key) {
+		_, err := pictures.Get(ctx, k)
+		assert.ErrorIs(t, err, storage.ErrNotFound, k)
+	}
+	repo.AssertExpectations(t)
+}
+
+func TestService_WriteExport(t *testing.T) {
+	repo := new(MockPrivacyRepo)
+	name, nationalID := "Rabe Hery", "101 234"
+	profiles := &fakeProfiles{
+		user:     user.User{ID: 7, Email: "rabe@example.com", PasswordHash: "$2a$secret", FullName: &name, NationalID: &nationalID},
+		children: []user.Child{{ID: 1, FullName: "Toky", BirthDate: "2015-03-01"}},
+	}
+	auditLog := &fakeAuditLog{}
+	for i := 0; i < 501; i++ {
+		auditLog.entries = append(auditLog.entries, audit.Entry{ID: int64(i + 1), Action: "user.update", TargetType: "user", TargetID: "7"})
+	}
+	svc := newService(repo, profiles, auditLog, &fakeRoles{})
+
+	expires := time.Now().Add(time.Hour)
+	r := privacy.Request{ID: 3, UserID: 7, Kind: privacy.KindExport, Status: privacy.StatusApproved, ExpiresAt: &expires}
+	repo.On("Get", mock.Anything, int64(3)).Return(r, nil)
+	repo.On("ListFunctions", mock.Anything, int64(7)).Return([]privacy.FunctionRecord{{Function: "pilot", UnitID: 2, Unit: "Flight Ops"}}, nil)
+	repo.On("ListUnits", mock.Anything, int64(7)).Return([]privacy.UnitRecord{{ID: 2, Name: "Flight Ops", Type: "department"}}, nil)
+	repo.On("ListAssignments", mock.Anything, int64(7)).Return([]privacy.AssignmentRecord{{ID: 9, CrewRole: "CDB"}}, nil)
+	repo.On("ListQualifications", mock.Anything, int64(7)).Return([]privacy.QualificationRecord{}, nil)
+	userID := int64(7)
+	repo.On("List", mock.Anything, privacy.Filter{UserID: &userID}).Return([]privacy.Request{r}, nil)
+
+	var buf bytes.Buffer
+	require.NoError(t, svc.WriteExport(context.Background(), 3, &buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		_ = rc.Close()
+	}
+
+	var manifest privacy.Manifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Equal(t, map[string]int{
+		"user.json": 1, "children.json": 1, "functions.json": 1, "org_units.json": 1,
+		"crew_assignments.json": 1, "qualifications.json": 0, "data_requests.json": 1, "audit_log.json": 501,
+	}, manifest.Files)
+	assert.Contains(t, string(files["user.json"]), `"national_id": "101 234"`)
+	assert.NotContains(t, string(files["user.json"]), "secret", "credentials are not exported")
+	assert.Contains(t, string(files["children.json"]), "Toky")
+	assert.Len(t, auditLog.filters, 2, "audit entries are read in pages")
+	assert.Equal(t, "7", auditLog.filters[0].TargetID)
+}
+
+func TestService_WriteExportUnavailable(t *testing.T) {
+	repo := new(MockPrivacyRepo)
+	svc := newService(repo, &fakeProfiles{}, &fakeAuditLog{}, &fakeRoles{})
+	expired := time.Now().Add(-time.Minute)
+	repo.On("Get", mock.Anything, int64(3)).Return(privacy.Request{ID: 3, UserID: 7, Kind: privacy.KindExport, Status: privacy.StatusApproved, ExpiresAt: &expired}, nil)
+	repo.On("Get", mock.Anything, int64(4)).Return(privacy.Request{ID: 4, UserID: 7, Kind: privacy.KindExport, Status: privacy.StatusPending}, nil)
+
+	var buf bytes.Buffer
+	assert.ErrorIs(t, svc.WriteExport(context.Background(), 3, &buf), privacy.ErrExportUnavailable)
+	assert.ErrorIs(t, svc.WriteExport(context.Background(), 4, &buf), privacy.ErrExportUnavailable)
+	assert.Zero(t, buf.Len())
+}
@@ -0,0 +1,232 @@
+// Package qualification handles HTTP endpoints for qualification types and user records.
+package qualification
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/common/utils"
+)
+
+// managerRole may manage the catalogue and any user's qualifications.
+const managerRole = "admin"
+
+// Handler defines the HTTP handler for qualification operations.
+type Handler struct {
+	service ServiceInterface
+}
+
+// NewHandler creates a new Handler instance for the qualification service.
+func NewHandler(s ServiceInterface) *Handler {
+	return &Handler{service: s}
+}
+
+// ListTypes lists the qualification catalogue.
+// GET /qualifications/types
+func (h *Handler) ListTypes(c *gin.Context) {
+	types, err := h.service.ListTypes(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, types)
+}
+
+// CreateType adds a qualification type.
+// POST /qualifications/types
+func (h *Handler) CreateType(c *gin.Context) {
+	var req TypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	t, err := h.service.CreateType(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// UpdateType changes a qualification type.
+// PUT /qualifications/types/:id
+func (h *Handler) UpdateType(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var req TypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	t, err := h.service.UpdateType(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// DeleteType removes a qualification type.
+// DELETE /qualifications/types/:id
+func (h *Handler) DeleteType(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteType(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListUserQualifications lists a user's qualifications. Users may read their own.
+// GET /qualifications/users/:user_id
+func (h *Handler) ListUserQualifications(c *gin.Context) {
+	userID, ok := idParam(c, "user_id")
+	if !ok {
+		return
+	}
+	if !canRead(c, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+	records, err := h.service.ListUserQualifications(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+// AddQualification records a qualification for a user.
+// POST /qualifications/users/:user_id
+func (h *Handler) AddQualification(c *gin.Context) {
+	userID, ok := idParam(c, "user_id")
+	if !ok {
+		return
+	}
+	var req RecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	createdBy, _ := utils.GetUserIDFromContext(c)
+
+	rec, err := h.service.AddQualification(c.Request.Context(), userID, req, createdBy)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rec)
+}
+
+// GetQualification returns a single qualification. Users may read their own.
+// GET /qualifications/records/:id
+func (h *Handler) GetQualification(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	rec, err := h.service.GetQualification(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !canRead(c, rec.UserID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrRecordNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// UpdateQualification overwrites a qualification, e.g. after renewal.
+// PUT /qualifications/records/:id
+func (h *Handler) UpdateQualification(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var req RecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	rec, err := h.service.UpdateQualification(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// DeleteQualification removes a qualification.
+// DELETE /qualifications/records/:id
+func (h *Handler) DeleteQualification(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteQualification(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListExpiring lists qualifications lapsing soon.
+// GET /qualifications/expiring?within_days=30
+func (h *Handler) ListExpiring(c *gin.Context) {
+	days := 0
+	if v := c.Query("within_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "within_days must be a positive integer"})
+			return
+		}
+		days = n
+	}
+	records, err := h.service.ListExpiring(c.Request.Context(), days)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+// canRead allows managers to read anyone's qualifications and users their own.
+func canRead(c *gin.Context, userID int64) bool {
+	if c.GetString("userRole") == managerRole {
+		return true
+	}
+	caller, ok := utils.GetUserIDFromContext(c)
+	return ok && caller == userID
+}
+
+func idParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidQualification):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTypeNotFound), errors.Is(err, ErrRecordNotFound), errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDuplicateType), errors.Is(err, ErrTypeInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Qualification request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
@@ -0,0 +1,90 @@
+// Package qualification defines crew qualification types and the records users hold.
+package qualification
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Qualification categories.
+const (
+	CategoryTypeRating        = "type_rating"
+	CategoryMedical           = "medical"
+	CategoryLineCheck         = "line_check"
+	CategoryRecurrentTraining = "recurrent_training"
+	CategoryLicense           = "license"
+	CategoryOther             = "other"
+)
+
+// Record statuses, computed against the current date.
+const (
+	StatusValid       = "valid"
+	StatusExpiring    = "expiring"
+	StatusExpired     = "expired"
+	StatusNotYetValid = "not_yet_valid"
+)
+
+// Type is a kind of qualification, e.g. an ATR 72 type rating or a class 1 medical.
+type Type struct {
+	ID               int64          `db:"id" json:"id"`
+	Code             string         `db:"code" json:"code"` // e.g. TR-AT7
+	Name             string         `db:"name" json:"name"`
+	Category         string         `db:"category" json:"category"`
+	AircraftType     *string        `db:"aircraft_type" json:"aircraft_type"`           // only required on this IATA aircraft type
+	RequiredForRoles pq.StringArray `db:"required_for_roles" json:"required_for_roles"` // crew roles that must hold it
+	ValidityMonths   *int           `db:"validity_months" json:"validity_months"`       // default expiry for new records
+	CreatedAt        time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// TypeRequest is the payload for creating or updating a qualification type.
+type TypeRequest struct {
+	Code             string   `json:"code" binding:"required"`
+	Name             string   `json:"name" binding:"required"`
+	Category         string   `json:"category" binding:"required"`
+	AircraftType     *string  `json:"aircraft_type"`
+	RequiredForRoles []string `json:"required_for_roles"`
+	ValidityMonths   *int     `json:"validity_months"`
+}
+
+// Record is a qualification held by a user, with optional supporting document metadata.
+type Record struct {
+	ID                  int64      `db:"id" json:"id"`
+	UserID              int64      `db:"user_id" json:"user_id"`
+	UserName            *string    `db:"user_name" json:"user_name,omitempty"`
+	UserEmail           string     `db:"user_email" json:"user_email,omitempty"`
+	TypeID              int64      `db:"qualification_type_id" json:"qualification_type_id"`
+	TypeCode            string     `db:"type_code" json:"type_code"`
+	TypeName            string     `db:"type_name" json:"type_name"`
+	Reference           *string    `db:"reference" json:"reference"` // license / certificate number
+	IssuingAuthority    *string    `db:"issuing_authority" json:"issuing_authority"`
+	IssuedAt            time.Time  `db:"issued_at" json:"issued_at"`
+	ExpiresAt           *time.Time `db:"expires_at" json:"expires_at"` // nil = does not expire
+	Notes               *string    `db:"notes" json:"notes"`
+	DocumentName        *string    `db:"document_name" json:"document_name"`
+	DocumentContentType *string    `db:"document_content_type" json:"document_content_type"`
+	DocumentSizeBytes   *int64     `db:"document_size_bytes" json:"document_size_bytes"`
+	DocumentSHA256      *string    `db:"document_sha256" json:"document_sha256"`
+	DocumentURL         *string    `db:"document_url" json:"document_url"`
+	CreatedBy           *int64     `db:"created_by" json:"created_by"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt           time.Time  `db:"updated_at" json:"updated_at"`
+	Status              string     `db:"-" json:"status"`
+}
+
+// RecordRequest is the payload for creating or updating a user's qualification.
+// Dates are calendar dates (YYYY-MM-DD). A missing expiry defaults to the type's validity period.
+type RecordRequest struct {
+	TypeID              int64   `json:"qualification_type_id" binding:"required"`
+	Reference           *string `json:"reference"`
+	IssuingAuthority    *string `json:"issuing_authority"`
+	IssuedAt            string  `json:"issued_at" binding:"required"`
+	ExpiresAt           *string `json:"expires_at"`
+	Notes               *string `json:"notes"`
+	DocumentName        *string `json:"document_name"`
+	DocumentContentType *string `json:"document_content_type"`
+	DocumentSizeBytes   *int64  `json:"document_size_bytes"`
+	DocumentSHA256      *string `json:"document_sha256"`
+	DocumentURL         *string `json:"document_url"`
+}
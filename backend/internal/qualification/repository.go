@@ -0,0 +1,179 @@
+// Package qualification implements storage for qualification types and user records.
+package qualification
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Repository defines the interface for qualification storage.
+type Repository interface {
+	ListTypes(ctx context.Context) ([]Type, error)
+	GetType(ctx context.Context, id int64) (Type, error)
+	CreateType(ctx context.Context, t *Type) error
+	UpdateType(ctx context.Context, t *Type) error
+	DeleteType(ctx context.Context, id int64) (bool, error)
+	ListRecordsByUser(ctx context.Context, userID int64) ([]Record, error)
+	GetRecord(ctx context.Context, id int64) (Record, error)
+	CreateRecord(ctx context.Context, r *Record) error
+	UpdateRecord(ctx context.Context, r *Record) error
+	DeleteRecord(ctx context.Context, id int64) (bool, error)
+	ListExpiring(ctx context.Context, from, to time.Time) ([]Record, error)
+}
+
+// qualificationRepository is a concrete implementation of the Repository interface.
+type qualificationRepository struct {
+	db *sqlx.DB
+}
+
+// NewRepository returns a new instance of a qualification Repository.
+func NewRepository(db *sqlx.DB) Repository {
+	return &qualificationRepository{db: db}
+}
+
+const typeColumns = `id, code, name, category, aircraft_type, required_for_roles, validity_months, created_at, updated_at`
+
+const recordSelect = `
+	SELECT uq.id, uq.user_id, u.full_name AS user_name, u.email AS user_email,
+	       uq.qualification_type_id, qt.code AS type_code, qt.name AS type_name,
+	       uq.reference, uq.issuing_authority, uq.issued_at, uq.expires_at, uq.notes,
+	       uq.document_name, uq.document_content_type, uq.document_size_bytes,
+	       uq.document_sha256, uq.document_url, uq.created_by, uq.created_at, uq.updated_at
+	FROM user_qualifications uq
+	JOIN qualification_types qt ON qt.id = uq.qualification_type_id
+	JOIN users u ON u.id = uq.user_id`
+
+// ListTypes returns the qualification catalogue ordered by code.
+func (r *qualificationRepository) ListTypes(ctx context.Context) ([]Type, error) {
+	types := []Type{}
+	err := r.db.SelectContext(ctx, &types, `SELECT `+typeColumns+` FROM qualification_types ORDER BY code`)
+	return types, err
+}
+
+// GetType returns a single qualification type.
+func (r *qualificationRepository) GetType(ctx context.Context, id int64) (Type, error) {
+	var t Type
+	err := r.db.GetContext(ctx, &t, `SELECT `+typeColumns+` FROM qualification_types WHERE id = $1`, id)
+	return t, err
+}
+
+// CreateType inserts a qualification type and fills in its generated fields.
+func (r *qualificationRepository) CreateType(ctx context.Context, t *Type) error {
+	err := r.db.GetContext(ctx, t, `
+		INSERT INTO qualification_types (code, name, category, aircraft_type, required_for_roles, validity_months)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+typeColumns,
+		t.Code, t.Name, t.Category, t.AircraftType, t.RequiredForRoles, t.ValidityMonths)
+	return mapConstraintError(err)
+}
+
+// UpdateType overwrites a qualification type.
+func (r *qualificationRepository) UpdateType(ctx context.Context, t *Type) error {
+	err := r.db.GetContext(ctx, t, `
+		UPDATE qualification_types
+		SET code = $2, name = $3, category = $4, aircraft_type = $5,
+		    required_for_roles = $6, validity_months = $7, updated_at = now()
+		WHERE id = $1
+		RETURNING `+typeColumns,
+		t.ID, t.Code, t.Name, t.Category, t.AircraftType, t.RequiredForRoles, t.ValidityMonths)
+	return mapConstraintError(err)
+}
+
+// DeleteType removes a qualification type that no record refers to.
+func (r *qualificationRepository) DeleteType(ctx context.Context, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM qualification_types WHERE id = $1`, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return false, ErrTypeInUse
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListRecordsByUser returns a user's qualifications, latest expiry first within each type.
+func (r *qualificationRepository) ListRecordsByUser(ctx context.Context, userID int64) ([]Record, error) {
+	records := []Record{}
+	err := r.db.SelectContext(ctx, &records, recordSelect+`
+		WHERE uq.user_id = $1
+		ORDER BY qt.code, uq.expires_at DESC NULLS FIRST
+	`, userID)
+	return records, err
+}
+
+// GetRecord returns a single user qualification.
+func (r *qualificationRepository) GetRecord(ctx context.Context, id int64) (Record, error) {
+	var rec Record
+	err := r.db.GetContext(ctx, &rec, recordSelect+` WHERE uq.id = $1`, id)
+	return rec, err
+}
+
+// CreateRecord inserts a user qualification.
+func (r *qualificationRepository) CreateRecord(ctx context.Context, rec *Record) error {
+	err := r.db.GetContext(ctx, &rec.ID, `
+		INSERT INTO user_qualifications (
+			user_id, qualification_type_id, reference, issuing_authority, issued_at, expires_at, notes,
+			document_name, document_content_type, document_size_bytes, document_sha256, document_url, created_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`, rec.UserID, rec.TypeID, rec.Reference, rec.IssuingAuthority, rec.IssuedAt, rec.ExpiresAt, rec.Notes,
+		rec.DocumentName, rec.DocumentContentType, rec.DocumentSizeBytes, rec.DocumentSHA256, rec.DocumentURL, rec.CreatedBy)
+	return mapConstraintError(err)
+}
+
+// UpdateRecord overwrites a user qualification.
+func (r *qualificationRepository) UpdateRecord(ctx context.Context, rec *Record) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_qualifications
+		SET qualification_type_id = $2, reference = $3, issuing_authority = $4, issued_at = $5,
+		    expires_at = $6, notes = $7, document_name = $8, document_content_type = $9,
+		    document_size_bytes = $10, document_sha256 = $11, document_url = $12, updated_at = now()
+		WHERE id = $1
+	`, rec.ID, rec.TypeID, rec.Reference, rec.IssuingAuthority, rec.IssuedAt, rec.ExpiresAt, rec.Notes,
+		rec.DocumentName, rec.DocumentContentType, rec.DocumentSizeBytes, rec.DocumentSHA256, rec.DocumentURL)
+	return mapConstraintError(err)
+}
+
+// DeleteRecord removes a user qualification.
+func (r *qualificationRepository) DeleteRecord(ctx context.Context, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM user_qualifications WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListExpiring returns qualifications expiring within [from, to], soonest first.
+func (r *qualificationRepository) ListExpiring(ctx context.Context, from, to time.Time) ([]Record, error) {
+	records := []Record{}
+	err := r.db.SelectContext(ctx, &records, recordSelect+`
+		WHERE uq.expires_at BETWEEN $1 AND $2
+		ORDER BY uq.expires_at, qt.code
+	`, from, to)
+	return records, err
+}
+
+// mapConstraintError translates unique and foreign key violations into service errors.
+func mapConstraintError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch {
+	case pqErr.Code == "23505":
+		return ErrDuplicateType
+	case pqErr.Code == "23503" && pqErr.Constraint == "user_qualifications_user_id_fkey":
+		return ErrUserNotFound
+	case pqErr.Code == "23503" && pqErr.Constraint == "user_qualifications_qualification_type_id_fkey":
+		return ErrTypeNotFound
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+// Package qualification defines route registration for the qualification APIs.
+package qualification
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/internal/auth"
+)
+
+// RegisterRoutes sets up the HTTP endpoints for qualification management under the given route group.
+// Reads are open to the record holder; changes require the manager role.
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
+	manage := auth.RequireRoles(managerRole)
+
+	group := rg.Group("/qualifications")
+	group.GET("/types", h.ListTypes)
+	group.POST("/types", manage, h.CreateType)
+	group.PUT("/types/:id", manage, h.UpdateType)
+	group.DELETE("/types/:id", manage, h.DeleteType)
+
+	group.GET("/expiring", manage, h.ListExpiring)
+
+	group.GET("/users/:user_id", h.ListUserQualifications)
+	group.POST("/users/:user_id", manage, h.AddQualification)
+
+	group.GET("/records/:id", h.GetQualification)
+	group.PUT("/records/:id", manage, h.UpdateQualification)
+	group.DELETE("/records/:id", manage, h.DeleteQualification)
+}
@@ -0,0 +1,340 @@
+// Package qualification contains business logic for crew qualifications and their expiry.
+package qualification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTypeNotFound is returned when no qualification type matches the given ID.
+	ErrTypeNotFound = errors.New("qualification type not found")
+	// ErrRecordNotFound is returned when no user qualification matches the given ID.
+	ErrRecordNotFound = errors.New("qualification not found")
+	// ErrUserNotFound is returned when recording a qualification for an unknown user.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrDuplicateType is returned when a qualification type code is already used.
+	ErrDuplicateType = errors.New("qualification type code already exists")
+	// ErrTypeInUse is returned when deleting a type that users still hold.
+	ErrTypeInUse = errors.New("qualification type is still held by users")
+	// ErrInvalidQualification is wrapped by validation errors.
+	ErrInvalidQualification = errors.New("invalid qualification")
+)
+
+// expiringWindow marks valid records as expiring when they lapse within this many days.
+const expiringWindow = 30
+
+// maxExpiringDays bounds the look-ahead of ListExpiring.
+const maxExpiringDays = 365
+
+var (
+	typeCodePattern     = regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]{0,29}$`)
+	aircraftTypePattern = regexp.MustCompile(`^[A-Z0-9]{3}$`)
+	crewRolePattern     = regexp.MustCompile(`^[A-Z]{2,10}$`)
+	sha256Pattern       = regexp.MustCompile(`^[a-f0-9]{64}$`)
+	validCategories     = map[string]bool{
+		CategoryTypeRating: true, CategoryMedical: true, CategoryLineCheck: true,
+		CategoryRecurrentTraining: true, CategoryLicense: true, CategoryOther: true,
+	}
+)
+
+// ServiceInterface defines qualification catalogue and user record operations.
+type ServiceInterface interface {
+	ListTypes(ctx context.Context) ([]Type, error)
+	CreateType(ctx context.Context, req TypeRequest) (Type, error)
+	UpdateType(ctx context.Context, id int64, req TypeRequest) (Type, error)
+	DeleteType(ctx context.Context, id int64) error
+	ListUserQualifications(ctx context.Context, userID int64) ([]Record, error)
+	GetQualification(ctx context.Context, id int64) (Record, error)
+	AddQualification(ctx context.Context, userID int64, req RecordRequest, createdBy int64) (Record, error)
+	UpdateQualification(ctx context.Context, id int64, req RecordRequest) (Record, error)
+	DeleteQualification(ctx context.Context, id int64) error
+	ListExpiring(ctx context.Context, withinDays int) ([]Record, error)
+}
+
+// qualificationService implements the ServiceInterface using a data repository.
+type qualificationService struct {
+	repo Repository
+}
+
+// NewService creates a new instance of ServiceInterface using the provided repository.
+func NewService(repo Repository) ServiceInterface {
+	return &qualificationService{repo: repo}
+}
+
+// ListTypes returns the qualification catalogue.
+func (s *qualificationService) ListTypes(ctx context.Context) ([]Type, error) {
+	return s.repo.ListTypes(ctx)
+}
+
+// CreateType adds a qualification type to the catalogue.
+func (s *qualificationService) CreateType(ctx context.Context, req TypeRequest) (Type, error) {
+	var t Type
+	if err := applyType(&t, req); err != nil {
+		return Type{}, err
+	}
+	if err := s.repo.CreateType(ctx, &t); err != nil {
+		return Type{}, err
+	}
+	return t, nil
+}
+
+// UpdateType changes a qualification type. Existing records keep their expiry dates.
+func (s *qualificationService) UpdateType(ctx context.Context, id int64, req TypeRequest) (Type, error) {
+	t, err := s.getType(ctx, id)
+	if err != nil {
+		return Type{}, err
+	}
+	if err := applyType(&t, req); err != nil {
+		return Type{}, err
+	}
+	if err := s.repo.UpdateType(ctx, &t); err != nil {
+		return Type{}, err
+	}
+	return t, nil
+}
+
+// DeleteType removes a qualification type nobody holds.
+func (s *qualificationService) DeleteType(ctx context.Context, id int64) error {
+	deleted, err := s.repo.DeleteType(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTypeNotFound
+	}
+	return nil
+}
+
+// ListUserQualifications returns a user's qualifications with their current status.
+func (s *qualificationService) ListUserQualifications(ctx context.Context, userID int64) ([]Record, error) {
+	records, err := s.repo.ListRecordsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		records[i].Status = recordStatus(records[i], time.Now())
+	}
+	return records, nil
+}
+
+// GetQualification returns a single user qualification.
+func (s *qualificationService) GetQualification(ctx context.Context, id int64) (Record, error) {
+	rec, err := s.repo.GetRecord(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, ErrRecordNotFound
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	rec.Status = recordStatus(rec, time.Now())
+	return rec, nil
+}
+
+// AddQualification records a qualification for a user.
+func (s *qualificationService) AddQualification(ctx context.Context, userID int64, req RecordRequest, createdBy int64) (Record, error) {
+	rec := Record{UserID: userID, CreatedBy: &createdBy}
+	if err := s.applyRecord(ctx, &rec, req); err != nil {
+		return Record{}, err
+	}
+	if err := s.repo.CreateRecord(ctx, &rec); err != nil {
+		return Record{}, err
+	}
+	return s.GetQualification(ctx, rec.ID)
+}
+
+// UpdateQualification overwrites a user qualification, e.g. after a renewal.
+func (s *qualificationService) UpdateQualification(ctx context.Context, id int64, req RecordRequest) (Record, error) {
+	rec, err := s.GetQualification(ctx, id)
+	if err != nil {
+		return Record{}, err
+	}
+	if err := s.applyRecord(ctx, &rec, req); err != nil {
+		return Record{}, err
+	}
+	if err := s.repo.UpdateRecord(ctx, &rec); err != nil {
+		return Record{}, err
+	}
+	return s.GetQualification(ctx, id)
+}
+
+// DeleteQualification removes a user qualification.
+func (s *qualificationService) DeleteQualification(ctx context.Context, id int64) error {
+	deleted, err := s.repo.DeleteRecord(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListExpiring returns qualifications lapsing within the next withinDays days (default 30).
+func (s *qualificationService) ListExpiring(ctx context.Context, withinDays int) ([]Record, error) {
+	if withinDays <= 0 {
+		withinDays = expiringWindow
+	}
+	if withinDays > maxExpiringDays {
+		withinDays = maxExpiringDays
+	}
+	today := date(time.Now())
+	records, err := s.repo.ListExpiring(ctx, today, today.AddDate(0, 0, withinDays))
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		records[i].Status = recordStatus(records[i], time.Now())
+	}
+	return records, nil
+}
+
+func (s *qualificationService) getType(ctx context.Context, id int64) (Type, error) {
+	t, err := s.repo.GetType(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Type{}, ErrTypeNotFound
+	}
+	return t, err
+}
+
+// recordStatus classifies a record against the calendar day of now.
+func recordStatus(rec Record, now time.Time) string {
+	today := date(now)
+	switch {
+	case rec.IssuedAt.After(today):
+		return StatusNotYetValid
+	case rec.ExpiresAt == nil:
+		return StatusValid
+	case rec.ExpiresAt.Before(today):
+		return StatusExpired
+	case rec.ExpiresAt.Before(today.AddDate(0, 0, expiringWindow)):
+		return StatusExpiring
+	default:
+		return StatusValid
+	}
+}
+
+// applyType validates a type request and copies it onto the type.
+func applyType(t *Type, req TypeRequest) error {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if !typeCodePattern.MatchString(code) {
+		return fmt.Errorf("%w: code %q must be up to 30 letters, digits or dashes", ErrInvalidQualification, req.Code)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidQualification)
+	}
+	category := strings.ToLower(strings.TrimSpace(req.Category))
+	if !validCategories[category] {
+		return fmt.Errorf("%w: unknown category %q", ErrInvalidQualification, req.Category)
+	}
+
+	var aircraftType *string
+	if req.AircraftType != nil && strings.TrimSpace(*req.AircraftType) != "" {
+		v := strings.ToUpper(strings.TrimSpace(*req.AircraftType))
+		if !aircraftTypePattern.MatchString(v) {
+			return fmt.Errorf("%w: aircraft type %q is not a 3-character IATA code", ErrInvalidQualification, *req.AircraftType)
+		}
+		aircraftType = &v
+	}
+
+	roles := []string{}
+	seen := map[string]bool{}
+	for _, r := range req.RequiredForRoles {
+		role := strings.ToUpper(strings.TrimSpace(r))
+		if !crewRolePattern.MatchString(role) {
+			return fmt.Errorf("%w: crew role %q is not valid", ErrInvalidQualification, r)
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	if req.ValidityMonths != nil && *req.ValidityMonths <= 0 {
+		return fmt.Errorf("%w: validity_months must be positive", ErrInvalidQualification)
+	}
+
+	t.Code = code
+	t.Name = name
+	t.Category = category
+	t.AircraftType = aircraftType
+	t.RequiredForRoles = roles
+	t.ValidityMonths = req.ValidityMonths
+	return nil
+}
+
+// applyRecord validates a record request, derives a default expiry, and copies it onto the record.
+func (s *qualificationService) applyRecord(ctx context.Context, rec *Record, req RecordRequest) error {
+	t, err := s.getType(ctx, req.TypeID)
+	if err != nil {
+		return err
+	}
+
+	issued, err := time.Parse("2006-01-02", strings.TrimSpace(req.IssuedAt))
+	if err != nil {
+		return fmt.Errorf("%w: issued_at must be a YYYY-MM-DD date", ErrInvalidQualification)
+	}
+
+	var expires *time.Time
+	if req.ExpiresAt != nil && strings.TrimSpace(*req.ExpiresAt) != "" {
+		e, err := time.Parse("2006-01-02", strings.TrimSpace(*req.ExpiresAt))
+		if err != nil {
+			return fmt.Errorf("%w: expires_at must be a YYYY-MM-DD date", ErrInvalidQualification)
+		}
+		expires = &e
+	} else if t.ValidityMonths != nil {
+		e := issued.AddDate(0, *t.ValidityMonths, 0)
+		expires = &e
+	}
+	if expires != nil && expires.Before(issued) {
+		return fmt.Errorf("%w: expires_at cannot be before issued_at", ErrInvalidQualification)
+	}
+
+	if req.DocumentSizeBytes != nil && *req.DocumentSizeBytes < 0 {
+		return fmt.Errorf("%w: document_size_bytes cannot be negative", ErrInvalidQualification)
+	}
+	checksum := trimmed(req.DocumentSHA256)
+	if checksum != nil {
+		lower := strings.ToLower(*checksum)
+		if !sha256Pattern.MatchString(lower) {
+			return fmt.Errorf("%w: document_sha256 must be 64 hex characters", ErrInvalidQualification)
+		}
+		checksum = &lower
+	}
+
+	rec.TypeID = t.ID
+	rec.Reference = trimmed(req.Reference)
+	rec.IssuingAuthority = trimmed(req.IssuingAuthority)
+	rec.IssuedAt = issued
+	rec.ExpiresAt = expires
+	rec.Notes = trimmed(req.Notes)
+	rec.DocumentName = trimmed(req.DocumentName)
+	rec.DocumentContentType = trimmed(req.DocumentContentType)
+	rec.DocumentSizeBytes = req.DocumentSizeBytes
+	rec.DocumentSHA256 = checksum
+	rec.DocumentURL = trimmed(req.DocumentURL)
+	return nil
+}
+
+func trimmed(v *string) *string {
+	if v == nil {
+		return nil
+	}
+	t := strings.TrimSpace(*v)
+	if t == "" {
+		return nil
+	}
+	return &t
+}
+
+// date truncates t to midnight of its UTC calendar day.
+func date(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,173 @@
+package qualification_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/nomenarkt/lamina/internal/qualification"
+)
+
+type MockQualificationRepo struct {
+	mock.Mock
+}
+
+func (m *MockQualificationRepo) ListTypes(ctx context.Context) ([]qualification.Type, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]qualification.Type), args.Error(1)
+}
+
+func (m *MockQualificationRepo) GetType(ctx context.Context, id int64) (qualification.Type, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(qualification.Type), args.Error(1)
+}
+
+func (m *MockQualificationRepo) CreateType(ctx context.Context, t *qualification.Type) error {
+	return m.Called(ctx, t).Error(0)
+}
+
+func (m *MockQualificationRepo) UpdateType(ctx context.Context, t *qualification.Type) error {
+	return m.Called(ctx, t).Error(0)
+}
+
+func (m *MockQualificationRepo) DeleteType(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockQualificationRepo) ListRecordsByUser(ctx context.Context, userID int64) ([]qualification.Record, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]qualification.Record), args.Error(1)
+}
+
+func (m *MockQualificationRepo) GetRecord(ctx context.Context, id int64) (qualification.Record, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(qualification.Record), args.Error(1)
+}
+
+func (m *MockQualificationRepo) CreateRecord(ctx context.Context, r *qualification.Record) error {
+	return m.Called(ctx, r).Error(0)
+}
+
+func (m *MockQualificationRepo) UpdateRecord(ctx context.Context, r *qualification.Record) error {
+	return m.Called(ctx, r).Error(0)
+}
+
+func (m *MockQualificationRepo) DeleteRecord(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockQualificationRepo) ListExpiring(ctx context.Context, from, to time.Time) ([]qualification.Record, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]qualification.Record), args.Error(1)
+}
+
+func TestService_CreateType_NormalisesInput(t *testing.T) {
+	repo := new(MockQualificationRepo)
+	service := qualification.NewService(repo)
+
+	at7 := " at7 "
+	months := 12
+	repo.On("CreateType", mock.Anything, mock.MatchedBy(func(t *qualification.Type) bool {
+		return t.Code == "TR-AT7" && *t.AircraftType == "AT7" &&
+			len(t.RequiredForRoles) == 2 && t.RequiredForRoles[0] == "CDB" && t.RequiredForRoles[1] == "OPL"
+	})).Return(nil)
+
+	_, err := service.CreateType(context.Background(), qualification.TypeRequest{
+		Code: "tr-at7", Name: "ATR 72 type rating", Category: "Type_Rating",
+		AircraftType: &at7, RequiredForRoles: []string{"cdb", "OPL", "CDB"}, ValidityMonths: &months,
+	})
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestService_CreateType_RejectsUnknownCategory(t *testing.T) {
+	repo := new(MockQualificationRepo)
+	service := qualification.NewService(repo)
+
+	_, err := service.CreateType(context.Background(), qualification.TypeRequest{Code: "X", Name: "X", Category: "hobby"})
+	assert.ErrorIs(t, err, qualification.ErrInvalidQualification)
+	repo.AssertNotCalled(t, "CreateType", mock.Anything, mock.Anything)
+}
+
+func TestService_DeleteType_NotFound(t *testing.T) {
+	repo := new(MockQualificationRepo)
+	service := qualification.NewService(repo)
+
+	repo.On("DeleteType", mock.Anything, int64(9)).Return(false, nil)
+
+	assert.ErrorIs(t, service.DeleteType(context.Background(), 9), qualification.ErrTypeNotFound)
+}
+
+func TestService_AddQualification_DerivesExpiryFromValidity(t *testing.T) {
+	repo := new(MockQualificationRepo)
+	service := qualification.NewService(repo)
+
+	months := 12
+	repo.On("GetType", mock.Anything, int64(3)).Return(qualification.Type{ID: 3, ValidityMonths: &months}, nil)
+	repo.On("CreateRecord", mock.Anything, mock.MatchedBy(func(r *qualification.Record) bool {
+		return r.UserID == 7 && r.TypeID == 3 && *r.CreatedBy == 1 &&
+			r.ExpiresAt != nil && r.ExpiresAt.Equal(time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC))
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*qualification.Record).ID = 50
+	}).Return(nil)
+	repo.On("GetRecord", mock.Anything, int64(50)).Return(qualification.Record{ID: 50, IssuedAt: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)}, nil)
+
+	rec, err := service.AddQualification(context.Background(), 7, qualification.RecordRequest{TypeID: 3, IssuedAt: "2025-03-15"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), rec.ID)
+	repo.AssertExpectations(t)
+}
+
+func TestService_AddQualification_ExpiryBeforeIssue(t *testing.T) {
+	repo := new(MockQualificationRepo)
+	service := qualification.NewService(repo)
+
+	expires := "2025-01-01"
+	repo.On("GetType", mock.Anything, int64(3)).Return(qualification.Type{ID: 3}, nil)
+
+	_, err := service.AddQualification(context.Background(), 7, qualification.RecordRequest{TypeID: 3, IssuedAt: "2025-03-15", ExpiresAt: &expires}, 1)
+	assert.ErrorIs(t, err, qualification.ErrInvalidQualification)
+	repo.AssertNotCalled(t, "CreateRecord", mock.Anything, mock.Anything)
+}
+
+func TestService_AddQualification_UnknownType(t *testing.T) {
+	repo := new(MockQualificationRepo)
+	service := qualification.NewService(repo)
+
+	repo.On("GetType", mock.Anything, int64(4)).Return(qualification.Type{}, sql.ErrNoRows)
+
+	_, err := service.AddQualification(context.Background(), 7, qualification.RecordRequest{TypeID: 4, IssuedAt: "2025-03-15"}, 1)
+	assert.ErrorIs(t, err, qualification.ErrTypeNotFound)
+}
+
+func TestService_ListUserQualifications_ClassifiesStatus(t *testing.T) {
+	repo := new(MockQualificationRepo)
+	service := qualification.NewService(repo)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	past, soon, later := today.AddDate(0, 0, -1), today.AddDate(0, 0, 10), today.AddDate(1, 0, 0)
+	repo.On("ListRecordsByUser", mock.Anything, int64(7)).Return([]qualification.Record{
+		{ID: 1, IssuedAt: today.AddDate(-1, 0, 0), ExpiresAt: &past},
+		{ID: 2, IssuedAt: today.AddDate(-1, 0, 0), ExpiresAt: &soon},
+		{ID: 3, IssuedAt: today.AddDate(-1, 0, 0), ExpiresAt: &later},
+		{ID: 4, IssuedAt: today.AddDate(0, 0, 5)},
+		{ID: 5, IssuedAt: today.AddDate(-1, 0, 0)},
+	}, nil)
+
+	records, err := service.ListUserQualifications(context.Background(), 7)
+	assert.NoError(t, err)
+	statuses := []string{}
+	for _, r := range records {
+		statuses = append(statuses, r.Status)
+	}
+	assert.Equal(t, []string{
+		qualification.StatusExpired, qualification.StatusExpiring, qualification.StatusValid,
+		qualification.StatusNotYetValid, qualification.StatusValid,
+	}, statuses)
+}
@@ -0,0 +1,39 @@
+package tasks
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nomenarkt/lamina/common/mailer"
+)
+
+// StartEmailOutboxTask starts a background job that delivers queued emails at MAIL_OUTBOX_INTERVAL.
+func StartEmailOutboxTask(dispatcher *mailer.Dispatcher) {
+	intervalStr := os.Getenv("MAIL_OUTBOX_INTERVAL")
+	if intervalStr == "" {
+		intervalStr = "10s"
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil {
+		log.Printf("❌ Invalid MAIL_OUTBOX_INTERVAL=%q: %v. Using default 10s", intervalStr, err)
+		interval = 10 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			<-ticker.C
+			sent, err := dispatcher.DispatchDue(context.Background())
+			if err != nil {
+				log.Printf("❌ Failed to process email outbox: %v", err)
+			} else if sent > 0 {
+				log.Printf("📬 Delivered %d queued emails", sent)
+			}
+		}
+	}()
+}
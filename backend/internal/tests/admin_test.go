@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/common/mailer"
 	"github.com/nomenarkt/lamina/internal/admin"
 	"github.com/nomenarkt/lamina/internal/middleware"
 	testutils "github.com/nomenarkt/lamina/internal/tests/testutils"
@@ -55,7 +56,7 @@ func setupRouterWithService(service *admin.Service) *gin.Engine {
 func TestCreateUser_Unauthorized(t *testing.T) {
 	_ = os.Setenv("JWT_SECRET", "mytestsecret")
 
-	service := admin.NewAdminService(new(testutils.MockAdminRepo), new(testutils.MockHasher))
+	service := admin.NewAdminService(new(testutils.MockAdminRepo), new(testutils.MockHasher), new(testutils.MockMailer))
 	router := setupRouterWithService(service)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/create-user", nil)
@@ -68,7 +69,7 @@ func TestCreateUser_Unauthorized(t *testing.T) {
 func TestCreateUser_ForbiddenForViewer(t *testing.T) {
 	_ = os.Setenv("JWT_SECRET", "mytestsecret")
 
-	service := admin.NewAdminService(new(testutils.MockAdminRepo), new(testutils.MockHasher))
+	service := admin.NewAdminService(new(testutils.MockAdminRepo), new(testutils.MockHasher), new(testutils.MockMailer))
 	router := setupRouterWithService(service)
 
 	token, _ := middleware.GenerateJWT("mytestsecret", 1234, "viewer@madagascarairlines.com", "viewer")
@@ -95,7 +96,12 @@ func TestCreateUser_Success_WithAdminRole(t *testing.T) {
 	mockRepo.On("FindUserIDByEmail", mock.Anything, reqEmail).Return(tokenUserID, nil)
 	mockRepo.On("SetConfirmationToken", mock.Anything, tokenUserID, mock.AnythingOfType("string")).Return(nil)
 
-	service := admin.NewAdminService(mockRepo, mockHasher)
+	mockMailer := new(testutils.MockMailer)
+	mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == reqEmail
+	})).Return(nil)
+
+	service := admin.NewAdminService(mockRepo, mockHasher, mockMailer)
 	router := setupRouterWithService(service)
 
 	token, _ := middleware.GenerateJWT("mytestsecret", 1, "admin@madagascarairlines.com", "admin")
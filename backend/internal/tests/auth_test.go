@@ -48,6 +48,61 @@ func (m *MockAuthService) ConfirmRegistration(ctx context.Context, token string)
 	return args.Error(0)
 }
 
+func (m *MockAuthService) Refresh(ctx context.Context, refreshToken string) (auth.Response, error) {
+	args := m.Called(ctx, refreshToken)
+	return args.Get(0).(auth.Response), args.Error(1)
+}
+
+func (m *MockAuthService) Logout(ctx context.Context, refreshToken string) error {
+	args := m.Called(ctx, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ForgotPassword(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResetPassword(ctx context.Context, token string, password string) error {
+	args := m.Called(ctx, token, password)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) BeginMFAEnrollment(ctx context.Context, userID int64) (auth.MFAEnrollment, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(auth.MFAEnrollment), args.Error(1)
+}
+
+func (m *MockAuthService) BeginMFAEnrollmentWithToken(ctx context.Context, mfaToken string) (auth.MFAEnrollment, error) {
+	args := m.Called(ctx, mfaToken)
+	return args.Get(0).(auth.MFAEnrollment), args.Error(1)
+}
+
+func (m *MockAuthService) ConfirmMFAEnrollment(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) DisableMFA(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) CompleteMFALogin(ctx context.Context, mfaToken, code string) (auth.Response, error) {
+	args := m.Called(ctx, mfaToken, code)
+	return args.Get(0).(auth.Response), args.Error(1)
+}
+
+func (m *MockAuthService) ListMFARoleRequirements(ctx context.Context) ([]auth.MFARoleRequirement, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]auth.MFARoleRequirement), args.Error(1)
+}
+
+func (m *MockAuthService) SetMFARoleRequirement(ctx context.Context, role string, required bool, updatedBy int64) error {
+	args := m.Called(ctx, role, required, updatedBy)
+	return args.Error(0)
+}
+
 func setupRouterWithMock(t *testing.T, service auth.ServiceInterface) *gin.Engine {
 	t.Helper()
 
@@ -59,6 +114,9 @@ func setupRouterWithMock(t *testing.T, service auth.ServiceInterface) *gin.Engin
 	router := gin.New()
 	v1 := router.Group("/api/v1")
 	auth.RegisterRoutes(v1, nil, service)
+	if mfa, ok := service.(auth.MFAServiceInterface); ok {
+		auth.RegisterMFALoginRoutes(v1, mfa)
+	}
 	return router
 }
 
@@ -144,3 +202,112 @@ func TestLogin_BadJSON(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "invalid character")
 }
+
+func TestRefresh_Success(t *testing.T) {
+	mockService := new(MockAuthService)
+	router := setupRouterWithMock(t, mockService)
+
+	mockService.On("Refresh", mock.Anything, "refresh123").Return(auth.Response{
+		AccessToken:  "access456",
+		RefreshToken: "refresh456",
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewBufferString(`{"refresh_token":"refresh123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "refresh456")
+}
+
+func TestRefresh_ReuseDetected(t *testing.T) {
+	mockService := new(MockAuthService)
+	router := setupRouterWithMock(t, mockService)
+
+	mockService.On("Refresh", mock.Anything, "stolen").Return(auth.Response{}, auth.ErrRefreshTokenReuse)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewBufferString(`{"refresh_token":"stolen"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "session revoked")
+}
+
+func TestForgotPassword_SameResponseForUnknownEmail(t *testing.T) {
+	mockService := new(MockAuthService)
+	router := setupRouterWithMock(t, mockService)
+
+	mockService.On("ForgotPassword", mock.Anything, "known@madagascarairlines.com").Return(nil)
+	mockService.On("ForgotPassword", mock.Anything, "unknown@madagascarairlines.com").Return(nil)
+
+	send := func(email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/forgot-password", bytes.NewBufferString(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	known := send("known@madagascarairlines.com")
+	unknown := send("unknown@madagascarairlines.com")
+
+	assert.Equal(t, http.StatusOK, known.Code)
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+}
+
+func TestResetPassword_MismatchedPasswords(t *testing.T) {
+	mockService := new(MockAuthService)
+	router := setupRouterWithMock(t, mockService)
+
+	body := `{"token":"abc","password":"newpassword","confirm_password":"different1"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/reset-password", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "passwords do not match")
+	mockService.AssertNotCalled(t, "ResetPassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLogin_MFARequired(t *testing.T) {
+	mockService := new(MockAuthService)
+	router := setupRouterWithMock(t, mockService)
+
+	loginReq := auth.LoginRequest{Email: "pilot@madagascarairlines.com", Password: "pass1234"}
+	mockService.On("Login", mock.Anything, loginReq).Return(auth.Response{MFARequired: true, MFAToken: "pending"}, nil)
+
+	body, _ := json.Marshal(loginReq)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"mfa_required":true`)
+	assert.Contains(t, w.Body.String(), `"mfa_token":"pending"`)
+}
+
+func TestMFALogin_InvalidCode(t *testing.T) {
+	mockService := new(MockAuthService)
+	router := setupRouterWithMock(t, mockService)
+
+	mockService.On("CompleteMFALogin", mock.Anything, "pending", "000000").Return(auth.Response{}, auth.ErrInvalidMFACode)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/mfa/login", bytes.NewBufferString(`{"mfa_token":"pending","code":"000000"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid mfa code")
+}
@@ -0,0 +1,322 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/internal/access"
+	"github.com/nomenarkt/lamina/internal/crew"
+	"github.com/nomenarkt/lamina/internal/flight"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockMembershipResolver struct {
+	mock.Mock
+}
+
+func (m *MockMembershipResolver) ListUserUnitIDs(ctx context.Context, userID int64) ([]int, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+// setupScopedRouter mounts the flight and crew routes behind the Casbin middleware with a
+// planner in org unit 1 who may read flights and crew lists.
+func setupScopedRouter(t *testing.T, userID int64, role string, members access.MembershipResolver) (*gin.Engine, *MockFlightService, *MockCrewService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	e := access.InitTestEnforcer(t)
+	_, err := e.AddPolicy("planner", "orgunit:1", "/api/v1/flights*", "GET")
+	require.NoError(t, err)
+	_, err = e.AddPolicy("planner", "orgunit:1", "/api/v1/crew/flight/*", "*")
+	require.NoError(t, err)
+	_, err = e.AddGroupingPolicy("user:201", "planner", "orgunit:1")
+	require.NoError(t, err)
+
+	flightService := new(MockFlightService)
+	crewService := new(MockCrewService)
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("userRole", role)
+		c.Next()
+	})
+	scope := access.CasbinMiddleware(members)
+	flight.RegisterRoutes(api, flight.NewHandler(flightService), scope)
+	crew.RegisterRoutes(api, crew.NewHandler(crewService), scope)
+	return r, flightService, crewService
+}
+
+func TestCasbinMiddleware_AllowsPolicyInMemberUnit(t *testing.T) {
+	members := new(MockMembershipResolver)
+	members.On("ListUserUnitIDs", mock.Anything, int64(201)).Return([]int{3, 1}, nil)
+	router, flights, _ := setupScopedRouter(t, 201, "user", members)
+	flights.On("ListFlights", mock.Anything, mock.Anything).Return([]flight.Flight{{ID: 1, FlightNumber: "MD700"}}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/flights", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "MD700")
+}
+
+func TestCasbinMiddleware_DeniesMethodWithoutPolicy(t *testing.T) {
+	members := new(MockMembershipResolver)
+	members.On("ListUserUnitIDs", mock.Anything, int64(201)).Return([]int{1}, nil)
+	router, flights, _ := setupScopedRouter(t, 201, "user", members)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/flights/7/cancel", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	flights.AssertNotCalled(t, "CancelFlight", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCasbinMiddleware_KeyMatchesPathParameters(t *testing.T) {
+	members := new(MockMembershipResolver)
+	members.On("ListUserUnitIDs", mock.Anything, int64(201)).Return([]int{1}, nil)
+	router, _, crewService := setupScopedRouter(t, 201, "user", members)
+	crewService.On("GetDetailedCrewByFlight", mock.Anything, int64(42)).Return([]crew.AssignmentDetail{}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/crew/flight/42", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCasbinMiddleware_ExplicitOrgUnit(t *testing.T) {
+	members := new(MockMembershipResolver)
+	router, flights, _ := setupScopedRouter(t, 201, "user", members)
+	flights.On("ListFlights", mock.Anything, mock.Anything).Return([]flight.Flight{}, nil)
+
+	for header, expected := range map[string]int{"1": http.StatusOK, "2": http.StatusForbidden, "abc": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/flights", nil)
+		req.Header.Set(access.OrgUnitHeader, header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, expected, w.Code, "org unit %s", header)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/flights?org_unit_id=1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	members.AssertNotCalled(t, "ListUserUnitIDs", mock.Anything, mock.Anything)
+}
+
+func TestCasbinMiddleware_RejectsUserWithoutUnits(t *testing.T) {
+	members := new(MockMembershipResolver)
+	members.On("ListUserUnitIDs", mock.Anything, int64(301)).Return([]int{}, nil)
+	router, _, _ := setupScopedRouter(t, 301, "user", members)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/flights", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "no organizational unit")
+}
+
+func TestCasbinMiddleware_OtherUserInSameUnitIsDenied(t *testing.T) {
+	members := new(MockMembershipResolver)
+	members.On("ListUserUnitIDs", mock.Anything, int64(302)).Return([]int{1}, nil)
+	router, _, _ := setupScopedRouter(t, 302, "user", members)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/flights", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCasbinMiddleware_AdminBypassesPolicies(t *testing.T) {
+	members := new(MockMembershipResolver)
+	router, flights, _ := setupScopedRouter(t, 1, "admin", members)
+	flights.On("ListFlights", mock.Anything, mock.Anything).Return([]flight.Flight{}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/flights", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	members.AssertNotCalled(t, "ListUserUnitIDs", mock.Anything, mock.Anything)
+}
+
+func TestCasbinMiddleware_OwnRosterIsNotScoped(t *testing.T) {
+	members := new(MockMembershipResolver)
+	router, _, crewService := setupScopedRouter(t, 302, "user", members)
+	crewService.On("RosterCalendar", mock.Anything, int64(302)).Return("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/crew/me/roster.ics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	members.AssertNotCalled(t, "ListUserUnitIDs", mock.Anything, mock.Anything)
+}
+
+func TestPolicyIndex_LinkRoleGrantsAccessWithoutWritingAdapter(t *testing.T) {
+	enforcer := access.InitTestEnforcer(t)
+	_, err := enforcer.AddPolicy("planner", "orgunit:1", "/api/v1/flights*", "GET")
+	require.NoError(t, err)
+
+	rules := access.PolicyIndex{}
+	require.NoError(t, rules.LinkRole(42, "planner", 1))
+	require.NoError(t, rules.LinkRole(42, "planner", 1)) // already loaded
+
+	ok, err := enforcer.Enforce("user:42", "orgunit:1", "/api/v1/flights", "GET")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, rules.UnlinkRole(42, "planner", 1))
+	ok, err = enforcer.Enforce("user:42", "orgunit:1", "/api/v1/flights", "GET")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The repository stores the rule itself; reloading shows the adapter was never written.
+	require.NoError(t, rules.LinkRole(42, "planner", 1))
+	require.NoError(t, rules.ReloadPolicies())
+	linked, err := enforcer.HasGroupingPolicy("user:42", "planner", "orgunit:1")
+	require.NoError(t, err)
+	assert.False(t, linked)
+}
+
+// setupHierarchicalRouter mounts the flight routes behind the Casbin middleware using the
+// hierarchical model over direction 1 > department 2 > section 3, with a planner of the
+// direction who may read flights.
+func setupHierarchicalRouter(t *testing.T, parents map[int]int) (*gin.Engine, *MockFlightService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	e := access.InitHierarchicalTestEnforcer(t, parents)
+	_, err := e.AddPolicy("planner", "orgunit:1", "/api/v1/flights*", "GET")
+	require.NoError(t, err)
+	_, err = e.AddGroupingPolicy("user:201", "planner", "orgunit:1")
+	require.NoError(t, err)
+
+	flightService := new(MockFlightService)
+	flightService.On("ListFlights", mock.Anything, mock.Anything).Return([]flight.Flight{}, nil)
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) {
+		c.Set("userID", int64(201))
+		c.Set("userRole", "user")
+		c.Next()
+	})
+	flight.RegisterRoutes(api, flight.NewHandler(flightService), access.CasbinMiddleware(new(MockMembershipResolver)))
+	return r, flightService
+}
+
+func getFlightsIn(router *gin.Engine, unit string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/flights", nil)
+	req.Header.Set(access.OrgUnitHeader, unit)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestHierarchicalModel_ParentGrantAppliesToDescendants(t *testing.T) {
+	parents := map[int]int{1: 0, 2: 1, 3: 2, 4: 0}
+	router, _ := setupHierarchicalRouter(t, parents)
+
+	assert.Equal(t, http.StatusOK, getFlightsIn(router, "1"))
+	assert.Equal(t, http.StatusOK, getFlightsIn(router, "3"))
+	assert.Equal(t, http.StatusForbidden, getFlightsIn(router, "4"))
+
+	// Moving section 3 under direction 4 takes it out of the grant once the tree is refreshed.
+	parents[3] = 4
+	require.NoError(t, access.PolicyIndex{}.UnitsChanged())
+	assert.Equal(t, http.StatusForbidden, getFlightsIn(router, "3"))
+	assert.Equal(t, http.StatusOK, getFlightsIn(router, "2"))
+}
+
+func TestHierarchicalModel_DenyOnSubUnit(t *testing.T) {
+	router, _ := setupHierarchicalRouter(t, map[int]int{1: 0, 2: 1, 3: 2})
+	_, err := access.GetEnforcer().AddNamedPolicy(access.DenyPolicyType, "planner", "orgunit:2", "/api/v1/flights*", "*")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, getFlightsIn(router, "1"))
+	assert.Equal(t, http.StatusForbidden, getFlightsIn(router, "2"))
+	assert.Equal(t, http.StatusForbidden, getFlightsIn(router, "3"))
+
+	d, err := access.Explain("user:201", 3, "/api/v1/flights", "GET")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, []string{"planner", "orgunit:2", "/api/v1/flights*", "*"}, d.DeniedBy)
+
+	d, err = access.Explain("user:201", 1, "/api/v1/flights", "GET")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, []string{"user:201", "planner"}, d.RoleChain)
+}
+
+func TestApplyPolicyUpdate_ChangesModelWithoutWritingAdapter(t *testing.T) {
+	e := access.InitTestEnforcer(t)
+
+	require.NoError(t, access.ApplyPolicyUpdate(e, access.PolicyUpdate{
+		Op: access.UpdateAdd, Sec: "p", PType: "p",
+		Rules: [][]string{{"planner", "orgunit:1", "/api/v1/flights*", "GET"}},
+	}))
+	require.NoError(t, access.ApplyPolicyUpdate(e, access.PolicyUpdate{
+		Op: access.UpdateAdd, Sec: "g", PType: "g",
+		Rules: [][]string{{"user:201", "planner", "orgunit:1"}, {"user:202", "planner", "orgunit:1"}},
+	}))
+	ok, err := e.Enforce("user:202", "orgunit:1", "/api/v1/flights", "GET")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, access.ApplyPolicyUpdate(e, access.PolicyUpdate{
+		Op: access.UpdateRemove, Sec: "g", PType: "g",
+		Rules: [][]string{{"user:202", "planner", "orgunit:1"}},
+	}))
+	ok, _ = e.Enforce("user:202", "orgunit:1", "/api/v1/flights", "GET")
+	assert.False(t, ok)
+	ok, _ = e.Enforce("user:201", "orgunit:1", "/api/v1/flights", "GET")
+	assert.True(t, ok)
+
+	// The publishing instance stored the rules; reloading from this adapter drops them.
+	require.NoError(t, access.ApplyPolicyUpdate(e, access.PolicyUpdate{Op: access.UpdateReload}))
+	ok, _ = e.Enforce("user:201", "orgunit:1", "/api/v1/flights", "GET")
+	assert.False(t, ok)
+}
+
+// Run with -race: the watcher applies updates while requests enforce on the same enforcer.
+func TestApplyPolicyUpdate_ConcurrentWithEnforce(t *testing.T) {
+	e := access.InitHierarchicalTestEnforcer(t, map[int]int{1: 0, 2: 1})
+	_, err := e.AddPolicy("planner", "orgunit:1", "/api/v1/flights*", "GET")
+	require.NoError(t, err)
+	_, err = e.AddNamedPolicy(access.DenyPolicyType, "planner", "orgunit:2", "/api/v1/flights*", "GET")
+	require.NoError(t, err)
+
+	grouping := access.PolicyUpdate{Sec: "g", PType: "g", Rules: [][]string{{"user:201", "planner", "orgunit:1"}}}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			grouping.Op = access.UpdateAdd
+			assert.NoError(t, access.ApplyPolicyUpdate(e, grouping))
+			grouping.Op = access.UpdateRemove
+			assert.NoError(t, access.ApplyPolicyUpdate(e, grouping))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			_, err := e.Enforce("user:201", "orgunit:1", "/api/v1/flights", "GET")
+			assert.NoError(t, err)
+			_, err = access.DenyingRule("user:201", "orgunit:2", "/api/v1/flights", "GET")
+			assert.NoError(t, err)
+		}
+	}()
+	wg.Wait()
+
+	ok, err := e.Enforce("user:201", "orgunit:1", "/api/v1/flights", "GET")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -1,7 +1,9 @@
 package tests
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -28,6 +30,12 @@ func (m *MockCrewService) AssignCrew(ctx context.Context, ca *crew.Assignment) e
 	return args.Error(0)
 }
 
+func (m *MockCrewService) AssignCrewWithOverride(ctx context.Context, ca *crew.Assignment, reason string, supervisorID int64) (*crew.FTLOverride, error) {
+	args := m.Called(ctx, ca, reason, supervisorID)
+	override, _ := args.Get(0).(*crew.FTLOverride)
+	return override, args.Error(1)
+}
+
 func (m *MockCrewService) RemoveCrewByFlight(ctx context.Context, flightID int64) error {
 	args := m.Called(ctx, flightID)
 	return args.Error(0)
@@ -38,11 +46,31 @@ func (m *MockCrewService) GetDetailedCrewByFlight(ctx context.Context, flightID
 	return args.Get(0).([]crew.AssignmentDetail), args.Error(1)
 }
 
-func (m *MockCrewService) ResolveFlightID(ctx context.Context, flightNumber string) (int64, error) {
-	args := m.Called(ctx, flightNumber)
+func (m *MockCrewService) ResolveFlightID(ctx context.Context, ref crew.FlightRef) (int64, error) {
+	args := m.Called(ctx, ref)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockCrewService) RosterCalendar(ctx context.Context, crewID int64) (string, error) {
+	args := m.Called(ctx, crewID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockCrewService) RosterCalendarByToken(ctx context.Context, token string) (string, error) {
+	args := m.Called(ctx, token)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockCrewService) CreateRosterFeedToken(ctx context.Context, userID int64) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockCrewService) RevokeRosterFeedToken(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 func setupRouterWithHandler(handler *crew.Handler) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -99,6 +127,134 @@ func TestGetCrewByFlight_Success(t *testing.T) {
 	assert.JSONEq(t, expected, w.Body.String())
 }
 
+var md700 = crew.FlightRef{Number: "MD700", Date: "2025-03-03"}
+
+const assignPayload = `{"flight_number":"MD700","flight_date":"2025-03-03","crew_id":3199,"crew_role":"CDB","in_function":true,
+	"checkin_time":"2025-03-03T05:00:00Z","checkout_time":"2025-03-03T08:00:00Z"%s}`
+
+func TestAssignCrew_IllegalAssignmentExplainsViolations(t *testing.T) {
+	mockService := new(MockCrewService)
+	router := setupRouterWithHandler(crew.NewHandler(mockService))
+
+	mockService.On("ResolveFlightID", mock.Anything, md700).Return(int64(42), nil)
+	mockService.On("AssignCrew", mock.Anything, mock.Anything).Return(&crew.LegalityError{
+		RuleSet:    "EASA",
+		Violations: []crew.Violation{{Rule: crew.RuleMinRest, LimitMinutes: 720, ActualMinutes: 540}},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/crew/assign", bytes.NewBufferString(fmt.Sprintf(assignPayload, "")))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"rule":"min_rest"`)
+	assert.Contains(t, w.Body.String(), `"rule_set":"EASA"`)
+}
+
+func TestAssignCrew_MissingQualificationsAreListed(t *testing.T) {
+	mockService := new(MockCrewService)
+	router := setupRouterWithHandler(crew.NewHandler(mockService))
+
+	mockService.On("ResolveFlightID", mock.Anything, md700).Return(int64(42), nil)
+	mockService.On("AssignCrew", mock.Anything, mock.Anything).Return(&crew.QualificationError{
+		Missing: []crew.MissingQualification{{Code: "MED-1", Name: "Class 1 medical", Reason: "expired"}},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/crew/assign", bytes.NewBufferString(fmt.Sprintf(assignPayload, "")))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"missing_qualifications"`)
+	assert.Contains(t, w.Body.String(), `"code":"MED-1"`)
+}
+
+func TestAssignCrew_FlightMustBeIdentifiedByDate(t *testing.T) {
+	mockService := new(MockCrewService)
+	router := setupRouterWithHandler(crew.NewHandler(mockService))
+
+	req := httptest.NewRequest(http.MethodPost, "/crew/assign", bytes.NewBufferString(`{"flight_number":"MD700","crew_id":3199}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.On("ResolveFlightID", mock.Anything, md700).Return(int64(0), crew.ErrAmbiguousFlight)
+	req = httptest.NewRequest(http.MethodPost, "/crew/assign", bytes.NewBufferString(fmt.Sprintf(assignPayload, "")))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.AssertNotCalled(t, "AssignCrew", mock.Anything, mock.Anything)
+}
+
+func TestAssignCrew_OverrideRequiresSupervisor(t *testing.T) {
+	mockService := new(MockCrewService)
+	handler := crew.NewHandler(mockService)
+	mockService.On("ResolveFlightID", mock.Anything, md700).Return(int64(42), nil)
+
+	for role, expected := range map[string]int{"viewer": http.StatusForbidden, "supervisor": http.StatusCreated} {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.POST("/crew/assign", func(c *gin.Context) {
+			c.Set("userID", int64(7))
+			c.Set("userRole", role)
+			c.Next()
+		}, handler.AssignCrew)
+
+		if role == "supervisor" {
+			mockService.On("AssignCrewWithOverride", mock.Anything, mock.Anything, "AOG recovery", int64(7)).
+				Return(&crew.FTLOverride{Reason: "AOG recovery"}, nil)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/crew/assign",
+			bytes.NewBufferString(fmt.Sprintf(assignPayload, `,"override_reason":"AOG recovery"`)))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Code, role)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestRosterFeed_ServesCalendarAndRejectsUnknownToken(t *testing.T) {
+	mockService := new(MockCrewService)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	crew.RegisterPublicRoutes(r.Group("/api/v1"), crew.NewHandler(mockService))
+
+	mockService.On("RosterCalendarByToken", mock.Anything, "abc").Return("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n", nil)
+	mockService.On("RosterCalendarByToken", mock.Anything, "bad").Return("", crew.ErrInvalidRosterToken)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/crew/roster/abc.ics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "BEGIN:VCALENDAR")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/crew/roster/bad.ics", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateRosterFeed_ReturnsSubscriptionURL(t *testing.T) {
+	mockService := new(MockCrewService)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) { c.Set("userID", int64(5)); c.Next() })
+	crew.RegisterRoutes(api, crew.NewHandler(mockService))
+
+	mockService.On("CreateRosterFeedToken", mock.Anything, int64(5)).Return("tok", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/crew/me/roster/feed", nil)
+	req.Host = "lamina.test"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"url":"http://lamina.test/api/v1/crew/roster/tok.ics"`)
+	assert.Contains(t, w.Body.String(), `"webcal_url":"webcal://lamina.test/api/v1/crew/roster/tok.ics"`)
+}
+
 func TestAccessCrossOrgCrew_ShouldFail(t *testing.T) {
 	// Simulate a request where viewer is trying to access a flight not belonging to their org
 	gin.SetMode(gin.TestMode)
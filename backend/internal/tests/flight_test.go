@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nomenarkt/lamina/internal/flight"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFlightService struct {
+	mock.Mock
+}
+
+func (m *MockFlightService) CreateFlight(ctx context.Context, req flight.ScheduleRequest) (flight.Flight, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightService) GetFlight(ctx context.Context, id int64) (flight.Flight, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightService) ListFlights(ctx context.Context, filter flight.ListFilter) ([]flight.Flight, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightService) UpdateSchedule(ctx context.Context, id int64, req flight.ScheduleRequest) (flight.Flight, error) {
+	args := m.Called(ctx, id, req)
+	return args.Get(0).(flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightService) RecordActuals(ctx context.Context, id int64, req flight.ActualsRequest) (flight.Flight, error) {
+	args := m.Called(ctx, id, req)
+	return args.Get(0).(flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightService) CancelFlight(ctx context.Context, id int64, reason string) (flight.Flight, error) {
+	args := m.Called(ctx, id, reason)
+	return args.Get(0).(flight.Flight), args.Error(1)
+}
+
+func (m *MockFlightService) ImportSSIM(ctx context.Context, r io.Reader, dryRun bool) (flight.ImportReport, error) {
+	args := m.Called(ctx, r, dryRun)
+	return args.Get(0).(flight.ImportReport), args.Error(1)
+}
+
+func setupFlightRouter(service flight.ServiceInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	flight.RegisterRoutes(r.Group("/api/v1"), flight.NewHandler(service))
+	return r
+}
+
+func TestListFlights_ParsesFilters(t *testing.T) {
+	mockService := new(MockFlightService)
+	router := setupFlightRouter(mockService)
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	mockService.On("ListFlights", mock.Anything, flight.ListFilter{
+		From: &from, To: &to, Station: "TNR", Registration: "5R-MJA",
+	}).Return([]flight.Flight{{ID: 1, FlightNumber: "MD700"}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/flights?from=2025-03-01&to=2025-03-02&station=TNR&registration=5R-MJA", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "MD700")
+}
+
+func TestCreateFlight_ValidationError(t *testing.T) {
+	mockService := new(MockFlightService)
+	router := setupFlightRouter(mockService)
+
+	mockService.On("CreateFlight", mock.Anything, mock.Anything).
+		Return(flight.Flight{}, flight.ErrInvalidFlight)
+
+	body := `{"flight_number":"MD700","departure_code":"TNR","arrival_code":"TNR",
+		"scheduled_departure":"2025-03-01T06:00:00Z","scheduled_arrival":"2025-03-01T07:15:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/flights", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCancelFlight_Conflict(t *testing.T) {
+	mockService := new(MockFlightService)
+	router := setupFlightRouter(mockService)
+
+	mockService.On("CancelFlight", mock.Anything, int64(7), "weather").
+		Return(flight.Flight{}, flight.ErrFlightCancelled)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/flights/7/cancel", bytes.NewBufferString(`{"reason":"weather"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestImportSSIM_DryRunFromRawBody(t *testing.T) {
+	mockService := new(MockFlightService)
+	router := setupFlightRouter(mockService)
+
+	mockService.On("ImportSSIM", mock.Anything, mock.Anything, true).
+		Return(flight.ImportReport{DryRun: true, Records: 1, Flights: 2}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/flights/import?dry_run=true", bytes.NewBufferString("3 MD  700..."))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"dry_run":true`)
+	mockService.AssertExpectations(t)
+}
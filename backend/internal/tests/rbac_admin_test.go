@@ -42,7 +42,7 @@ func setupRouterWithDeleteSelf(_ *admin.Service) *gin.Engine {
 func TestAdminSelfDelete_ShouldFail(t *testing.T) {
 	_ = os.Setenv("JWT_SECRET", "selfsecret")
 
-	service := admin.NewAdminService(nil, nil)
+	service := admin.NewAdminService(nil, nil, nil)
 	router := setupRouterWithDeleteSelf(service)
 
 	token, _ := middleware.GenerateJWT("selfsecret", 1, "admin@madagascarairlines.com", "admin")
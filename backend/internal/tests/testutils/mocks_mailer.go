@@ -0,0 +1,19 @@
+package testutils
+
+import (
+	"context"
+
+	"github.com/nomenarkt/lamina/common/mailer"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMailer mocks outgoing email delivery.
+type MockMailer struct {
+	mock.Mock
+}
+
+// Send mocks handing a message to the mailer.
+func (m *MockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
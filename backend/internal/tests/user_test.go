@@ -20,6 +20,23 @@ type MockUserService struct {
 	ListUsersFunc         func(ctx context.Context) ([]user.User, error)
 	UpdateUserProfileFunc func(ctx context.Context, userID int64, req user.UpdateProfileRequest) error
 	CreateUserFunc        func(ctx context.Context, u *user.User) error
+	DirectoryFunc         func(ctx context.Context, f user.DirectoryFilter, view user.DirectoryView) (*user.DirectoryPage, error)
+}
+
+func (m *MockUserService) ListChildren(_ context.Context, _ int64) ([]user.Child, error) {
+	return []user.Child{}, nil
+}
+func (m *MockUserService) AddChild(_ context.Context, _ int64, c user.Child) (*user.Child, error) {
+	return &c, nil
+}
+func (m *MockUserService) UpdateChild(_ context.Context, _ int64, _ user.Child) error {
+	return nil
+}
+func (m *MockUserService) DeleteChild(_ context.Context, _, _ int64) error {
+	return nil
+}
+func (m *MockUserService) UploadProfilePicture(_ context.Context, _ int64, _ []byte) (*user.ProfilePicture, error) {
+	return &user.ProfilePicture{}, nil
 }
 
 func (m *MockUserService) GetMe(ctx context.Context, id int64) (*user.User, error) {
@@ -42,6 +59,13 @@ func (m *MockUserService) CreateUser(ctx context.Context, u *user.User) error {
 	return nil
 }
 
+func (m *MockUserService) Directory(ctx context.Context, f user.DirectoryFilter, view user.DirectoryView) (*user.DirectoryPage, error) {
+	if m.DirectoryFunc != nil {
+		return m.DirectoryFunc(ctx, f, view)
+	}
+	return &user.DirectoryPage{Users: []user.DirectoryEntry{}}, nil
+}
+
 // mockMiddleware injects a fixed userID into the Gin context to simulate an authenticated request
 func mockMiddleware(userID int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,207 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDirectoryLimit = 25
+	maxDirectoryLimit     = 100
+)
+
+// ErrInvalidDirectoryQuery is returned for unknown sort keys and malformed cursors.
+var ErrInvalidDirectoryQuery = errors.New("invalid directory query")
+
+// directorySorts maps the sort keys accepted by the directory to the SQL they order by
+// and the type their cursor value is cast back to.
+var directorySorts = map[string]struct{ expr, cast string }{
+	"name":       {"LOWER(COALESCE(u.full_name, ''))", "text"},
+	"email":      {"LOWER(u.email)", "text"},
+	"created_at": {"COALESCE(u.created_at, 'epoch'::timestamp)", "timestamp"},
+}
+
+// DirectoryFilter narrows and orders the user directory. Zero values are ignored.
+// Sort is name, email or created_at, prefixed with - for descending order.
+type DirectoryFilter struct {
+	Status     string
+	UserType   string
+	Role       string
+	UnitID     *int
+	FunctionID *int
+	RankID     *int
+	NationalID string // exact match; HR only
+	Search     string
+	Sort       string
+	Cursor     string
+	Limit      int
+
+	nationalIDIndex string // blind index of NationalID, set by EncryptedRepo
+}
+
+// DirectoryCursor marks the last entry of a page. It is opaque to clients.
+type DirectoryCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// Encode returns the cursor as a URL-safe string.
+func (c DirectoryCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeDirectoryCursor parses a cursor returned with a previous page.
+func DecodeDirectoryCursor(s string) (DirectoryCursor, error) {
+	var c DirectoryCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(raw, &c) != nil {
+		return DirectoryCursor{}, errors.New("malformed cursor")
+	}
+	return c, nil
+}
+
+// DirectoryRow is a user as read for the directory, with every field a caller may see.
+type DirectoryRow struct {
+	ID                    int64      `db:"id"`
+	Email                 string     `db:"email"`
+	FullName              *string    `db:"full_name"`
+	Role                  string     `db:"role"`
+	Status                string     `db:"status"`
+	UserType              string     `db:"user_type"`
+	ProfilePictureURL     *string    `db:"profile_picture_url"`
+	ProfilePictureKey     *string    `db:"profile_picture_key"`
+	CreatedAt             *time.Time `db:"created_at"`
+	Phone                 *string    `db:"phone"`
+	Address               *string    `db:"address"`
+	EmployeeID            *int       `db:"company_id"`
+	Sex                   *string    `db:"sex"`
+	Birthday              *string    `db:"birthday"`
+	MaritalStatus         *bool      `db:"marital_status"`
+	SpouseName            *string    `db:"spouse_name"`
+	HasChildren           *bool      `db:"has_children"`
+	NumberOfChildren      *int       `db:"number_of_children"`
+	NationalID            *string    `db:"national_id"`
+	EmergencyContactName  *string    `db:"emergency_contact_name"`
+	EmergencyContactPhone *string    `db:"emergency_contact_phone"`
+	AccessExpiresAt       *time.Time `db:"access_expires_at"`
+	SortKey               string     `db:"sort_key"`
+}
+
+// DirectoryContact holds the contact fields shown to planners.
+type DirectoryContact struct {
+	Phone   *string `json:"phone"`
+	Address *string `json:"address"`
+}
+
+// DirectoryHR holds the HR fields shown to HR roles.
+type DirectoryHR struct {
+	EmployeeID            *int       `json:"employee_id"`
+	Sex                   *string    `json:"sex"`
+	Birthday              *string    `json:"birthday"`
+	MaritalStatus         *bool      `json:"marital_status"`
+	SpouseName            *string    `json:"spouse_name"`
+	HasChildren           *bool      `json:"has_children"`
+	NumberOfChildren      *int       `json:"number_of_children"`
+	NationalID            *string    `json:"national_id"`
+	EmergencyContactName  *string    `json:"emergency_contact_name"`
+	EmergencyContactPhone *string    `json:"emergency_contact_phone"`
+	AccessExpiresAt       *time.Time `json:"access_expires_at"`
+}
+
+// DirectoryEntry is a user as shown in the directory. Contact and HR are present only
+// when the caller may see them.
+type DirectoryEntry struct {
+	ID                int64             `json:"id"`
+	Email             string            `json:"email"`
+	FullName          *string           `json:"full_name"`
+	Role              string            `json:"role"`
+	Status            string            `json:"status"`
+	UserType          string            `json:"user_type"`
+	ProfilePictureURL *string           `json:"profile_picture_url"`
+	Contact           *DirectoryContact `json:"contact,omitempty"`
+	HR                *DirectoryHR      `json:"hr,omitempty"`
+}
+
+// DirectoryPage is one page of the directory. NextCursor is empty on the last page.
+type DirectoryPage struct {
+	Users      []DirectoryEntry `json:"users"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// DirectoryView selects the field groups a caller may see.
+type DirectoryView struct {
+	Contact bool
+	HR      bool
+}
+
+// DirectoryViewFor returns the fields visible to a caller with the given JWT role and
+// department roles. Admins see everything; roles listed in DIRECTORY_HR_ROLES (default "hr")
+// see HR and contact fields, and those in DIRECTORY_CONTACT_ROLES (default "planner") contact fields.
+func DirectoryViewFor(role string, departments []DepartmentAccess) DirectoryView {
+	if role == "admin" {
+		return DirectoryView{Contact: true, HR: true}
+	}
+	roles := []string{role}
+	for _, d := range departments {
+		roles = append(roles, d.Role)
+	}
+	hr := hasListedRole("DIRECTORY_HR_ROLES", "hr", roles)
+	return DirectoryView{
+		HR:      hr,
+		Contact: hr || hasListedRole("DIRECTORY_CONTACT_ROLES", "planner", roles),
+	}
+}
+
+func hasListedRole(env, fallback string, roles []string) bool {
+	listed := os.Getenv(env)
+	if listed == "" {
+		listed = fallback
+	}
+	for _, l := range strings.Split(listed, ",") {
+		l = strings.TrimSpace(l)
+		for _, r := range roles {
+			if r != "" && r == l {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Entry projects the row onto the fields the view allows.
+func (r DirectoryRow) Entry(view DirectoryView) DirectoryEntry {
+	e := DirectoryEntry{
+		ID:                r.ID,
+		Email:             r.Email,
+		FullName:          r.FullName,
+		Role:              r.Role,
+		Status:            r.Status,
+		UserType:          r.UserType,
+		ProfilePictureURL: r.ProfilePictureURL,
+	}
+	if view.Contact {
+		e.Contact = &DirectoryContact{Phone: r.Phone, Address: r.Address}
+	}
+	if view.HR {
+		e.HR = &DirectoryHR{
+			EmployeeID:            r.EmployeeID,
+			Sex:                   r.Sex,
+			Birthday:              r.Birthday,
+			MaritalStatus:         r.MaritalStatus,
+			SpouseName:            r.SpouseName,
+			HasChildren:           r.HasChildren,
+			NumberOfChildren:      r.NumberOfChildren,
+			NationalID:            r.NationalID,
+			EmergencyContactName:  r.EmergencyContactName,
+			EmergencyContactPhone: r.EmergencyContactPhone,
+			AccessExpiresAt:       r.AccessExpiresAt,
+		}
+	}
+	return e
+}
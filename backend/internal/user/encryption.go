@@ -0,0 +1,219 @@
+package user
+
+import (
+	"context"
+	"sort"
+
+	"github.com/nomenarkt/lamina/common/fieldcrypt"
+)
+
+// The sensitive HR columns. Ciphertexts are bound to "<table>.<column>" and to the user the row
+// belongs to.
+var (
+	usersTable = fieldcrypt.Table{
+		Name:         "users",
+		ID:           "id",
+		Owner:        "id",
+		Columns:      []string{"national_id", "spouse_name", "emergency_contact_name", "emergency_contact_phone"},
+		BlindIndexes: map[string]string{"national_id": "national_id_bidx"},
+	}
+	childrenTable = fieldcrypt.Table{
+		Name:    "user_children",
+		ID:      "id",
+		Owner:   "user_id",
+		Columns: []string{"full_name", "birth_date"},
+	}
+)
+
+// EncryptedTables lists the columns EncryptedRepo encrypts, for re-encryption passes.
+func EncryptedTables() []fieldcrypt.Table {
+	return []fieldcrypt.Table{usersTable, childrenTable}
+}
+
+// FieldCipher encrypts single column values and computes blind indexes; *fieldcrypt.Keyring implements it.
+type FieldCipher interface {
+	Encrypt(column string, owner int64, plaintext string) (string, error)
+	Decrypt(ctx context.Context, column string, owner int64, value string) (string, error)
+	BlindIndex(column, value string) string
+}
+
+// EncryptedRepo wraps a Repo so that national IDs, spouse names, emergency contacts and
+// children are stored encrypted, and maintains the blind index used to look up national IDs.
+// Methods that do not touch those columns pass straight through.
+type EncryptedRepo struct {
+	Repo
+	cipher FieldCipher
+}
+
+// NewEncryptedRepo wraps inner with field-level encryption.
+func NewEncryptedRepo(inner Repo, cipher FieldCipher) *EncryptedRepo {
+	return &EncryptedRepo{Repo: inner, cipher: cipher}
+}
+
+// FindByID retrieves a user by ID with sensitive fields decrypted.
+func (r *EncryptedRepo) FindByID(ctx context.Context, id int64) (*User, error) {
+	u, err := r.Repo.FindByID(ctx, id)
+	if err != nil || u == nil {
+		return u, err
+	}
+	return u, r.decryptUser(ctx, u)
+}
+
+// FindByEmail retrieves a user by email with sensitive fields decrypted.
+func (r *EncryptedRepo) FindByEmail(ctx context.Context, email string) (*User, error) {
+	u, err := r.Repo.FindByEmail(ctx, email)
+	if err != nil || u == nil {
+		return u, err
+	}
+	return u, r.decryptUser(ctx, u)
+}
+
+// UpdateUserProfile encrypts the sensitive fields of req before storing it.
+func (r *EncryptedRepo) UpdateUserProfile(ctx context.Context, userID int64, req UpdateProfileRequest) error {
+	if req.NationalID != nil {
+		index := r.cipher.BlindIndex(usersTable.Column("national_id"), *req.NationalID)
+		req.nationalIDIndex = &index
+	}
+	for column, field := range map[string]**string{
+		"national_id":             &req.NationalID,
+		"spouse_name":             &req.SpouseName,
+		"emergency_contact_name":  &req.EmergencyContactName,
+		"emergency_contact_phone": &req.EmergencyContactPhone,
+	} {
+		sealed, err := r.encrypt(usersTable.Column(column), userID, *field)
+		if err != nil {
+			return err
+		}
+		*field = sealed
+	}
+	if req.Children != nil {
+		children := make([]Child, len(req.Children))
+		for i, c := range req.Children {
+			sealed, err := r.encryptChild(userID, c)
+			if err != nil {
+				return err
+			}
+			children[i] = sealed
+		}
+		req.Children = children
+	}
+	return r.Repo.UpdateUserProfile(ctx, userID, req)
+}
+
+// ListDirectory turns a national ID filter into a blind index lookup and decrypts the results.
+func (r *EncryptedRepo) ListDirectory(ctx context.Context, f DirectoryFilter, after *DirectoryCursor) ([]DirectoryRow, error) {
+	if f.NationalID != "" {
+		f.nationalIDIndex = r.cipher.BlindIndex(usersTable.Column("national_id"), f.NationalID)
+	}
+	rows, err := r.Repo.ListDirectory(ctx, f, after)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		row := &rows[i]
+		for column, field := range map[string]*string{
+			"national_id":             row.NationalID,
+			"spouse_name":             row.SpouseName,
+			"emergency_contact_name":  row.EmergencyContactName,
+			"emergency_contact_phone": row.EmergencyContactPhone,
+		} {
+			if err := r.decrypt(ctx, usersTable.Column(column), row.ID, field); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rows, nil
+}
+
+// ListChildren returns the user's decrypted children, oldest first.
+func (r *EncryptedRepo) ListChildren(ctx context.Context, userID int64) ([]Child, error) {
+	children, err := r.Repo.ListChildren(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range children {
+		if err := r.decrypt(ctx, childrenTable.Column("full_name"), userID, &children[i].FullName); err != nil {
+			return nil, err
+		}
+		if err := r.decrypt(ctx, childrenTable.Column("birth_date"), userID, &children[i].BirthDate); err != nil {
+			return nil, err
+		}
+	}
+	// Ciphertexts do not sort by date.
+	sort.SliceStable(children, func(i, j int) bool {
+		if children[i].BirthDate != children[j].BirthDate {
+			return children[i].BirthDate < children[j].BirthDate
+		}
+		return children[i].ID < children[j].ID
+	})
+	return children, nil
+}
+
+// CreateChild encrypts and stores a child.
+func (r *EncryptedRepo) CreateChild(ctx context.Context, userID int64, c *Child) error {
+	sealed, err := r.encryptChild(userID, *c)
+	if err != nil {
+		return err
+	}
+	if err := r.Repo.CreateChild(ctx, userID, &sealed); err != nil {
+		return err
+	}
+	c.ID = sealed.ID
+	return nil
+}
+
+// UpdateChild encrypts and stores a changed child.
+func (r *EncryptedRepo) UpdateChild(ctx context.Context, userID int64, c Child) error {
+	sealed, err := r.encryptChild(userID, c)
+	if err != nil {
+		return err
+	}
+	return r.Repo.UpdateChild(ctx, userID, sealed)
+}
+
+func (r *EncryptedRepo) decryptUser(ctx context.Context, u *User) error {
+	for column, field := range map[string]*string{
+		"national_id":             u.NationalID,
+		"spouse_name":             u.SpouseName,
+		"emergency_contact_name":  u.EmergencyContactName,
+		"emergency_contact_phone": u.EmergencyContactPhone,
+	} {
+		if err := r.decrypt(ctx, usersTable.Column(column), u.ID, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *EncryptedRepo) encryptChild(userID int64, c Child) (Child, error) {
+	var err error
+	if c.FullName, err = r.cipher.Encrypt(childrenTable.Column("full_name"), userID, c.FullName); err != nil {
+		return c, err
+	}
+	c.BirthDate, err = r.cipher.Encrypt(childrenTable.Column("birth_date"), userID, c.BirthDate)
+	return c, err
+}
+
+func (r *EncryptedRepo) encrypt(column string, owner int64, value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	sealed, err := r.cipher.Encrypt(column, owner, *value)
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// decrypt replaces *value with its plaintext in place; nil values are left alone.
+func (r *EncryptedRepo) decrypt(ctx context.Context, column string, owner int64, value *string) error {
+	if value == nil {
+		return nil
+	}
+	plain, err := r.cipher.Decrypt(ctx, column, owner, *value)
+	if err != nil {
+		return err
+	}
+	*value = plain
+	return nil
+}
@@ -0,0 +1,104 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCipher marks values instead of encrypting them so expectations stay readable.
+type fakeCipher struct{}
+
+func (fakeCipher) Encrypt(column string, owner int64, plaintext string) (string, error) {
+	return fmt.Sprintf("sealed(%s@%d:%s)", column, owner, plaintext), nil
+}
+
+func (fakeCipher) Decrypt(_ context.Context, column string, owner int64, value string) (string, error) {
+	inner, ok := strings.CutPrefix(value, fmt.Sprintf("sealed(%s@%d:", column, owner))
+	if !ok {
+		return value, nil
+	}
+	return strings.TrimSuffix(inner, ")"), nil
+}
+
+func (fakeCipher) BlindIndex(column, value string) string {
+	return "bidx(" + column + ":" + value + ")"
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestEncryptedRepo_UpdateUserProfile(t *testing.T) {
+	inner := new(MockUserRepo)
+	repo := NewEncryptedRepo(inner, fakeCipher{})
+
+	inner.On("UpdateUserProfile", mock.Anything, int64(5), mock.MatchedBy(func(req UpdateProfileRequest) bool {
+		return *req.NationalID == "sealed(users.national_id@5:101)" &&
+			*req.nationalIDIndex == "bidx(users.national_id:101)" &&
+			*req.SpouseName == "sealed(users.spouse_name@5:Hery)" &&
+			req.EmergencyContactName == nil &&
+			req.FullName == "Rabe" &&
+			req.Children[0].FullName == "sealed(user_children.full_name@5:Toky)" &&
+			req.Children[0].BirthDate == "sealed(user_children.birth_date@5:2015-03-01)"
+	})).Return(nil)
+
+	req := UpdateProfileRequest{
+		FullName:   "Rabe",
+		NationalID: strPtr("101"),
+		SpouseName: strPtr("Hery"),
+		Children:   []Child{{FullName: "Toky", BirthDate: "2015-03-01"}},
+	}
+	require.NoError(t, repo.UpdateUserProfile(context.Background(), 5, req))
+	assert.Equal(t, "101", *req.NationalID, "the caller's request is left untouched")
+	assert.Equal(t, "Toky", req.Children[0].FullName)
+	inner.AssertExpectations(t)
+}
+
+func TestEncryptedRepo_FindByIDDecrypts(t *testing.T) {
+	inner := new(MockUserRepo)
+	repo := NewEncryptedRepo(inner, fakeCipher{})
+	inner.On("FindByID", mock.Anything, int64(5)).Return(User{
+		ID:                    5,
+		NationalID:            strPtr("sealed(users.national_id@5:101)"),
+		EmergencyContactPhone: strPtr("+261 34 00 000 00"),
+	}, nil)
+
+	u, err := repo.FindByID(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, "101", *u.NationalID)
+	assert.Equal(t, "+261 34 00 000 00", *u.EmergencyContactPhone, "plaintext rows stay readable")
+	assert.Nil(t, u.SpouseName)
+}
+
+func TestEncryptedRepo_ListChildrenSortsByDecryptedDate(t *testing.T) {
+	inner := new(MockUserRepo)
+	repo := NewEncryptedRepo(inner, fakeCipher{})
+	inner.On("ListChildren", mock.Anything, int64(5)).Return([]Child{
+		{ID: 1, FullName: "sealed(user_children.full_name@5:Zo)", BirthDate: "sealed(user_children.birth_date@5:2018-01-01)"},
+		{ID: 2, FullName: "sealed(user_children.full_name@5:Aina)", BirthDate: "sealed(user_children.birth_date@5:2012-06-30)"},
+	}, nil)
+
+	children, err := repo.ListChildren(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, []Child{
+		{ID: 2, FullName: "Aina", BirthDate: "2012-06-30"},
+		{ID: 1, FullName: "Zo", BirthDate: "2018-01-01"},
+	}, children)
+}
+
+func TestEncryptedRepo_ListDirectoryByNationalID(t *testing.T) {
+	inner := new(MockUserRepo)
+	repo := NewEncryptedRepo(inner, fakeCipher{})
+	inner.On("ListDirectory", mock.Anything, mock.MatchedBy(func(f DirectoryFilter) bool {
+		return f.nationalIDIndex == "bidx(users.national_id:101)"
+	}), (*DirectoryCursor)(nil)).Return([]DirectoryRow{{ID: 5, NationalID: strPtr("sealed(users.national_id@5:101)")}}, nil)
+
+	rows, err := repo.ListDirectory(context.Background(), DirectoryFilter{NationalID: "101"}, nil)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "101", *rows[0].NationalID)
+}
@@ -3,8 +3,11 @@ package user
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nomenarkt/lamina/common/utils"
@@ -17,6 +20,12 @@ type ServiceInterface interface {
 	ListUsers(ctx context.Context) ([]User, error)
 	UpdateUserProfile(ctx context.Context, userID int64, req UpdateProfileRequest) error
 	CreateUser(ctx context.Context, u *User) error // ✅ Added CreateUser
+	Directory(ctx context.Context, f DirectoryFilter, view DirectoryView) (*DirectoryPage, error)
+	ListChildren(ctx context.Context, userID int64) ([]Child, error)
+	AddChild(ctx context.Context, userID int64, c Child) (*Child, error)
+	UpdateChild(ctx context.Context, userID int64, c Child) error
+	DeleteChild(ctx context.Context, userID, childID int64) error
+	UploadProfilePicture(ctx context.Context, userID int64, data []byte) (*ProfilePicture, error)
 }
 
 // Handler handles HTTP requests related to user operations.
@@ -77,6 +86,9 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateUserProfile(c, userID, req); err != nil {
+		if respondProfileError(c, err) {
+			return
+		}
 		log.Printf("❌ UpdateUserProfile error for user %d: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -85,6 +97,173 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "profile updated"})
 }
 
+// UploadProfilePicture handles POST /user/profile/picture - replaces the caller's picture
+// with the image in the multipart "file" field.
+func (h *Handler) UploadProfilePicture(c *gin.Context) {
+	userID, exists := utils.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	limit := MaxPictureBytes()
+	// Leave room for the multipart envelope around the file.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+64<<10)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": ErrPictureTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field \"file\" is required"})
+		return
+	}
+	if header.Size > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": ErrPictureTooLarge.Error()})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unreadable upload"})
+		return
+	}
+	defer func() { _ = file.Close() }()
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unreadable upload"})
+		return
+	}
+
+	picture, err := h.service.UploadProfilePicture(c.Request.Context(), userID, data)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, picture)
+	case errors.Is(err, ErrPictureTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUnsupportedPicture):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrPictureStorageUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ UploadProfilePicture error for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store profile picture"})
+	}
+}
+
+// ListChildren handles GET /user/children - the caller's dependents.
+func (h *Handler) ListChildren(c *gin.Context) {
+	userID, exists := utils.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	children, err := h.service.ListChildren(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ ListChildren error for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list children"})
+		return
+	}
+	c.JSON(http.StatusOK, children)
+}
+
+// AddChild handles POST /user/children - adds a dependent for the caller.
+func (h *Handler) AddChild(c *gin.Context) {
+	userID, exists := utils.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req Child
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format", "details": err.Error()})
+		return
+	}
+
+	child, err := h.service.AddChild(c.Request.Context(), userID, req)
+	if err != nil {
+		if respondProfileError(c, err) {
+			return
+		}
+		log.Printf("❌ AddChild error for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add child"})
+		return
+	}
+	c.JSON(http.StatusCreated, child)
+}
+
+// UpdateChild handles PUT /user/children/:id - changes one of the caller's dependents.
+func (h *Handler) UpdateChild(c *gin.Context) {
+	userID, exists := utils.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	childID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid child id"})
+		return
+	}
+
+	var req Child
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format", "details": err.Error()})
+		return
+	}
+	req.ID = childID
+
+	if err := h.service.UpdateChild(c.Request.Context(), userID, req); err != nil {
+		if respondProfileError(c, err) {
+			return
+		}
+		log.Printf("❌ UpdateChild error for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update child"})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// DeleteChild handles DELETE /user/children/:id - removes one of the caller's dependents.
+func (h *Handler) DeleteChild(c *gin.Context) {
+	userID, exists := utils.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	childID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid child id"})
+		return
+	}
+
+	if err := h.service.DeleteChild(c.Request.Context(), userID, childID); err != nil {
+		if respondProfileError(c, err) {
+			return
+		}
+		log.Printf("❌ DeleteChild error for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete child"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondProfileError writes the response for validation and not-found errors and
+// reports whether it did.
+func respondProfileError(c *gin.Context, err error) bool {
+	var perr *ProfileError
+	switch {
+	case errors.As(err, &perr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidProfile.Error(), "fields": perr.Fields})
+	case errors.Is(err, ErrChildNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
 // CreateUser handles POST /user - creates a new user.
 func (h *Handler) CreateUser(c *gin.Context) {
 	var u User
@@ -99,11 +278,80 @@ func (h *Handler) CreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"id": u.ID})
 }
 
+// Directory handles GET /user/directory - a paginated, filterable list of users.
+// Contact and HR fields are included only when the caller's roles allow them.
+func (h *Handler) Directory(c *gin.Context) {
+	f := DirectoryFilter{
+		Status:     c.Query("status"),
+		UserType:   c.Query("user_type"),
+		Role:       c.Query("role"),
+		Search:     c.Query("q"),
+		NationalID: c.Query("national_id"),
+		Sort:       c.Query("sort"),
+		Cursor:     c.Query("cursor"),
+	}
+	var err error
+	if f.UnitID, err = optionalIntQuery(c, "unit_id"); err == nil {
+		if f.FunctionID, err = optionalIntQuery(c, "function_id"); err == nil {
+			f.RankID, err = optionalIntQuery(c, "rank_id")
+		}
+	}
+	if err == nil && c.Query("limit") != "" {
+		f.Limit, err = strconv.Atoi(c.Query("limit"))
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "details": err.Error()})
+		return
+	}
+
+	departments, _ := c.Get("departments")
+	depts, _ := departments.([]DepartmentAccess)
+	view := DirectoryViewFor(c.GetString("userRole"), depts)
+	if f.NationalID != "" && !view.HR {
+		c.JSON(http.StatusForbidden, gin.H{"error": "national_id lookups are restricted to HR"})
+		return
+	}
+
+	page, err := h.service.Directory(c.Request.Context(), f, view)
+	if err != nil {
+		if errors.Is(err, ErrInvalidDirectoryQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Directory error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list users"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func optionalIntQuery(c *gin.Context, key string) (*int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, errors.New(key + " must be an integer")
+	}
+	return &v, nil
+}
+
 // RegisterRoutes binds user-related endpoints to the router group.
-func RegisterRoutes(router *gin.RouterGroup, h *Handler) {
+// Listing and creating users pass through the optional scope middleware (e.g. access.CasbinMiddleware);
+// the caller's own profile does not.
+func RegisterRoutes(router *gin.RouterGroup, h *Handler, scope ...gin.HandlerFunc) {
 	group := router.Group("/user")
 	group.GET("/me", h.GetMe)
-	group.GET("/", h.ListAll)
 	group.PUT("/profile", h.UpdateProfile)
-	group.POST("/", h.CreateUser) // ✅ Route registered here
+	group.POST("/profile/picture", h.UploadProfilePicture)
+	group.GET("/directory", h.Directory)
+	group.GET("/children", h.ListChildren)
+	group.POST("/children", h.AddChild)
+	group.PUT("/children/:id", h.UpdateChild)
+	group.DELETE("/children/:id", h.DeleteChild)
+
+	scoped := router.Group("/user", scope...)
+	scoped.GET("/", h.ListAll)
+	scoped.POST("/", h.CreateUser) // ✅ Route registered here
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -42,6 +43,46 @@ func (m *mockService) CreateUser(_ context.Context, _ *User) error {
 	return nil
 }
 
+func (m *mockService) Directory(ctx context.Context, f DirectoryFilter, view DirectoryView) (*DirectoryPage, error) {
+	args := m.Called(ctx, f, view)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*DirectoryPage), args.Error(1)
+}
+
+func (m *mockService) ListChildren(ctx context.Context, userID int64) ([]Child, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Child), args.Error(1)
+}
+
+func (m *mockService) AddChild(ctx context.Context, userID int64, c Child) (*Child, error) {
+	args := m.Called(ctx, userID, c)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Child), args.Error(1)
+}
+
+func (m *mockService) UpdateChild(ctx context.Context, userID int64, c Child) error {
+	return m.Called(ctx, userID, c).Error(0)
+}
+
+func (m *mockService) DeleteChild(ctx context.Context, userID, childID int64) error {
+	return m.Called(ctx, userID, childID).Error(0)
+}
+
+func (m *mockService) UploadProfilePicture(ctx context.Context, userID int64, data []byte) (*ProfilePicture, error) {
+	args := m.Called(ctx, userID, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ProfilePicture), args.Error(1)
+}
+
 // mock middleware: simulate setting userID in context
 func setUserIDMiddleware(userID int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -215,3 +256,162 @@ func TestRegisterRoutes_BindsCorrectly(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, resp.Code)
 }
+
+func TestHandler_Directory_FiltersAndView(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ms := new(mockService)
+	h := NewUserHandler(ms)
+
+	unit, rank := 7, 3
+	want := DirectoryFilter{Status: "active", Role: "user", UnitID: &unit, RankID: &rank, Search: "rabe", Sort: "-email", Limit: 10}
+	ms.On("Directory", mock.Anything, want, DirectoryView{Contact: true}).
+		Return(&DirectoryPage{Users: []DirectoryEntry{{ID: 1, Email: "a@b.c"}}, NextCursor: "next"}, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userRole", "user")
+		c.Set("departments", []DepartmentAccess{{Name: "ops", Role: "planner"}})
+	})
+	router.GET("/user/directory", h.Directory)
+
+	req := httptest.NewRequest("GET", "/user/directory?status=active&role=user&unit_id=7&rank_id=3&q=rabe&sort=-email&limit=10", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"next_cursor":"next"`)
+	ms.AssertExpectations(t)
+}
+
+func TestHandler_Directory_BadQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ms := new(mockService)
+	h := NewUserHandler(ms)
+	ms.On("Directory", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, ErrInvalidDirectoryQuery)
+
+	router := gin.New()
+	router.GET("/user/directory", h.Directory)
+
+	for _, q := range []string{"unit_id=x", "limit=many", "sort=password"} {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest("GET", "/user/directory?"+q, nil))
+		assert.Equal(t, http.StatusBadRequest, resp.Code, q)
+	}
+}
+
+func TestHandler_Directory_NationalIDRequiresHR(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ms := new(mockService)
+	h := NewUserHandler(ms)
+	ms.On("Directory", mock.Anything, DirectoryFilter{NationalID: "101"}, DirectoryView{Contact: true, HR: true}).
+		Return(&DirectoryPage{Users: []DirectoryEntry{}}, nil)
+
+	role := "user"
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userRole", role) })
+	router.GET("/user/directory", h.Directory)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", "/user/directory?national_id=101", nil))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	role = "admin"
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", "/user/directory?national_id=101", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	ms.AssertExpectations(t)
+}
+
+func TestHandler_UpdateProfile_InvalidFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ms := new(mockService)
+	h := NewUserHandler(ms)
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(101))
+	router.PUT("/user/profile", h.UpdateProfile)
+
+	ms.On("UpdateUserProfile", mock.Anything, int64(101), mock.Anything).
+		Return(&ProfileError{Fields: map[string]string{"sex": "must be one of male, female, non_binary"}})
+
+	req := httptest.NewRequest("PUT", "/user/profile", bytes.NewReader([]byte(`{"full_name":"Rabe","sex":"x"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"sex":"must be one of male, female, non_binary"`)
+}
+
+func TestHandler_Children(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ms := new(mockService)
+	h := NewUserHandler(ms)
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(101))
+	router.POST("/user/children", h.AddChild)
+	router.PUT("/user/children/:id", h.UpdateChild)
+	router.DELETE("/user/children/:id", h.DeleteChild)
+
+	child := Child{FullName: "Koto", BirthDate: "2015-04-01"}
+	ms.On("AddChild", mock.Anything, int64(101), child).Return(&Child{ID: 5, FullName: "Koto", BirthDate: "2015-04-01"}, nil)
+	ms.On("UpdateChild", mock.Anything, int64(101), Child{ID: 5, FullName: "Koto", BirthDate: "2015-04-01"}).Return(nil)
+	ms.On("DeleteChild", mock.Anything, int64(101), int64(9)).Return(ErrChildNotFound)
+
+	body, _ := json.Marshal(child)
+	req := httptest.NewRequest("POST", "/user/children", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"id":5`)
+
+	req = httptest.NewRequest("PUT", "/user/children/5", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("DELETE", "/user/children/9", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	ms.AssertExpectations(t)
+}
+
+func TestHandler_UploadProfilePicture(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ms := new(mockService)
+	h := NewUserHandler(ms)
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(101))
+	router.POST("/user/profile/picture", h.UploadProfilePicture)
+
+	upload := func(content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, _ := w.CreateFormFile("file", "me.png")
+		_, _ = part.Write(content)
+		_ = w.Close()
+		req := httptest.NewRequest("POST", "/user/profile/picture", &body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	ms.On("UploadProfilePicture", mock.Anything, int64(101), []byte("image")).
+		Return(&ProfilePicture{URL: "/files/x", Thumbnails: map[string]string{"64": "/files/t"}}, nil)
+	ms.On("UploadProfilePicture", mock.Anything, int64(101), []byte("text")).Return(nil, ErrUnsupportedPicture)
+
+	resp := upload([]byte("image"))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"profile_picture_url":"/files/x"`)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, upload([]byte("text")).Code)
+
+	t.Setenv("PROFILE_PICTURE_MAX_BYTES", "4")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload([]byte("image")).Code)
+}
@@ -46,6 +46,46 @@ func (m *MockService) CreateUser(_ context.Context, _ *User) error {
 	return nil // No-op for integration test unless you're testing CreateUser
 }
 
+func (m *MockService) Directory(ctx context.Context, f DirectoryFilter, view DirectoryView) (*DirectoryPage, error) {
+	args := m.Called(ctx, f, view)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*DirectoryPage), args.Error(1)
+}
+
+func (m *MockService) ListChildren(ctx context.Context, userID int64) ([]Child, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Child), args.Error(1)
+}
+
+func (m *MockService) AddChild(ctx context.Context, userID int64, c Child) (*Child, error) {
+	args := m.Called(ctx, userID, c)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Child), args.Error(1)
+}
+
+func (m *MockService) UpdateChild(ctx context.Context, userID int64, c Child) error {
+	return m.Called(ctx, userID, c).Error(0)
+}
+
+func (m *MockService) DeleteChild(ctx context.Context, userID, childID int64) error {
+	return m.Called(ctx, userID, childID).Error(0)
+}
+
+func (m *MockService) UploadProfilePicture(ctx context.Context, userID int64, data []byte) (*ProfilePicture, error) {
+	args := m.Called(ctx, userID, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ProfilePicture), args.Error(1)
+}
+
 func TestIntegration_GetUserMe_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	_ = os.Setenv("JWT_SECRET", "test-secret")
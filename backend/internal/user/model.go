@@ -13,10 +13,11 @@ type DepartmentAccess struct {
 	Rank string `json:"rank,omitempty"`
 }
 
-// Child represents dependent info for HR profiles.
+// Child represents dependent info for HR profiles, stored in user_children.
 type Child struct {
-	FullName  string    `json:"full_name"`
-	BirthDate time.Time `json:"birth_date"`
+	ID        int64  `db:"id" json:"id,omitempty"`
+	FullName  string `db:"full_name" json:"full_name"`
+	BirthDate string `db:"birth_date" json:"birth_date"` // YYYY-MM-DD
 }
 
 // InviteRequest is used when an admin invites a new user.
@@ -41,13 +42,15 @@ type User struct {
 	Phone                 *string            `db:"phone"`
 	Address               *string            `db:"address"`
 	ProfilePictureURL     *string            `db:"profile_picture_url" json:"profile_picture_url,omitempty"`
+	ProfilePictureKey     *string            `db:"profile_picture_key" json:"-"` // storage key of an uploaded picture
+	ProfilePictureThumbs  map[string]string  `json:"profile_picture_thumbnails,omitempty"`
 	Sex                   *string            `db:"sex" json:"sex,omitempty"` // male, female, non_binary
 	Birthday              *string            `db:"birthday" json:"birthday,omitempty"`
 	MaritalStatus         *bool              `db:"marital_status" json:"marital_status,omitempty"`
 	SpouseName            *string            `db:"spouse_name" json:"spouse_name,omitempty"`
 	HasChildren           *bool              `db:"has_children" json:"has_children,omitempty"`
 	NumberOfChildren      *int               `db:"number_of_children" json:"number_of_children,omitempty"`
-	Children              []Child            `json:"children,omitempty"` // not stored directly in DB unless serialized or in separate table
+	Children              []Child            `json:"children,omitempty"` // stored in user_children
 	NationalID            *string            `db:"national_id" json:"national_id,omitempty"`
 	EmergencyContactName  *string            `db:"emergency_contact_name" json:"emergency_contact_name,omitempty"`
 	EmergencyContactPhone *string            `db:"emergency_contact_phone" json:"emergency_contact_phone,omitempty"`
@@ -63,6 +66,7 @@ type Claims struct {
 	Departments []DepartmentAccess `json:"departments"`
 	Modules     []string           `json:"modules"`
 	Role        string             `json:"role"`
+	SessionID   string             `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -79,8 +83,10 @@ type UpdateProfileRequest struct {
 	SpouseName            *string `json:"spouse_name,omitempty"`
 	HasChildren           *bool   `json:"has_children,omitempty"`
 	NumberOfChildren      *int    `json:"number_of_children,omitempty"`
-	Children              []Child `json:"children,omitempty"`
+	Children              []Child `json:"children,omitempty"` // replaces the stored children when present
 	NationalID            *string `json:"national_id,omitempty"`
 	EmergencyContactName  *string `json:"emergency_contact_name,omitempty"`
 	EmergencyContactPhone *string `json:"emergency_contact_phone,omitempty"`
+
+	nationalIDIndex *string // blind index of NationalID, set by EncryptedRepo
 }
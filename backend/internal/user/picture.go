@@ -0,0 +1,258 @@
+package user
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register GIF decoding for uploads
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	defaultMaxPictureBytes = 5 << 20
+	// maxPicturePixels guards against decompression bombs.
+	maxPicturePixels = 40_000_000
+	jpegQuality      = 90
+)
+
+// pictureThumbnailSizes are the edge lengths of the square thumbnails generated on upload.
+var pictureThumbnailSizes = []int{64, 256}
+
+var (
+	// ErrPictureTooLarge is returned for uploads above MaxPictureBytes.
+	ErrPictureTooLarge = errors.New("profile picture is too large")
+	// ErrUnsupportedPicture is returned for uploads that are not JPEG, PNG or GIF images.
+	ErrUnsupportedPicture = errors.New("profile picture must be a JPEG, PNG or GIF image")
+	// ErrPictureStorageUnavailable is returned when no storage driver is configured.
+	ErrPictureStorageUnavailable = errors.New("profile picture storage is not configured")
+)
+
+// MaxPictureBytes returns the upload limit from PROFILE_PICTURE_MAX_BYTES (default 5 MiB).
+func MaxPictureBytes() int64 {
+	if v := os.Getenv("PROFILE_PICTURE_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxPictureBytes
+}
+
+// ProfilePicture holds signed URLs for a user's picture and its thumbnails, keyed by edge length.
+type ProfilePicture struct {
+	URL        string            `json:"profile_picture_url"`
+	Thumbnails map[string]string `json:"profile_picture_thumbnails"`
+}
+
+// processedPicture is an upload re-encoded without metadata, with its thumbnails.
+type processedPicture struct {
+	contentType string
+	ext         string
+	original    []byte
+	thumbnails  map[int][]byte
+}
+
+// processPicture sniffs the type of data, decodes it and re-encodes it. Re-encoding drops
+// EXIF and other metadata; the EXIF orientation of JPEGs is applied to the pixels first.
+// The declared content type of the upload is ignored.
+func processPicture(data []byte) (*processedPicture, error) {
+	contentType := mimetype.Detect(data).String()
+	if contentType != "image/jpeg" && contentType != "image/png" && contentType != "image/gif" {
+		return nil, ErrUnsupportedPicture
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedPicture
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPicturePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrPictureTooLarge, cfg.Width, cfg.Height)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedPicture
+	}
+
+	img := toRGBA(decoded)
+	if contentType == "image/jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+
+	p := &processedPicture{contentType: "image/png", ext: ".png", thumbnails: map[int][]byte{}}
+	if contentType == "image/jpeg" {
+		p.contentType, p.ext = "image/jpeg", ".jpg"
+	}
+	if p.original, err = p.encode(img); err != nil {
+		return nil, err
+	}
+	for _, size := range pictureThumbnailSizes {
+		if p.thumbnails[size], err = p.encode(thumbnail(img, size)); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// PictureObjectKeys returns the storage key of an uploaded picture followed by the key of
+// every thumbnail generated for it.
+func PictureObjectKeys(originalKey string) []string {
+	keys := []string{originalKey}
+	for _, size := range pictureThumbnailSizes {
+		keys = append(keys, thumbnailKey(originalKey, size))
+	}
+	return keys
+}
+
+// thumbnailKey returns the key of a thumbnail stored next to the original picture key.
+func thumbnailKey(originalKey string, size int) string {
+	return fmt.Sprintf("%s/thumb_%d%s", path.Dir(originalKey), size, path.Ext(originalKey))
+}
+
+func (p *processedPicture) encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if p.contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// thumbnail crops the centre square of img and scales it down to size×size by averaging
+// the source pixels behind each target pixel. Smaller images are not enlarged.
+func thumbnail(img *image.RGBA, size int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	side := min(w, h)
+	x0, y0 := (w-side)/2, (h-side)/2
+	size = min(size, side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for ty := 0; ty < size; ty++ {
+		sy0, sy1 := y0+ty*side/size, y0+(ty+1)*side/size
+		for tx := 0; tx < size; tx++ {
+			sx0, sx1 := x0+tx*side/size, x0+(tx+1)*side/size
+			var sum [4]int
+			for sy := sy0; sy < sy1; sy++ {
+				row := img.Pix[sy*img.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			n := (sy1 - sy0) * (sx1 - sx0)
+			o := ty*dst.Stride + tx*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[o+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// orient applies an EXIF orientation (1-8) so the image displays upright without metadata.
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], img.Pix[y*img.Stride+x*4:])
+		}
+	}
+	return dst
+}
+
+// exifOrientation returns the orientation tag of a JPEG's EXIF block, or 1 when there is none.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan, end of image
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 {
+			return 1
+		}
+		segment := data[i+4 : min(len(data), i+2+length)]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		off := ifd + 2 + e*12
+		if off+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[off:]) == 0x0112 {
+			return int(order.Uint16(tiff[off+8:]))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,127 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dateLayout is the format of birthdays and children's birth dates.
+const dateLayout = "2006-01-02"
+
+var (
+	// ErrInvalidProfile is matched by ProfileError.
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrChildNotFound is returned when a child does not exist or belongs to another user.
+	ErrChildNotFound = errors.New("child not found")
+)
+
+// validSexes lists the accepted values of User.Sex.
+var validSexes = map[string]bool{"male": true, "female": true, "non_binary": true}
+
+// ProfileError lists the profile fields that failed validation, keyed by JSON field name.
+type ProfileError struct {
+	Fields map[string]string
+}
+
+func (e *ProfileError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range sortedKeys(e.Fields) {
+		parts = append(parts, f+" "+e.Fields[f])
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidProfile, strings.Join(parts, "; "))
+}
+
+// Is lets errors.Is match a ProfileError against ErrInvalidProfile.
+func (e *ProfileError) Is(target error) bool {
+	return target == ErrInvalidProfile
+}
+
+func (e *ProfileError) add(field, problem string) {
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+	e.Fields[field] = problem
+}
+
+func (e *ProfileError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validatePastDate checks that s is a YYYY-MM-DD date that is not in the future.
+func validatePastDate(s string, now time.Time) string {
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return "must be a date in YYYY-MM-DD format"
+	}
+	if d.After(now) {
+		return "must not be in the future"
+	}
+	return ""
+}
+
+// validateProfile checks the enum and date fields of a profile update.
+func validateProfile(req UpdateProfileRequest, now time.Time) error {
+	var perr ProfileError
+	if strings.TrimSpace(req.FullName) == "" {
+		perr.add("full_name", "is required")
+	}
+	if req.Sex != nil && !validSexes[*req.Sex] {
+		perr.add("sex", "must be one of male, female, non_binary")
+	}
+	if req.Birthday != nil {
+		if problem := validatePastDate(*req.Birthday, now); problem != "" {
+			perr.add("birthday", problem)
+		}
+	}
+	if req.NumberOfChildren != nil && *req.NumberOfChildren < 0 {
+		perr.add("number_of_children", "must not be negative")
+	}
+	for i, c := range req.Children {
+		if err := validateChild(c, now); err != nil {
+			for f, problem := range err.(*ProfileError).Fields {
+				perr.add(fmt.Sprintf("children[%d].%s", i, f), problem)
+			}
+		}
+	}
+	return perr.orNil()
+}
+
+// validateChild checks a dependent's name and birth date.
+func validateChild(c Child, now time.Time) error {
+	var perr ProfileError
+	if strings.TrimSpace(c.FullName) == "" {
+		perr.add("full_name", "is required")
+	}
+	if problem := validatePastDate(c.BirthDate, now); problem != "" {
+		perr.add("birth_date", problem)
+	}
+	return perr.orNil()
+}
+
+// checkChildCounts verifies that has_children and number_of_children, when supplied,
+// agree with the number of children on record.
+func checkChildCounts(req UpdateProfileRequest, count int) error {
+	var perr ProfileError
+	if req.NumberOfChildren != nil && *req.NumberOfChildren != count {
+		perr.add("number_of_children", fmt.Sprintf("must match the %d children on record", count))
+	}
+	if req.HasChildren != nil && *req.HasChildren != (count > 0) {
+		perr.add("has_children", fmt.Sprintf("must match the %d children on record", count))
+	}
+	return perr.orNil()
+}
@@ -3,6 +3,7 @@ package user
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 
@@ -15,12 +16,25 @@ type Repo interface {
 	FindAll(ctx context.Context) ([]User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	IsAdmin(ctx context.Context, id int64) (bool, error)
-	UpdateUserProfile(ctx context.Context, userID int64, fullName string, employeeID *int, phone, address *string) error
+	UpdateUserProfile(ctx context.Context, userID int64, req UpdateProfileRequest) error
 	MarkUserActive(ctx context.Context, id int64) error
 	DeleteExpiredPendingUsers(ctx context.Context) error
 	Create(ctx context.Context, u *User) error
+	ListDirectory(ctx context.Context, f DirectoryFilter, after *DirectoryCursor) ([]DirectoryRow, error)
+	ListChildren(ctx context.Context, userID int64) ([]Child, error)
+	CreateChild(ctx context.Context, userID int64, c *Child) error
+	UpdateChild(ctx context.Context, userID int64, c Child) error
+	DeleteChild(ctx context.Context, userID, childID int64) error
+	SetProfilePictureKey(ctx context.Context, userID int64, key string) (previous *string, err error)
 }
 
+// userColumns are the users columns scanned into User. Columns the struct does not map, such as
+// national_id_bidx, must stay out: sqlx rejects them.
+const userColumns = `id, email, password_hash, role, status, confirmation_token, created_at, full_name,
+	user_type, company_id, phone, address, profile_picture_url, profile_picture_key, sex, birthday,
+	marital_status, spouse_name, has_children, number_of_children, national_id,
+	emergency_contact_name, emergency_contact_phone, access_expires_at`
+
 // Repository implements the Repo interface using sqlx for DB interaction.
 type Repository struct {
 	db *sqlx.DB
@@ -34,7 +48,7 @@ func NewUserRepository(db *sqlx.DB) *Repository {
 // FindByID retrieves a user by their ID.
 func (r *Repository) FindByID(ctx context.Context, id int64) (*User, error) {
 	var user User
-	if err := r.db.GetContext(ctx, &user, "SELECT * FROM users WHERE id=$1", id); err != nil {
+	if err := r.db.GetContext(ctx, &user, "SELECT "+userColumns+" FROM users WHERE id=$1", id); err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -50,7 +64,7 @@ func (r *Repository) FindAll(ctx context.Context) ([]User, error) {
 // FindByEmail looks up a user by their email address.
 func (r *Repository) FindByEmail(ctx context.Context, email string) (*User, error) {
 	var user User
-	if err := r.db.GetContext(ctx, &user, "SELECT * FROM users WHERE email=$1", email); err != nil {
+	if err := r.db.GetContext(ctx, &user, "SELECT "+userColumns+" FROM users WHERE email=$1", email); err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -66,8 +80,10 @@ func (r *Repository) IsAdmin(ctx context.Context, id int64) (bool, error) {
 	return role == "admin", nil
 }
 
-// UpdateUserProfile updates the user's full name and optionally company ID, phone, and address.
-func (r *Repository) UpdateUserProfile(ctx context.Context, userID int64, fullName string, employeeID *int, phone, address *string) error {
+// UpdateUserProfile updates the user's full name and every supplied optional profile field.
+// When req.Children is non-nil it replaces the stored children and derives has_children and
+// number_of_children from them, all in one transaction.
+func (r *Repository) UpdateUserProfile(ctx context.Context, userID int64, req UpdateProfileRequest) error {
 	var (
 		setClauses []string
 		args       = map[string]interface{}{
@@ -77,20 +93,34 @@ func (r *Repository) UpdateUserProfile(ctx context.Context, userID int64, fullNa
 
 	// Required field
 	setClauses = append(setClauses, "full_name = :full_name")
-	args["full_name"] = fullName
+	args["full_name"] = req.FullName
 
 	// Optional fields
-	if employeeID != nil {
-		setClauses = append(setClauses, "company_id = :company_id")
-		args["company_id"] = *employeeID
-	}
-	if phone != nil {
-		setClauses = append(setClauses, "phone = :phone")
-		args["phone"] = *phone
+	optional := []struct {
+		column string
+		value  interface{}
+		set    bool
+	}{
+		{"company_id", req.EmployeeID, req.EmployeeID != nil},
+		{"phone", req.Phone, req.Phone != nil},
+		{"address", req.Address, req.Address != nil},
+		{"profile_picture_url", req.ProfilePictureURL, req.ProfilePictureURL != nil},
+		{"sex", req.Sex, req.Sex != nil},
+		{"birthday", req.Birthday, req.Birthday != nil},
+		{"marital_status", req.MaritalStatus, req.MaritalStatus != nil},
+		{"spouse_name", req.SpouseName, req.SpouseName != nil},
+		{"has_children", req.HasChildren, req.HasChildren != nil && req.Children == nil},
+		{"number_of_children", req.NumberOfChildren, req.NumberOfChildren != nil && req.Children == nil},
+		{"national_id", req.NationalID, req.NationalID != nil},
+		{"national_id_bidx", req.nationalIDIndex, req.nationalIDIndex != nil},
+		{"emergency_contact_name", req.EmergencyContactName, req.EmergencyContactName != nil},
+		{"emergency_contact_phone", req.EmergencyContactPhone, req.EmergencyContactPhone != nil},
 	}
-	if address != nil {
-		setClauses = append(setClauses, "address = :address")
-		args["address"] = *address
+	for _, f := range optional {
+		if f.set {
+			setClauses = append(setClauses, fmt.Sprintf("%[1]s = :%[1]s", f.column))
+			args[f.column] = f.value
+		}
 	}
 
 	query := fmt.Sprintf(`
@@ -99,10 +129,136 @@ func (r *Repository) UpdateUserProfile(ctx context.Context, userID int64, fullNa
 		WHERE id = :user_id
 	`, strings.Join(setClauses, ", "))
 
-	_, err := r.db.NamedExecContext(ctx, query, args)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.NamedExecContext(ctx, query, args); err != nil {
+		return err
+	}
+	if req.Children != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_children WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		for _, c := range req.Children {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO user_children (user_id, full_name, birth_date) VALUES ($1, $2, $3)
+			`, userID, c.FullName, c.BirthDate); err != nil {
+				return err
+			}
+		}
+		if err := syncChildCounts(ctx, tx, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetProfilePictureKey points the user's profile picture at an uploaded object and returns
+// the key it replaces, if any.
+func (r *Repository) SetProfilePictureKey(ctx context.Context, userID int64, key string) (*string, error) {
+	var previous *string
+	err := r.db.GetContext(ctx, &previous, `
+		WITH old AS (
+			SELECT id, profile_picture_key FROM users WHERE id = $1 FOR UPDATE
+		)
+		UPDATE users u
+		SET profile_picture_key = $2
+		FROM old
+		WHERE u.id = old.id
+		RETURNING old.profile_picture_key
+	`, userID, key)
+	return previous, err
+}
+
+// ListChildren returns the user's children, oldest first.
+func (r *Repository) ListChildren(ctx context.Context, userID int64) ([]Child, error) {
+	children := []Child{}
+	err := r.db.SelectContext(ctx, &children, `
+		SELECT id, full_name, birth_date::text AS birth_date
+		FROM user_children
+		WHERE user_id = $1
+		ORDER BY birth_date, id
+	`, userID)
+	return children, err
+}
+
+// CreateChild stores a child for the user and updates the user's child counts.
+func (r *Repository) CreateChild(ctx context.Context, userID int64, c *Child) error {
+	return r.withChildCounts(ctx, userID, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &c.ID, `
+			INSERT INTO user_children (user_id, full_name, birth_date) VALUES ($1, $2, $3)
+			RETURNING id
+		`, userID, c.FullName, c.BirthDate)
+	})
+}
+
+// UpdateChild changes a child of the user. It returns ErrChildNotFound if the child
+// does not belong to the user.
+func (r *Repository) UpdateChild(ctx context.Context, userID int64, c Child) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_children SET full_name = $1, birth_date = $2
+		WHERE id = $3 AND user_id = $4
+	`, c.FullName, c.BirthDate, c.ID, userID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// DeleteChild removes a child of the user and updates the user's child counts.
+// It returns ErrChildNotFound if the child does not belong to the user.
+func (r *Repository) DeleteChild(ctx context.Context, userID, childID int64) error {
+	return r.withChildCounts(ctx, userID, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM user_children WHERE id = $1 AND user_id = $2`, childID, userID)
+		if err != nil {
+			return err
+		}
+		return expectOneRow(res)
+	})
+}
+
+// withChildCounts runs fn and re-derives the user's child counts in one transaction.
+func (r *Repository) withChildCounts(ctx context.Context, userID int64, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := syncChildCounts(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// syncChildCounts sets has_children and number_of_children from user_children.
+func syncChildCounts(ctx context.Context, tx *sqlx.Tx, userID int64) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET number_of_children = (SELECT COUNT(*) FROM user_children WHERE user_id = $1),
+		    has_children = EXISTS (SELECT 1 FROM user_children WHERE user_id = $1)
+		WHERE id = $1
+	`, userID)
 	return err
 }
 
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrChildNotFound
+	}
+	return nil
+}
+
 // MarkUserActive sets status = 'active' for a confirmed user.
 func (r *Repository) MarkUserActive(ctx context.Context, id int64) error {
 	_, err := r.db.ExecContext(ctx, `UPDATE users SET status = 'active' WHERE id = $1`, id)
@@ -110,11 +266,13 @@ func (r *Repository) MarkUserActive(ctx context.Context, id int64) error {
 }
 
 // DeleteExpiredPendingUsers removes pending users who didn't confirm within 24h.
+// Users already rostered on flights are kept, since their assignments may not be deleted.
 func (r *Repository) DeleteExpiredPendingUsers(ctx context.Context) error {
 	_, err := r.db.ExecContext(ctx, `
 		DELETE FROM users
 		WHERE status = 'pending'
 		  AND created_at < NOW() - interval '24 hours'
+		  AND NOT EXISTS (SELECT 1 FROM crew_assignments ca WHERE ca.crew_id = users.id)
 	`)
 	return err
 }
@@ -139,3 +297,85 @@ func (r *Repository) Create(ctx context.Context, u *User) error {
 	}
 	return nil
 }
+
+// ListDirectory returns up to f.Limit+1 directory rows after the cursor so the caller can tell
+// whether another page follows. f must already be normalised by the service.
+func (r *Repository) ListDirectory(ctx context.Context, f DirectoryFilter, after *DirectoryCursor) ([]DirectoryRow, error) {
+	desc := strings.HasPrefix(f.Sort, "-")
+	sort := directorySorts[strings.TrimPrefix(f.Sort, "-")]
+
+	var (
+		where []string
+		args  []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.Status != "" {
+		where = append(where, "u.status = "+arg(f.Status))
+	}
+	if f.UserType != "" {
+		where = append(where, "u.user_type = "+arg(f.UserType))
+	}
+	if f.Role != "" {
+		where = append(where, "u.role = "+arg(f.Role))
+	}
+	if f.UnitID != nil {
+		p := arg(*f.UnitID)
+		where = append(where, fmt.Sprintf(`(EXISTS (SELECT 1 FROM user_organizational_units m WHERE m.user_id = u.id AND m.unit_id = %[1]s)
+			OR EXISTS (SELECT 1 FROM user_functions uf WHERE uf.user_id = u.id AND uf.unit_id = %[1]s))`, p))
+	}
+	if f.FunctionID != nil || f.RankID != nil {
+		conds := []string{"uf.user_id = u.id"}
+		if f.FunctionID != nil {
+			conds = append(conds, "uf.function_id = "+arg(*f.FunctionID))
+		}
+		if f.RankID != nil {
+			conds = append(conds, "uf.rank_id = "+arg(*f.RankID))
+		}
+		if f.UnitID != nil {
+			conds = append(conds, "uf.unit_id = "+arg(*f.UnitID))
+		}
+		where = append(where, "EXISTS (SELECT 1 FROM user_functions uf WHERE "+strings.Join(conds, " AND ")+")")
+	}
+	switch {
+	case f.nationalIDIndex != "":
+		where = append(where, "u.national_id_bidx = "+arg(f.nationalIDIndex))
+	case f.NationalID != "":
+		where = append(where, "u.national_id = "+arg(f.NationalID))
+	}
+	if f.Search != "" {
+		p := arg("%" + escapeLike(f.Search) + "%")
+		where = append(where, fmt.Sprintf("(u.full_name ILIKE %[1]s OR u.email ILIKE %[1]s)", p))
+	}
+	cmp, dir := ">", "ASC"
+	if desc {
+		cmp, dir = "<", "DESC"
+	}
+	if after != nil {
+		where = append(where, fmt.Sprintf("(%s, u.id) %s (%s::%s, %s)", sort.expr, cmp, arg(after.Value), sort.cast, arg(after.ID)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT u.id, u.email, u.full_name, u.role, u.status, u.user_type, u.profile_picture_url, u.profile_picture_key,
+		       u.created_at, u.phone, u.address, u.company_id, u.sex, u.birthday::text AS birthday,
+		       u.marital_status, u.spouse_name, u.has_children, u.number_of_children, u.national_id,
+		       u.emergency_contact_name, u.emergency_contact_phone, u.access_expires_at,
+		       (%[1]s)::text AS sort_key
+		FROM users u`, sort.expr)
+	if len(where) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(where, "\n\t\t  AND ")
+	}
+	query += fmt.Sprintf("\n\t\tORDER BY %s %s, u.id %s\n\t\tLIMIT %s", sort.expr, dir, dir, arg(f.Limit+1))
+
+	var rows []DirectoryRow
+	err := r.db.SelectContext(ctx, &rows, query, args...)
+	return rows, err
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
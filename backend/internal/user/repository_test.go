@@ -47,6 +47,33 @@ func TestRepository_FindByID(t *testing.T) {
 	assert.Equal(t, "Jane Doe", *user.FullName)
 }
 
+func TestRepository_FindSkipsUnmappedColumns(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(testDB)
+
+	_, err := testDB.ExecContext(ctx, `DELETE FROM users`)
+	assert.NoError(t, err)
+
+	// national_id_bidx has no User field; selecting it would fail every lookup.
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO users (id, email, password_hash, national_id, national_id_bidx)
+		VALUES (43, 'bidx@example.com', 'hashed', 'enc:v2:k:AAAA', 'abc123')
+	`)
+	assert.NoError(t, err)
+
+	user, err := repo.FindByID(ctx, 43)
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) && assert.NotNil(t, user.NationalID) {
+		assert.Equal(t, "enc:v2:k:AAAA", *user.NationalID)
+	}
+
+	user, err = repo.FindByEmail(ctx, "bidx@example.com")
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, int64(43), user.ID)
+	}
+}
+
 func TestRepository_FindAll(t *testing.T) {
 	ctx := context.Background()
 	repo := NewUserRepository(testDB)
@@ -91,7 +118,12 @@ func TestRepository_UpdateUserProfile(t *testing.T) {
 	phone := "1234567890"
 	address := "Somewhere"
 
-	err = repo.UpdateUserProfile(ctx, 101, fullName, &companyID, &phone, &address)
+	err = repo.UpdateUserProfile(ctx, 101, UpdateProfileRequest{
+		FullName:   fullName,
+		EmployeeID: &companyID,
+		Phone:      &phone,
+		Address:    &address,
+	})
 	assert.NoError(t, err)
 
 	// Verify update
@@ -191,3 +223,38 @@ func TestRepository_DeleteExpiredPendingUsers(t *testing.T) {
 
 	assert.ElementsMatch(t, []string{"recent@example.com", "active@example.com"}, emails)
 }
+
+func TestRepository_ChildrenKeepCountsInSync(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(testDB)
+
+	_, err := testDB.ExecContext(ctx, `DELETE FROM users`)
+	assert.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO users (id, email, password_hash, role, status, user_type)
+		VALUES (401, 'parent@example.com', 'hash', 'viewer', 'active', 'internal')
+	`)
+	assert.NoError(t, err)
+
+	err = repo.UpdateUserProfile(ctx, 401, UpdateProfileRequest{
+		FullName: "Parent",
+		Children: []Child{{FullName: "First", BirthDate: "2012-01-05"}, {FullName: "Second", BirthDate: "2016-07-30"}},
+	})
+	assert.NoError(t, err)
+
+	third := Child{FullName: "Third", BirthDate: "2020-02-29"}
+	assert.NoError(t, repo.CreateChild(ctx, 401, &third))
+
+	children, err := repo.ListChildren(ctx, 401)
+	assert.NoError(t, err)
+	assert.Len(t, children, 3)
+	assert.Equal(t, "2012-01-05", children[0].BirthDate)
+
+	assert.NoError(t, repo.DeleteChild(ctx, 401, children[0].ID))
+	assert.ErrorIs(t, repo.DeleteChild(ctx, 401, children[0].ID), ErrChildNotFound)
+
+	user, err := repo.FindByID(ctx, 401)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *user.NumberOfChildren)
+	assert.True(t, *user.HasChildren)
+}
@@ -2,14 +2,25 @@
 package user
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/nomenarkt/lamina/common/storage"
 )
 
 // Service provides methods for working with user entities.
 type Service struct {
-	repo Repo
+	repo     Repo
+	pictures storage.Storage
+	urlTTL   time.Duration
 }
 
 // NewUserService creates a new instance of Service.
@@ -17,9 +28,24 @@ func NewUserService(repo Repo) *Service {
 	return &Service{repo: repo}
 }
 
+// SetPictureStorage configures where uploaded profile pictures are stored and how long
+// their signed URLs stay valid.
+func (s *Service) SetPictureStorage(st storage.Storage, urlTTL time.Duration) {
+	s.pictures = st
+	s.urlTTL = urlTTL
+}
+
 // GetMe retrieves the user associated with the given ID.
 func (s *Service) GetMe(ctx context.Context, id int64) (*User, error) {
-	return s.repo.FindByID(ctx, id)
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if picture := s.signPicture(user.ProfilePictureKey); picture != nil {
+		user.ProfilePictureURL = &picture.URL
+		user.ProfilePictureThumbs = picture.Thumbnails
+	}
+	return user, nil
 }
 
 // FindAll retrieves a list of all users.
@@ -32,8 +58,8 @@ func (s *Service) ListUsers(ctx context.Context) ([]User, error) {
 	return s.repo.FindAll(ctx)
 }
 
-// UpdateUserProfile updates a user's full name and optionally their employee ID,
-// depending on whether the user's email belongs to the internal domain.
+// UpdateUserProfile validates and stores a user's profile. The employee ID is only kept
+// when the user's email belongs to the internal domain.
 func (s *Service) UpdateUserProfile(ctx context.Context, userID int64, req UpdateProfileRequest) error {
 	user, err := s.repo.FindByID(ctx, userID)
 	if err != nil {
@@ -49,7 +75,7 @@ func (s *Service) UpdateUserProfile(ctx context.Context, userID int64, req Updat
 		req.EmployeeID = nil
 	}
 
-	return s.repo.UpdateUserProfile(ctx, userID, req.FullName, req.EmployeeID, req.Phone, req.Address)
+	return s.saveProfile(ctx, user.ID, req)
 }
 
 // CompleteProfileByUserType validates profile fields based on user type and updates user.
@@ -74,7 +100,62 @@ func (s *Service) CompleteProfileByUserType(ctx context.Context, userID int64, r
 		}
 	}
 
-	return s.repo.UpdateUserProfile(ctx, userID, req.FullName, req.EmployeeID, req.Phone, req.Address)
+	return s.saveProfile(ctx, user.ID, req)
+}
+
+// saveProfile validates req and persists it for the user. has_children and number_of_children
+// follow the children: supplied values must agree with them.
+func (s *Service) saveProfile(ctx context.Context, userID int64, req UpdateProfileRequest) error {
+	if err := validateProfile(req, time.Now()); err != nil {
+		return err
+	}
+
+	switch {
+	case req.Children != nil:
+		if err := checkChildCounts(req, len(req.Children)); err != nil {
+			return err
+		}
+	case req.HasChildren != nil || req.NumberOfChildren != nil:
+		children, err := s.repo.ListChildren(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkChildCounts(req, len(children)); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.UpdateUserProfile(ctx, userID, req)
+}
+
+// ListChildren returns the user's dependents.
+func (s *Service) ListChildren(ctx context.Context, userID int64) ([]Child, error) {
+	return s.repo.ListChildren(ctx, userID)
+}
+
+// AddChild validates and stores a dependent for the user.
+func (s *Service) AddChild(ctx context.Context, userID int64, c Child) (*Child, error) {
+	if err := validateChild(c, time.Now()); err != nil {
+		return nil, err
+	}
+	c.ID = 0
+	if err := s.repo.CreateChild(ctx, userID, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// UpdateChild validates and changes one of the user's dependents.
+func (s *Service) UpdateChild(ctx context.Context, userID int64, c Child) error {
+	if err := validateChild(c, time.Now()); err != nil {
+		return err
+	}
+	return s.repo.UpdateChild(ctx, userID, c)
+}
+
+// DeleteChild removes one of the user's dependents.
+func (s *Service) DeleteChild(ctx context.Context, userID, childID int64) error {
+	return s.repo.DeleteChild(ctx, userID, childID)
 }
 
 // IsAdmin checks if a user is an admin.
@@ -103,3 +184,121 @@ func (s *Service) CreateUser(ctx context.Context, user *User) error {
 	}
 	return s.repo.Create(ctx, user)
 }
+
+// Directory returns one page of the user directory, showing only the fields the view allows.
+func (s *Service) Directory(ctx context.Context, f DirectoryFilter, view DirectoryView) (*DirectoryPage, error) {
+	if f.Sort == "" {
+		f.Sort = "name"
+	}
+	if _, ok := directorySorts[strings.TrimPrefix(f.Sort, "-")]; !ok {
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrInvalidDirectoryQuery, f.Sort)
+	}
+	if f.Limit <= 0 {
+		f.Limit = defaultDirectoryLimit
+	}
+	if f.Limit > maxDirectoryLimit {
+		f.Limit = maxDirectoryLimit
+	}
+	f.Search = strings.TrimSpace(f.Search)
+
+	var after *DirectoryCursor
+	if f.Cursor != "" {
+		c, err := DecodeDirectoryCursor(f.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDirectoryQuery, err)
+		}
+		if c.Sort != f.Sort {
+			return nil, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidDirectoryQuery, c.Sort)
+		}
+		after = &c
+	}
+
+	rows, err := s.repo.ListDirectory(ctx, f, after)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &DirectoryPage{Users: make([]DirectoryEntry, 0, len(rows))}
+	if len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = DirectoryCursor{Sort: f.Sort, Value: last.SortKey, ID: last.ID}.Encode()
+	}
+	for _, r := range rows {
+		if picture := s.signPicture(r.ProfilePictureKey); picture != nil {
+			r.ProfilePictureURL = &picture.URL
+		}
+		page.Users = append(page.Users, r.Entry(view))
+	}
+	return page, nil
+}
+
+// UploadProfilePicture re-encodes an uploaded image without metadata, stores it with its
+// thumbnails and makes it the user's profile picture. The previous picture is deleted.
+func (s *Service) UploadProfilePicture(ctx context.Context, userID int64, data []byte) (*ProfilePicture, error) {
+	if s.pictures == nil {
+		return nil, ErrPictureStorageUnavailable
+	}
+	if int64(len(data)) > MaxPictureBytes() {
+		return nil, ErrPictureTooLarge
+	}
+	p, err := processPicture(data)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("profile-pictures/%d/%s/original%s", userID, hex.EncodeToString(nonce), p.ext)
+	objects := map[string][]byte{key: p.original}
+	for size, thumb := range p.thumbnails {
+		objects[thumbnailKey(key, size)] = thumb
+	}
+
+	for k, body := range objects {
+		if err := s.pictures.Put(ctx, k, bytes.NewReader(body), int64(len(body)), p.contentType); err != nil {
+			s.deletePicture(ctx, key)
+			return nil, err
+		}
+	}
+
+	previous, err := s.repo.SetProfilePictureKey(ctx, userID, key)
+	if err != nil {
+		s.deletePicture(ctx, key)
+		return nil, err
+	}
+	if previous != nil && *previous != key {
+		s.deletePicture(ctx, *previous)
+	}
+	return s.signPicture(&key), nil
+}
+
+// signPicture returns signed URLs for a stored picture, or nil when there is none.
+func (s *Service) signPicture(key *string) *ProfilePicture {
+	if key == nil || s.pictures == nil {
+		return nil
+	}
+	url, err := s.pictures.SignedURL(*key, s.urlTTL)
+	if err != nil {
+		log.Printf("⚠️ Could not sign profile picture %s: %v", *key, err)
+		return nil
+	}
+	picture := &ProfilePicture{URL: url, Thumbnails: map[string]string{}}
+	for _, size := range pictureThumbnailSizes {
+		if thumb, err := s.pictures.SignedURL(thumbnailKey(*key, size), s.urlTTL); err == nil {
+			picture.Thumbnails[strconv.Itoa(size)] = thumb
+		}
+	}
+	return picture
+}
+
+// deletePicture removes a picture and its thumbnails, logging failures.
+func (s *Service) deletePicture(ctx context.Context, key string) {
+	for _, k := range PictureObjectKeys(key) {
+		if err := s.pictures.Delete(ctx, k); err != nil {
+			log.Printf("⚠️ Could not delete profile picture object %s: %v", k, err)
+		}
+	}
+}
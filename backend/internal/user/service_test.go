@@ -1,10 +1,16 @@
 package user
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"image"
+	"image/jpeg"
+	"io"
 	"testing"
+	"time"
 
+	"github.com/nomenarkt/lamina/common/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -43,11 +49,43 @@ func (m *MockUserRepo) FindAll(ctx context.Context) ([]User, error) {
 	return args.Get(0).([]User), args.Error(1)
 }
 
-func (m *MockUserRepo) UpdateUserProfile(ctx context.Context, userID int64, fullName string, employeeID *int, phone, address *string) error {
-	args := m.Called(ctx, userID, fullName, employeeID, phone, address)
+func (m *MockUserRepo) UpdateUserProfile(ctx context.Context, userID int64, req UpdateProfileRequest) error {
+	args := m.Called(ctx, userID, req)
 	return args.Error(0)
 }
 
+func (m *MockUserRepo) ListChildren(ctx context.Context, userID int64) ([]Child, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Child), args.Error(1)
+}
+
+func (m *MockUserRepo) CreateChild(ctx context.Context, userID int64, c *Child) error {
+	args := m.Called(ctx, userID, c)
+	if args.Error(0) == nil {
+		c.ID = 1
+	}
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) UpdateChild(ctx context.Context, userID int64, c Child) error {
+	return m.Called(ctx, userID, c).Error(0)
+}
+
+func (m *MockUserRepo) DeleteChild(ctx context.Context, userID, childID int64) error {
+	return m.Called(ctx, userID, childID).Error(0)
+}
+
+func (m *MockUserRepo) SetProfilePictureKey(ctx context.Context, userID int64, key string) (*string, error) {
+	args := m.Called(ctx, userID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*string), args.Error(1)
+}
+
 func (m *MockUserRepo) MarkUserActive(ctx context.Context, userID int64) error {
 	args := m.Called(ctx, userID)
 	return args.Error(0)
@@ -187,7 +225,7 @@ func TestUserService_CompleteProfileByUserType(t *testing.T) {
 			repo.On("FindByID", mock.Anything, tc.user.ID).Return(tc.user, nil)
 
 			if tc.expectCall {
-				repo.On("UpdateUserProfile", mock.Anything, tc.user.ID, tc.request.FullName, tc.request.EmployeeID, tc.request.Phone, tc.request.Address).Return(nil)
+				repo.On("UpdateUserProfile", mock.Anything, tc.user.ID, tc.request).Return(nil)
 			}
 
 			err := svc.CompleteProfileByUserType(context.Background(), tc.user.ID, tc.request)
@@ -235,6 +273,14 @@ func (m *MockUserRepo) Create(ctx context.Context, user *User) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepo) ListDirectory(ctx context.Context, f DirectoryFilter, after *DirectoryCursor) ([]DirectoryRow, error) {
+	args := m.Called(ctx, f, after)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]DirectoryRow), args.Error(1)
+}
+
 func TestUserService_CreateUser(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		svc, repo := newMockedUserService()
@@ -266,3 +312,188 @@ func TestUserService_CreateUser(t *testing.T) {
 		assert.Equal(t, "email already in use", err.Error())
 	})
 }
+
+func TestUserService_Directory_PagesWithCursor(t *testing.T) {
+	svc, repo := newMockedUserService()
+	phone := "+261"
+
+	rows := []DirectoryRow{
+		{ID: 1, Email: "a@x.mg", Phone: &phone, SortKey: "a"},
+		{ID: 2, Email: "b@x.mg", SortKey: "b"},
+		{ID: 3, Email: "c@x.mg", SortKey: "c"},
+	}
+	repo.On("ListDirectory", mock.Anything, DirectoryFilter{Sort: "name", Limit: 2}, (*DirectoryCursor)(nil)).Return(rows, nil)
+
+	page, err := svc.Directory(context.Background(), DirectoryFilter{Limit: 2}, DirectoryView{Contact: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 2)
+	assert.Equal(t, &phone, page.Users[0].Contact.Phone)
+	assert.Nil(t, page.Users[0].HR)
+
+	cursor, err := DecodeDirectoryCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, DirectoryCursor{Sort: "name", Value: "b", ID: 2}, cursor)
+
+	after := &DirectoryCursor{Sort: "name", Value: "b", ID: 2}
+	repo.On("ListDirectory", mock.Anything, DirectoryFilter{Sort: "name", Cursor: page.NextCursor, Limit: 2}, after).Return(rows[2:], nil)
+
+	page, err = svc.Directory(context.Background(), DirectoryFilter{Cursor: page.NextCursor, Limit: 2}, DirectoryView{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+	assert.Empty(t, page.NextCursor)
+	assert.Nil(t, page.Users[0].Contact)
+}
+
+func TestUserService_Directory_RejectsBadInput(t *testing.T) {
+	svc, _ := newMockedUserService()
+
+	_, err := svc.Directory(context.Background(), DirectoryFilter{Sort: "password_hash"}, DirectoryView{})
+	assert.ErrorIs(t, err, ErrInvalidDirectoryQuery)
+
+	_, err = svc.Directory(context.Background(), DirectoryFilter{Cursor: "not-a-cursor"}, DirectoryView{})
+	assert.ErrorIs(t, err, ErrInvalidDirectoryQuery)
+
+	other := DirectoryCursor{Sort: "email", Value: "a", ID: 1}.Encode()
+	_, err = svc.Directory(context.Background(), DirectoryFilter{Sort: "name", Cursor: other}, DirectoryView{})
+	assert.ErrorIs(t, err, ErrInvalidDirectoryQuery)
+}
+
+func TestDirectoryViewFor(t *testing.T) {
+	t.Setenv("DIRECTORY_HR_ROLES", "")
+	t.Setenv("DIRECTORY_CONTACT_ROLES", "")
+
+	assert.Equal(t, DirectoryView{Contact: true, HR: true}, DirectoryViewFor("admin", nil))
+	assert.Equal(t, DirectoryView{Contact: true, HR: true}, DirectoryViewFor("user", []DepartmentAccess{{Role: "hr"}}))
+	assert.Equal(t, DirectoryView{Contact: true}, DirectoryViewFor("planner", nil))
+	assert.Equal(t, DirectoryView{}, DirectoryViewFor("user", nil))
+
+	t.Setenv("DIRECTORY_CONTACT_ROLES", "dispatcher, planner")
+	assert.Equal(t, DirectoryView{Contact: true}, DirectoryViewFor("user", []DepartmentAccess{{Role: "dispatcher"}}))
+}
+
+func TestUserService_UpdateUserProfile_Validation(t *testing.T) {
+	sex := "unknown"
+	birthday := "01/02/1990"
+	future := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+
+	svc, repo := newMockedUserService()
+	repo.On("FindByID", mock.Anything, int64(7)).Return(User{ID: 7, Status: "active"}, nil)
+
+	err := svc.UpdateUserProfile(context.Background(), 7, UpdateProfileRequest{
+		FullName: "Rabe",
+		Sex:      &sex,
+		Birthday: &birthday,
+		Children: []Child{{FullName: "", BirthDate: future}},
+	})
+	var perr *ProfileError
+	assert.ErrorAs(t, err, &perr)
+	assert.ErrorIs(t, err, ErrInvalidProfile)
+	assert.Contains(t, perr.Fields, "sex")
+	assert.Contains(t, perr.Fields, "birthday")
+	assert.Equal(t, "is required", perr.Fields["children[0].full_name"])
+	assert.Equal(t, "must not be in the future", perr.Fields["children[0].birth_date"])
+	repo.AssertNotCalled(t, "UpdateUserProfile", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_UpdateUserProfile_ChildCounts(t *testing.T) {
+	two, yes := 2, true
+
+	t.Run("must match supplied children", func(t *testing.T) {
+		svc, repo := newMockedUserService()
+		repo.On("FindByID", mock.Anything, int64(7)).Return(User{ID: 7, Status: "active"}, nil)
+
+		err := svc.UpdateUserProfile(context.Background(), 7, UpdateProfileRequest{
+			FullName:         "Rabe",
+			NumberOfChildren: &two,
+			Children:         []Child{{FullName: "Koto", BirthDate: "2015-04-01"}},
+		})
+		assert.ErrorIs(t, err, ErrInvalidProfile)
+	})
+
+	t.Run("checked against stored children", func(t *testing.T) {
+		svc, repo := newMockedUserService()
+		repo.On("FindByID", mock.Anything, int64(7)).Return(User{ID: 7, Status: "active"}, nil)
+		repo.On("ListChildren", mock.Anything, int64(7)).Return([]Child{{ID: 1}, {ID: 2}}, nil)
+		req := UpdateProfileRequest{FullName: "Rabe", HasChildren: &yes, NumberOfChildren: &two}
+		repo.On("UpdateUserProfile", mock.Anything, int64(7), req).Return(nil)
+
+		assert.NoError(t, svc.UpdateUserProfile(context.Background(), 7, req))
+	})
+}
+
+func TestUserService_AddChild(t *testing.T) {
+	svc, repo := newMockedUserService()
+
+	_, err := svc.AddChild(context.Background(), 7, Child{FullName: "Koto", BirthDate: "2015-13-01"})
+	assert.ErrorIs(t, err, ErrInvalidProfile)
+
+	repo.On("CreateChild", mock.Anything, int64(7), &Child{FullName: "Koto", BirthDate: "2015-04-01"}).Return(nil)
+	child, err := svc.AddChild(context.Background(), 7, Child{FullName: "Koto", BirthDate: "2015-04-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), child.ID)
+}
+
+// jpegWithOrientation encodes a w×h JPEG carrying an EXIF orientation tag.
+func jpegWithOrientation(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil))
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0, 0, 0, 0, 0}
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1, byte((len(app1) + 2) >> 8), byte(len(app1) + 2)}, app1...)
+	raw := buf.Bytes()
+	return append(append(append([]byte{}, raw[:2]...), segment...), raw[2:]...)
+}
+
+func TestUserService_UploadProfilePicture(t *testing.T) {
+	svc, repo := newMockedUserService()
+	store := &storage.LocalStorage{Root: t.TempDir(), BaseURL: "/files", Secret: []byte("s")}
+	svc.SetPictureStorage(store, time.Minute)
+	ctx := context.Background()
+
+	data := jpegWithOrientation(t, 40, 20, 6)
+	assert.Equal(t, 6, exifOrientation(data))
+
+	previous := "profile-pictures/7/old/original.png"
+	assert.NoError(t, store.Put(ctx, previous, bytes.NewReader([]byte("old")), 3, "image/png"))
+	repo.On("SetProfilePictureKey", mock.Anything, int64(7), mock.AnythingOfType("string")).Return(&previous, nil)
+
+	picture, err := svc.UploadProfilePicture(ctx, 7, data)
+	assert.NoError(t, err)
+	assert.Contains(t, picture.URL, "/files/profile-pictures/7/")
+	assert.Contains(t, picture.Thumbnails, "64")
+	assert.Contains(t, picture.Thumbnails, "256")
+
+	key := repo.Calls[0].Arguments.String(2)
+	rc, err := store.Get(ctx, key)
+	assert.NoError(t, err)
+	stored, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	assert.Equal(t, 1, exifOrientation(stored), "EXIF is stripped")
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(stored))
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int{20, 40}, [2]int{cfg.Width, cfg.Height}, "orientation 6 is applied")
+
+	rc, err = store.Get(ctx, thumbnailKey(key, 64))
+	assert.NoError(t, err)
+	cfg, _ = jpeg.DecodeConfig(rc)
+	_ = rc.Close()
+	assert.Equal(t, 20, cfg.Width, "small images are not enlarged")
+
+	_, err = store.Get(ctx, previous)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestUserService_UploadProfilePicture_Rejects(t *testing.T) {
+	svc, _ := newMockedUserService()
+	_, err := svc.UploadProfilePicture(context.Background(), 7, []byte("GIF89a"))
+	assert.ErrorIs(t, err, ErrPictureStorageUnavailable)
+
+	svc.SetPictureStorage(&storage.LocalStorage{Root: t.TempDir()}, time.Minute)
+	_, err = svc.UploadProfilePicture(context.Background(), 7, []byte("<svg xmlns='http://www.w3.org/2000/svg'/>"))
+	assert.ErrorIs(t, err, ErrUnsupportedPicture)
+
+	t.Setenv("PROFILE_PICTURE_MAX_BYTES", "10")
+	_, err = svc.UploadProfilePicture(context.Background(), 7, make([]byte, 11))
+	assert.ErrorIs(t, err, ErrPictureTooLarge)
+}
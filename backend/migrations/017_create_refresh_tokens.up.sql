@@ -0,0 +1,15 @@
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    jti TEXT NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL,            -- SHA-256 of the signed token, never the token itself
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,                 -- set when rotated; a second use revokes the family
+    revoked_at TIMESTAMPTZ,
+    replaced_by TEXT,                    -- jti of the token issued on rotation
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
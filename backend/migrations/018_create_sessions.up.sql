@@ -0,0 +1,19 @@
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY,                 -- refresh token family ID, carried as "sid" in access tokens
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+-- Refresh tokens issued before sessions existed can't be tied to one; force those clients to log in again.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT refresh_tokens_family_id_fkey
+FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
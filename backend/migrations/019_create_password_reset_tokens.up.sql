@@ -0,0 +1,10 @@
+CREATE TABLE password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,     -- SHA-256 of the emailed token
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,                 -- single use: set on reset or when superseded
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
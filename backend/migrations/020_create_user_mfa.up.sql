@@ -0,0 +1,25 @@
+CREATE TABLE user_mfa (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,                         -- base32 TOTP secret (RFC 6238)
+    enabled_at TIMESTAMPTZ,                       -- NULL until the first code is verified
+    last_used_step BIGINT NOT NULL DEFAULT 0,     -- last accepted time step, rejects code replay
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,                      -- SHA-256 of the normalized code
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    UNIQUE (user_id, code_hash)
+);
+
+-- A row means MFA is mandatory for every user holding that role.
+CREATE TABLE mfa_role_requirements (
+    role TEXT PRIMARY KEY,
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ DEFAULT now()
+);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_flights_registration;
+DROP INDEX IF EXISTS idx_flights_arrival_code;
+DROP INDEX IF EXISTS idx_flights_departure_code;
+DROP INDEX IF EXISTS idx_flights_scheduled_departure;
+
+ALTER TABLE flights
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS cancel_reason,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS status;
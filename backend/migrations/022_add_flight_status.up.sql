@@ -0,0 +1,11 @@
+ALTER TABLE flights
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'scheduled'
+        CHECK (status IN ('scheduled', 'cancelled')),
+    ADD COLUMN cancelled_at TIMESTAMP,
+    ADD COLUMN cancel_reason TEXT,
+    ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT now();
+
+CREATE INDEX idx_flights_scheduled_departure ON flights(scheduled_departure);
+CREATE INDEX idx_flights_departure_code ON flights(departure_code);
+CREATE INDEX idx_flights_arrival_code ON flights(arrival_code);
+CREATE INDEX idx_flights_registration ON flights(airplane_immatriculation);
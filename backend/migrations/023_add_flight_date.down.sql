@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS uq_flights_number_date_departure;
+ALTER TABLE flights DROP COLUMN IF EXISTS flight_date;
@@ -0,0 +1,7 @@
+-- Operating date of a flight (SSIM "flight date"). Legs departing after midnight
+-- keep the date of the flight they belong to.
+ALTER TABLE flights ADD COLUMN flight_date DATE;
+UPDATE flights SET flight_date = scheduled_departure::date;
+ALTER TABLE flights ALTER COLUMN flight_date SET NOT NULL;
+
+CREATE UNIQUE INDEX uq_flights_number_date_departure ON flights(flight_number, flight_date, departure_code);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_crew_assignments_crew_checkin;
+DROP TABLE IF EXISTS ftl_overrides;
@@ -0,0 +1,14 @@
+-- Supervisor overrides of flight time limitation (FTL) checks on crew assignments.
+CREATE TABLE IF NOT EXISTS ftl_overrides (
+    id SERIAL PRIMARY KEY,
+    assignment_id INTEGER NOT NULL REFERENCES crew_assignments(id) ON DELETE CASCADE,
+    crew_id INTEGER NOT NULL,
+    rule_set VARCHAR(20) NOT NULL,
+    violations JSONB NOT NULL,
+    reason TEXT NOT NULL,
+    overridden_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_ftl_overrides_crew_id ON ftl_overrides(crew_id);
+CREATE INDEX idx_crew_assignments_crew_checkin ON crew_assignments(crew_id, checkin_time);
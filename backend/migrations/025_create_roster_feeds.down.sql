@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_crew_assignment_cancellation ON crew_assignments;
+DROP FUNCTION IF EXISTS record_crew_assignment_cancellation();
+DROP TABLE IF EXISTS crew_assignment_cancellations;
+DROP TABLE IF EXISTS roster_feed_tokens;
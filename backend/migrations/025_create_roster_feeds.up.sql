@@ -0,0 +1,46 @@
+-- Secret subscription tokens for crew roster calendar feeds (stored hashed).
+CREATE TABLE IF NOT EXISTS roster_feed_tokens (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Deleted crew assignments, kept so calendar feeds can publish them as cancelled events.
+CREATE TABLE IF NOT EXISTS crew_assignment_cancellations (
+    assignment_id INTEGER PRIMARY KEY,
+    crew_id INTEGER NOT NULL,
+    flight_id INTEGER,
+    flight_number VARCHAR(10),
+    departure_code VARCHAR(5),
+    arrival_code VARCHAR(5),
+    crew_role VARCHAR(10),
+    in_function BOOLEAN,
+    pickup_time TIMESTAMP,
+    checkin_time TIMESTAMP,
+    checkout_time TIMESTAMP,
+    cancelled_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_crew_assignment_cancellations_crew ON crew_assignment_cancellations(crew_id, cancelled_at);
+
+-- Runs for direct deletes and for cascades from deleted flights or users alike.
+CREATE OR REPLACE FUNCTION record_crew_assignment_cancellation() RETURNS trigger AS $$
+BEGIN
+    IF OLD.crew_id IS NOT NULL THEN
+        INSERT INTO crew_assignment_cancellations (
+            assignment_id, crew_id, flight_id, flight_number, departure_code, arrival_code,
+            crew_role, in_function, pickup_time, checkin_time, checkout_time
+        )
+        SELECT OLD.id, OLD.crew_id, OLD.flight_id, f.flight_number, f.departure_code, f.arrival_code,
+               OLD.crew_role, OLD.in_function, OLD.pickup_time, OLD.checkin_time, OLD.checkout_time
+        FROM (SELECT 1) AS one
+        LEFT JOIN flights f ON f.id = OLD.flight_id
+        ON CONFLICT (assignment_id) DO NOTHING;
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_crew_assignment_cancellation
+    AFTER DELETE ON crew_assignments
+    FOR EACH ROW EXECUTE FUNCTION record_crew_assignment_cancellation();
@@ -0,0 +1,2 @@
+-- IATA aircraft type code (e.g. AT7), used to match type ratings on crew assignments.
+ALTER TABLE flights ADD COLUMN aircraft_type VARCHAR(3);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_qualifications;
+DROP TABLE IF EXISTS qualification_types;
@@ -0,0 +1,37 @@
+-- Qualification catalogue: type ratings, medicals, line checks, recurrent training...
+CREATE TABLE IF NOT EXISTS qualification_types (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(30) NOT NULL UNIQUE,            -- e.g. TR-AT7, MED-1, LC, SEP
+    name TEXT NOT NULL,
+    category VARCHAR(20) NOT NULL
+        CHECK (category IN ('type_rating', 'medical', 'line_check', 'recurrent_training', 'license', 'other')),
+    aircraft_type VARCHAR(3),                    -- only required on flights of this IATA type
+    required_for_roles TEXT[] NOT NULL DEFAULT '{}', -- crew roles (CDB, OPL, CCA...) that must hold it
+    validity_months INTEGER CHECK (validity_months > 0), -- default expiry for new records
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Qualifications held by users, with optional supporting document metadata.
+CREATE TABLE IF NOT EXISTS user_qualifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    qualification_type_id INTEGER NOT NULL REFERENCES qualification_types(id) ON DELETE RESTRICT,
+    reference TEXT,                              -- license / certificate number
+    issuing_authority TEXT,
+    issued_at DATE NOT NULL,
+    expires_at DATE,                             -- NULL = does not expire
+    notes TEXT,
+    document_name TEXT,
+    document_content_type TEXT,
+    document_size_bytes BIGINT CHECK (document_size_bytes >= 0),
+    document_sha256 VARCHAR(64),
+    document_url TEXT,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK (expires_at IS NULL OR expires_at >= issued_at)
+);
+
+CREATE INDEX idx_user_qualifications_user_type ON user_qualifications(user_id, qualification_type_id);
+CREATE INDEX idx_user_qualifications_expires_at ON user_qualifications(expires_at);
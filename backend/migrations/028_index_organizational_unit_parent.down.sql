@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_organizational_units_parent;
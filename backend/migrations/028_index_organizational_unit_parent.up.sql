@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_organizational_units_parent ON organizational_units(parent_id);
@@ -0,0 +1,14 @@
+-- Application modules granted to holders of a function and/or members of a unit (and the units below it).
+-- With both set, only holders of the function in that unit's subtree get the module.
+CREATE TABLE IF NOT EXISTS module_entitlements (
+    id SERIAL PRIMARY KEY,
+    module VARCHAR(50) NOT NULL,                 -- e.g. crew, flights, hr
+    function_id INTEGER REFERENCES functions(id) ON DELETE CASCADE,
+    unit_id INTEGER REFERENCES organizational_units(id) ON DELETE CASCADE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK (function_id IS NOT NULL OR unit_id IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX idx_module_entitlements_grant
+    ON module_entitlements(module, COALESCE(function_id, 0), COALESCE(unit_id, 0));
@@ -0,0 +1,36 @@
+-- Append-only, hash-chained log of administrative actions.
+-- hash = SHA-256 over prev_hash and the entry's fields (see audit.ComputeHash); prev_hash of the first entry is all zeros.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id BIGINT,                             -- no foreign key: entries outlive the users they name
+    actor_role TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,                        -- e.g. user.invite, policy.add, crew.unassign_flight
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL DEFAULT '',
+    before JSON,                                 -- JSON, not JSONB, so the hashed text is kept byte for byte
+    after JSON,
+    ip TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_id);
+CREATE INDEX idx_audit_log_action ON audit_log(action);
+CREATE INDEX idx_audit_log_target ON audit_log(target_type, target_id);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,8 @@
+-- Last policy synchronization of each API instance, written by access.PolicyWatcher.
+CREATE TABLE IF NOT EXISTS casbin_sync_status (
+    instance_id TEXT PRIMARY KEY,
+    listening BOOLEAN NOT NULL DEFAULT FALSE,    -- LISTEN connection currently up
+    last_sync_at TIMESTAMPTZ,                    -- last successful (re)load or applied notification
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now() -- heartbeat; stale rows belong to stopped instances
+);
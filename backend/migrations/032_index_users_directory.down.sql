@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_directory_email;
+DROP INDEX IF EXISTS idx_users_directory_name;
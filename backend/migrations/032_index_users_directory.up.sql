@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_users_directory_name ON users ((LOWER(COALESCE(full_name, ''))), id);
+CREATE INDEX IF NOT EXISTS idx_users_directory_email ON users ((LOWER(email)), id);
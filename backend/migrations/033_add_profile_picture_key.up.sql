@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_picture_key TEXT;
@@ -0,0 +1,7 @@
+-- Run `go run ./cmd/reencryptfields -decrypt` first; encrypted birth dates cannot be cast back.
+ALTER TABLE user_children ALTER COLUMN birth_date TYPE DATE USING birth_date::date;
+
+DROP INDEX IF EXISTS idx_users_national_id_bidx;
+ALTER TABLE users DROP COLUMN IF EXISTS national_id_bidx;
+
+DROP TABLE IF EXISTS field_encryption_keys;
@@ -0,0 +1,12 @@
+CREATE TABLE field_encryption_keys (
+    id TEXT PRIMARY KEY,
+    master_key_id TEXT NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE users ADD COLUMN national_id_bidx TEXT;
+CREATE INDEX idx_users_national_id_bidx ON users(national_id_bidx);
+
+-- Encrypted birth dates are stored as text.
+ALTER TABLE user_children ALTER COLUMN birth_date TYPE TEXT USING birth_date::text;
@@ -0,0 +1,6 @@
+ALTER TABLE crew_assignments DROP CONSTRAINT IF EXISTS crew_assignments_crew_id_fkey;
+ALTER TABLE crew_assignments
+ADD CONSTRAINT crew_assignments_crew_id_fkey
+FOREIGN KEY (crew_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS data_subject_requests;
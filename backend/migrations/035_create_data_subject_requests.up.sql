@@ -0,0 +1,26 @@
+-- Personal data exports and erasures requested by or for a user; both need an administrator's approval.
+CREATE TABLE IF NOT EXISTS data_subject_requests (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('export', 'erasure')),
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'completed')),
+    reason TEXT NOT NULL DEFAULT '',
+    requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    review_note TEXT NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,                      -- approved exports can be downloaded until then
+    completed_at TIMESTAMPTZ,                    -- erasure carried out
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One open request of each kind per user.
+CREATE UNIQUE INDEX uq_data_subject_requests_pending ON data_subject_requests(user_id, kind) WHERE status = 'pending';
+CREATE INDEX idx_data_subject_requests_status ON data_subject_requests(status, created_at);
+
+-- Erased users are anonymised in place; deleting a user must no longer wipe their flight history.
+ALTER TABLE crew_assignments DROP CONSTRAINT IF EXISTS crew_assignments_crew_id_fkey;
+ALTER TABLE crew_assignments
+ADD CONSTRAINT crew_assignments_crew_id_fkey
+FOREIGN KEY (crew_id) REFERENCES users(id) ON DELETE RESTRICT;
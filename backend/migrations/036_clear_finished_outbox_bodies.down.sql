@@ -0,0 +1,2 @@
+-- Cleared bodies cannot be restored.
+SELECT 1;
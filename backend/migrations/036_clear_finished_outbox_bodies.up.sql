@@ -0,0 +1,2 @@
+-- Bodies of delivered or abandoned emails may still hold live confirmation or reset tokens.
+UPDATE email_outbox SET html_body = '' WHERE status IN ('sent', 'failed');
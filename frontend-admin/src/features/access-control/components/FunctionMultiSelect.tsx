@@ -1,6 +1,6 @@
 'use client';
 
-const FUNCTIONS = ['planner', 'auditor', 'admin'];
+import { useFunctions } from '../hooks/useFunctions';
 
 export default function FunctionMultiSelect({
   selected,
@@ -9,6 +9,8 @@ export default function FunctionMultiSelect({
   selected: string[];
   onChange: (newList: string[]) => void;
 }) {
+  const { data: functions = [], isLoading, isError } = useFunctions();
+
   const toggle = (role: string) => {
     if (selected.includes(role)) {
       onChange(selected.filter((r) => r !== role));
@@ -17,16 +19,19 @@ export default function FunctionMultiSelect({
     }
   };
 
+  if (isLoading) return <p className="text-sm text-gray-500">Loading functions…</p>;
+  if (isError) return <p className="text-sm text-red-600">Could not load functions</p>;
+
   return (
     <div className="flex gap-4 flex-wrap">
-      {FUNCTIONS.map((role) => (
-        <label key={role} className="flex items-center gap-2">
+      {functions.map((fn) => (
+        <label key={fn.id} className="flex items-center gap-2" title={fn.description ?? undefined}>
           <input
             type="checkbox"
-            checked={selected.includes(role)}
-            onChange={() => toggle(role)}
+            checked={selected.includes(fn.name)}
+            onChange={() => toggle(fn.name)}
           />
-          {role}
+          {fn.name}
         </label>
       ))}
     </div>
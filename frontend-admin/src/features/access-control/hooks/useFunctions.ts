@@ -0,0 +1,17 @@
+import { useQuery } from '@tanstack/react-query';
+
+const API_BASE = '/api/v1/functions';
+
+export type Rank = { id: number; function_id: number; name: string; level: number | null };
+export type OrgFunction = { id: number; name: string; description: string | null; ranks: Rank[] };
+
+export function useFunctions() {
+  return useQuery<OrgFunction[]>({
+    queryKey: ['functions'],
+    queryFn: async () => {
+      const res = await fetch(API_BASE);
+      if (!res.ok) throw new Error('Failed to load functions');
+      return res.json();
+    },
+  });
+}